@@ -1,11 +1,13 @@
 package rest
 
 import (
+	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/abcfe/abcfe-node/api"
@@ -44,7 +46,7 @@ func GetStatus(bc *core.BlockChain) http.HandlerFunc {
 		// Mempool size
 		mempoolSize := 0
 		if bc != nil && bc.Mempool != nil {
-			mempoolSize = len(bc.Mempool.GetTxs())
+			mempoolSize = bc.Mempool.GetTxCount()
 		}
 
 		response := map[string]interface{}{
@@ -181,8 +183,15 @@ func GetAddressUtxo(bc *core.BlockChain) http.HandlerFunc {
 			return
 		}
 
+		locked, err := bc.GetLockedUtxoList(address)
+		if err != nil {
+			sendResp(w, http.StatusInternalServerError, nil, err)
+			return
+		}
+
 		response := map[string]interface{}{
-			"utxos": formatUtxoResp(utxos),
+			"utxos":  formatUtxoResp(utxos),
+			"locked": formatUtxoResp(locked), // Timelocked, not spendable yet
 		}
 		sendResp(w, http.StatusOK, response, nil)
 	}
@@ -206,15 +215,37 @@ func GetBalanceByUtxo(bc *core.BlockChain) http.HandlerFunc {
 			return
 		}
 
+		lockedBalance, err := bc.GetLockedBalance(address)
+		if err != nil {
+			sendResp(w, http.StatusInternalServerError, nil, err)
+			return
+		}
+
+		stakedBalance, err := bc.GetStakedBalance(address)
+		if err != nil {
+			sendResp(w, http.StatusInternalServerError, nil, err)
+			return
+		}
+
+		delegatedBalance, err := bc.GetDelegatedBalance(address)
+		if err != nil {
+			sendResp(w, http.StatusInternalServerError, nil, err)
+			return
+		}
+
 		response := map[string]interface{}{
-			"address": addrStr,
-			"balance": balance,
+			"address":   addrStr,
+			"balance":   balance,          // Spendable
+			"locked":    lockedBalance,    // Timelocked (including unbonding)
+			"staked":    stakedBalance,    // Staked
+			"delegated": delegatedBalance, // Delegated to validators
 		}
 		sendResp(w, http.StatusOK, response, nil)
 	}
 }
 
 // get address transactions response
+// query params: fromHeight, toHeight (default latest), cursor, limit (default 20), order (asc/desc, default desc)
 func GetAddressTransactions(bc *core.BlockChain) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		vars := mux.Vars(r)
@@ -226,7 +257,36 @@ func GetAddressTransactions(bc *core.BlockChain) http.HandlerFunc {
 			return
 		}
 
-		txInfos, err := bc.GetAddressTransactions(address)
+		// Parse query parameters
+		query := core.AddressTxQuery{
+			Cursor: r.URL.Query().Get("cursor"),
+			Desc:   r.URL.Query().Get("order") != "asc", // Default desc (newest first)
+		}
+		if fromStr := r.URL.Query().Get("fromHeight"); fromStr != "" {
+			if query.FromHeight, err = strconv.ParseUint(fromStr, 10, 64); err != nil {
+				sendResp(w, http.StatusBadRequest, nil, fmt.Errorf("invalid fromHeight: %w", err))
+				return
+			}
+		}
+		if toStr := r.URL.Query().Get("toHeight"); toStr != "" {
+			if query.ToHeight, err = strconv.ParseUint(toStr, 10, 64); err != nil {
+				sendResp(w, http.StatusBadRequest, nil, fmt.Errorf("invalid toHeight: %w", err))
+				return
+			}
+		}
+		if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+			if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= core.MaxAddressTxLimit {
+				query.Limit = l
+			}
+		}
+		if query.Cursor != "" {
+			if _, _, err := core.ParseAddressTxCursor(query.Cursor); err != nil {
+				sendResp(w, http.StatusBadRequest, nil, err)
+				return
+			}
+		}
+
+		txInfos, nextCursor, err := bc.GetAddressTransactions(address, query)
 		if err != nil {
 			sendResp(w, http.StatusInternalServerError, nil, err)
 			return
@@ -235,6 +295,7 @@ func GetAddressTransactions(bc *core.BlockChain) http.HandlerFunc {
 		response := map[string]interface{}{
 			"address":      addrStr,
 			"transactions": formatAddressTxInfos(txInfos),
+			"nextCursor":   nextCursor,
 		}
 		sendResp(w, http.StatusOK, response, nil)
 	}
@@ -254,7 +315,7 @@ func SubmitTransferTx(bc *core.BlockChain) http.HandlerFunc {
 			return
 		}
 
-		to, err := utils.StringToAddress(req.To)
+		recipients, err := parseRecipients(req.To, req.Amount, req.Recipients)
 		if err != nil {
 			sendResp(w, http.StatusBadRequest, nil, err)
 			return
@@ -268,7 +329,7 @@ func SubmitTransferTx(bc *core.BlockChain) http.HandlerFunc {
 
 		txType := core.TxTypeGeneral // General transaction
 
-		if err := bc.SubmitTx(from, to, req.Amount, fee, req.Memo, req.Data, txType); err != nil {
+		if err := bc.SubmitMultiTx(from, recipients, fee, req.Memo, req.Data, txType); err != nil {
 			sendResp(w, http.StatusInternalServerError, nil, err)
 			return
 		}
@@ -307,7 +368,7 @@ func ComposeAndAddBlock(bc *core.BlockChain) http.HandlerFunc {
 func GetMempoolList(bc *core.BlockChain) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 
-		mempoolTxs := bc.Mempool.GetTxs()
+		mempoolTxs := bc.Mempool.GetAllTxs()
 
 		response := formatTxsResp(mempoolTxs, bc)
 
@@ -315,6 +376,93 @@ func GetMempoolList(bc *core.BlockChain) http.HandlerFunc {
 	}
 }
 
+// GetMempoolStatus returns mempool size, limits and eviction counters
+func GetMempoolStatus(bc *core.BlockChain) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sendResp(w, http.StatusOK, bc.Mempool.GetStats(), nil)
+	}
+}
+
+// GetBlockTemplate returns mempool txs selected for the next block and why other txs were left out
+func GetBlockTemplate(bc *core.BlockChain) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		latestHeight, err := bc.GetLatestHeight()
+		if err != nil {
+			sendResp(w, http.StatusInternalServerError, nil, err)
+			return
+		}
+
+		template := bc.BuildBlockTemplate(latestHeight+1, time.Now().Unix())
+
+		response := BlockTemplateResp{
+			Height:    template.Height,
+			TxCount:   len(template.Included),
+			TotalFees: template.TotalFees,
+			Size:      template.Size,
+			MaxSize:   template.MaxSize,
+			MaxTxs:    template.MaxTxs,
+			Included:  formatTemplateTxsResp(template.Included),
+			Skipped:   formatTemplateTxsResp(template.Skipped),
+		}
+
+		sendResp(w, http.StatusOK, response, nil)
+	}
+}
+
+func formatTemplateTxsResp(txs []core.TemplateTx) []TemplateTxResp {
+	result := make([]TemplateTxResp, len(txs))
+	for i, tx := range txs {
+		result[i] = TemplateTxResp{
+			TxID:    utils.HashToString(tx.TxID),
+			Fee:     tx.Fee,
+			Size:    tx.Size,
+			FeeRate: tx.FeeRate(),
+			Reason:  tx.Reason,
+		}
+	}
+	return result
+}
+
+// GetFeeEstimate returns suggested fees for next block, within 3 blocks and economy targets
+// Query: size (tx size in bytes, default typical 1 input / 2 output tx)
+func GetFeeEstimate(bc *core.BlockChain) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var txSize uint64
+		if sizeStr := r.URL.Query().Get("size"); sizeStr != "" {
+			size, err := strconv.ParseUint(sizeStr, 10, 64)
+			if err != nil {
+				sendResp(w, http.StatusBadRequest, nil, fmt.Errorf("invalid size: %w", err))
+				return
+			}
+			txSize = size
+		}
+
+		estimate := bc.EstimateFees(txSize)
+
+		response := FeeEstimateResp{
+			NextBlock:     formatFeeSuggestionResp(estimate.NextBlock),
+			Within3Blocks: formatFeeSuggestionResp(estimate.Within3Blocks),
+			Economy:       formatFeeSuggestionResp(estimate.Economy),
+			TxSize:        estimate.TxSize,
+			MinFee:        estimate.MinFee,
+			MempoolTxs:    estimate.MempoolTxs,
+			MempoolBytes:  estimate.MempoolBytes,
+			RecentBlocks:  estimate.RecentBlocks,
+			FullBlocks:    estimate.FullBlocks,
+		}
+
+		sendResp(w, http.StatusOK, response, nil)
+	}
+}
+
+func formatFeeSuggestionResp(suggestion core.FeeSuggestion) FeeSuggestionResp {
+	return FeeSuggestionResp{
+		TargetBlocks: suggestion.TargetBlocks,
+		FeeRate:      suggestion.FeeRate,
+		Fee:          suggestion.Fee,
+	}
+}
+
 // send response
 func sendResp(w http.ResponseWriter, statusCode int, data interface{}, err error) {
 	w.Header().Set("Content-Type", "application/json")
@@ -387,12 +535,16 @@ func formatTxResp(tx *core.Transaction, bc *core.BlockChain) TxResp {
 func formatTxInputsResp(inputs []*core.TxInput) []interface{} {
 	result := make([]interface{}, len(inputs))
 	for i, input := range inputs {
-		result[i] = map[string]interface{}{
+		resp := map[string]interface{}{
 			"txid":        utils.HashToString(input.TxID),
 			"outputIndex": input.OutputIndex,
 			"signature":   utils.SignatureToString(input.Signature),
 			"publicKey":   input.PublicKey,
 		}
+		if len(input.Preimage) > 0 {
+			resp["preimage"] = hex.EncodeToString(input.Preimage) // Revealed by HTLC claim
+		}
+		result[i] = resp
 	}
 	return result
 }
@@ -401,11 +553,13 @@ func formatTxInputsResp(inputs []*core.TxInput) []interface{} {
 func formatTxOutputsResp(outputs []*core.TxOutput) []interface{} {
 	result := make([]interface{}, len(outputs))
 	for i, output := range outputs {
-		result[i] = map[string]interface{}{
+		resp := map[string]interface{}{
 			"address": utils.AddressToString(output.Address),
 			"amount":  output.Amount,
 			"txType":  output.TxType,
 		}
+		addTimelockResp(resp, output)
+		result[i] = resp
 	}
 	return result
 }
@@ -422,26 +576,56 @@ func formatTxOutputsRespWithSpent(txID prt.Hash, outputs []*core.TxOutput, bc *c
 			isSpent = utxo.Spent
 		}
 
-		result[i] = map[string]interface{}{
+		resp := map[string]interface{}{
 			"address": utils.AddressToString(output.Address),
 			"amount":  output.Amount,
 			"txType":  output.TxType,
 			"spent":   isSpent,
 		}
+		addTimelockResp(resp, output)
+		result[i] = resp
 	}
 	return result
 }
 
+// addTimelockResp adds lock conditions of output to response (only the ones set)
+func addTimelockResp(resp map[string]interface{}, output *core.TxOutput) {
+	if output.LockHeight > 0 {
+		resp["lockHeight"] = output.LockHeight
+	}
+	if output.LockTime > 0 {
+		resp["lockTime"] = output.LockTime
+	}
+	if output.RelativeLockHeight > 0 {
+		resp["relativeLockHeight"] = output.RelativeLockHeight
+	}
+	if output.HTLC != nil {
+		resp["htlc"] = formatHashLockResp(output.HTLC)
+	}
+}
+
+// formatHashLockResp get hash lock response
+func formatHashLockResp(lock *core.HashLock) map[string]interface{} {
+	return map[string]interface{}{
+		"hash":      utils.HashToString(lock.Hash),
+		"recipient": utils.AddressToString(lock.Recipient),
+		"refund":    utils.AddressToString(lock.Refund),
+		"expiry":    lock.Expiry,
+	}
+}
+
 func formatUtxoResp(utxos []*core.UTXO) []interface{} {
 	result := make([]interface{}, len(utxos))
 	for i, utxo := range utxos {
-		result[i] = map[string]interface{}{
+		resp := map[string]interface{}{
 			"txId":        utils.HashToString(utxo.TxId),
 			"outputIndex": utxo.OutputIndex,
 			"amount":      utxo.TxOut.Amount,
 			"address":     utils.AddressToString(utxo.TxOut.Address),
 			"height":      utxo.Height,
 		}
+		addTimelockResp(resp, &utxo.TxOut)
+		result[i] = resp
 	}
 	return result
 }
@@ -457,6 +641,10 @@ func formatAddressTxInfos(txInfos []*core.AddressTxInfo) []interface{} {
 			"height":    info.Height,
 			"spent":     info.Spent,
 			"index":     info.Index,
+			"received":  info.Received,
+			"sent":      info.Sent,
+			"netAmount": info.NetAmount,
+			"txIndex":   info.TxIndex,
 		}
 	}
 	return result
@@ -492,9 +680,9 @@ func SubmitSignedTx(bc *core.BlockChain, p2pService *p2p.P2PService) http.Handle
 			return
 		}
 
-		// Add to mempool
-		if err := bc.Mempool.NewTranaction(tx); err != nil {
-			sendResp(w, http.StatusInternalServerError, nil, err)
+		// Add to mempool (replaces conflicting RBF txs with lower fee)
+		if _, err := bc.AddTxToMempool(tx); err != nil {
+			sendResp(w, http.StatusBadRequest, nil, err)
 			return
 		}
 
@@ -538,9 +726,9 @@ func SendTxWithWallet(bc *core.BlockChain, wm *wallet.WalletManager, p2pService
 		account := accounts[req.AccountIndex]
 		from := account.Address
 
-		to, err := utils.StringToAddress(req.To)
+		recipients, err := parseRecipients(req.To, req.Amount, req.Recipients)
 		if err != nil {
-			sendResp(w, http.StatusBadRequest, nil, fmt.Errorf("invalid to address: %w", err))
+			sendResp(w, http.StatusBadRequest, nil, err)
 			return
 		}
 
@@ -550,16 +738,17 @@ func SendTxWithWallet(bc *core.BlockChain, wm *wallet.WalletManager, p2pService
 			fee = bc.GetMinFee()
 		}
 
-		// Create signed transaction (including fee)
-		tx, err := bc.CreateSignedTx(from, to, req.Amount, fee, req.Memo, req.Data, core.TxTypeGeneral, account.PrivateKey, account.PublicKey)
+		// Create signed transaction (including fee, one output per recipient)
+		opts := core.TxBuildOptions{Replaceable: req.Replaceable, CoinSelection: req.CoinSelection}
+		tx, selection, err := bc.BuildSignedTx(from, recipients, fee, req.Memo, req.Data, core.TxTypeGeneral, opts, account.PrivateKey, account.PublicKey)
 		if err != nil {
 			sendResp(w, http.StatusInternalServerError, nil, fmt.Errorf("failed to create signed tx: %w", err))
 			return
 		}
 
-		// Add to mempool
-		if err := bc.Mempool.NewTranaction(tx); err != nil {
-			sendResp(w, http.StatusInternalServerError, nil, err)
+		// Add to mempool (replaces conflicting RBF txs with lower fee)
+		if _, err := bc.AddTxToMempool(tx); err != nil {
+			sendResp(w, http.StatusBadRequest, nil, err)
 			return
 		}
 
@@ -573,14 +762,150 @@ func SendTxWithWallet(bc *core.BlockChain, wm *wallet.WalletManager, p2pService
 			}
 		}
 
-		sendResp(w, http.StatusOK, map[string]string{
-			"txId": utils.HashToString(tx.ID),
-			"from": utils.AddressToString(from),
-			"to":   req.To,
+		sendResp(w, http.StatusOK, map[string]interface{}{
+			"txId":          utils.HashToString(tx.ID),
+			"from":          utils.AddressToString(from),
+			"to":            req.To,
+			"recipients":    len(recipients),
+			"fee":           fee + selection.Excess, // Surplus too small for change is added to fee
+			"coinSelection": selection.Strategy,
+			"inputs":        formatUtxoResp(selection.Utxos),
+			"change":        selection.Change,
+		}, nil)
+	}
+}
+
+// BumpTxFee replaces a pending replaceable tx of the server wallet with a higher fee version
+func BumpTxFee(bc *core.BlockChain, wm *wallet.WalletManager, p2pService *p2p.P2PService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req BumpFeeReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendResp(w, http.StatusBadRequest, nil, err)
+			return
+		}
+
+		if wm == nil || wm.Wallet == nil {
+			sendResp(w, http.StatusInternalServerError, nil, fmt.Errorf("wallet not initialized"))
+			return
+		}
+
+		accounts := wm.Wallet.Accounts
+		if req.AccountIndex < 0 || req.AccountIndex >= len(accounts) {
+			sendResp(w, http.StatusBadRequest, nil, fmt.Errorf("invalid account index: %d", req.AccountIndex))
+			return
+		}
+		account := accounts[req.AccountIndex]
+
+		txId, err := utils.StringToHash(req.TxID)
+		if err != nil {
+			sendResp(w, http.StatusBadRequest, nil, fmt.Errorf("invalid txId: %w", err))
+			return
+		}
+
+		tx, err := bc.BumpTxFee(txId, req.Fee, account.PrivateKey, account.PublicKey)
+		if err != nil {
+			sendResp(w, http.StatusBadRequest, nil, fmt.Errorf("failed to create replacement tx: %w", err))
+			return
+		}
+
+		replaced, err := bc.AddTxToMempool(tx)
+		if err != nil {
+			sendResp(w, http.StatusBadRequest, nil, err)
+			return
+		}
+
+		// Broadcast replacement to P2P network
+		if p2pService != nil {
+			if err := p2pService.BroadcastTx(tx); err != nil {
+				fmt.Printf("[API] Failed to broadcast tx: %v\n", err)
+			} else {
+				fmt.Printf("[API] Broadcasted replacement tx: %s\n", utils.HashToString(tx.ID))
+			}
+		}
+
+		replacedIds := make([]string, len(replaced))
+		for i, replacedTx := range replaced {
+			replacedIds[i] = utils.HashToString(replacedTx.ID)
+		}
+
+		sendResp(w, http.StatusOK, map[string]interface{}{
+			"txId":     utils.HashToString(tx.ID),
+			"replaced": replacedIds,
+			"fee":      req.Fee,
 		}, nil)
 	}
 }
 
+// SweepWallet consolidates small UTXOs of a server wallet account into larger outputs
+func SweepWallet(bc *core.BlockChain, wm *wallet.WalletManager, p2pService *p2p.P2PService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req SweepReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendResp(w, http.StatusBadRequest, nil, err)
+			return
+		}
+
+		if wm == nil || wm.Wallet == nil {
+			sendResp(w, http.StatusInternalServerError, nil, fmt.Errorf("wallet not initialized"))
+			return
+		}
+
+		accounts := wm.Wallet.Accounts
+		if req.AccountIndex < 0 || req.AccountIndex >= len(accounts) {
+			sendResp(w, http.StatusBadRequest, nil, fmt.Errorf("invalid account index: %d", req.AccountIndex))
+			return
+		}
+		account := accounts[req.AccountIndex]
+
+		opts := core.SweepOptions{
+			MaxInputsPerTx: req.MaxInputsPerTx,
+			MaxTxs:         req.MaxTxs,
+			MaxUtxoAmount:  req.MaxUtxoAmount,
+			Fee:            req.Fee,
+		}
+		if req.To != "" {
+			to, err := utils.StringToAddress(req.To)
+			if err != nil {
+				sendResp(w, http.StatusBadRequest, nil, fmt.Errorf("invalid to address: %w", err))
+				return
+			}
+			opts.To = to
+		}
+
+		// Txs added before an error are already in mempool and are still broadcast
+		result, sweepErr := bc.SweepUtxos(account.Address, opts, account.PrivateKey, account.PublicKey)
+		if result == nil {
+			sendResp(w, http.StatusBadRequest, nil, sweepErr)
+			return
+		}
+
+		txIds := make([]string, len(result.Txs))
+		for i, tx := range result.Txs {
+			txIds[i] = utils.HashToString(tx.ID)
+			if p2pService != nil {
+				if err := p2pService.BroadcastTx(tx); err != nil {
+					fmt.Printf("[API] Failed to broadcast tx: %v\n", err)
+				} else {
+					fmt.Printf("[API] Broadcasted sweep tx: %s\n", txIds[i])
+				}
+			}
+		}
+
+		status := http.StatusOK
+		if sweepErr != nil {
+			status = http.StatusInternalServerError
+		}
+		sendResp(w, status, map[string]interface{}{
+			"address":   utils.AddressToString(account.Address),
+			"txIds":     txIds,
+			"swept":     result.Swept,
+			"amount":    result.Amount,
+			"fees":      result.Fees,
+			"remaining": result.Remaining,
+		}, sweepErr)
+	}
+}
+
 // GetWalletAccounts gets wallet account list
 func GetWalletAccounts(wm *wallet.WalletManager) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -593,9 +918,10 @@ func GetWalletAccounts(wm *wallet.WalletManager) http.HandlerFunc {
 		result := make([]WalletAccountResp, len(accounts))
 		for i, acc := range accounts {
 			result[i] = WalletAccountResp{
-				Index:   acc.Index,
-				Address: utils.AddressToString(acc.Address),
-				Path:    acc.Path,
+				Index:     acc.Index,
+				Address:   utils.AddressToString(acc.Address),
+				Path:      acc.Path,
+				PublicKey: hex.EncodeToString(acc.PublicKey),
 			}
 		}
 
@@ -631,6 +957,37 @@ func CreateNewAccount(wm *wallet.WalletManager) http.HandlerFunc {
 	}
 }
 
+// parseRecipients converts single to/amount or recipient list of a transfer request
+func parseRecipients(to string, amount uint64, reqs []RecipientReq) ([]core.TxRecipient, error) {
+	if len(reqs) == 0 {
+		addr, err := utils.StringToAddress(to)
+		if err != nil {
+			return nil, fmt.Errorf("invalid to address: %w", err)
+		}
+		return []core.TxRecipient{{Address: addr, Amount: amount}}, nil
+	}
+
+	if to != "" || amount != 0 {
+		return nil, fmt.Errorf("use either to/amount or recipients, not both")
+	}
+
+	recipients := make([]core.TxRecipient, 0, len(reqs))
+	for i, req := range reqs {
+		addr, err := utils.StringToAddress(req.Address)
+		if err != nil {
+			return nil, fmt.Errorf("invalid recipient[%d] address: %w", i, err)
+		}
+		recipients = append(recipients, core.TxRecipient{
+			Address:            addr,
+			Amount:             req.Amount,
+			LockHeight:         req.LockHeight,
+			LockTime:           req.LockTime,
+			RelativeLockHeight: req.RelativeLockHeight,
+		})
+	}
+	return recipients, nil
+}
+
 // convertSignedTxReqToTx converts request to Transaction
 func convertSignedTxReqToTx(req *SubmitSignedTxReq, networkID string) (*core.Transaction, error) {
 	// Parse signatures and public keys first (for later use)
@@ -659,10 +1016,17 @@ func convertSignedTxReqToTx(req *SubmitSignedTxReq, networkID string) (*core.Tra
 			return nil, fmt.Errorf("invalid txId in input[%d]: %w", i, err)
 		}
 
+		preimage, err := hex.DecodeString(in.Preimage)
+		if err != nil {
+			return nil, fmt.Errorf("invalid preimage in input[%d]: %w", i, err)
+		}
+
 		inputs[i] = &core.TxInput{
 			TxID:        txID,
 			OutputIndex: in.OutputIndex,
 			PublicKey:   publicKeys[i],
+			Sequence:    in.Sequence,
+			Preimage:    preimage,
 			// Signature is NOT set here - will be added AFTER TX ID calculation
 		}
 	}
@@ -675,9 +1039,19 @@ func convertSignedTxReqToTx(req *SubmitSignedTxReq, networkID string) (*core.Tra
 		}
 
 		outputs[i] = &core.TxOutput{
-			Address: addr,
-			Amount:  out.Amount,
-			TxType:  out.TxType,
+			Address:            addr,
+			Amount:             out.Amount,
+			TxType:             out.TxType,
+			LockHeight:         out.LockHeight,
+			LockTime:           out.LockTime,
+			RelativeLockHeight: out.RelativeLockHeight,
+		}
+		if out.HTLC != nil {
+			lock, err := parseHashLockReq(out.HTLC)
+			if err != nil {
+				return nil, fmt.Errorf("invalid htlc in output[%d]: %w", i, err)
+			}
+			outputs[i].HTLC = lock
 		}
 	}
 
@@ -813,8 +1187,7 @@ func GetNetworkStats(bc *core.BlockChain, hub *api.WSHub) http.HandlerFunc {
 		latestHash, _ := bc.GetLatestBlockHash()
 
 		// Mempool info
-		mempoolTxs := bc.Mempool.GetTxs()
-		mempoolCount := len(mempoolTxs)
+		mempoolCount := bc.Mempool.GetTxCount()
 
 		// WebSocket connection count
 		wsClients := 0
@@ -880,9 +1253,23 @@ func GetConsensusStatus(cons *consensus.Consensus, consEngine *consensus.Consens
 			"votingPower":   votingPower,
 		}
 
+		// Epoch of the current validator set
+		if cons.Epoch != nil {
+			status["epoch"] = cons.Epoch.Number
+			status["epochStartHeight"] = cons.Epoch.StartHeight
+		}
+
 		// Add vote progress if ConsensusEngine is available
 		if consEngine != nil {
 			status["voteProgress"] = consEngine.GetVoteProgress()
+
+			// Lock of the height being decided (-1 if none)
+			engineStatus := consEngine.GetStatus()
+			status["lockedRound"] = engineStatus["lockedRound"]
+			status["validRound"] = engineStatus["validRound"]
+
+			// Phase durations and round timeout of the network
+			status["timing"] = engineStatus["timing"]
 		}
 
 		sendResp(w, http.StatusOK, status, nil)
@@ -939,3 +1326,1152 @@ func GetP2PStatus(p2pService *p2p.P2PService) http.HandlerFunc {
 		sendResp(w, http.StatusOK, status, nil)
 	}
 }
+
+// parseMultisigScript converts hex key set request to canonical multisig script
+func parseMultisigScript(req *MultisigScriptReq) (*core.MultiSigScript, error) {
+	keys := make([][]byte, len(req.PublicKeys))
+	for i, keyHex := range req.PublicKeys {
+		key, err := hex.DecodeString(strings.TrimPrefix(keyHex, "0x"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid public key %d: %w", i, err)
+		}
+		keys[i] = key
+	}
+	return core.NewMultiSigScript(req.Threshold, keys)
+}
+
+// formatMultisigTxResp encodes multisig tx with its signature status
+func formatMultisigTxResp(tx *core.Transaction) (*MultisigTxResp, error) {
+	txHex, err := core.EncodeTxHex(tx)
+	if err != nil {
+		return nil, err
+	}
+	status := core.GetMultisigStatus(tx)
+	return &MultisigTxResp{
+		TxID:     utils.HashToString(tx.ID),
+		Tx:       txHex,
+		Signed:   status.Signed,
+		Required: status.Required,
+		Complete: status.Complete,
+	}, nil
+}
+
+// GetMultisigAddress derives m-of-n multisig address of a key set
+func GetMultisigAddress() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req MultisigScriptReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendResp(w, http.StatusBadRequest, nil, err)
+			return
+		}
+
+		script, err := parseMultisigScript(&req)
+		if err != nil {
+			sendResp(w, http.StatusBadRequest, nil, err)
+			return
+		}
+		address, err := script.Address()
+		if err != nil {
+			sendResp(w, http.StatusBadRequest, nil, err)
+			return
+		}
+
+		sortedKeys := make([]string, len(script.PublicKeys))
+		for i, key := range script.PublicKeys {
+			sortedKeys[i] = hex.EncodeToString(key)
+		}
+		sendResp(w, http.StatusOK, map[string]interface{}{
+			"address":    utils.AddressToString(address),
+			"threshold":  script.Threshold,
+			"publicKeys": sortedKeys, // Signature slot order
+		}, nil)
+	}
+}
+
+// CreateMultisigTx builds unsigned tx spending from a multisig address
+func CreateMultisigTx(bc *core.BlockChain) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req MultisigTxReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendResp(w, http.StatusBadRequest, nil, err)
+			return
+		}
+
+		script, err := parseMultisigScript(&req.MultisigScriptReq)
+		if err != nil {
+			sendResp(w, http.StatusBadRequest, nil, err)
+			return
+		}
+		recipients, err := parseRecipients("", 0, req.Recipients)
+		if err != nil {
+			sendResp(w, http.StatusBadRequest, nil, err)
+			return
+		}
+
+		fee := req.Fee
+		if fee == 0 {
+			fee = bc.GetMinFee()
+		}
+
+		tx, _, err := bc.CreateMultisigTx(script, recipients, fee, req.Memo, req.Data, core.TxTypeGeneral)
+		if err != nil {
+			sendResp(w, http.StatusBadRequest, nil, fmt.Errorf("failed to create multisig tx: %w", err))
+			return
+		}
+
+		resp, err := formatMultisigTxResp(tx)
+		if err != nil {
+			sendResp(w, http.StatusInternalServerError, nil, err)
+			return
+		}
+		sendResp(w, http.StatusOK, resp, nil)
+	}
+}
+
+// SignMultisigTx adds signature of a server wallet account to a multisig tx
+func SignMultisigTx(wm *wallet.WalletManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req MultisigSignReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendResp(w, http.StatusBadRequest, nil, err)
+			return
+		}
+
+		if wm == nil || wm.Wallet == nil {
+			sendResp(w, http.StatusInternalServerError, nil, fmt.Errorf("wallet not initialized"))
+			return
+		}
+		accounts := wm.Wallet.Accounts
+		if req.AccountIndex < 0 || req.AccountIndex >= len(accounts) {
+			sendResp(w, http.StatusBadRequest, nil, fmt.Errorf("invalid account index: %d", req.AccountIndex))
+			return
+		}
+		account := accounts[req.AccountIndex]
+
+		tx, err := core.DecodeTxHex(req.Tx)
+		if err != nil {
+			sendResp(w, http.StatusBadRequest, nil, err)
+			return
+		}
+		if _, err := core.SignMultisigTx(tx, account.PrivateKey, account.PublicKey); err != nil {
+			sendResp(w, http.StatusBadRequest, nil, err)
+			return
+		}
+
+		resp, err := formatMultisigTxResp(tx)
+		if err != nil {
+			sendResp(w, http.StatusInternalServerError, nil, err)
+			return
+		}
+		sendResp(w, http.StatusOK, resp, nil)
+	}
+}
+
+// CombineMultisigTxs merges signatures collected by different parties
+func CombineMultisigTxs() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req MultisigCombineReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendResp(w, http.StatusBadRequest, nil, err)
+			return
+		}
+
+		txs := make([]*core.Transaction, len(req.Txs))
+		for i, txHex := range req.Txs {
+			tx, err := core.DecodeTxHex(txHex)
+			if err != nil {
+				sendResp(w, http.StatusBadRequest, nil, fmt.Errorf("tx %d: %w", i, err))
+				return
+			}
+			txs[i] = tx
+		}
+
+		combined, err := core.CombineMultisigTxs(txs)
+		if err != nil {
+			sendResp(w, http.StatusBadRequest, nil, err)
+			return
+		}
+
+		resp, err := formatMultisigTxResp(combined)
+		if err != nil {
+			sendResp(w, http.StatusInternalServerError, nil, err)
+			return
+		}
+		sendResp(w, http.StatusOK, resp, nil)
+	}
+}
+
+// SubmitMultisigTx validates fully signed multisig tx, adds it to mempool and broadcasts it
+func SubmitMultisigTx(bc *core.BlockChain, p2pService *p2p.P2PService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req MultisigSubmitReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendResp(w, http.StatusBadRequest, nil, err)
+			return
+		}
+
+		tx, err := core.DecodeTxHex(req.Tx)
+		if err != nil {
+			sendResp(w, http.StatusBadRequest, nil, err)
+			return
+		}
+		if status := core.GetMultisigStatus(tx); !status.Complete {
+			sendResp(w, http.StatusBadRequest, nil, fmt.Errorf("multisig tx is not fully signed: signed %v, required %v", status.Signed, status.Required))
+			return
+		}
+
+		if err := addAndBroadcastTx(bc, p2pService, tx); err != nil {
+			sendResp(w, http.StatusBadRequest, nil, err)
+			return
+		}
+
+		sendResp(w, http.StatusOK, map[string]string{
+			"txId": utils.HashToString(tx.ID),
+		}, nil)
+	}
+}
+
+// addAndBroadcastTx adds externally signed tx to mempool and broadcasts it
+func addAndBroadcastTx(bc *core.BlockChain, p2pService *p2p.P2PService, tx *core.Transaction) error {
+	if _, err := bc.AddTxToMempool(tx); err != nil {
+		return err
+	}
+
+	if p2pService != nil {
+		if err := p2pService.BroadcastTx(tx); err != nil {
+			// Already in local mempool, log only
+			fmt.Printf("[API] Failed to broadcast tx: %v\n", err)
+		} else {
+			fmt.Printf("[API] Broadcasted tx: %s\n", utils.HashToString(tx.ID))
+		}
+	}
+	return nil
+}
+
+// formatPartialTxResp encodes partial tx with its amounts and signature status
+func formatPartialTxResp(ptx *core.PartialTx) (*PartialTxResp, error) {
+	encoded, err := core.EncodePartialTx(ptx)
+	if err != nil {
+		return nil, err
+	}
+	inputTotal, err := ptx.InputTotal()
+	if err != nil {
+		return nil, err
+	}
+	fee, err := ptx.Fee()
+	if err != nil {
+		return nil, err
+	}
+
+	inputs := make([]interface{}, len(ptx.Inputs))
+	for i, spent := range ptx.Inputs {
+		resp := map[string]interface{}{
+			"txId":        utils.HashToString(spent.TxID),
+			"outputIndex": spent.OutputIndex,
+			"amount":      spent.Output.Amount,
+			"address":     utils.AddressToString(spent.Output.Address),
+			"height":      spent.Height,
+		}
+		if ptx.Tx.Inputs[i].MultiSig != nil {
+			resp["multisig"] = fmt.Sprintf("%d-of-%d", ptx.Tx.Inputs[i].MultiSig.Threshold, len(ptx.Tx.Inputs[i].MultiSig.PublicKeys))
+		}
+		addTimelockResp(resp, &spent.Output)
+		inputs[i] = resp
+	}
+
+	status := ptx.Status()
+	return &PartialTxResp{
+		TxID:       utils.HashToString(ptx.Tx.ID),
+		Psbt:       encoded,
+		Inputs:     inputs,
+		Outputs:    formatTxOutputsResp(ptx.Tx.Outputs),
+		InputTotal: inputTotal,
+		Fee:        fee,
+		Signed:     status.Signed,
+		Required:   status.Required,
+		Complete:   status.Complete,
+	}, nil
+}
+
+// decodePartialTx decodes and validates encoded partial tx
+func decodePartialTx(ptxHex string) (*core.PartialTx, error) {
+	ptx, err := core.DecodePartialTx(strings.TrimSpace(ptxHex))
+	if err != nil {
+		return nil, err
+	}
+	if err := ptx.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid partial tx: %w", err)
+	}
+	return ptx, nil
+}
+
+// CreatePartialTx builds unsigned partial tx for a single key or multisig owner
+func CreatePartialTx(bc *core.BlockChain) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req PartialTxCreateReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendResp(w, http.StatusBadRequest, nil, err)
+			return
+		}
+
+		var spender core.TxSpender
+		switch {
+		case req.PublicKey != "" && len(req.PublicKeys) > 0:
+			sendResp(w, http.StatusBadRequest, nil, fmt.Errorf("use either publicKey or threshold/publicKeys"))
+			return
+		case req.PublicKey != "":
+			publicKey, err := hex.DecodeString(strings.TrimPrefix(req.PublicKey, "0x"))
+			if err != nil {
+				sendResp(w, http.StatusBadRequest, nil, fmt.Errorf("invalid public key: %w", err))
+				return
+			}
+			spender.PublicKey = publicKey
+		case len(req.PublicKeys) > 0:
+			script, err := parseMultisigScript(&MultisigScriptReq{Threshold: req.Threshold, PublicKeys: req.PublicKeys})
+			if err != nil {
+				sendResp(w, http.StatusBadRequest, nil, err)
+				return
+			}
+			spender.MultiSig = script
+		default:
+			sendResp(w, http.StatusBadRequest, nil, fmt.Errorf("publicKey or threshold/publicKeys is required"))
+			return
+		}
+
+		recipients, err := parseRecipients("", 0, req.Recipients)
+		if err != nil {
+			sendResp(w, http.StatusBadRequest, nil, err)
+			return
+		}
+
+		fee := req.Fee
+		if fee == 0 {
+			fee = bc.GetMinFee()
+		}
+
+		opts := core.TxBuildOptions{Replaceable: req.Replaceable, CoinSelection: req.CoinSelection}
+		ptx, _, err := bc.CreatePartialTx(spender, recipients, fee, req.Memo, req.Data, core.TxTypeGeneral, opts)
+		if err != nil {
+			sendResp(w, http.StatusBadRequest, nil, fmt.Errorf("failed to create partial tx: %w", err))
+			return
+		}
+
+		resp, err := formatPartialTxResp(ptx)
+		if err != nil {
+			sendResp(w, http.StatusInternalServerError, nil, err)
+			return
+		}
+		sendResp(w, http.StatusOK, resp, nil)
+	}
+}
+
+// InspectPartialTx decodes partial tx and checks its spent outputs against the chain
+func InspectPartialTx(bc *core.BlockChain) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req PartialTxReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendResp(w, http.StatusBadRequest, nil, err)
+			return
+		}
+
+		ptx, err := decodePartialTx(req.Psbt)
+		if err != nil {
+			sendResp(w, http.StatusBadRequest, nil, err)
+			return
+		}
+
+		resp, err := formatPartialTxResp(ptx)
+		if err != nil {
+			sendResp(w, http.StatusBadRequest, nil, err)
+			return
+		}
+		resp.ChainCheck = "ok"
+		if err := bc.CheckPartialTxInputs(ptx); err != nil {
+			resp.ChainCheck = err.Error()
+		}
+		sendResp(w, http.StatusOK, resp, nil)
+	}
+}
+
+// CombinePartialTxs merges signatures of partial tx copies signed by different parties
+func CombinePartialTxs() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req PartialTxCombineReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendResp(w, http.StatusBadRequest, nil, err)
+			return
+		}
+
+		ptxs := make([]*core.PartialTx, len(req.Psbts))
+		for i, ptxHex := range req.Psbts {
+			ptx, err := core.DecodePartialTx(strings.TrimSpace(ptxHex))
+			if err != nil {
+				sendResp(w, http.StatusBadRequest, nil, fmt.Errorf("psbt %d: %w", i, err))
+				return
+			}
+			ptxs[i] = ptx
+		}
+
+		combined, err := core.CombinePartialTxs(ptxs)
+		if err != nil {
+			sendResp(w, http.StatusBadRequest, nil, err)
+			return
+		}
+
+		resp, err := formatPartialTxResp(combined)
+		if err != nil {
+			sendResp(w, http.StatusInternalServerError, nil, err)
+			return
+		}
+		sendResp(w, http.StatusOK, resp, nil)
+	}
+}
+
+// FinalizePartialTx verifies signatures of fully signed partial tx and returns the final tx
+func FinalizePartialTx() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req PartialTxReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendResp(w, http.StatusBadRequest, nil, err)
+			return
+		}
+
+		ptx, err := decodePartialTx(req.Psbt)
+		if err != nil {
+			sendResp(w, http.StatusBadRequest, nil, err)
+			return
+		}
+		tx, err := ptx.Finalize()
+		if err != nil {
+			sendResp(w, http.StatusBadRequest, nil, err)
+			return
+		}
+
+		txHex, err := core.EncodeTxHex(tx)
+		if err != nil {
+			sendResp(w, http.StatusInternalServerError, nil, err)
+			return
+		}
+		sendResp(w, http.StatusOK, map[string]string{
+			"txId": utils.HashToString(tx.ID),
+			"tx":   txHex,
+		}, nil)
+	}
+}
+
+// BroadcastPartialTx finalizes partial tx (or takes a tx finalized offline), adds it to mempool and broadcasts it
+func BroadcastPartialTx(bc *core.BlockChain, p2pService *p2p.P2PService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req PartialTxBroadcastReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendResp(w, http.StatusBadRequest, nil, err)
+			return
+		}
+
+		var tx *core.Transaction
+		switch {
+		case req.Psbt != "" && req.Tx != "":
+			sendResp(w, http.StatusBadRequest, nil, fmt.Errorf("use either psbt or tx"))
+			return
+		case req.Psbt != "":
+			ptx, err := decodePartialTx(req.Psbt)
+			if err != nil {
+				sendResp(w, http.StatusBadRequest, nil, err)
+				return
+			}
+			if tx, err = ptx.Finalize(); err != nil {
+				sendResp(w, http.StatusBadRequest, nil, err)
+				return
+			}
+		case req.Tx != "":
+			decoded, err := core.DecodeTxHex(strings.TrimSpace(req.Tx))
+			if err != nil {
+				sendResp(w, http.StatusBadRequest, nil, err)
+				return
+			}
+			tx = decoded
+		default:
+			sendResp(w, http.StatusBadRequest, nil, fmt.Errorf("psbt or tx is required"))
+			return
+		}
+
+		if err := addAndBroadcastTx(bc, p2pService, tx); err != nil {
+			sendResp(w, http.StatusBadRequest, nil, err)
+			return
+		}
+
+		sendResp(w, http.StatusOK, map[string]string{
+			"txId": utils.HashToString(tx.ID),
+		}, nil)
+	}
+}
+
+// getWalletAccount returns server wallet account by index
+func getWalletAccount(wm *wallet.WalletManager, index int) (*wallet.Account, error) {
+	if wm == nil || wm.Wallet == nil {
+		return nil, fmt.Errorf("wallet not initialized")
+	}
+	if index < 0 || index >= len(wm.Wallet.Accounts) {
+		return nil, fmt.Errorf("invalid account index: %d", index)
+	}
+	return wm.Wallet.Accounts[index], nil
+}
+
+// parseHashLockReq converts hex hash lock request
+func parseHashLockReq(req *HashLockReq) (*core.HashLock, error) {
+	hash, err := utils.StringToHash(req.Hash)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hash: %w", err)
+	}
+	recipient, err := utils.StringToAddress(req.Recipient)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipient: %w", err)
+	}
+	refund, err := utils.StringToAddress(req.Refund)
+	if err != nil {
+		return nil, fmt.Errorf("invalid refund address: %w", err)
+	}
+	return &core.HashLock{Hash: hash, Recipient: recipient, Refund: refund, Expiry: req.Expiry}, nil
+}
+
+// LockHTLC funds a hash timelock contract from a server wallet account (first step of an atomic swap).
+// Without a hash a new preimage is generated and returned; keep it secret until claiming the other side.
+func LockHTLC(bc *core.BlockChain, wm *wallet.WalletManager, p2pService *p2p.P2PService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req HTLCLockReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendResp(w, http.StatusBadRequest, nil, err)
+			return
+		}
+
+		account, err := getWalletAccount(wm, req.AccountIndex)
+		if err != nil {
+			sendResp(w, http.StatusBadRequest, nil, err)
+			return
+		}
+
+		lock := &core.HashLock{Refund: account.Address, Expiry: req.Expiry}
+		if lock.Recipient, err = utils.StringToAddress(req.Recipient); err != nil {
+			sendResp(w, http.StatusBadRequest, nil, fmt.Errorf("invalid recipient: %w", err))
+			return
+		}
+		if req.Refund != "" {
+			if lock.Refund, err = utils.StringToAddress(req.Refund); err != nil {
+				sendResp(w, http.StatusBadRequest, nil, fmt.Errorf("invalid refund address: %w", err))
+				return
+			}
+		}
+		if req.ExpiryBlocks > 0 {
+			if req.Expiry > 0 {
+				sendResp(w, http.StatusBadRequest, nil, fmt.Errorf("use either expiry or expiryBlocks"))
+				return
+			}
+			lock.Expiry = bc.GetChainStatus().LatestHeight + 1 + req.ExpiryBlocks
+		}
+		if lock.Expiry == 0 {
+			sendResp(w, http.StatusBadRequest, nil, fmt.Errorf("expiry or expiryBlocks is required"))
+			return
+		}
+
+		// Generate secret if the hash is not given (the party starting the swap)
+		var preimage []byte
+		if req.Hash != "" {
+			if lock.Hash, err = utils.StringToHash(req.Hash); err != nil {
+				sendResp(w, http.StatusBadRequest, nil, fmt.Errorf("invalid hash: %w", err))
+				return
+			}
+		} else {
+			preimage = make([]byte, 32)
+			if _, err := rand.Read(preimage); err != nil {
+				sendResp(w, http.StatusInternalServerError, nil, fmt.Errorf("failed to generate preimage: %w", err))
+				return
+			}
+			lock.Hash = core.HashPreimage(preimage)
+		}
+
+		fee := req.Fee
+		if fee == 0 {
+			fee = bc.GetMinFee()
+		}
+
+		recipients := []core.TxRecipient{{Amount: req.Amount, HTLC: lock}}
+		tx, _, err := bc.BuildSignedTx(account.Address, recipients, fee, req.Memo, nil, core.TxTypeGeneral, core.TxBuildOptions{}, account.PrivateKey, account.PublicKey)
+		if err != nil {
+			sendResp(w, http.StatusBadRequest, nil, fmt.Errorf("failed to create HTLC tx: %w", err))
+			return
+		}
+		if err := addAndBroadcastTx(bc, p2pService, tx); err != nil {
+			sendResp(w, http.StatusBadRequest, nil, err)
+			return
+		}
+
+		resp := map[string]interface{}{
+			"txId":        utils.HashToString(tx.ID),
+			"outputIndex": 0, // Recipients come before change
+			"contract":    utils.AddressToString(lock.Address()),
+			"amount":      req.Amount,
+			"htlc":        formatHashLockResp(lock),
+		}
+		if preimage != nil {
+			resp["preimage"] = hex.EncodeToString(preimage)
+		}
+		sendResp(w, http.StatusOK, resp, nil)
+	}
+}
+
+// ClaimHTLC spends HTLC output with its preimage using the recipient's server wallet account
+func ClaimHTLC(bc *core.BlockChain, wm *wallet.WalletManager, p2pService *p2p.P2PService) http.HandlerFunc {
+	return spendHTLC(bc, wm, p2pService, true)
+}
+
+// RefundHTLC takes expired HTLC output back using the refund address' server wallet account
+func RefundHTLC(bc *core.BlockChain, wm *wallet.WalletManager, p2pService *p2p.P2PService) http.HandlerFunc {
+	return spendHTLC(bc, wm, p2pService, false)
+}
+
+func spendHTLC(bc *core.BlockChain, wm *wallet.WalletManager, p2pService *p2p.P2PService, claim bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req HTLCSpendReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendResp(w, http.StatusBadRequest, nil, err)
+			return
+		}
+
+		account, err := getWalletAccount(wm, req.AccountIndex)
+		if err != nil {
+			sendResp(w, http.StatusBadRequest, nil, err)
+			return
+		}
+		txID, err := utils.StringToHash(req.TxID)
+		if err != nil {
+			sendResp(w, http.StatusBadRequest, nil, fmt.Errorf("invalid txId: %w", err))
+			return
+		}
+		to := account.Address
+		if req.To != "" {
+			if to, err = utils.StringToAddress(req.To); err != nil {
+				sendResp(w, http.StatusBadRequest, nil, fmt.Errorf("invalid to address: %w", err))
+				return
+			}
+		}
+
+		var tx *core.Transaction
+		if claim {
+			preimage, err := hex.DecodeString(strings.TrimPrefix(req.Preimage, "0x"))
+			if err != nil {
+				sendResp(w, http.StatusBadRequest, nil, fmt.Errorf("invalid preimage: %w", err))
+				return
+			}
+			tx, err = bc.BuildHTLCClaimTx(txID, req.OutputIndex, preimage, to, req.Fee, account.PrivateKey, account.PublicKey)
+			if err != nil {
+				sendResp(w, http.StatusBadRequest, nil, fmt.Errorf("failed to create claim tx: %w", err))
+				return
+			}
+		} else {
+			tx, err = bc.BuildHTLCRefundTx(txID, req.OutputIndex, to, req.Fee, account.PrivateKey, account.PublicKey)
+			if err != nil {
+				sendResp(w, http.StatusBadRequest, nil, fmt.Errorf("failed to create refund tx: %w", err))
+				return
+			}
+		}
+
+		if err := addAndBroadcastTx(bc, p2pService, tx); err != nil {
+			sendResp(w, http.StatusBadRequest, nil, err)
+			return
+		}
+
+		sendResp(w, http.StatusOK, map[string]interface{}{
+			"txId":   utils.HashToString(tx.ID),
+			"to":     utils.AddressToString(to),
+			"amount": tx.Outputs[0].Amount,
+		}, nil)
+	}
+}
+
+// GetHTLC returns HTLC output status and the preimage once a claim revealed it
+func GetHTLC(bc *core.BlockChain) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		txID, err := utils.StringToHash(vars["txid"])
+		if err != nil {
+			sendResp(w, http.StatusBadRequest, nil, fmt.Errorf("invalid txid: %w", err))
+			return
+		}
+		outputIndex, err := strconv.ParseUint(vars["index"], 10, 64)
+		if err != nil {
+			sendResp(w, http.StatusBadRequest, nil, fmt.Errorf("invalid output index: %w", err))
+			return
+		}
+
+		status, err := bc.GetHTLC(txID, outputIndex)
+		if err != nil {
+			sendResp(w, http.StatusNotFound, nil, err)
+			return
+		}
+
+		resp := map[string]interface{}{
+			"txId":        utils.HashToString(txID),
+			"outputIndex": outputIndex,
+			"contract":    utils.AddressToString(status.Utxo.TxOut.Address),
+			"amount":      status.Utxo.TxOut.Amount,
+			"height":      status.Utxo.Height,
+			"unconfirmed": status.Utxo.Unconfirmed,
+			"htlc":        formatHashLockResp(status.Utxo.TxOut.HTLC),
+			"expired":     status.Expired,
+			"spent":       status.Spender != nil,
+		}
+		if status.Spender != nil {
+			resp["spentBy"] = utils.HashToString(status.Spender.ID)
+			resp["spentConfirmed"] = status.Confirmed
+			resp["refunded"] = status.Refunded
+		}
+		if len(status.Preimage) > 0 {
+			resp["preimage"] = hex.EncodeToString(status.Preimage)
+		}
+		sendResp(w, http.StatusOK, resp, nil)
+	}
+}
+
+// StakeWithWallet stakes amount of a server wallet account (applies from the block after the stake tx)
+func StakeWithWallet(bc *core.BlockChain, wm *wallet.WalletManager, p2pService *p2p.P2PService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req StakeReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendResp(w, http.StatusBadRequest, nil, err)
+			return
+		}
+
+		account, err := getWalletAccount(wm, req.AccountIndex)
+		if err != nil {
+			sendResp(w, http.StatusBadRequest, nil, err)
+			return
+		}
+
+		tx, _, err := bc.BuildStakeTx(account.Address, req.Amount, req.Fee, account.PrivateKey, account.PublicKey)
+		if err != nil {
+			sendResp(w, http.StatusBadRequest, nil, fmt.Errorf("failed to create stake tx: %w", err))
+			return
+		}
+		if err := addAndBroadcastTx(bc, p2pService, tx); err != nil {
+			sendResp(w, http.StatusBadRequest, nil, err)
+			return
+		}
+
+		sendResp(w, http.StatusOK, map[string]interface{}{
+			"txId":    utils.HashToString(tx.ID),
+			"address": utils.AddressToString(account.Address),
+			"amount":  req.Amount,
+		}, nil)
+	}
+}
+
+// UnstakeWithWallet unstakes amount of a server wallet account (spendable after the unbonding period)
+func UnstakeWithWallet(bc *core.BlockChain, wm *wallet.WalletManager, p2pService *p2p.P2PService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req StakeReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendResp(w, http.StatusBadRequest, nil, err)
+			return
+		}
+
+		account, err := getWalletAccount(wm, req.AccountIndex)
+		if err != nil {
+			sendResp(w, http.StatusBadRequest, nil, err)
+			return
+		}
+
+		tx, err := bc.BuildUnstakeTx(account.Address, req.Amount, req.Fee, account.PrivateKey, account.PublicKey)
+		if err != nil {
+			sendResp(w, http.StatusBadRequest, nil, fmt.Errorf("failed to create unstake tx: %w", err))
+			return
+		}
+		if err := addAndBroadcastTx(bc, p2pService, tx); err != nil {
+			sendResp(w, http.StatusBadRequest, nil, err)
+			return
+		}
+
+		sendResp(w, http.StatusOK, map[string]interface{}{
+			"txId":            utils.HashToString(tx.ID),
+			"address":         utils.AddressToString(account.Address),
+			"amount":          req.Amount,
+			"unbondingBlocks": bc.GetUnbondingBlocks(),
+		}, nil)
+	}
+}
+
+// GetStakers returns stakes committed up to the latest block
+func GetStakers(bc *core.BlockChain) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		snapshot, err := bc.GetStakeSnapshot()
+		if err != nil {
+			sendResp(w, http.StatusInternalServerError, nil, err)
+			return
+		}
+
+		stakers := []map[string]interface{}{}
+		var totalStaked uint64
+		for _, stake := range snapshot.Stakes {
+			stakers = append(stakers, map[string]interface{}{
+				"address":   utils.AddressToString(stake.Address),
+				"publicKey": hex.EncodeToString(stake.PublicKey),
+				"amount":    stake.Amount,
+				"height":    stake.Height,
+				"validator": stake.Amount >= consensus.MinStakeAmount,
+			})
+			totalStaked += stake.Amount
+		}
+
+		sendResp(w, http.StatusOK, map[string]interface{}{
+			"height":          snapshot.Height,
+			"stakers":         stakers,
+			"totalStaked":     totalStaked,
+			"minStakeAmount":  consensus.MinStakeAmount,
+			"unbondingBlocks": bc.GetUnbondingBlocks(),
+		}, nil)
+	}
+}
+
+// GetEpoch returns validator set active at block height (which set signed / signs the block)
+func GetEpoch(bc *core.BlockChain) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		height, err := strconv.ParseUint(mux.Vars(r)["height"], 10, 64)
+		if err != nil {
+			sendResp(w, http.StatusBadRequest, nil, fmt.Errorf("invalid height: %w", err))
+			return
+		}
+
+		epoch, err := bc.GetEpoch(height)
+		if err != nil {
+			sendResp(w, http.StatusNotFound, nil, err)
+			return
+		}
+
+		validators := []map[string]interface{}{}
+		for _, v := range epoch.Validators {
+			validators = append(validators, map[string]interface{}{
+				"address":     utils.AddressToString(v.Address),
+				"publicKey":   hex.EncodeToString(v.PublicKey),
+				"votingPower": v.VotingPower,
+				"delegated":   v.Delegated,
+				"delegators":  len(v.Delegations),
+				"jailed":      v.Jailed,
+			})
+		}
+
+		epochBlocks := bc.GetEpochBlocks()
+		sendResp(w, http.StatusOK, map[string]interface{}{
+			"epoch":            epoch.Number,
+			"startHeight":      epoch.StartHeight,
+			"endHeight":        epoch.EndHeight(epochBlocks),
+			"epochBlocks":      epochBlocks,
+			"boundaryHash":     utils.HashToString(epoch.BoundaryHash),
+			"validators":       validators,
+			"totalVotingPower": epoch.TotalVotingPower,
+			"commissionRate":   epoch.CommissionRate,
+		}, nil)
+	}
+}
+
+// DelegateWithWallet delegates amount of a server wallet account to a validator (counts from the next epoch)
+func DelegateWithWallet(bc *core.BlockChain, wm *wallet.WalletManager, p2pService *p2p.P2PService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req DelegateReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendResp(w, http.StatusBadRequest, nil, err)
+			return
+		}
+
+		account, err := getWalletAccount(wm, req.AccountIndex)
+		if err != nil {
+			sendResp(w, http.StatusBadRequest, nil, err)
+			return
+		}
+		validator, err := utils.StringToAddress(req.Validator)
+		if err != nil {
+			sendResp(w, http.StatusBadRequest, nil, fmt.Errorf("invalid validator address: %w", err))
+			return
+		}
+
+		tx, _, err := bc.BuildDelegateTx(account.Address, validator, req.Amount, req.Fee, account.PrivateKey, account.PublicKey)
+		if err != nil {
+			sendResp(w, http.StatusBadRequest, nil, fmt.Errorf("failed to create delegate tx: %w", err))
+			return
+		}
+		if err := addAndBroadcastTx(bc, p2pService, tx); err != nil {
+			sendResp(w, http.StatusBadRequest, nil, err)
+			return
+		}
+
+		sendResp(w, http.StatusOK, map[string]interface{}{
+			"txId":      utils.HashToString(tx.ID),
+			"address":   utils.AddressToString(account.Address),
+			"validator": req.Validator,
+			"amount":    req.Amount,
+		}, nil)
+	}
+}
+
+// UndelegateWithWallet undelegates amount of a server wallet account from a validator (spendable after the unbonding period)
+func UndelegateWithWallet(bc *core.BlockChain, wm *wallet.WalletManager, p2pService *p2p.P2PService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req DelegateReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendResp(w, http.StatusBadRequest, nil, err)
+			return
+		}
+
+		account, err := getWalletAccount(wm, req.AccountIndex)
+		if err != nil {
+			sendResp(w, http.StatusBadRequest, nil, err)
+			return
+		}
+		validator, err := utils.StringToAddress(req.Validator)
+		if err != nil {
+			sendResp(w, http.StatusBadRequest, nil, fmt.Errorf("invalid validator address: %w", err))
+			return
+		}
+
+		tx, err := bc.BuildUndelegateTx(account.Address, validator, req.Amount, req.Fee, account.PrivateKey, account.PublicKey)
+		if err != nil {
+			sendResp(w, http.StatusBadRequest, nil, fmt.Errorf("failed to create undelegate tx: %w", err))
+			return
+		}
+		if err := addAndBroadcastTx(bc, p2pService, tx); err != nil {
+			sendResp(w, http.StatusBadRequest, nil, err)
+			return
+		}
+
+		sendResp(w, http.StatusOK, map[string]interface{}{
+			"txId":            utils.HashToString(tx.ID),
+			"address":         utils.AddressToString(account.Address),
+			"validator":       req.Validator,
+			"amount":          req.Amount,
+			"unbondingBlocks": bc.GetUnbondingBlocks(),
+		}, nil)
+	}
+}
+
+// ClaimRewardWithWallet withdraws the whole reward balance of a server wallet account (minus fee)
+func ClaimRewardWithWallet(bc *core.BlockChain, wm *wallet.WalletManager, p2pService *p2p.P2PService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req ClaimRewardReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendResp(w, http.StatusBadRequest, nil, err)
+			return
+		}
+
+		account, err := getWalletAccount(wm, req.AccountIndex)
+		if err != nil {
+			sendResp(w, http.StatusBadRequest, nil, err)
+			return
+		}
+
+		tx, err := bc.BuildClaimRewardTx(account.Address, req.Fee, account.PrivateKey, account.PublicKey)
+		if err != nil {
+			sendResp(w, http.StatusBadRequest, nil, fmt.Errorf("failed to create claim tx: %w", err))
+			return
+		}
+		if err := addAndBroadcastTx(bc, p2pService, tx); err != nil {
+			sendResp(w, http.StatusBadRequest, nil, err)
+			return
+		}
+
+		sendResp(w, http.StatusOK, map[string]interface{}{
+			"txId":    utils.HashToString(tx.ID),
+			"address": utils.AddressToString(account.Address),
+			"claimed": tx.Claim,
+		}, nil)
+	}
+}
+
+// GetValidatorDelegations returns delegators of a validator committed up to the latest block
+func GetValidatorDelegations(bc *core.BlockChain) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		validator, err := utils.StringToAddress(mux.Vars(r)["validator"])
+		if err != nil {
+			sendResp(w, http.StatusBadRequest, nil, err)
+			return
+		}
+
+		delegations, err := bc.GetDelegations(validator)
+		if err != nil {
+			sendResp(w, http.StatusInternalServerError, nil, err)
+			return
+		}
+
+		delegators := []map[string]interface{}{}
+		var totalDelegated uint64
+		for _, delegation := range delegations {
+			delegators = append(delegators, map[string]interface{}{
+				"delegator": utils.AddressToString(delegation.Delegator),
+				"amount":    delegation.Amount,
+				"height":    delegation.Height,
+			})
+			totalDelegated += delegation.Amount
+		}
+
+		sendResp(w, http.StatusOK, map[string]interface{}{
+			"validator":      utils.AddressToString(validator),
+			"delegators":     delegators,
+			"totalDelegated": totalDelegated,
+			"commissionRate": bc.GetCommissionRate(),
+		}, nil)
+	}
+}
+
+// GetJailedValidators returns validators jailed for double-signing or downtime
+func GetJailedValidators(bc *core.BlockChain) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		jails, err := bc.GetJails()
+		if err != nil {
+			sendResp(w, http.StatusInternalServerError, nil, err)
+			return
+		}
+
+		jailed := []map[string]interface{}{}
+		for _, jail := range jails {
+			entry := map[string]interface{}{
+				"address": utils.AddressToString(jail.Address),
+				"height":  jail.Height,
+				"reason":  jail.Reason.String(),
+			}
+			if jail.Reason == core.JailReasonDoubleSign {
+				entry["evidenceTx"] = utils.HashToString(jail.Evidence)
+			} else {
+				entry["unjailHeight"] = jail.Height + bc.GetDowntimeJailBlocks()
+			}
+			jailed = append(jailed, entry)
+		}
+
+		sendResp(w, http.StatusOK, map[string]interface{}{
+			"jailed":    jailed,
+			"count":     len(jailed),
+			"slashRate": bc.GetSlashRate(),
+		}, nil)
+	}
+}
+
+// GetValidatorUptimes returns signed / missed blocks within the window of each validator of the current epoch
+func GetValidatorUptimes(bc *core.BlockChain) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		latestHeight, err := bc.GetLatestHeight()
+		if err != nil {
+			sendResp(w, http.StatusInternalServerError, nil, err)
+			return
+		}
+		epoch, err := bc.GetEpoch(latestHeight + 1)
+		if err != nil {
+			sendResp(w, http.StatusInternalServerError, nil, err)
+			return
+		}
+
+		validators := []map[string]interface{}{}
+		for _, v := range epoch.Validators {
+			uptime, err := bc.GetUptime(v.Address)
+			if err != nil {
+				sendResp(w, http.StatusInternalServerError, nil, err)
+				return
+			}
+			jail, err := bc.GetJail(v.Address)
+			if err != nil {
+				sendResp(w, http.StatusInternalServerError, nil, err)
+				return
+			}
+
+			tracked := len(uptime.Signed) + len(uptime.Missed)
+			percent := 100.0
+			if tracked > 0 {
+				percent = float64(len(uptime.Signed)) * 100 / float64(tracked)
+			}
+			entry := map[string]interface{}{
+				"address": utils.AddressToString(v.Address),
+				"signed":  len(uptime.Signed),
+				"missed":  len(uptime.Missed),
+				"uptime":  percent,
+				"jailed":  jail != nil,
+			}
+			if jail != nil {
+				entry["jailReason"] = jail.Reason.String()
+			}
+			validators = append(validators, entry)
+		}
+
+		sendResp(w, http.StatusOK, map[string]interface{}{
+			"height":             latestHeight,
+			"validators":         validators,
+			"signedBlocksWindow": bc.GetSignedBlocksWindow(),
+			"minSignedPerWindow": bc.GetMinSignedPerWindow(),
+			"downtimeJailBlocks": bc.GetDowntimeJailBlocks(),
+		}, nil)
+	}
+}
+
+// UnjailWithWallet releases the validator of a server wallet account jailed for downtime
+func UnjailWithWallet(bc *core.BlockChain, wm *wallet.WalletManager, p2pService *p2p.P2PService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req UnjailReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendResp(w, http.StatusBadRequest, nil, err)
+			return
+		}
+
+		account, err := getWalletAccount(wm, req.AccountIndex)
+		if err != nil {
+			sendResp(w, http.StatusBadRequest, nil, err)
+			return
+		}
+
+		tx, err := bc.BuildUnjailTx(account.Address, req.Fee, account.PrivateKey, account.PublicKey)
+		if err != nil {
+			sendResp(w, http.StatusBadRequest, nil, fmt.Errorf("failed to create unjail tx: %w", err))
+			return
+		}
+		if err := addAndBroadcastTx(bc, p2pService, tx); err != nil {
+			sendResp(w, http.StatusBadRequest, nil, err)
+			return
+		}
+
+		sendResp(w, http.StatusOK, map[string]interface{}{
+			"txId":      utils.HashToString(tx.ID),
+			"validator": utils.AddressToString(account.Address),
+		}, nil)
+	}
+}
+
+// GetRewards returns claimable delegation reward and delegations of an address
+func GetRewards(bc *core.BlockChain) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		addrStr := mux.Vars(r)["address"]
+		address, err := utils.StringToAddress(addrStr)
+		if err != nil {
+			sendResp(w, http.StatusBadRequest, nil, err)
+			return
+		}
+
+		claimable, err := bc.GetRewardBalance(address)
+		if err != nil {
+			sendResp(w, http.StatusInternalServerError, nil, err)
+			return
+		}
+		delegations, err := bc.GetDelegatorDelegations(address)
+		if err != nil {
+			sendResp(w, http.StatusInternalServerError, nil, err)
+			return
+		}
+
+		validators := []map[string]interface{}{}
+		for _, delegation := range delegations {
+			validators = append(validators, map[string]interface{}{
+				"validator": utils.AddressToString(delegation.Validator),
+				"amount":    delegation.Amount,
+				"height":    delegation.Height,
+			})
+		}
+
+		sendResp(w, http.StatusOK, map[string]interface{}{
+			"address":     addrStr,
+			"claimable":   claimable,
+			"delegations": validators,
+		}, nil)
+	}
+}
@@ -35,10 +35,13 @@ func setupPublicRouter(blockchain *core.BlockChain, wsHub *api.WSHub, cons *cons
 
 	// Consensus status API (조회)
 	apiRouter.HandleFunc("/consensus/status", GetConsensusStatus(cons, consEngine)).Methods("GET")
+	apiRouter.HandleFunc("/consensus/epoch/{height}", GetEpoch(blockchain)).Methods("GET")    // 해당 높이에 적용되는 검증자 세트
+	apiRouter.HandleFunc("/consensus/uptime", GetValidatorUptimes(blockchain)).Methods("GET") // 검증자별 서명 / 누락 블록 수
 
 	// Block related API (조회)
 	apiRouter.HandleFunc("/blocks", GetBlocks(blockchain)).Methods("GET")
 	apiRouter.HandleFunc("/block/latest", GetLatestBlock(blockchain)).Methods("GET")
+	apiRouter.HandleFunc("/block/template", GetBlockTemplate(blockchain)).Methods("GET") // 다음 블록 후보 TX ({height}보다 먼저 등록)
 	apiRouter.HandleFunc("/block/height/{height}", GetBlockByHeight(blockchain)).Methods("GET")
 	apiRouter.HandleFunc("/block/{height}", GetBlockByHeight(blockchain)).Methods("GET")
 	apiRouter.HandleFunc("/block/hash/{hash}", GetBlockByHash(blockchain)).Methods("GET")
@@ -47,13 +50,37 @@ func setupPublicRouter(blockchain *core.BlockChain, wsHub *api.WSHub, cons *cons
 	apiRouter.HandleFunc("/tx/signed", SubmitSignedTx(blockchain, p2pService)).Methods("POST") // 클라이언트가 서명한 TX는 공개
 	apiRouter.HandleFunc("/tx/{txid}", GetTx(blockchain)).Methods("GET")
 
+	// Multisig API (주소 생성, 미서명 TX 생성, 서명 병합, 제출 - 서명은 클라이언트가 수행)
+	apiRouter.HandleFunc("/multisig/address", GetMultisigAddress()).Methods("POST")
+	apiRouter.HandleFunc("/multisig/tx", CreateMultisigTx(blockchain)).Methods("POST")
+	apiRouter.HandleFunc("/multisig/tx/combine", CombineMultisigTxs()).Methods("POST")
+	apiRouter.HandleFunc("/multisig/tx/submit", SubmitMultisigTx(blockchain, p2pService)).Methods("POST")
+
+	// Partially signed TX API (PSBT 방식 오프라인 서명 - 노드는 서명 키를 보관하지 않음)
+	apiRouter.HandleFunc("/psbt/create", CreatePartialTx(blockchain)).Methods("POST")
+	apiRouter.HandleFunc("/psbt/inspect", InspectPartialTx(blockchain)).Methods("POST")
+	apiRouter.HandleFunc("/psbt/combine", CombinePartialTxs()).Methods("POST")
+	apiRouter.HandleFunc("/psbt/finalize", FinalizePartialTx()).Methods("POST")
+	apiRouter.HandleFunc("/psbt/broadcast", BroadcastPartialTx(blockchain, p2pService)).Methods("POST")
+
+	// HTLC 조회 API (클레임 시 공개된 preimage 포함)
+	apiRouter.HandleFunc("/htlc/{txid}/{index}", GetHTLC(blockchain)).Methods("GET")
+
+	// Staking 조회 API (체인에 확정된 스테이크 기준)
+	apiRouter.HandleFunc("/staking/stakers", GetStakers(blockchain)).Methods("GET")
+	apiRouter.HandleFunc("/staking/delegations/{validator}", GetValidatorDelegations(blockchain)).Methods("GET") // 검증자별 위임자 목록
+	apiRouter.HandleFunc("/staking/jailed", GetJailedValidators(blockchain)).Methods("GET")                      // 이중 서명 / 다운타임으로 감금된 검증자 목록
+
 	// Mempool related API (조회)
+	apiRouter.HandleFunc("/mempool", GetMempoolStatus(blockchain)).Methods("GET")
+	apiRouter.HandleFunc("/fee/estimate", GetFeeEstimate(blockchain)).Methods("GET")
 	apiRouter.HandleFunc("/mempool/list", GetMempoolList(blockchain)).Methods("GET")
 
 	// UTXO related API (조회)
 	apiRouter.HandleFunc("/address/{address}/utxo", GetAddressUtxo(blockchain)).Methods("GET")
 	apiRouter.HandleFunc("/address/{address}/balance", GetBalanceByUtxo(blockchain)).Methods("GET")
 	apiRouter.HandleFunc("/address/{address}/txs", GetAddressTransactions(blockchain)).Methods("GET")
+	apiRouter.HandleFunc("/address/{address}/rewards", GetRewards(blockchain)).Methods("GET") // 청구 가능한 위임 보상
 
 	// WebSocket status API (조회)
 	apiRouter.HandleFunc("/ws/status", GetWSStatus(wsHub)).Methods("GET")
@@ -88,11 +115,14 @@ func setupInternalRouter(blockchain *core.BlockChain, walletMgr *wallet.WalletMa
 
 	// Consensus status API
 	apiRouter.HandleFunc("/consensus/status", GetConsensusStatus(cons, consEngine)).Methods("GET")
+	apiRouter.HandleFunc("/consensus/epoch/{height}", GetEpoch(blockchain)).Methods("GET")    // 해당 높이에 적용되는 검증자 세트
+	apiRouter.HandleFunc("/consensus/uptime", GetValidatorUptimes(blockchain)).Methods("GET") // 검증자별 서명 / 누락 블록 수
 
 	// Block related API
 	apiRouter.HandleFunc("/blocks", GetBlocks(blockchain)).Methods("GET")
 	apiRouter.HandleFunc("/block", ComposeAndAddBlock(blockchain)).Methods("POST") // 테스트용 블록 생성 (내부 전용)
 	apiRouter.HandleFunc("/block/latest", GetLatestBlock(blockchain)).Methods("GET")
+	apiRouter.HandleFunc("/block/template", GetBlockTemplate(blockchain)).Methods("GET") // 다음 블록 후보 TX ({height}보다 먼저 등록)
 	apiRouter.HandleFunc("/block/height/{height}", GetBlockByHeight(blockchain)).Methods("GET")
 	apiRouter.HandleFunc("/block/{height}", GetBlockByHeight(blockchain)).Methods("GET")
 	apiRouter.HandleFunc("/block/hash/{hash}", GetBlockByHash(blockchain)).Methods("GET")
@@ -101,13 +131,37 @@ func setupInternalRouter(blockchain *core.BlockChain, walletMgr *wallet.WalletMa
 	apiRouter.HandleFunc("/tx/signed", SubmitSignedTx(blockchain, p2pService)).Methods("POST")
 	apiRouter.HandleFunc("/tx/{txid}", GetTx(blockchain)).Methods("GET")
 
+	// Multisig API
+	apiRouter.HandleFunc("/multisig/address", GetMultisigAddress()).Methods("POST")
+	apiRouter.HandleFunc("/multisig/tx", CreateMultisigTx(blockchain)).Methods("POST")
+	apiRouter.HandleFunc("/multisig/tx/combine", CombineMultisigTxs()).Methods("POST")
+	apiRouter.HandleFunc("/multisig/tx/submit", SubmitMultisigTx(blockchain, p2pService)).Methods("POST")
+
+	// Partially signed TX API
+	apiRouter.HandleFunc("/psbt/create", CreatePartialTx(blockchain)).Methods("POST")
+	apiRouter.HandleFunc("/psbt/inspect", InspectPartialTx(blockchain)).Methods("POST")
+	apiRouter.HandleFunc("/psbt/combine", CombinePartialTxs()).Methods("POST")
+	apiRouter.HandleFunc("/psbt/finalize", FinalizePartialTx()).Methods("POST")
+	apiRouter.HandleFunc("/psbt/broadcast", BroadcastPartialTx(blockchain, p2pService)).Methods("POST")
+
+	// HTLC 조회 API (클레임 시 공개된 preimage 포함)
+	apiRouter.HandleFunc("/htlc/{txid}/{index}", GetHTLC(blockchain)).Methods("GET")
+
+	// Staking 조회 API (체인에 확정된 스테이크 기준)
+	apiRouter.HandleFunc("/staking/stakers", GetStakers(blockchain)).Methods("GET")
+	apiRouter.HandleFunc("/staking/delegations/{validator}", GetValidatorDelegations(blockchain)).Methods("GET") // 검증자별 위임자 목록
+	apiRouter.HandleFunc("/staking/jailed", GetJailedValidators(blockchain)).Methods("GET")                      // 이중 서명 / 다운타임으로 감금된 검증자 목록
+
 	// Mempool related API
+	apiRouter.HandleFunc("/mempool", GetMempoolStatus(blockchain)).Methods("GET")
+	apiRouter.HandleFunc("/fee/estimate", GetFeeEstimate(blockchain)).Methods("GET")
 	apiRouter.HandleFunc("/mempool/list", GetMempoolList(blockchain)).Methods("GET")
 
 	// UTXO related API
 	apiRouter.HandleFunc("/address/{address}/utxo", GetAddressUtxo(blockchain)).Methods("GET")
 	apiRouter.HandleFunc("/address/{address}/balance", GetBalanceByUtxo(blockchain)).Methods("GET")
 	apiRouter.HandleFunc("/address/{address}/txs", GetAddressTransactions(blockchain)).Methods("GET")
+	apiRouter.HandleFunc("/address/{address}/rewards", GetRewards(blockchain)).Methods("GET") // 청구 가능한 위임 보상
 
 	// WebSocket status API
 	apiRouter.HandleFunc("/ws/status", GetWSStatus(wsHub)).Methods("GET")
@@ -120,10 +174,28 @@ func setupInternalRouter(blockchain *core.BlockChain, walletMgr *wallet.WalletMa
 
 	// 서버 지갑으로 TX 서명 및 전송 (내부 전용)
 	apiRouter.HandleFunc("/tx/send", SendTxWithWallet(blockchain, walletMgr, p2pService)).Methods("POST")
+	apiRouter.HandleFunc("/tx/bump", BumpTxFee(blockchain, walletMgr, p2pService)).Methods("POST") // RBF 수수료 인상 (내부 전용)
 
 	// Wallet 관리 API (내부 전용)
 	apiRouter.HandleFunc("/wallet/accounts", GetWalletAccounts(walletMgr)).Methods("GET")
 	apiRouter.HandleFunc("/wallet/account/new", CreateNewAccount(walletMgr)).Methods("POST")
+	apiRouter.HandleFunc("/wallet/sweep", SweepWallet(blockchain, walletMgr, p2pService)).Methods("POST") // 작은 UTXO 통합 (내부 전용)
+	apiRouter.HandleFunc("/wallet/multisig/sign", SignMultisigTx(walletMgr)).Methods("POST")              // 서버 지갑 계정으로 멀티시그 TX 서명 (내부 전용)
+
+	// HTLC 잠금 / 클레임 / 환불 (서버 지갑 서명, 내부 전용)
+	apiRouter.HandleFunc("/htlc/lock", LockHTLC(blockchain, walletMgr, p2pService)).Methods("POST")
+	apiRouter.HandleFunc("/htlc/claim", ClaimHTLC(blockchain, walletMgr, p2pService)).Methods("POST")
+	apiRouter.HandleFunc("/htlc/refund", RefundHTLC(blockchain, walletMgr, p2pService)).Methods("POST")
+
+	// 스테이킹 / 언스테이킹 (서버 지갑 서명, 내부 전용)
+	apiRouter.HandleFunc("/staking/stake", StakeWithWallet(blockchain, walletMgr, p2pService)).Methods("POST")
+	apiRouter.HandleFunc("/staking/unstake", UnstakeWithWallet(blockchain, walletMgr, p2pService)).Methods("POST")
+
+	// 위임 / 위임 해제 / 보상 청구 (서버 지갑 서명, 내부 전용)
+	apiRouter.HandleFunc("/staking/delegate", DelegateWithWallet(blockchain, walletMgr, p2pService)).Methods("POST")
+	apiRouter.HandleFunc("/staking/undelegate", UndelegateWithWallet(blockchain, walletMgr, p2pService)).Methods("POST")
+	apiRouter.HandleFunc("/staking/rewards/claim", ClaimRewardWithWallet(blockchain, walletMgr, p2pService)).Methods("POST")
+	apiRouter.HandleFunc("/staking/unjail", UnjailWithWallet(blockchain, walletMgr, p2pService)).Methods("POST") // 다운타임 감금 해제 (내부 전용)
 
 	return r
 }
@@ -50,13 +50,64 @@ type TxResp struct {
 	Fee       uint64        `json:"fee"` // Implicit fee (InputSum - OutputSum)
 }
 
+// Block template response (next block candidate txs)
+type BlockTemplateResp struct {
+	Height    uint64           `json:"height"`
+	TxCount   int              `json:"txCount"`
+	TotalFees uint64           `json:"totalFees"`
+	Size      uint64           `json:"size"`    // Sum of included tx sizes (bytes, coinbase excluded)
+	MaxSize   uint64           `json:"maxSize"` // Byte limit for non-coinbase txs
+	MaxTxs    int              `json:"maxTxs"`  // Tx count limit for non-coinbase txs
+	Included  []TemplateTxResp `json:"included"`
+	Skipped   []TemplateTxResp `json:"skipped"` // Mempool txs left out (with reason)
+}
+
+type TemplateTxResp struct {
+	TxID    string  `json:"txId"`
+	Fee     uint64  `json:"fee"`
+	Size    uint64  `json:"size"`
+	FeeRate float64 `json:"feeRate"` // Fee per byte
+	Reason  string  `json:"reason,omitempty"`
+}
+
+// Fee estimate response
+type FeeEstimateResp struct {
+	NextBlock     FeeSuggestionResp `json:"nextBlock"`
+	Within3Blocks FeeSuggestionResp `json:"within3Blocks"`
+	Economy       FeeSuggestionResp `json:"economy"`
+	TxSize        uint64            `json:"txSize"` // Tx size (bytes) fees are calculated for
+	MinFee        uint64            `json:"minFee"`
+	MempoolTxs    int               `json:"mempoolTxs"`
+	MempoolBytes  uint64            `json:"mempoolBytes"`
+	RecentBlocks  int               `json:"recentBlocks"` // Blocks used for history
+	FullBlocks    int               `json:"fullBlocks"`   // Full blocks among them
+}
+
+type FeeSuggestionResp struct {
+	TargetBlocks int     `json:"targetBlocks"`
+	FeeRate      float64 `json:"feeRate"` // Fee per byte
+	Fee          uint64  `json:"fee"`     // Suggested fee for txSize (use as `fee` of /tx/send)
+}
+
 type SubmitTxReq struct {
-	From   string `json:"from"`
-	To     string `json:"to"`
-	Amount uint64 `json:"amount"`
-	Fee    uint64 `json:"fee"` // Fee (optional, minimum fee applies if 0)
-	Memo   string `json:"memo"`
-	Data   []byte `json:"data"`
+	From       string         `json:"from"`
+	To         string         `json:"to"`
+	Amount     uint64         `json:"amount"`
+	Recipients []RecipientReq `json:"recipients"` // Multiple recipients in one tx (use instead of to/amount)
+	Fee        uint64         `json:"fee"`        // Fee (optional, minimum fee applies if 0)
+	Memo       string         `json:"memo"`
+	Data       []byte         `json:"data"`
+}
+
+// Recipient of a multi recipient transfer
+type RecipientReq struct {
+	Address string `json:"address"` // hex string
+	Amount  uint64 `json:"amount"`
+
+	// Optional timelocks of the output
+	LockHeight         uint64 `json:"lockHeight,omitempty"`         // Spendable from this block height
+	LockTime           int64  `json:"lockTime,omitempty"`           // Spendable from this block timestamp
+	RelativeLockHeight uint64 `json:"relativeLockHeight,omitempty"` // Spendable this many blocks after confirmation
 }
 
 // Submit signed transaction request (signed by client)
@@ -74,27 +125,199 @@ type SignedTxInput struct {
 	OutputIndex uint64 `json:"outputIndex"`
 	Signature   string `json:"signature"` // hex string
 	PublicKey   string `json:"publicKey"` // hex string
+	Sequence    uint64 `json:"sequence"`  // >= 1 opts in to replace-by-fee
+	Preimage    string `json:"preimage"`  // hex, HTLC claim only (part of tx hash)
 }
 
 type TxOutputReq struct {
 	Address string `json:"address"` // hex string
 	Amount  uint64 `json:"amount"`
 	TxType  uint8  `json:"txType"`
+
+	// Optional timelocks (must match the signed tx, omitted from tx hash when 0)
+	LockHeight         uint64 `json:"lockHeight"`
+	LockTime           int64  `json:"lockTime"`
+	RelativeLockHeight uint64 `json:"relativeLockHeight"`
+
+	// Optional hash timelock contract (txType must be 5 and address the contract address)
+	HTLC *HashLockReq `json:"htlc"`
+}
+
+type HashLockReq struct {
+	Hash      string `json:"hash"`      // hex, sha256 of the preimage
+	Recipient string `json:"recipient"` // hex address
+	Refund    string `json:"refund"`    // hex address
+	Expiry    uint64 `json:"expiry"`    // Block height
 }
 
 // Send request using server wallet
 type SendTxReq struct {
-	AccountIndex int    `json:"accountIndex"` // Wallet account index (default 0)
-	To           string `json:"to"`
+	AccountIndex int            `json:"accountIndex"` // Wallet account index (default 0)
+	To           string         `json:"to"`
+	Amount       uint64         `json:"amount"`
+	Recipients   []RecipientReq `json:"recipients"` // Multiple recipients in one tx (use instead of to/amount)
+	Fee          uint64         `json:"fee"`        // Fee (optional, minimum fee applies if 0)
+	Memo         string         `json:"memo"`
+	Data         []byte         `json:"data"`
+	Replaceable  bool           `json:"replaceable"` // Opt in to replace-by-fee
+	// Coin selection strategy: "bnb" (default, exact match without change), "largest", "smallest", "random"
+	CoinSelection string `json:"coinSelection"`
+}
+
+// Bump fee of a pending replaceable tx using server wallet
+type BumpFeeReq struct {
+	AccountIndex int    `json:"accountIndex"` // Wallet account index which signed the tx (default 0)
+	TxID         string `json:"txId"`         // Pending tx to replace
+	Fee          uint64 `json:"fee"`          // New total fee (must be higher than current fee)
+}
+
+// Consolidate UTXOs of a server wallet account
+type SweepReq struct {
+	AccountIndex   int    `json:"accountIndex"`   // Wallet account index to sweep (default 0)
+	To             string `json:"to"`             // Destination address (optional, default: same account)
+	MaxInputsPerTx int    `json:"maxInputsPerTx"` // Inputs per sweep tx (optional, default 50, max 200)
+	MaxTxs         int    `json:"maxTxs"`         // Sweep txs to create (optional, default 10)
+	MaxUtxoAmount  uint64 `json:"maxUtxoAmount"`  // Only sweep UTXOs worth at most this amount (optional)
+	Fee            uint64 `json:"fee"`            // Fee per tx (optional, economy estimate if 0)
+}
+
+// Wallet account response
+type WalletAccountResp struct {
+	Index     int    `json:"index"`
+	Address   string `json:"address"`
+	Path      string `json:"path"`
+	PublicKey string `json:"publicKey"` // hex (used for multisig key sets)
+}
+
+// Multisig key set (m-of-n)
+type MultisigScriptReq struct {
+	Threshold  int      `json:"threshold"`
+	PublicKeys []string `json:"publicKeys"` // hex, any order
+}
+
+// Create unsigned multisig spend
+type MultisigTxReq struct {
+	MultisigScriptReq
+	Recipients []RecipientReq `json:"recipients"`
+	Fee        uint64         `json:"fee"` // Fee (optional, minimum fee applies if 0)
+	Memo       string         `json:"memo"`
+	Data       []byte         `json:"data"`
+}
+
+// Sign multisig tx with server wallet account
+type MultisigSignReq struct {
+	AccountIndex int    `json:"accountIndex"`
+	Tx           string `json:"tx"` // Encoded tx (hex)
+}
+
+// Merge signatures of copies of the same multisig tx
+type MultisigCombineReq struct {
+	Txs []string `json:"txs"` // Encoded txs (hex)
+}
+
+// Submit fully signed multisig tx
+type MultisigSubmitReq struct {
+	Tx string `json:"tx"` // Encoded tx (hex)
+}
+
+type MultisigTxResp struct {
+	TxID     string `json:"txId"`
+	Tx       string `json:"tx"`       // Encoded tx (hex), pass to the next signer
+	Signed   []int  `json:"signed"`   // Valid signatures per input
+	Required []int  `json:"required"` // Threshold per input
+	Complete bool   `json:"complete"` // Ready to submit
+}
+
+// Create partially signed tx (signing keys stay off the node)
+type PartialTxCreateReq struct {
+	PublicKey  string         `json:"publicKey"`  // Single key owner (hex)
+	Threshold  int            `json:"threshold"`  // Multisig owner threshold (use with publicKeys instead of publicKey)
+	PublicKeys []string       `json:"publicKeys"` // Multisig owner keys (hex)
+	Recipients []RecipientReq `json:"recipients"`
+	Fee        uint64         `json:"fee"` // Fee (optional, minimum fee applies if 0)
+	Memo       string         `json:"memo"`
+	Data       []byte         `json:"data"`
+	// Opt in to replace-by-fee
+	Replaceable bool `json:"replaceable"`
+	// Coin selection strategy: "bnb" (default), "largest", "smallest", "random"
+	CoinSelection string `json:"coinSelection"`
+}
+
+// Partially signed tx request (inspect, finalize)
+type PartialTxReq struct {
+	Psbt string `json:"psbt"` // Encoded partial tx (hex)
+}
+
+// Merge signatures of copies of the same partial tx
+type PartialTxCombineReq struct {
+	Psbts []string `json:"psbts"` // Encoded partial txs (hex)
+}
+
+// Broadcast fully signed partial tx (or tx finalized offline)
+type PartialTxBroadcastReq struct {
+	Psbt string `json:"psbt"` // Encoded partial tx (hex)
+	Tx   string `json:"tx"`   // Finalized tx (hex), use instead of psbt
+}
+
+type PartialTxResp struct {
+	TxID       string        `json:"txId"`
+	Psbt       string        `json:"psbt"`   // Encoded partial tx (hex), pass to the next signer
+	Inputs     []interface{} `json:"inputs"` // Spent outputs
+	Outputs    []interface{} `json:"outputs"`
+	InputTotal uint64        `json:"inputTotal"`
+	Fee        uint64        `json:"fee"`
+	Signed     []int         `json:"signed"`               // Valid signatures per input
+	Required   []int         `json:"required"`             // Signatures required per input
+	Complete   bool          `json:"complete"`             // Ready to finalize
+	ChainCheck string        `json:"chainCheck,omitempty"` // "ok" or why the spent outputs do not match the chain (inspect only)
+}
+
+// Lock funds of server wallet account in a hash timelock contract
+type HTLCLockReq struct {
+	AccountIndex int    `json:"accountIndex"` // Wallet account which funds the HTLC (default 0)
+	Recipient    string `json:"recipient"`    // Claims with the preimage before expiry
+	Refund       string `json:"refund"`       // Takes the funds back after expiry (optional, default: funding account)
+	Hash         string `json:"hash"`         // sha256 of the preimage (hex, optional: a new preimage is generated if empty)
+	Expiry       uint64 `json:"expiry"`       // Expiry block height
+	ExpiryBlocks uint64 `json:"expiryBlocks"` // Expiry relative to the next block (use instead of expiry)
 	Amount       uint64 `json:"amount"`
 	Fee          uint64 `json:"fee"` // Fee (optional, minimum fee applies if 0)
 	Memo         string `json:"memo"`
-	Data         []byte `json:"data"`
 }
 
-// Wallet account response
-type WalletAccountResp struct {
-	Index   int    `json:"index"`
-	Address string `json:"address"`
-	Path    string `json:"path"`
+// Claim (with preimage) or refund HTLC output using server wallet account
+type HTLCSpendReq struct {
+	AccountIndex int    `json:"accountIndex"` // Wallet account of the recipient (claim) or refund address (refund)
+	TxID         string `json:"txId"`         // Tx which created the HTLC output
+	OutputIndex  uint64 `json:"outputIndex"`
+	Preimage     string `json:"preimage"` // hex (claim only)
+	To           string `json:"to"`       // Destination address (optional, default: signing account)
+	Fee          uint64 `json:"fee"`      // Fee (optional, minimum fee applies if 0)
+}
+
+// Stake or unstake using server wallet account (staked outputs are paid back to the account)
+type StakeReq struct {
+	AccountIndex int    `json:"accountIndex"` // Wallet account index (default 0)
+	Amount       uint64 `json:"amount"`
+	Fee          uint64 `json:"fee"` // Fee (optional, minimum fee applies if 0)
+}
+
+// Delegate to or undelegate from a validator using server wallet account (delegated outputs stay owned by the account)
+type DelegateReq struct {
+	AccountIndex int    `json:"accountIndex"` // Wallet account index (default 0)
+	Validator    string `json:"validator"`    // Validator address
+	Amount       uint64 `json:"amount"`
+	Fee          uint64 `json:"fee"` // Fee (optional, minimum fee applies if 0)
+}
+
+// Claim delegation rewards of server wallet account
+type ClaimRewardReq struct {
+	AccountIndex int    `json:"accountIndex"` // Wallet account index (default 0)
+	Fee          uint64 `json:"fee"`          // Fee paid from the reward (optional, minimum fee applies if 0)
+}
+
+// Unjail the validator of server wallet account jailed for downtime
+type UnjailReq struct {
+	AccountIndex int    `json:"accountIndex"` // Wallet account index of the validator (default 0)
+	Fee          uint64 `json:"fee"`          // Fee (optional, minimum fee applies if 0)
 }
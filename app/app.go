@@ -34,6 +34,7 @@ type App struct {
 	// Consensus & P2P
 	Consensus       *consensus.Consensus
 	ConsensusEngine *consensus.ConsensusEngine
+	ConsensusWAL    *consensus.WAL // Signed proposals/votes (validators only)
 	P2PService      *p2p.P2PService
 }
 
@@ -68,6 +69,23 @@ func New(configPath string) (*App, error) {
 		return nil, err
 	}
 
+	if err := core.ConfigConsensusTiming(cfg).Validate(); err != nil {
+		logger.Error("Invalid consensus timing: ", err)
+		return nil, err
+	}
+	// Genesis timing (known now unless the genesis block is synced from peers later)
+	if timing, err := bc.GetConsensusTiming(); err == nil {
+		logger.Info("Consensus timing: proposing ", timing.ProposingMs, "ms, voting ", timing.VotingMs, "ms, committing ", timing.CommittingMs,
+			"ms, round timeout ", timing.RoundTimeoutMs, "ms + ", timing.RoundTimeoutDeltaMs, "ms/round")
+	}
+
+	// Reload pending txs saved before the last shutdown
+	if loaded, dropped, err := bc.LoadMempool(); err != nil {
+		logger.Error("Failed to load mempool snapshot: ", err)
+	} else if loaded > 0 || dropped > 0 {
+		logger.Info("[Mempool] Restored ", loaded, " txs from snapshot (", dropped, " dropped)")
+	}
+
 	// Initialize Consensus
 	cons, err := consensus.NewConsensus(cfg, db)
 	if err != nil {
@@ -77,20 +95,43 @@ func New(configPath string) (*App, error) {
 
 	// If BlockProducer, register as validator
 	if cfg.Common.BlockProducer {
-		account := wallet.Wallet.Accounts[0]
-		if err := cons.RegisterValidator(account.Address, account.PublicKey, account.PrivateKey); err != nil {
+		address, publicKey, signer, err := newValidatorSigner(cfg, wallet)
+		if err != nil {
+			logger.Error("Failed to load validator signer: ", err)
+			return nil, err
+		}
+		if err := cons.RegisterValidator(address, publicKey, signer); err != nil {
 			logger.Error("Failed to register validator: ", err)
 			return nil, err
 		}
-		logger.Info("Registered as validator: ", crypto.AddressTo0xPrefixString(account.Address))
+		logger.Info("Registered as validator: ", crypto.AddressTo0xPrefixString(address))
 	}
 
 	// Initialize Consensus Engine
 	consEngine := consensus.NewConsensusEngine(cons, bc)
 
+	// Validators log signed proposals/votes in the data directory (replayed on restart against double-signing)
+	var consWAL *consensus.WAL
+	if cfg.Common.BlockProducer {
+		walPath := fmt.Sprintf("%sconsensus_wal_%d.log", cfg.DB.Path, cfg.Common.Port)
+		consWAL, err = consensus.OpenWAL(walPath)
+		if err != nil {
+			logger.Error("Failed to open consensus WAL: ", err)
+			return nil, err
+		}
+		consEngine.SetWAL(consWAL)
+		logger.Info("Consensus WAL opened: ", walPath)
+	}
+
 	// Set ProposerValidator in BlockChain (for PoA verification)
 	bc.SetProposerValidator(cons)
 
+	// Load validator set of the current epoch from chain data
+	if err := cons.SyncValidators(bc); err != nil {
+		logger.Error("Failed to sync validators: ", err)
+		return nil, err
+	}
+
 	// Initialize P2P (Create first to connect to ConsensusEngine)
 	p2pService, err := p2p.NewP2PService(
 		cfg.P2P.Address,
@@ -111,6 +152,7 @@ func New(configPath string) (*App, error) {
 		Wallet:          wallet,
 		Consensus:       cons,
 		ConsensusEngine: consEngine,
+		ConsensusWAL:    consWAL,
 		P2PService:      p2pService,
 	}
 
@@ -182,8 +224,16 @@ func New(configPath string) (*App, error) {
 	})
 
 	// Deliver Proposal to ConsensusEngine when received from P2P
-	app.P2PService.SetProposalHandler(func(height uint64, round uint32, blockHash prt.Hash, block *core.Block) {
-		app.ConsensusEngine.HandleProposal(height, round, blockHash, block)
+	app.P2PService.SetProposalHandler(func(height uint64, round uint32, polRound int32, blockHash prt.Hash, block *core.Block, proposer prt.Address, signature prt.Signature) {
+		app.ConsensusEngine.HandleProposal(&consensus.Proposal{
+			Height:    height,
+			Round:     round,
+			POLRound:  polRound,
+			BlockHash: blockHash,
+			Block:     block,
+			Proposer:  proposer,
+			Signature: signature,
+		})
 	})
 
 	// Deliver Vote to ConsensusEngine when received from P2P
@@ -204,22 +254,17 @@ func New(configPath string) (*App, error) {
 		// Transaction ID string
 		txID := utils.HashToString(tx.ID)
 
-		// 1. Validate
-		if err := app.BlockChain.ValidateTransaction(tx); err != nil {
-			logger.Debug("[TxHandler] Invalid tx received: ", txID[:16], " error: ", err)
-			return
-		}
-
-		// 2. Add to Mempool
-		// Returns error if already exists, preventing duplicate propagation
-		if err := app.BlockChain.Mempool.NewTranaction(tx); err != nil {
-			// Do not propagate if already exists or error occurs
+		// 1. Validate and add to Mempool (replace-by-fee evicts conflicting replaceable txs)
+		// Returns error if invalid or already exists, preventing duplicate propagation
+		replaced, err := app.BlockChain.AddTxToMempool(tx)
+		if err != nil {
+			logger.Debug("[TxHandler] Tx rejected: ", txID[:16], " error: ", err)
 			return
 		}
 
-		logger.Debug("[TxHandler] Added tx to mempool: ", txID[:16])
+		logger.Debug("[TxHandler] Added tx to mempool: ", txID[:16], " replaced: ", len(replaced))
 
-		// 3. Rebroadcast to other peers (Gossip)
+		// 2. Rebroadcast to other peers (Gossip)
 		// If I received valid transaction for the first time, tell other peers
 		if app.P2PService != nil {
 			if err := app.P2PService.BroadcastTx(tx); err != nil {
@@ -253,8 +298,11 @@ func New(configPath string) (*App, error) {
 			return
 		}
 
-		if block.Header.Height <= currentHeight {
-			logger.Debug("[BlockHandler] Block already exists, skipping")
+		// Block on another branch (or already known): let fork choice decide
+		latestHash, _ := app.BlockChain.GetLatestBlockHash()
+		if block.Header.Height <= currentHeight ||
+			(block.Header.Height == currentHeight+1 && utils.HashToString(block.Header.PrevHash) != latestHash) {
+			app.handleForkBlock(block)
 			return
 		}
 
@@ -342,6 +390,53 @@ func New(configPath string) (*App, error) {
 	return app, nil
 }
 
+// handleForkBlock imports a block which does not extend the local tip.
+// It is stored as side-chain block, triggers a reorg when its branch wins the fork choice,
+// or requests the missing ancestors when its parent is unknown.
+func (p *App) handleForkBlock(block *core.Block) {
+	status, err := p.BlockChain.ImportBlock(*block)
+	if err != nil {
+		logger.Warn("[BlockHandler] Fork block rejected height=", block.Header.Height, ": ", err)
+		return
+	}
+
+	switch status {
+	case core.BlockImportKnown:
+		logger.Debug("[BlockHandler] Block already exists, skipping")
+
+	case core.BlockImportOrphan:
+		// Walk back towards the common ancestor
+		peers := p.P2PService.GetPeers()
+		if len(peers) > 0 && block.Header.Height > 0 {
+			var startHeight uint64
+			if block.Header.Height > core.MaxReorgDepth {
+				startHeight = block.Header.Height - core.MaxReorgDepth
+			}
+			if err := p.P2PService.RequestBlocks(peers[0], startHeight, block.Header.Height-1); err != nil {
+				logger.Error("[BlockHandler] Failed to request fork ancestors: ", err)
+			}
+		}
+
+	case core.BlockImportSideChain:
+		logger.Info("[BlockHandler] Side-chain block stored: height=", block.Header.Height)
+
+	case core.BlockImportExtended, core.BlockImportReorged:
+		newHeight, _ := p.BlockChain.GetLatestHeight()
+		if status == core.BlockImportReorged {
+			logger.Warn("[BlockHandler] Chain reorganized to height ", newHeight)
+		}
+		// BFT: Sync consensus height to the new tip
+		if p.Consensus != nil {
+			p.Consensus.UpdateHeight(newHeight + 1)
+		}
+		if p.P2PService != nil {
+			if err := p.P2PService.BroadcastBlock(block); err != nil {
+				logger.Debug("[BlockHandler] Failed to rebroadcast block: ", err)
+			}
+		}
+	}
+}
+
 func (p *App) NewRest() error {
 	// Start REST API server
 	if err := p.restServer.Start(); err != nil {
@@ -430,6 +525,9 @@ func (p *App) StartAll() error {
 		go p.startPeriodicSync()
 	}
 
+	// 멤풀 스냅샷 주기 저장
+	go p.startMempoolSnapshot()
+
 	// BFT 모드: 모든 검증자 노드에서 컨센서스 시작 (투표 참여를 위해)
 	// BlockProducer 여부와 관계없이 컨센서스 엔진은 모든 노드에서 실행되어야 함
 	if err := p.StartConsensus(); err != nil {
@@ -464,6 +562,28 @@ func (p *App) startPeriodicSync() {
 	}
 }
 
+// startMempoolSnapshot 멤풀 스냅샷 주기 저장 (비정상 종료 대비)
+func (p *App) startMempoolSnapshot() {
+	interval := core.DefaultMempoolSnapshotInterval
+	if p.Conf.Mempool.SnapshotIntervalSec > 0 {
+		interval = time.Duration(p.Conf.Mempool.SnapshotIntervalSec) * time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			if err := p.BlockChain.SaveMempool(); err != nil {
+				logger.Error("[Mempool] Failed to save snapshot: ", err)
+			}
+		}
+	}
+}
+
 // Cleanup 애플리케이션 정리
 func (p *App) Cleanup() {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -475,6 +595,13 @@ func (p *App) Cleanup() {
 		logger.Info("Consensus engine stopped")
 	}
 
+	// Consensus WAL 닫기
+	if p.ConsensusWAL != nil {
+		if err := p.ConsensusWAL.Close(); err != nil {
+			logger.Error("Error closing consensus WAL:", err)
+		}
+	}
+
 	// P2P 서비스 종료
 	if p.P2PService != nil {
 		if err := p.P2PService.Stop(); err != nil {
@@ -491,6 +618,15 @@ func (p *App) Cleanup() {
 		}
 	}
 
+	// 멤풀 스냅샷 저장 (재시작 후 복원)
+	if p.BlockChain != nil {
+		if err := p.BlockChain.SaveMempool(); err != nil {
+			logger.Error("Error saving mempool snapshot:", err)
+		} else {
+			logger.Info("Mempool snapshot saved (", p.BlockChain.Mempool.GetTxCount(), " txs)")
+		}
+	}
+
 	// DB 연결 닫기
 	if p.DB != nil {
 		if err := p.DB.Close(); err != nil {
@@ -501,6 +637,38 @@ func (p *App) Cleanup() {
 	logger.Info("All resources cleaned up")
 }
 
+// newValidatorSigner returns validator address, public key and signer: the remote signer process if
+// [consensus] signerAddress is set (the key stays out of the node), else the first wallet account
+func newValidatorSigner(cfg *conf.Config, wm *wallet.WalletManager) (prt.Address, []byte, consensus.Signer, error) {
+	if cfg.Consensus.SignerAddress == "" {
+		account := wm.Wallet.Accounts[0]
+		signer, err := consensus.NewLocalSigner(account.PrivateKey)
+		if err != nil {
+			return prt.Address{}, nil, nil, err
+		}
+		return account.Address, account.PublicKey, signer, nil
+	}
+
+	signer, err := consensus.NewRemoteSigner(cfg.Consensus.SignerAddress, []byte(cfg.Consensus.SignerSecret))
+	if err != nil {
+		return prt.Address{}, nil, nil, err
+	}
+	publicKey, err := signer.PublicKey()
+	if err != nil {
+		return prt.Address{}, nil, nil, fmt.Errorf("failed to get public key from signer: %w", err)
+	}
+	pub, err := crypto.BytesToPublicKey(publicKey)
+	if err != nil {
+		return prt.Address{}, nil, nil, err
+	}
+	address, err := crypto.PublicKeyToAddress(pub)
+	if err != nil {
+		return prt.Address{}, nil, nil, err
+	}
+	logger.Info("Using remote signer: ", cfg.Consensus.SignerAddress)
+	return address, publicKey, signer, nil
+}
+
 func (p *App) Wait() {
 	<-p.stop // 채널에서 값 읽으려고 시도
 }
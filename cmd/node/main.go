@@ -1,18 +1,28 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"encoding/csv"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"os/exec"
 	"os/signal"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"syscall"
 
+	"github.com/abcfe/abcfe-node/api/rest"
 	"github.com/abcfe/abcfe-node/app"
 	"github.com/abcfe/abcfe-node/common/logger"
+	conf "github.com/abcfe/abcfe-node/config"
+	"github.com/abcfe/abcfe-node/core"
+	"github.com/abcfe/abcfe-node/storage"
 	"github.com/abcfe/abcfe-node/wallet"
 	"github.com/spf13/cobra"
 )
@@ -103,6 +113,8 @@ func nodeCmd() *cobra.Command {
 		},
 	})
 
+	cmd.AddCommand(nodeRollbackCmd())
+
 	cmd.AddCommand(&cobra.Command{
 		Use:   "info",
 		Short: "Show detailed node information",
@@ -116,6 +128,60 @@ func nodeCmd() *cobra.Command {
 	return cmd
 }
 
+// Roll back chain to height (node must be stopped)
+func nodeRollbackCmd() *cobra.Command {
+	var height uint64
+
+	cmd := &cobra.Command{
+		Use:   "rollback",
+		Short: "Roll back the chain to a given height",
+		Long:  `Rolls back committed blocks above the given height using the per-block undo records. The node must be stopped.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if isRunning(pidFile) {
+				fmt.Println("Node is running. Stop the node before rolling back.")
+				return
+			}
+
+			cfg, err := conf.NewConfig(configFile)
+			if err != nil {
+				fmt.Printf("Failed to load config: %v\n", err)
+				return
+			}
+			if err := logger.InitLogger(cfg); err != nil {
+				fmt.Printf("Failed to initialize logger: %v\n", err)
+				return
+			}
+
+			db, err := storage.InitDB(cfg)
+			if err != nil {
+				fmt.Printf("Failed to open db: %v\n", err)
+				return
+			}
+			defer db.Close()
+
+			bc, err := core.NewChainState(db, cfg)
+			if err != nil {
+				fmt.Printf("Failed to load chain state: %v\n", err)
+				return
+			}
+
+			fromHeight, _ := bc.GetLatestHeight()
+			if err := bc.RollbackToHeight(height); err != nil {
+				fmt.Printf("Failed to roll back: %v\n", err)
+				return
+			}
+
+			latestHash, _ := bc.GetLatestBlockHash()
+			fmt.Printf("Rolled back from height %d to %d\n", fromHeight, height)
+			fmt.Printf("Latest block hash: %s\n", latestHash)
+		},
+	}
+
+	cmd.Flags().Uint64Var(&height, "height", 0, "Target block height")
+	cmd.MarkFlagRequired("height")
+	return cmd
+}
+
 func runNode() {
 	application, err := app.New(configFile)
 	if err != nil {
@@ -366,6 +432,16 @@ func walletCmd() *cobra.Command {
 	cmd.AddCommand(walletListCmd())
 	cmd.AddCommand(walletAddAccountCmd())
 	cmd.AddCommand(walletShowMnemonicCmd())
+	cmd.AddCommand(walletBatchPayCmd())
+	cmd.AddCommand(walletSweepCmd())
+	cmd.AddCommand(walletStakeCmd())
+	cmd.AddCommand(walletUnstakeCmd())
+	cmd.AddCommand(walletDelegateCmd())
+	cmd.AddCommand(walletUndelegateCmd())
+	cmd.AddCommand(walletClaimRewardsCmd())
+	cmd.AddCommand(walletUnjailCmd())
+	cmd.AddCommand(walletMultisigCmd())
+	cmd.AddCommand(walletPsbtCmd())
 
 	return cmd
 }
@@ -500,6 +576,7 @@ func walletListCmd() *cobra.Command {
 				fmt.Printf("[%d]%s\n", i, current)
 				fmt.Printf("  Address: %s\n", hex.EncodeToString(account.Address[:]))
 				fmt.Printf("  Path: %s\n", account.Path)
+				fmt.Printf("  Public Key: %s\n", hex.EncodeToString(account.PublicKey))
 				fmt.Println("")
 			}
 		},
@@ -537,6 +614,7 @@ func walletAddAccountCmd() *cobra.Command {
 			fmt.Printf("Index: %d\n", account.Index)
 			fmt.Printf("Address: %s\n", hex.EncodeToString(account.Address[:]))
 			fmt.Printf("Path: %s\n", account.Path)
+			fmt.Printf("Public Key: %s\n", hex.EncodeToString(account.PublicKey))
 		},
 	}
 }
@@ -568,3 +646,430 @@ func walletShowMnemonicCmd() *cobra.Command {
 		},
 	}
 }
+
+// Pay many recipients from a payout file using the running node's wallet
+func walletBatchPayCmd() *cobra.Command {
+	var (
+		file          string
+		nodeURL       string
+		accountIndex  int
+		fee           uint64
+		memo          string
+		replaceable   bool
+		coinSelection string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "batch-pay",
+		Short: "Pay multiple recipients in batched transactions",
+		Long: `Reads "address,amount[,lockHeight]" lines from a CSV file and sends them through the node's internal API (/api/v1/tx/send).
+Recipients are packed into as few transactions as possible (one output per recipient, one change output, one fee per tx).
+The node must be running; transactions are signed with the node wallet account given by --account.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			recipients, err := readPayoutFile(file)
+			if err != nil {
+				fmt.Printf("Failed to read payout file: %v\n", err)
+				return
+			}
+
+			var total uint64
+			for _, recipient := range recipients {
+				total += recipient.Amount
+			}
+			fmt.Printf("Paying %d recipients, total amount %d\n", len(recipients), total)
+
+			// Later batches spend unconfirmed change of earlier ones
+			for start := 0; start < len(recipients); start += core.MaxTxRecipients {
+				end := start + core.MaxTxRecipients
+				if end > len(recipients) {
+					end = len(recipients)
+				}
+
+				req := rest.SendTxReq{
+					AccountIndex:  accountIndex,
+					Recipients:    recipients[start:end],
+					Fee:           fee,
+					Memo:          memo,
+					Replaceable:   replaceable,
+					CoinSelection: coinSelection,
+				}
+				txId, err := postSendTx(nodeURL, &req)
+				if err != nil {
+					fmt.Printf("Failed to send recipients %d-%d: %v\n", start+1, end, err)
+					if start > 0 {
+						fmt.Printf("Recipients 1-%d were already sent\n", start)
+					}
+					return
+				}
+				fmt.Printf("Sent recipients %d-%d: txId=%s\n", start+1, end, txId)
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&file, "file", "f", "", "CSV payout file (address,amount[,lockHeight] per line, # for comments)")
+	cmd.Flags().StringVar(&nodeURL, "node", "http://localhost:8800", "Node internal REST API URL")
+	cmd.Flags().IntVarP(&accountIndex, "account", "a", 0, "Node wallet account index to pay from")
+	cmd.Flags().Uint64Var(&fee, "fee", 0, "Fee per transaction (0 = minimum fee)")
+	cmd.Flags().StringVar(&memo, "memo", "", "Memo attached to every transaction")
+	cmd.Flags().BoolVar(&replaceable, "replaceable", false, "Opt in to replace-by-fee")
+	cmd.Flags().StringVar(&coinSelection, "coin-selection", core.DefaultCoinSelection, "Coin selection strategy (bnb, largest, smallest, random)")
+	cmd.MarkFlagRequired("file")
+	return cmd
+}
+
+// Consolidate small UTXOs of a node wallet account
+func walletSweepCmd() *cobra.Command {
+	var (
+		nodeURL string
+		req     rest.SweepReq
+	)
+
+	cmd := &cobra.Command{
+		Use:   "sweep",
+		Short: "Consolidate small UTXOs into larger outputs",
+		Long: `Spends confirmed UTXOs of a node wallet account (smallest first) into one output per transaction
+through the node's internal API (/api/v1/wallet/sweep). The node must be running.
+Run again to continue when UTXOs remain.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			var result struct {
+				Address   string   `json:"address"`
+				TxIDs     []string `json:"txIds"`
+				Swept     int      `json:"swept"`
+				Amount    uint64   `json:"amount"`
+				Fees      uint64   `json:"fees"`
+				Remaining int      `json:"remaining"`
+			}
+			err := postNodeAPI(nodeURL, "/wallet/sweep", &req, &result)
+
+			for _, txId := range result.TxIDs {
+				fmt.Printf("Sweep tx: %s\n", txId)
+			}
+			if err != nil {
+				fmt.Printf("Failed to sweep: %v\n", err)
+				return
+			}
+
+			fmt.Println("=== Sweep Result ===")
+			fmt.Printf("Address: %s\n", result.Address)
+			fmt.Printf("Transactions: %d\n", len(result.TxIDs))
+			fmt.Printf("UTXOs swept: %d\n", result.Swept)
+			fmt.Printf("Amount: %d (fees %d)\n", result.Amount, result.Fees)
+			if result.Remaining > 0 {
+				fmt.Printf("Remaining UTXOs: %d (run sweep again)\n", result.Remaining)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&nodeURL, "node", "http://localhost:8800", "Node internal REST API URL")
+	cmd.Flags().IntVarP(&req.AccountIndex, "account", "a", 0, "Node wallet account index to sweep")
+	cmd.Flags().StringVar(&req.To, "to", "", "Destination address (default: same account)")
+	cmd.Flags().IntVar(&req.MaxInputsPerTx, "max-inputs", core.DefaultSweepInputsPerTx, "Inputs per sweep transaction")
+	cmd.Flags().IntVar(&req.MaxTxs, "max-txs", core.DefaultSweepMaxTxs, "Sweep transactions to create")
+	cmd.Flags().Uint64Var(&req.MaxUtxoAmount, "max-utxo-amount", 0, "Only sweep UTXOs worth at most this amount (0 = all)")
+	cmd.Flags().Uint64Var(&req.Fee, "fee", 0, "Fee per transaction (0 = economy estimate)")
+	return cmd
+}
+
+// Stake funds of a node wallet account
+func walletStakeCmd() *cobra.Command {
+	var (
+		nodeURL string
+		req     rest.StakeReq
+	)
+
+	cmd := &cobra.Command{
+		Use:   "stake",
+		Short: "Stake funds of a node wallet account",
+		Long: `Locks funds as stake through the node's internal API (/api/v1/staking/stake).
+The stake counts for validator selection from the block after the stake tx is committed.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			var result struct {
+				TxID    string `json:"txId"`
+				Address string `json:"address"`
+			}
+			if err := postNodeAPI(nodeURL, "/staking/stake", &req, &result); err != nil {
+				fmt.Printf("Failed to stake: %v\n", err)
+				return
+			}
+			fmt.Printf("Staked %d from %s: txId=%s\n", req.Amount, result.Address, result.TxID)
+		},
+	}
+
+	cmd.Flags().StringVar(&nodeURL, "node", "http://localhost:8800", "Node internal REST API URL")
+	cmd.Flags().IntVarP(&req.AccountIndex, "account", "a", 0, "Node wallet account index to stake from")
+	cmd.Flags().Uint64Var(&req.Amount, "amount", 0, "Amount to stake")
+	cmd.Flags().Uint64Var(&req.Fee, "fee", 0, "Fee (0 = minimum fee)")
+	cmd.MarkFlagRequired("amount")
+	return cmd
+}
+
+// Unstake funds of a node wallet account
+func walletUnstakeCmd() *cobra.Command {
+	var (
+		nodeURL string
+		req     rest.StakeReq
+	)
+
+	cmd := &cobra.Command{
+		Use:   "unstake",
+		Short: "Unstake funds of a node wallet account",
+		Long: `Releases stake through the node's internal API (/api/v1/staking/unstake). The fee is paid from the stake.
+Unstaked funds stay locked for the unbonding period after the unstake tx is committed.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			var result struct {
+				TxID            string `json:"txId"`
+				Address         string `json:"address"`
+				UnbondingBlocks uint64 `json:"unbondingBlocks"`
+			}
+			if err := postNodeAPI(nodeURL, "/staking/unstake", &req, &result); err != nil {
+				fmt.Printf("Failed to unstake: %v\n", err)
+				return
+			}
+			fmt.Printf("Unstaked %d from %s: txId=%s\n", req.Amount, result.Address, result.TxID)
+			fmt.Printf("Spendable %d blocks after the tx is committed\n", result.UnbondingBlocks)
+		},
+	}
+
+	cmd.Flags().StringVar(&nodeURL, "node", "http://localhost:8800", "Node internal REST API URL")
+	cmd.Flags().IntVarP(&req.AccountIndex, "account", "a", 0, "Node wallet account index to unstake from")
+	cmd.Flags().Uint64Var(&req.Amount, "amount", 0, "Amount to unstake")
+	cmd.Flags().Uint64Var(&req.Fee, "fee", 0, "Fee (0 = minimum fee)")
+	cmd.MarkFlagRequired("amount")
+	return cmd
+}
+
+// Delegate funds of a node wallet account to a validator
+func walletDelegateCmd() *cobra.Command {
+	var (
+		nodeURL string
+		req     rest.DelegateReq
+	)
+
+	cmd := &cobra.Command{
+		Use:   "delegate",
+		Short: "Delegate funds of a node wallet account to a validator",
+		Long: `Delegates funds through the node's internal API (/api/v1/staking/delegate). The funds stay owned by the account.
+The delegation adds to the validator's voting power and earns a share of its block rewards from the next epoch.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			var result struct {
+				TxID    string `json:"txId"`
+				Address string `json:"address"`
+			}
+			if err := postNodeAPI(nodeURL, "/staking/delegate", &req, &result); err != nil {
+				fmt.Printf("Failed to delegate: %v\n", err)
+				return
+			}
+			fmt.Printf("Delegated %d from %s to %s: txId=%s\n", req.Amount, result.Address, req.Validator, result.TxID)
+		},
+	}
+
+	cmd.Flags().StringVar(&nodeURL, "node", "http://localhost:8800", "Node internal REST API URL")
+	cmd.Flags().IntVarP(&req.AccountIndex, "account", "a", 0, "Node wallet account index to delegate from")
+	cmd.Flags().StringVar(&req.Validator, "validator", "", "Validator address")
+	cmd.Flags().Uint64Var(&req.Amount, "amount", 0, "Amount to delegate")
+	cmd.Flags().Uint64Var(&req.Fee, "fee", 0, "Fee (0 = minimum fee)")
+	cmd.MarkFlagRequired("validator")
+	cmd.MarkFlagRequired("amount")
+	return cmd
+}
+
+// Undelegate funds of a node wallet account from a validator
+func walletUndelegateCmd() *cobra.Command {
+	var (
+		nodeURL string
+		req     rest.DelegateReq
+	)
+
+	cmd := &cobra.Command{
+		Use:   "undelegate",
+		Short: "Undelegate funds of a node wallet account from a validator",
+		Long: `Releases a delegation through the node's internal API (/api/v1/staking/undelegate). The fee is paid from the delegation.
+Undelegated funds stay locked for the unbonding period after the undelegate tx is committed.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			var result struct {
+				TxID            string `json:"txId"`
+				Address         string `json:"address"`
+				UnbondingBlocks uint64 `json:"unbondingBlocks"`
+			}
+			if err := postNodeAPI(nodeURL, "/staking/undelegate", &req, &result); err != nil {
+				fmt.Printf("Failed to undelegate: %v\n", err)
+				return
+			}
+			fmt.Printf("Undelegated %d of %s from %s: txId=%s\n", req.Amount, result.Address, req.Validator, result.TxID)
+			fmt.Printf("Spendable %d blocks after the tx is committed\n", result.UnbondingBlocks)
+		},
+	}
+
+	cmd.Flags().StringVar(&nodeURL, "node", "http://localhost:8800", "Node internal REST API URL")
+	cmd.Flags().IntVarP(&req.AccountIndex, "account", "a", 0, "Node wallet account index to undelegate from")
+	cmd.Flags().StringVar(&req.Validator, "validator", "", "Validator address")
+	cmd.Flags().Uint64Var(&req.Amount, "amount", 0, "Amount to undelegate")
+	cmd.Flags().Uint64Var(&req.Fee, "fee", 0, "Fee (0 = minimum fee)")
+	cmd.MarkFlagRequired("validator")
+	cmd.MarkFlagRequired("amount")
+	return cmd
+}
+
+// Claim delegation rewards of a node wallet account
+func walletClaimRewardsCmd() *cobra.Command {
+	var (
+		nodeURL string
+		req     rest.ClaimRewardReq
+	)
+
+	cmd := &cobra.Command{
+		Use:   "claim-rewards",
+		Short: "Claim delegation rewards of a node wallet account",
+		Long: `Withdraws the whole claimable reward balance through the node's internal API (/api/v1/staking/rewards/claim).
+The fee is paid from the reward.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			var result struct {
+				TxID    string `json:"txId"`
+				Address string `json:"address"`
+				Claimed uint64 `json:"claimed"`
+			}
+			if err := postNodeAPI(nodeURL, "/staking/rewards/claim", &req, &result); err != nil {
+				fmt.Printf("Failed to claim rewards: %v\n", err)
+				return
+			}
+			fmt.Printf("Claimed %d to %s: txId=%s\n", result.Claimed, result.Address, result.TxID)
+		},
+	}
+
+	cmd.Flags().StringVar(&nodeURL, "node", "http://localhost:8800", "Node internal REST API URL")
+	cmd.Flags().IntVarP(&req.AccountIndex, "account", "a", 0, "Node wallet account index to claim for")
+	cmd.Flags().Uint64Var(&req.Fee, "fee", 0, "Fee (0 = minimum fee)")
+	return cmd
+}
+
+// Release the validator of a node wallet account jailed for downtime
+func walletUnjailCmd() *cobra.Command {
+	var (
+		nodeURL string
+		req     rest.UnjailReq
+	)
+
+	cmd := &cobra.Command{
+		Use:   "unjail",
+		Short: "Release the validator of a node wallet account jailed for downtime",
+		Long: `Submits an unjail tx through the node's internal API (/api/v1/staking/unjail).
+Allowed once the validator has been jailed for downtimeJailBlocks; double-signers cannot be unjailed.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			var result struct {
+				TxID      string `json:"txId"`
+				Validator string `json:"validator"`
+			}
+			if err := postNodeAPI(nodeURL, "/staking/unjail", &req, &result); err != nil {
+				fmt.Printf("Failed to unjail: %v\n", err)
+				return
+			}
+			fmt.Printf("Unjail of %s submitted: txId=%s\n", result.Validator, result.TxID)
+		},
+	}
+
+	cmd.Flags().StringVar(&nodeURL, "node", "http://localhost:8800", "Node internal REST API URL")
+	cmd.Flags().IntVarP(&req.AccountIndex, "account", "a", 0, "Node wallet account index of the validator")
+	cmd.Flags().Uint64Var(&req.Fee, "fee", 0, "Fee (0 = minimum fee)")
+	return cmd
+}
+
+// readPayoutFile parses "address,amount[,lockHeight]" CSV lines
+func readPayoutFile(path string) ([]rest.RecipientReq, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var recipients []rest.RecipientReq
+	for i, record := range records {
+		if len(record) != 2 && len(record) != 3 {
+			return nil, fmt.Errorf("line %d: expected address,amount[,lockHeight]", i+1)
+		}
+		address := strings.TrimSpace(record[0])
+		amount, err := strconv.ParseUint(strings.TrimSpace(record[1]), 10, 64)
+		if err != nil {
+			// Allow header line
+			if i == 0 {
+				continue
+			}
+			return nil, fmt.Errorf("line %d: invalid amount %q", i+1, record[1])
+		}
+		if amount == 0 {
+			return nil, fmt.Errorf("line %d: amount must be positive", i+1)
+		}
+		recipient := rest.RecipientReq{Address: address, Amount: amount}
+		if len(record) == 3 && strings.TrimSpace(record[2]) != "" {
+			// Vesting: output spendable from this block height
+			lockHeight, err := strconv.ParseUint(strings.TrimSpace(record[2]), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid lock height %q", i+1, record[2])
+			}
+			recipient.LockHeight = lockHeight
+		}
+		recipients = append(recipients, recipient)
+	}
+
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("no recipients in %s", path)
+	}
+	return recipients, nil
+}
+
+// postSendTx sends request to /tx/send and returns tx ID
+func postSendTx(nodeURL string, req *rest.SendTxReq) (string, error) {
+	var data struct {
+		TxID string `json:"txId"`
+	}
+	if err := postNodeAPI(nodeURL, "/tx/send", req, &data); err != nil {
+		return "", err
+	}
+	return data.TxID, nil
+}
+
+// postNodeAPI posts JSON request to node internal REST API and decodes response data
+func postNodeAPI(nodeURL string, path string, req interface{}, data interface{}) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.Post(strings.TrimRight(nodeURL, "/")+"/api/v1"+path, "application/json", bytes.NewBuffer(body))
+	if err != nil {
+		return fmt.Errorf("failed to reach node: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	var result struct {
+		Success bool            `json:"success"`
+		Data    json.RawMessage `json:"data"`
+		Error   string          `json:"error"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return fmt.Errorf("unexpected response (%d): %s", resp.StatusCode, string(respBody))
+	}
+	if len(result.Data) > 0 && data != nil {
+		if err := json.Unmarshal(result.Data, data); err != nil {
+			return fmt.Errorf("failed to decode response data: %w", err)
+		}
+	}
+	if !result.Success {
+		return fmt.Errorf("%s", result.Error)
+	}
+	return nil
+}
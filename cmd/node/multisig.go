@@ -0,0 +1,312 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/abcfe/abcfe-node/api/rest"
+	"github.com/abcfe/abcfe-node/common/utils"
+	"github.com/abcfe/abcfe-node/core"
+	"github.com/spf13/cobra"
+)
+
+// Multisig (m-of-n) address and signature gathering commands
+func walletMultisigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "multisig",
+		Short: "M-of-n multisig addresses and signing",
+		Long: `Flow for spending from a multisig address:
+  1. multisig address  - derive the address from the threshold and every party's public key
+  2. multisig create   - build the unsigned spend on a node
+  3. multisig sign     - each party signs the encoded tx with its local wallet (offline)
+  4. multisig combine  - merge the signed copies
+  5. multisig submit   - broadcast once the threshold is reached`,
+	}
+
+	cmd.AddCommand(walletMultisigAddressCmd())
+	cmd.AddCommand(walletMultisigCreateCmd())
+	cmd.AddCommand(walletMultisigSignCmd())
+	cmd.AddCommand(walletMultisigCombineCmd())
+	cmd.AddCommand(walletMultisigSubmitCmd())
+
+	return cmd
+}
+
+// Derive multisig address (offline)
+func walletMultisigAddressCmd() *cobra.Command {
+	var req rest.MultisigScriptReq
+
+	cmd := &cobra.Command{
+		Use:   "address",
+		Short: "Derive an m-of-n multisig address",
+		Run: func(cmd *cobra.Command, args []string) {
+			script, err := parseMultisigKeys(req.Threshold, req.PublicKeys)
+			if err != nil {
+				fmt.Printf("Invalid key set: %v\n", err)
+				return
+			}
+			address, err := script.Address()
+			if err != nil {
+				fmt.Printf("Failed to derive address: %v\n", err)
+				return
+			}
+
+			fmt.Println("=== Multisig Address ===")
+			fmt.Printf("Address: %s\n", utils.AddressToString(address))
+			fmt.Printf("Threshold: %d of %d\n", script.Threshold, len(script.PublicKeys))
+			fmt.Println("Keys (signature slot order):")
+			for i, key := range script.PublicKeys {
+				fmt.Printf("  [%d] %s\n", i, hex.EncodeToString(key))
+			}
+		},
+	}
+
+	cmd.Flags().IntVarP(&req.Threshold, "threshold", "m", 0, "Required signatures")
+	cmd.Flags().StringSliceVarP(&req.PublicKeys, "key", "k", nil, "Public key of a party (hex, repeat for each party)")
+	cmd.MarkFlagRequired("threshold")
+	cmd.MarkFlagRequired("key")
+	return cmd
+}
+
+// Build unsigned multisig spend on the node
+func walletMultisigCreateCmd() *cobra.Command {
+	var (
+		nodeURL string
+		file    string
+		to      string
+		amount  uint64
+		req     rest.MultisigTxReq
+	)
+
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Create an unsigned transaction spending from a multisig address",
+		Long: `Builds the transaction through the node API (/api/v1/multisig/tx) and prints it encoded as hex.
+Pass the encoded transaction to each party for 'wallet multisig sign'.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if file != "" {
+				recipients, err := readPayoutFile(file)
+				if err != nil {
+					fmt.Printf("Failed to read payout file: %v\n", err)
+					return
+				}
+				req.Recipients = recipients
+			} else {
+				req.Recipients = []rest.RecipientReq{{Address: to, Amount: amount}}
+			}
+
+			var resp rest.MultisigTxResp
+			if err := postNodeAPI(nodeURL, "/multisig/tx", &req, &resp); err != nil {
+				fmt.Printf("Failed to create multisig tx: %v\n", err)
+				return
+			}
+			printMultisigTx(&resp)
+		},
+	}
+
+	cmd.Flags().StringVar(&nodeURL, "node", "http://localhost:8800", "Node REST API URL")
+	cmd.Flags().IntVarP(&req.Threshold, "threshold", "m", 0, "Required signatures")
+	cmd.Flags().StringSliceVarP(&req.PublicKeys, "key", "k", nil, "Public key of a party (hex, repeat for each party)")
+	cmd.Flags().StringVar(&to, "to", "", "Recipient address")
+	cmd.Flags().Uint64Var(&amount, "amount", 0, "Amount to send")
+	cmd.Flags().StringVarP(&file, "file", "f", "", "CSV payout file instead of --to/--amount")
+	cmd.Flags().Uint64Var(&req.Fee, "fee", 0, "Fee (0 = minimum fee)")
+	cmd.Flags().StringVar(&req.Memo, "memo", "", "Memo")
+	cmd.MarkFlagRequired("threshold")
+	cmd.MarkFlagRequired("key")
+	return cmd
+}
+
+// Sign multisig tx with a local wallet account (offline)
+func walletMultisigSignCmd() *cobra.Command {
+	var (
+		txHex        string
+		accountIndex int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "sign",
+		Short: "Add this wallet's signature to an encoded multisig transaction",
+		Long:  `Signs with a local wallet account; no node connection is needed.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			account, err := loadWalletAccount(accountIndex)
+			if err != nil {
+				fmt.Printf("Failed to load account: %v\n", err)
+				return
+			}
+
+			tx, err := readTxArg(txHex)
+			if err != nil {
+				fmt.Printf("Failed to read tx: %v\n", err)
+				return
+			}
+			signed, err := core.SignMultisigTx(tx, account.PrivateKey, account.PublicKey)
+			if err != nil {
+				fmt.Printf("Failed to sign: %v\n", err)
+				return
+			}
+			fmt.Printf("Signed %d input(s) with account %d\n", signed, accountIndex)
+
+			resp, err := multisigTxResp(tx)
+			if err != nil {
+				fmt.Printf("Failed to encode tx: %v\n", err)
+				return
+			}
+			printMultisigTx(resp)
+		},
+	}
+
+	cmd.Flags().StringVar(&txHex, "tx", "", "Encoded tx (hex, or @file)")
+	cmd.Flags().IntVarP(&accountIndex, "account", "a", 0, "Wallet account index to sign with")
+	cmd.MarkFlagRequired("tx")
+	return cmd
+}
+
+// Merge signed copies (offline)
+func walletMultisigCombineCmd() *cobra.Command {
+	var txHexes []string
+
+	cmd := &cobra.Command{
+		Use:   "combine",
+		Short: "Merge signatures of copies of the same multisig transaction",
+		Run: func(cmd *cobra.Command, args []string) {
+			txs := make([]*core.Transaction, len(txHexes))
+			for i, txHex := range txHexes {
+				tx, err := readTxArg(txHex)
+				if err != nil {
+					fmt.Printf("Failed to read tx %d: %v\n", i+1, err)
+					return
+				}
+				txs[i] = tx
+			}
+
+			combined, err := core.CombineMultisigTxs(txs)
+			if err != nil {
+				fmt.Printf("Failed to combine: %v\n", err)
+				return
+			}
+			resp, err := multisigTxResp(combined)
+			if err != nil {
+				fmt.Printf("Failed to encode tx: %v\n", err)
+				return
+			}
+			printMultisigTx(resp)
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&txHexes, "tx", nil, "Encoded tx (hex, or @file, repeat for each copy)")
+	cmd.MarkFlagRequired("tx")
+	return cmd
+}
+
+// Broadcast fully signed multisig tx
+func walletMultisigSubmitCmd() *cobra.Command {
+	var (
+		nodeURL string
+		txHex   string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "submit",
+		Short: "Submit a multisig transaction which reached its threshold",
+		Run: func(cmd *cobra.Command, args []string) {
+			tx, err := readTxArg(txHex)
+			if err != nil {
+				fmt.Printf("Failed to read tx: %v\n", err)
+				return
+			}
+			encoded, err := core.EncodeTxHex(tx)
+			if err != nil {
+				fmt.Printf("Failed to encode tx: %v\n", err)
+				return
+			}
+
+			var data struct {
+				TxID string `json:"txId"`
+			}
+			if err := postNodeAPI(nodeURL, "/multisig/tx/submit", &rest.MultisigSubmitReq{Tx: encoded}, &data); err != nil {
+				fmt.Printf("Failed to submit: %v\n", err)
+				return
+			}
+			fmt.Printf("Submitted: txId=%s\n", data.TxID)
+		},
+	}
+
+	cmd.Flags().StringVar(&nodeURL, "node", "http://localhost:8800", "Node REST API URL")
+	cmd.Flags().StringVar(&txHex, "tx", "", "Encoded tx (hex, or @file)")
+	cmd.MarkFlagRequired("tx")
+	return cmd
+}
+
+// parseMultisigKeys builds multisig script from hex public keys
+func parseMultisigKeys(threshold int, keyHexes []string) (*core.MultiSigScript, error) {
+	keys := make([][]byte, len(keyHexes))
+	for i, keyHex := range keyHexes {
+		key, err := hex.DecodeString(strings.TrimPrefix(keyHex, "0x"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid public key %d: %w", i+1, err)
+		}
+		keys[i] = key
+	}
+	return core.NewMultiSigScript(threshold, keys)
+}
+
+// readTxArg decodes tx given as hex, or read from a file with "@path"
+func readTxArg(arg string) (*core.Transaction, error) {
+	txHex, err := readHexArg(arg)
+	if err != nil {
+		return nil, err
+	}
+	return core.DecodeTxHex(txHex)
+}
+
+// readHexArg returns hex argument, reading it from a file when given as "@path".
+// Files may hold saved command output; the encoded value is its last line.
+func readHexArg(arg string) (string, error) {
+	if !strings.HasPrefix(arg, "@") {
+		return strings.TrimSpace(arg), nil
+	}
+
+	content, err := os.ReadFile(arg[1:])
+	if err != nil {
+		return "", err
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	return strings.TrimSpace(lines[len(lines)-1]), nil
+}
+
+// multisigTxResp encodes tx with its signature status (same shape as the node API response)
+func multisigTxResp(tx *core.Transaction) (*rest.MultisigTxResp, error) {
+	encoded, err := core.EncodeTxHex(tx)
+	if err != nil {
+		return nil, err
+	}
+	status := core.GetMultisigStatus(tx)
+	return &rest.MultisigTxResp{
+		TxID:     utils.HashToString(tx.ID),
+		Tx:       encoded,
+		Signed:   status.Signed,
+		Required: status.Required,
+		Complete: status.Complete,
+	}, nil
+}
+
+func printMultisigTx(resp *rest.MultisigTxResp) {
+	fmt.Println("=== Multisig Transaction ===")
+	fmt.Printf("TxID: %s\n", resp.TxID)
+	for i := range resp.Signed {
+		if resp.Signed[i] < 0 {
+			continue // Single key input
+		}
+		fmt.Printf("Input %d: %d of %d signatures\n", i, resp.Signed[i], resp.Required[i])
+	}
+	if resp.Complete {
+		fmt.Println("Status: complete, ready to submit")
+	} else {
+		fmt.Println("Status: more signatures needed")
+	}
+	fmt.Println("")
+	fmt.Println(resp.Tx)
+}
@@ -0,0 +1,362 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/abcfe/abcfe-node/api/rest"
+	"github.com/abcfe/abcfe-node/common/utils"
+	"github.com/abcfe/abcfe-node/core"
+	"github.com/abcfe/abcfe-node/wallet"
+	"github.com/spf13/cobra"
+)
+
+// Partially signed transaction (offline signing) commands
+func walletPsbtCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "psbt",
+		Short: "Partially signed transactions for offline signing",
+		Long: `Keeps signing keys off the networked node:
+  1. psbt create     - online: build the unsigned tx from a public key (or multisig key set) on a node
+  2. psbt inspect    - offline: show inputs, outputs and fee
+  3. psbt sign       - offline: sign with a local wallet account (e.g. on an air-gapped machine)
+  4. psbt combine    - merge copies signed by different parties
+  5. psbt finalize   - verify signatures and print the final tx
+  6. psbt broadcast  - online: submit the signed psbt (or finalized tx)`,
+	}
+
+	cmd.AddCommand(walletPsbtCreateCmd())
+	cmd.AddCommand(walletPsbtInspectCmd())
+	cmd.AddCommand(walletPsbtSignCmd())
+	cmd.AddCommand(walletPsbtCombineCmd())
+	cmd.AddCommand(walletPsbtFinalizeCmd())
+	cmd.AddCommand(walletPsbtBroadcastCmd())
+
+	return cmd
+}
+
+// Build unsigned partial tx on the node
+func walletPsbtCreateCmd() *cobra.Command {
+	var (
+		nodeURL string
+		file    string
+		to      string
+		amount  uint64
+		req     rest.PartialTxCreateReq
+	)
+
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Create an unsigned partially signed transaction",
+		Long: `Builds the transaction through the node API (/api/v1/psbt/create) from the owner's public key
+(or --threshold/--key for a multisig address). The node never sees a private key.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if file != "" {
+				recipients, err := readPayoutFile(file)
+				if err != nil {
+					fmt.Printf("Failed to read payout file: %v\n", err)
+					return
+				}
+				req.Recipients = recipients
+			} else {
+				req.Recipients = []rest.RecipientReq{{Address: to, Amount: amount}}
+			}
+
+			var resp rest.PartialTxResp
+			if err := postNodeAPI(nodeURL, "/psbt/create", &req, &resp); err != nil {
+				fmt.Printf("Failed to create psbt: %v\n", err)
+				return
+			}
+			printPartialTx(&resp)
+		},
+	}
+
+	cmd.Flags().StringVar(&nodeURL, "node", "http://localhost:8800", "Node REST API URL")
+	cmd.Flags().StringVar(&req.PublicKey, "public-key", "", "Owner public key (hex, see 'wallet list')")
+	cmd.Flags().IntVarP(&req.Threshold, "threshold", "m", 0, "Multisig owner: required signatures")
+	cmd.Flags().StringSliceVarP(&req.PublicKeys, "key", "k", nil, "Multisig owner: public key of a party (hex, repeat for each party)")
+	cmd.Flags().StringVar(&to, "to", "", "Recipient address")
+	cmd.Flags().Uint64Var(&amount, "amount", 0, "Amount to send")
+	cmd.Flags().StringVarP(&file, "file", "f", "", "CSV payout file instead of --to/--amount")
+	cmd.Flags().Uint64Var(&req.Fee, "fee", 0, "Fee (0 = minimum fee)")
+	cmd.Flags().StringVar(&req.Memo, "memo", "", "Memo")
+	cmd.Flags().BoolVar(&req.Replaceable, "replaceable", false, "Opt in to replace-by-fee")
+	cmd.Flags().StringVar(&req.CoinSelection, "coin-selection", core.DefaultCoinSelection, "Coin selection strategy (bnb, largest, smallest, random)")
+	return cmd
+}
+
+// Show partial tx contents (offline)
+func walletPsbtInspectCmd() *cobra.Command {
+	var psbtHex string
+
+	cmd := &cobra.Command{
+		Use:   "inspect",
+		Short: "Show inputs, outputs, fee and signatures of a partially signed transaction",
+		Run: func(cmd *cobra.Command, args []string) {
+			ptx, err := readPartialTxArg(psbtHex)
+			if err != nil {
+				fmt.Printf("Invalid psbt: %v\n", err)
+				return
+			}
+			if err := printPartialTxDetails(ptx); err != nil {
+				fmt.Printf("Failed to inspect psbt: %v\n", err)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&psbtHex, "psbt", "", "Encoded psbt (hex, or @file)")
+	cmd.MarkFlagRequired("psbt")
+	return cmd
+}
+
+// Sign partial tx with a local wallet account (offline)
+func walletPsbtSignCmd() *cobra.Command {
+	var (
+		psbtHex      string
+		accountIndex int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "sign",
+		Short: "Sign a partially signed transaction with a local wallet account",
+		Long: `Signs every input owned by the account (single key or multisig member); no node connection is needed.
+Check the printed inputs, outputs and fee before passing the result on.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			account, err := loadWalletAccount(accountIndex)
+			if err != nil {
+				fmt.Printf("Failed to load account: %v\n", err)
+				return
+			}
+
+			ptx, err := readPartialTxArg(psbtHex)
+			if err != nil {
+				fmt.Printf("Invalid psbt: %v\n", err)
+				return
+			}
+			signed, err := ptx.Sign(account.PrivateKey, account.PublicKey)
+			if err != nil {
+				fmt.Printf("Failed to sign: %v\n", err)
+				return
+			}
+			fmt.Printf("Signed %d input(s) with account %d (%s)\n", signed, accountIndex, utils.AddressToString(account.Address))
+			fmt.Println("")
+
+			if err := printPartialTxDetails(ptx); err != nil {
+				fmt.Printf("Failed to encode psbt: %v\n", err)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&psbtHex, "psbt", "", "Encoded psbt (hex, or @file)")
+	cmd.Flags().IntVarP(&accountIndex, "account", "a", 0, "Wallet account index to sign with")
+	cmd.MarkFlagRequired("psbt")
+	return cmd
+}
+
+// Merge signed copies (offline)
+func walletPsbtCombineCmd() *cobra.Command {
+	var psbtHexes []string
+
+	cmd := &cobra.Command{
+		Use:   "combine",
+		Short: "Merge signatures of copies of the same partially signed transaction",
+		Run: func(cmd *cobra.Command, args []string) {
+			ptxs := make([]*core.PartialTx, len(psbtHexes))
+			for i, psbtHex := range psbtHexes {
+				ptx, err := readPartialTxArg(psbtHex)
+				if err != nil {
+					fmt.Printf("Invalid psbt %d: %v\n", i+1, err)
+					return
+				}
+				ptxs[i] = ptx
+			}
+
+			combined, err := core.CombinePartialTxs(ptxs)
+			if err != nil {
+				fmt.Printf("Failed to combine: %v\n", err)
+				return
+			}
+			if err := printPartialTxDetails(combined); err != nil {
+				fmt.Printf("Failed to encode psbt: %v\n", err)
+			}
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&psbtHexes, "psbt", nil, "Encoded psbt (hex, or @file, repeat for each copy)")
+	cmd.MarkFlagRequired("psbt")
+	return cmd
+}
+
+// Verify signatures and print final tx (offline)
+func walletPsbtFinalizeCmd() *cobra.Command {
+	var psbtHex string
+
+	cmd := &cobra.Command{
+		Use:   "finalize",
+		Short: "Verify a fully signed psbt and print the final transaction",
+		Run: func(cmd *cobra.Command, args []string) {
+			ptx, err := readPartialTxArg(psbtHex)
+			if err != nil {
+				fmt.Printf("Invalid psbt: %v\n", err)
+				return
+			}
+			tx, err := ptx.Finalize()
+			if err != nil {
+				fmt.Printf("Failed to finalize: %v\n", err)
+				return
+			}
+			txHex, err := core.EncodeTxHex(tx)
+			if err != nil {
+				fmt.Printf("Failed to encode tx: %v\n", err)
+				return
+			}
+
+			fmt.Println("=== Final Transaction ===")
+			fmt.Printf("TxID: %s\n", utils.HashToString(tx.ID))
+			fmt.Println("")
+			fmt.Println(txHex)
+		},
+	}
+
+	cmd.Flags().StringVar(&psbtHex, "psbt", "", "Encoded psbt (hex, or @file)")
+	cmd.MarkFlagRequired("psbt")
+	return cmd
+}
+
+// Submit signed partial tx (or finalized tx) to the node
+func walletPsbtBroadcastCmd() *cobra.Command {
+	var (
+		nodeURL string
+		psbtHex string
+		txHex   string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "broadcast",
+		Short: "Broadcast a fully signed psbt or finalized transaction",
+		Run: func(cmd *cobra.Command, args []string) {
+			var req rest.PartialTxBroadcastReq
+			var err error
+			switch {
+			case psbtHex != "" && txHex != "":
+				fmt.Println("Use either --psbt or --tx")
+				return
+			case psbtHex != "":
+				req.Psbt, err = readHexArg(psbtHex)
+			case txHex != "":
+				req.Tx, err = readHexArg(txHex)
+			default:
+				fmt.Println("--psbt or --tx is required")
+				return
+			}
+			if err != nil {
+				fmt.Printf("Failed to read input: %v\n", err)
+				return
+			}
+
+			var data struct {
+				TxID string `json:"txId"`
+			}
+			if err := postNodeAPI(nodeURL, "/psbt/broadcast", &req, &data); err != nil {
+				fmt.Printf("Failed to broadcast: %v\n", err)
+				return
+			}
+			fmt.Printf("Broadcasted: txId=%s\n", data.TxID)
+		},
+	}
+
+	cmd.Flags().StringVar(&nodeURL, "node", "http://localhost:8800", "Node REST API URL")
+	cmd.Flags().StringVar(&psbtHex, "psbt", "", "Encoded psbt (hex, or @file)")
+	cmd.Flags().StringVar(&txHex, "tx", "", "Finalized tx (hex, or @file)")
+	return cmd
+}
+
+// loadWalletAccount loads account of the local wallet file
+func loadWalletAccount(index int) (*wallet.Account, error) {
+	wm := wallet.NewWalletManager(walletDir)
+	if err := wm.LoadWalletFile(); err != nil {
+		return nil, fmt.Errorf("failed to load wallet: %w", err)
+	}
+	if index < 0 || index >= len(wm.Wallet.Accounts) {
+		return nil, fmt.Errorf("invalid account index: %d", index)
+	}
+	return wm.Wallet.Accounts[index], nil
+}
+
+// readPartialTxArg decodes and validates psbt given as hex or "@path"
+func readPartialTxArg(arg string) (*core.PartialTx, error) {
+	ptxHex, err := readHexArg(arg)
+	if err != nil {
+		return nil, err
+	}
+	ptx, err := core.DecodePartialTx(ptxHex)
+	if err != nil {
+		return nil, err
+	}
+	if err := ptx.Validate(); err != nil {
+		return nil, err
+	}
+	return ptx, nil
+}
+
+// printPartialTxDetails prints what the signer is about to approve, then the encoded psbt
+func printPartialTxDetails(ptx *core.PartialTx) error {
+	encoded, err := core.EncodePartialTx(ptx)
+	if err != nil {
+		return err
+	}
+	inputTotal, err := ptx.InputTotal()
+	if err != nil {
+		return err
+	}
+	fee, err := ptx.Fee()
+	if err != nil {
+		return err
+	}
+	status := ptx.Status()
+
+	owners := make(map[string]bool)
+	fmt.Println("=== Partially Signed Transaction ===")
+	fmt.Printf("TxID: %s\n", utils.HashToString(ptx.Tx.ID))
+	fmt.Println("Inputs:")
+	for i, spent := range ptx.Inputs {
+		owner := utils.AddressToString(spent.Output.Address)
+		owners[owner] = true
+		fmt.Printf("  [%d] %s:%d  %s  %d  (signatures %d/%d)\n", i, utils.HashToString(spent.TxID), spent.OutputIndex, owner, spent.Output.Amount, status.Signed[i], status.Required[i])
+	}
+	fmt.Println("Outputs:")
+	for i, output := range ptx.Tx.Outputs {
+		address := utils.AddressToString(output.Address)
+		note := ""
+		if owners[address] {
+			note = "  (change)"
+		}
+		if output.HasTimelock() {
+			note += fmt.Sprintf("  (locked: height %d, time %d, relative %d)", output.LockHeight, output.LockTime, output.RelativeLockHeight)
+		}
+		fmt.Printf("  [%d] %s  %d%s\n", i, address, output.Amount, note)
+	}
+	fmt.Printf("Input total: %d\n", inputTotal)
+	fmt.Printf("Fee: %d\n", fee)
+	if ptx.Tx.Memo != "" {
+		fmt.Printf("Memo: %s\n", ptx.Tx.Memo)
+	}
+	if status.Complete {
+		fmt.Println("Status: complete, ready to finalize")
+	} else {
+		fmt.Println("Status: more signatures needed")
+	}
+	fmt.Println("")
+	fmt.Println(encoded)
+	return nil
+}
+
+// printPartialTx prints node API psbt response
+func printPartialTx(resp *rest.PartialTxResp) {
+	fmt.Println("=== Partially Signed Transaction ===")
+	fmt.Printf("TxID: %s\n", resp.TxID)
+	fmt.Printf("Inputs: %d (total %d)\n", len(resp.Inputs), resp.InputTotal)
+	fmt.Printf("Outputs: %d\n", len(resp.Outputs))
+	fmt.Printf("Fee: %d\n", resp.Fee)
+	fmt.Println("")
+	fmt.Println(resp.Psbt)
+}
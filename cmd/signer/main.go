@@ -0,0 +1,97 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/abcfe/abcfe-node/common/crypto"
+	"github.com/abcfe/abcfe-node/common/logger"
+	conf "github.com/abcfe/abcfe-node/config"
+	"github.com/abcfe/abcfe-node/consensus"
+	"github.com/abcfe/abcfe-node/wallet"
+)
+
+var (
+	configFile   string
+	listenAddr   string
+	secret       string
+	statePath    string
+	accountIndex int
+)
+
+// Signer process holding the validator key: the node connects to it with [consensus] signerAddress
+// and never loads the key itself.
+func main() {
+	flag.StringVar(&configFile, "config", "", "Path to config file (wallet and log settings)")
+	flag.StringVar(&listenAddr, "listen", "", "Address to listen on (unix:///path or tcp://host:port, default: [consensus] signerAddress)")
+	flag.StringVar(&secret, "secret", "", "Shared secret nodes authenticate with (required for tcp, default: [consensus] signerSecret)")
+	flag.StringVar(&statePath, "state", "", "Path to last signed state file (default: signer_state.json in the wallet directory)")
+	flag.IntVar(&accountIndex, "account", 0, "Wallet account index of the validator key")
+	flag.Parse()
+
+	if err := run(); err != nil {
+		fmt.Println("Signer failed:", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	cfg, err := conf.NewConfig(configFile)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if err := logger.InitLogger(cfg); err != nil {
+		return fmt.Errorf("failed to init logger: %w", err)
+	}
+
+	if listenAddr == "" {
+		listenAddr = cfg.Consensus.SignerAddress
+	}
+	if listenAddr == "" {
+		return fmt.Errorf("listen address is not set (-listen or [consensus] signerAddress)")
+	}
+	if secret == "" {
+		secret = cfg.Consensus.SignerSecret
+	}
+	if statePath == "" {
+		statePath = filepath.Join(cfg.Wallet.Path, "signer_state.json")
+	}
+
+	wm, err := wallet.InitWallet(cfg)
+	if err != nil {
+		return err
+	}
+	if accountIndex < 0 || accountIndex >= len(wm.Wallet.Accounts) {
+		return fmt.Errorf("invalid account index: %d", accountIndex)
+	}
+	account := wm.Wallet.Accounts[accountIndex]
+
+	server, err := consensus.NewSignerServer(account.PrivateKey, statePath, []byte(secret))
+	if err != nil {
+		return err
+	}
+	listener, err := consensus.ListenSigner(listenAddr, []byte(secret))
+	if err != nil {
+		return err
+	}
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		sig := <-sigCh
+		logger.Info("[Signer] Arrived terminate signal: ", sig)
+		listener.Close()
+	}()
+
+	state := server.State()
+	logger.Info("[Signer] Validator ", crypto.AddressTo0xPrefixString(account.Address), " listening on ", listenAddr)
+	logger.Info("[Signer] Last signed height ", state.Height, " round ", state.Round, " step ", state.Step)
+	fmt.Printf("Signer for %s listening on %s\n", crypto.AddressTo0xPrefixString(account.Address), listenAddr)
+
+	server.Serve(listener)
+	return nil
+}
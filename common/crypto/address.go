@@ -3,7 +3,9 @@ package crypto
 import (
 	"crypto/ecdsa"
 	"crypto/elliptic"
+	"encoding/binary"
 	"encoding/hex"
+	"fmt"
 
 	prt "github.com/abcfe/abcfe-node/protocol"
 	"golang.org/x/crypto/sha3"
@@ -29,3 +31,44 @@ func PublicKeyToAddress(publicKey *ecdsa.PublicKey) (prt.Address, error) {
 func AddressTo0xPrefixString(address prt.Address) string {
 	return "0x" + hex.EncodeToString(address[:])
 }
+
+// MultisigAddress derives m-of-n multisig address from threshold and public keys (in the given order).
+// Keys are hashed in compressed form after a domain prefix so the address cannot collide with a single key address.
+func MultisigAddress(threshold int, publicKeys []*ecdsa.PublicKey) (prt.Address, error) {
+	var address prt.Address
+	if threshold <= 0 || threshold > len(publicKeys) || len(publicKeys) > 255 {
+		return address, fmt.Errorf("invalid multisig threshold %d of %d keys", threshold, len(publicKeys))
+	}
+
+	hash := sha3.NewLegacyKeccak256()
+	hash.Write([]byte("abcfe-multisig"))
+	hash.Write([]byte{byte(threshold), byte(len(publicKeys))})
+	for i, publicKey := range publicKeys {
+		if publicKey == nil {
+			return address, fmt.Errorf("public key %d is nil", i)
+		}
+		hash.Write(elliptic.MarshalCompressed(publicKey.Curve, publicKey.X, publicKey.Y))
+	}
+	hashBytes := hash.Sum(nil)
+
+	copy(address[:], hashBytes[len(hashBytes)-20:])
+	return address, nil
+}
+
+// HTLCAddress derives the contract address of a hash timelock output from its conditions.
+// Like multisig addresses it is domain separated, so no key can own it directly.
+func HTLCAddress(hash [32]byte, recipient, refund prt.Address, expiry uint64) prt.Address {
+	h := sha3.NewLegacyKeccak256()
+	h.Write([]byte("abcfe-htlc"))
+	h.Write(hash[:])
+	h.Write(recipient[:])
+	h.Write(refund[:])
+	var expiryBytes [8]byte
+	binary.BigEndian.PutUint64(expiryBytes[:], expiry)
+	h.Write(expiryBytes[:])
+	hashBytes := h.Sum(nil)
+
+	var address prt.Address
+	copy(address[:], hashBytes[len(hashBytes)-20:])
+	return address
+}
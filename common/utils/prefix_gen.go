@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"fmt"
 	"strconv"
 
 	prt "github.com/abcfe/abcfe-node/protocol"
@@ -20,6 +21,12 @@ func GetBlockHashKey(hash prt.Hash) []byte {
 	return blkKey
 }
 
+// "side:blk:"
+func GetSideBlockKey(hash prt.Hash) []byte {
+	blkHashStr := HashToString(hash)
+	return []byte(prt.PrefixSideBlock + blkHashStr)
+}
+
 // "tx:"
 func GetTxHashKey(txHash prt.Hash) []byte {
 	txHashStr := HashToString(txHash)
@@ -83,3 +90,84 @@ func GetUtxoBalanceKey(address prt.Address) []byte {
 	utxoBalanceKey := []byte(prt.PrefixUtxoBalance + addressStr)
 	return utxoBalanceKey
 }
+
+// "utxo:undo:"
+func GetUtxoUndoKey(blockHash prt.Hash) []byte {
+	blkHashStr := HashToString(blockHash)
+	return []byte(prt.PrefixUtxoUndo + blkHashStr)
+}
+
+// "addr:hist:address:"
+func GetAddressHistoryPrefix(address prt.Address) []byte {
+	addressStr := AddressToString(address)
+	return []byte(prt.PrefixAddressHistory + addressStr + ":")
+}
+
+// "addr:hist:address:height:txindex"
+// Height and index are zero padded so that keys sort in chain order
+func GetAddressHistoryKey(address prt.Address, height uint64, txIndex uint32) []byte {
+	return []byte(fmt.Sprintf("%s%020d:%06d", GetAddressHistoryPrefix(address), height, txIndex))
+}
+
+// "stake:address:"
+func GetStakePrefix(address prt.Address) []byte {
+	addressStr := AddressToString(address)
+	return []byte(prt.PrefixStake + addressStr + ":")
+}
+
+// "stake:address:txhash:index"
+func GetStakeKey(address prt.Address, txHash prt.Hash, outputIndex int) []byte {
+	return []byte(string(GetStakePrefix(address)) + HashToString(txHash) + ":" + strconv.Itoa(outputIndex))
+}
+
+// "staker:key:address"
+func GetStakerKeyKey(address prt.Address) []byte {
+	addressStr := AddressToString(address)
+	return []byte(prt.PrefixStakerKey + addressStr)
+}
+
+// "unbond:address:"
+func GetUnbondingPrefix(address prt.Address) []byte {
+	addressStr := AddressToString(address)
+	return []byte(prt.PrefixUnbonding + addressStr + ":")
+}
+
+// "unbond:address:txhash:index"
+func GetUnbondingKey(address prt.Address, txHash prt.Hash, outputIndex int) []byte {
+	return []byte(string(GetUnbondingPrefix(address)) + HashToString(txHash) + ":" + strconv.Itoa(outputIndex))
+}
+
+// "epoch:startheight"
+// Height is zero padded so that keys sort in chain order
+func GetEpochKey(startHeight uint64) []byte {
+	return []byte(fmt.Sprintf("%s%020d", prt.PrefixEpoch, startHeight))
+}
+
+// "delegation:validator:"
+func GetDelegationPrefix(validator prt.Address) []byte {
+	validatorStr := AddressToString(validator)
+	return []byte(prt.PrefixDelegation + validatorStr + ":")
+}
+
+// "delegation:validator:delegator:txhash:index"
+func GetDelegationKey(validator, delegator prt.Address, txHash prt.Hash, outputIndex int) []byte {
+	return []byte(string(GetDelegationPrefix(validator)) + AddressToString(delegator) + ":" + HashToString(txHash) + ":" + strconv.Itoa(outputIndex))
+}
+
+// "reward:address"
+func GetRewardKey(address prt.Address) []byte {
+	addressStr := AddressToString(address)
+	return []byte(prt.PrefixReward + addressStr)
+}
+
+// "jail:address"
+func GetJailKey(address prt.Address) []byte {
+	addressStr := AddressToString(address)
+	return []byte(prt.PrefixJail + addressStr)
+}
+
+// "uptime:address"
+func GetUptimeKey(address prt.Address) []byte {
+	addressStr := AddressToString(address)
+	return []byte(prt.PrefixUptime + addressStr)
+}
@@ -49,7 +49,7 @@ type Genesis struct {
 // ValidatorConfig genesis validator config
 type ValidatorConfig struct {
 	Address     string `toml:"address"`
-	PublicKey   string `toml:"publicKey"`   // hex encoded
+	PublicKey   string `toml:"publicKey"` // hex encoded
 	VotingPower uint64 `toml:"votingPower"`
 }
 
@@ -81,6 +81,14 @@ type Transaction struct {
 	MaxDataSize uint64 `toml:"maxDataSize"` // Max data size (bytes)
 }
 
+// Mempool limit config (0 = default)
+type Mempool struct {
+	MaxTxs              int    `toml:"maxTxs"`              // Max tx count
+	MaxBytes            uint64 `toml:"maxBytes"`            // Max total serialized tx size (bytes)
+	MaxAgeSec           int64  `toml:"maxAgeSec"`           // Tx expires after this many seconds in mempool
+	SnapshotIntervalSec int64  `toml:"snapshotIntervalSec"` // Interval of on-disk mempool snapshot
+}
+
 // Consensus config
 type Consensus struct {
 	// Proposer selection mode: "roundrobin", "vrf", "hybrid"
@@ -88,6 +96,48 @@ type Consensus struct {
 	// - vrf: VRF-like hash-based selection (unpredictable)
 	// - hybrid: VRF for round 0, round-robin for timeouts
 	ProposerSelection string `toml:"proposerSelection"`
+
+	// Blocks unstaked funds stay locked after the unstake tx is committed (0 = default).
+	// Like the params below, a value other than the default is recorded in the genesis block,
+	// which every node then follows over its own config.
+	UnbondingBlocks uint64 `toml:"unbondingBlocks"`
+
+	// Blocks per epoch; the validator set only changes at epoch boundaries (0 = default).
+	// Recorded in the genesis block when not default.
+	EpochBlocks uint64 `toml:"epochBlocks"`
+
+	// Percent of the delegators' share of block rewards kept by the validator as commission (0 = default).
+	// Recorded in the genesis block when not default.
+	CommissionRate uint64 `toml:"commissionRate"`
+
+	// Percent of a double-signing validator's stake burnt by the evidence tx (0 = default).
+	// Recorded in the genesis block when not default.
+	SlashRate uint64 `toml:"slashRate"`
+
+	// Downtime tracking: a validator signing less than minSignedPerWindow percent of the last
+	// signedBlocksWindow blocks is jailed, and may unjail after downtimeJailBlocks (0 = default).
+	// Recorded in the genesis block when not default.
+	SignedBlocksWindow uint64 `toml:"signedBlocksWindow"`
+	MinSignedPerWindow uint64 `toml:"minSignedPerWindow"`
+	DowntimeJailBlocks uint64 `toml:"downtimeJailBlocks"`
+
+	// Consensus timing in milliseconds (0 = default). The timeout of round r is
+	// roundTimeoutMs + roundTimeoutDeltaMs*r. Timing other than the default is recorded in the
+	// genesis block, which every node then follows over its own config.
+	BlockProduceMs      uint64 `toml:"blockProduceMs"`
+	BlockIntervalMs     uint64 `toml:"blockIntervalMs"`
+	ProposingMs         uint64 `toml:"proposingMs"`
+	VotingMs            uint64 `toml:"votingMs"`
+	CommittingMs        uint64 `toml:"committingMs"`
+	RoundTimeoutMs      uint64 `toml:"roundTimeoutMs"`
+	RoundTimeoutDeltaMs uint64 `toml:"roundTimeoutDeltaMs"`
+
+	// Remote signer holding the validator key ("unix:///path/signer.sock" or "tcp://host:port").
+	// Empty: the node signs with its first wallet account.
+	SignerAddress string `toml:"signerAddress"`
+
+	// Shared secret authenticating the node to the remote signer (at least 16 bytes, required for tcp)
+	SignerSecret string `toml:"signerSecret"`
 }
 
 type Config struct {
@@ -97,11 +147,12 @@ type Config struct {
 	Wallet      Wallet
 	Version     Version
 	Genesis     Genesis
-	Validators  Validators  // Genesis validator list (PoA)
+	Validators  Validators // Genesis validator list (PoA)
 	Server      Server
 	P2P         P2P
 	Fee         Fee         // Fee config
 	Transaction Transaction // Transaction limit config
+	Mempool     Mempool     // Mempool limit config
 	Consensus   Consensus   // Consensus config
 }
 
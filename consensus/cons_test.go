@@ -1 +1,905 @@
 package consensus
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/abcfe/abcfe-node/common/crypto"
+	"github.com/abcfe/abcfe-node/common/logger"
+	"github.com/abcfe/abcfe-node/common/utils"
+	"github.com/abcfe/abcfe-node/config"
+	"github.com/abcfe/abcfe-node/core"
+	prt "github.com/abcfe/abcfe-node/protocol"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/storage"
+)
+
+// 지연 전달 네트워크 시뮬레이션 (검증자마다 잠금 상태를 갖고, 투표는 deliver 호출 전까지 지연)
+type simNetwork struct {
+	t       *testing.T
+	nodes   []*heightState
+	pending []simMessage
+}
+
+// 전달 대기 중인 투표
+type simMessage struct {
+	to   int
+	vote *Vote
+}
+
+// 투표력 1인 검증자 n명
+func newSimNetwork(t *testing.T, n int) *simNetwork {
+	net := &simNetwork{t: t}
+	for i := 0; i < n; i++ {
+		net.nodes = append(net.nodes, newHeightState(1, prt.Hash{0xaa}))
+	}
+	return net
+}
+
+func (net *simNetwork) totalPower() uint64 {
+	return uint64(len(net.nodes))
+}
+
+// 자신에게는 즉시, 다른 검증자에게는 지연 전달
+func (net *simNetwork) broadcast(from int, voteType VoteType, round uint32, blockHash prt.Hash) {
+	vote := &Vote{Height: 1, Round: round, Type: voteType, BlockHash: blockHash, VoterID: prt.Address{byte(from + 1)}}
+	net.nodes[from].addVote(vote, 1)
+	for i := range net.nodes {
+		if i != from {
+			net.pending = append(net.pending, simMessage{to: i, vote: vote})
+		}
+	}
+}
+
+// 대기 중인 round의 voteType 투표를 to에게 전달
+func (net *simNetwork) deliver(voteType VoteType, round uint32, to ...int) {
+	var rest []simMessage
+	for _, msg := range net.pending {
+		if msg.vote.Type == voteType && msg.vote.Round == round && contains(to, msg.to) {
+			net.nodes[msg.to].addVote(msg.vote, 1)
+			continue
+		}
+		rest = append(rest, msg)
+	}
+	net.pending = rest
+}
+
+// 제안 수신 후 잠금 규칙에 따라 prevote
+func (net *simNetwork) propose(round uint32, polRound int32, block *core.Block, to ...int) {
+	for _, i := range to {
+		net.nodes[i].addBlock(block)
+		blockHash, ok := net.nodes[i].prevote(round, polRound, block, net.totalPower())
+		if !ok {
+			net.t.Fatalf("검증자 %d가 라운드 %d에서 이미 prevote함", i, round)
+		}
+		net.broadcast(i, VoteTypePrevote, round, blockHash)
+	}
+}
+
+// prevote 2/3+를 본 검증자는 precommit (반환: precommit한 검증자)
+func (net *simNetwork) precommit(round uint32, from ...int) []int {
+	var voted []int
+	for _, i := range from {
+		net.nodes[i].updateValid(round, net.totalPower())
+		if blockHash, ok := net.nodes[i].precommit(round, net.totalPower()); ok {
+			net.broadcast(i, VoteTypePrecommit, round, blockHash)
+			voted = append(voted, i)
+		}
+	}
+	return voted
+}
+
+// 검증자 voter의 투표
+func voteOf(hs *heightState, voteType VoteType, round uint32, voter int) *Vote {
+	addr := prt.Address{byte(voter + 1)}
+	return hs.voteSet(voteType, round).Votes[string(addr[:])]
+}
+
+func contains(list []int, v int) bool {
+	for _, x := range list {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+func simBlock(round uint32, tag byte) *core.Block {
+	return &core.Block{Header: core.BlockHeader{Hash: prt.Hash{tag}, PrevHash: prt.Hash{0xaa}, Height: 1, Round: round}}
+}
+
+// 메시지 지연 하에서 라운드가 바뀌어도 서로 다른 블록이 확정되지 않는지 테스트
+func TestLockingSafetyUnderDelay(t *testing.T) {
+	net := newSimNetwork(t, 4)
+	all := []int{0, 1, 2, 3}
+	blockA := simBlock(0, 0xa)
+	blockB := simBlock(1, 0xb)
+
+	// 라운드 0: 모두 A에 prevote, V3에는 다른 검증자의 prevote가 지연되어 POL을 보지 못함
+	net.propose(0, -1, blockA, all...)
+	net.deliver(VoteTypePrevote, 0, 0, 1, 2)
+	if voted := net.precommit(0, all...); len(voted) != 3 || contains(voted, 3) {
+		t.Fatalf("라운드 0 precommit 검증자 오류: %v", voted)
+	}
+	for _, i := range []int{0, 1, 2} {
+		if net.nodes[i].lockedBlock != blockA || net.nodes[i].lockedRound != 0 {
+			t.Fatalf("검증자 %d가 A에 잠기지 않음", i)
+		}
+	}
+
+	// precommit은 V0에만 도착 -> V0만 A 확정
+	net.deliver(VoteTypePrecommit, 0, 0)
+	decided := make(map[int]*core.Block)
+	if block, _ := net.nodes[0].decided(net.totalPower()); block != blockA {
+		t.Fatalf("V0가 A를 확정하지 못함")
+	}
+	decided[0] = blockA
+	for _, i := range []int{1, 2, 3} {
+		if block, _ := net.nodes[i].decided(net.totalPower()); block != nil {
+			t.Fatalf("검증자 %d가 precommit 없이 확정함", i)
+		}
+	}
+
+	// 라운드 1: POL을 못 본 V3가 새 블록 B 제안, A에 잠긴 V1/V2는 nil prevote (V0는 다음 높이로 이동)
+	if block, polRound := net.nodes[3].proposal(); block != nil || polRound != -1 {
+		t.Fatalf("V3는 재제안할 블록이 없어야 함")
+	}
+	net.propose(1, -1, blockB, 1, 2, 3)
+	net.deliver(VoteTypePrevote, 1, 1, 2, 3)
+	for _, i := range []int{1, 2} {
+		if vote := voteOf(net.nodes[i], VoteTypePrevote, 1, i); vote == nil || vote.BlockHash != (prt.Hash{}) {
+			t.Fatalf("잠긴 검증자 %d가 B에 prevote함", i)
+		}
+	}
+	if voted := net.precommit(1, 1, 2, 3); len(voted) != 0 {
+		t.Fatalf("라운드 1에 2/3+ prevote 없이 precommit: %v", voted)
+	}
+
+	// 라운드 2: V1이 POL 라운드 0과 함께 A 재제안, 지연된 라운드 0 투표가 V3에 도착
+	block, polRound := net.nodes[1].proposal()
+	if block != blockA || polRound != 0 {
+		t.Fatalf("V1 재제안 오류: block %v, polRound %d", block, polRound)
+	}
+	net.deliver(VoteTypePrevote, 0, 3)
+	net.propose(2, polRound, block, 1, 2, 3)
+	net.deliver(VoteTypePrevote, 2, 1, 2, 3)
+	if voted := net.precommit(2, 1, 2, 3); len(voted) != 3 {
+		t.Fatalf("라운드 2 precommit 검증자 오류: %v", voted)
+	}
+	net.deliver(VoteTypePrecommit, 0, all...)
+	net.deliver(VoteTypePrecommit, 2, all...)
+
+	// 모든 검증자가 같은 블록 확정
+	for _, i := range []int{1, 2, 3} {
+		block, precommits := net.nodes[i].decided(net.totalPower())
+		if block == nil {
+			t.Fatalf("검증자 %d가 확정하지 못함", i)
+		}
+		if precommits.BlockPower[block.Header.Hash]*3 <= net.totalPower()*2 {
+			t.Fatalf("검증자 %d의 확정 precommit 부족", i)
+		}
+		decided[i] = block
+	}
+	for i, block := range decided {
+		if block.Header.Hash != blockA.Header.Hash {
+			t.Fatalf("검증자 %d가 다른 블록 확정: %x", i, block.Header.Hash)
+		}
+	}
+}
+
+// 잠금 해제 조건 테스트 (잠금 라운드 이후의 POL만 해제)
+func TestLockingUnlockOnNewerPOL(t *testing.T) {
+	net := newSimNetwork(t, 4)
+	all := []int{0, 1, 2, 3}
+	blockA := simBlock(0, 0xa)
+	blockB := simBlock(1, 0xb)
+
+	// 라운드 0: V0만 POL을 보고 A에 잠김 (다른 검증자는 2/3+ prevote를 보지 못해 precommit하지 않음)
+	net.propose(0, -1, blockA, all...)
+	net.deliver(VoteTypePrevote, 0, 0)
+	if voted := net.precommit(0, all...); len(voted) != 1 || voted[0] != 0 {
+		t.Fatalf("라운드 0 precommit 검증자 오류: %v", voted)
+	}
+	locked := net.nodes[0]
+
+	// 라운드 1: 다른 검증자는 잠기지 않아 B에 prevote, V0는 nil
+	net.propose(1, -1, blockB, all...)
+	if vote := voteOf(locked, VoteTypePrevote, 1, 0); vote.BlockHash != (prt.Hash{}) {
+		t.Fatalf("잠긴 V0가 B에 prevote함")
+	}
+	net.deliver(VoteTypePrevote, 1, all...)
+	locked.updateValid(1, net.totalPower())
+	if locked.validBlock != blockB || locked.validRound != 1 {
+		t.Fatalf("라운드 1 POL이 유효 블록이 되지 않음")
+	}
+
+	// POL 없는 라운드를 주장한 재제안은 잠금을 해제하지 못함
+	if blockHash, _ := locked.prevote(2, 0, blockB, net.totalPower()); blockHash != (prt.Hash{}) {
+		t.Fatalf("POL 없는 재제안에 prevote함")
+	}
+
+	// 잠금 라운드 이후(라운드 1)의 POL과 함께 재제안된 B는 prevote
+	if blockHash, _ := locked.prevote(3, 1, blockB, net.totalPower()); blockHash != blockB.Header.Hash {
+		t.Fatalf("새 POL로 잠금이 해제되지 않음")
+	}
+
+	// 잠금 라운드와 같거나 이후 라운드가 아니면 해제되지 않음
+	locked.lockedRound = 2
+	if blockHash, _ := locked.prevote(4, 1, blockB, net.totalPower()); blockHash != (prt.Hash{}) {
+		t.Fatalf("잠금 라운드 이전의 POL로 잠금이 해제됨")
+	}
+
+	// 한 라운드에 한 번만 prevote
+	if _, ok := locked.prevote(4, 1, blockB, net.totalPower()); ok {
+		t.Fatalf("같은 라운드에 두 번 prevote함")
+	}
+}
+
+// 테스트 로거 초기화
+func initTestLogger(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.LogInfo.Path = filepath.Join(t.TempDir(), "test")
+	cfg.LogInfo.MaxAgeHour = 1
+	cfg.LogInfo.RotateHour = 1
+	if err := logger.InitLogger(cfg); err != nil {
+		t.Fatalf("failed to init logger: %v", err)
+	}
+}
+
+// 테스트 검증자 개인키
+func newTestKey(t *testing.T) []byte {
+	priv, _, err := crypto.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	privBytes, _ := crypto.PrivateKeyToBytes(priv)
+	return privBytes
+}
+
+// WAL 기록 / 재시작 후 충돌 서명 거부 테스트
+func TestConsensusWAL(t *testing.T) {
+	initTestLogger(t)
+	signer, err := NewLocalSigner(newTestKey(t))
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+
+	walPath := filepath.Join(t.TempDir(), "consensus_wal.log")
+	openEngine := func() (*ConsensusEngine, *WAL) {
+		wal, err := OpenWAL(walPath)
+		if err != nil {
+			t.Fatalf("failed to open wal: %v", err)
+		}
+		timing := core.DefaultConsensusTiming()
+		return &ConsensusEngine{consensus: &Consensus{LocalProposer: NewProposer(nil, signer)}, wal: wal, netTiming: &timing}, wal
+	}
+	e, wal := openEngine()
+	blockA := simBlock(1, 0xa)
+	blockB := simBlock(1, 0xb)
+
+	// 같은 투표는 기록된 서명 재사용, 다른 블록 / nil 투표는 거부
+	sig, err := e.signVote(1, 0, VoteTypePrevote, blockA.Header.Hash)
+	if err != nil {
+		t.Fatalf("prevote 서명 실패: %v", err)
+	}
+	if again, err := e.signVote(1, 0, VoteTypePrevote, blockA.Header.Hash); err != nil || again != sig {
+		t.Fatalf("같은 투표의 서명이 재사용되지 않음: %v", err)
+	}
+	if _, err := e.signVote(1, 0, VoteTypePrevote, blockB.Header.Hash); err == nil {
+		t.Fatalf("충돌하는 prevote에 서명함")
+	}
+	if _, err := e.signVote(1, 0, VoteTypePrevote, prt.Hash{}); err == nil {
+		t.Fatalf("충돌하는 nil prevote에 서명함")
+	}
+
+	// 제안: 다른 블록 / 다른 POL 라운드는 거부
+	if _, err := e.signProposal(1, 1, -1, blockA); err != nil {
+		t.Fatalf("제안 서명 실패: %v", err)
+	}
+	if _, err := e.signProposal(1, 1, -1, blockB); err == nil {
+		t.Fatalf("충돌하는 제안에 서명함")
+	}
+	if _, err := e.signProposal(1, 1, 0, blockA); err == nil {
+		t.Fatalf("POL 라운드가 다른 제안에 서명함")
+	}
+
+	// 기록 중 중단된 마지막 레코드는 재시작 시 버려짐
+	wal.Close()
+	file, err := os.OpenFile(walPath, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatalf("failed to open wal file: %v", err)
+	}
+	file.WriteString(`{"type":1,"height":1,"rou`)
+	file.Close()
+
+	// 재시작 후에도 충돌 서명 거부, 서명한 제안 블록 복원
+	e, wal = openEngine()
+	if records := wal.Records(1); len(records) != 2 {
+		t.Fatalf("재시작 후 레코드 수 오류: %d", len(records))
+	}
+	if _, err := e.signVote(1, 0, VoteTypePrevote, blockB.Header.Hash); err == nil {
+		t.Fatalf("재시작 후 충돌하는 prevote에 서명함")
+	}
+	if again, err := e.signVote(1, 0, VoteTypePrevote, blockA.Header.Hash); err != nil || again != sig {
+		t.Fatalf("재시작 후 같은 투표의 서명이 재사용되지 않음: %v", err)
+	}
+	signed := e.signedProposal(1, 1, blockA.Header.PrevHash)
+	if signed == nil || signed.block.Header.Hash != blockA.Header.Hash || signed.polRound != -1 {
+		t.Fatalf("서명한 제안이 복원되지 않음")
+	}
+	if e.signedProposal(1, 2, blockA.Header.PrevHash) != nil {
+		t.Fatalf("서명하지 않은 라운드의 제안이 복원됨")
+	}
+
+	// 다음 높이 서명 시 이전 높이 기록은 삭제, 이전 높이 서명은 거부
+	if _, err := e.signVote(2, 0, VoteTypePrevote, prt.Hash{0xc}); err != nil {
+		t.Fatalf("다음 높이 prevote 서명 실패: %v", err)
+	}
+	if records := wal.Records(1); len(records) != 0 {
+		t.Fatalf("이전 높이 기록이 남음: %d", len(records))
+	}
+	if _, err := e.signVote(1, 1, VoteTypePrevote, blockA.Header.Hash); err == nil {
+		t.Fatalf("이전 높이 투표에 서명함")
+	}
+	wal.Close()
+
+	_, wal = openEngine()
+	defer wal.Close()
+	if records := wal.Records(2); len(records) != 1 {
+		t.Fatalf("재시작 후 다음 높이 레코드 수 오류: %d", len(records))
+	}
+}
+
+// 원격 서명자의 높이/라운드/단계 단조 증가 이중 서명 방지 테스트
+func TestSignerDoubleSignProtection(t *testing.T) {
+	initTestLogger(t)
+	dir := t.TempDir()
+	statePath := filepath.Join(dir, "signer_state.json")
+	privKey := newTestKey(t)
+
+	server, err := NewSignerServer(privKey, statePath, nil)
+	if err != nil {
+		t.Fatalf("failed to create signer server: %v", err)
+	}
+	header := &core.BlockHeader{Height: 1, Round: 0, PrevHash: prt.Hash{0x1}, Timestamp: 1000}
+	hashA, hashB := utils.Hash(*header), prt.Hash{0xb}
+	header.Hash = hashA
+
+	// 블록 서명은 헤더로 해시를 직접 계산 (임의 해시나 다른 높이의 헤더는 거부)
+	if _, err := server.Sign(&SignRequest{Height: 1, Round: 0, Step: SignStepBlock, BlockHash: hashA}); err == nil {
+		t.Fatalf("헤더 없는 블록 해시에 서명함")
+	}
+	if _, err := server.Sign(&SignRequest{Height: 1, Round: 0, Step: SignStepBlock, BlockHash: hashB, Header: header}); err == nil {
+		t.Fatalf("헤더와 다른 블록 해시에 서명함")
+	}
+	if _, err := server.Sign(&SignRequest{Height: 2, Round: 0, Step: SignStepBlock, BlockHash: hashA, Header: header}); err == nil {
+		t.Fatalf("다른 높이의 헤더에 서명함")
+	}
+
+	// 제안 블록 -> 제안 -> prevote -> precommit 순서 서명
+	steps := []*SignRequest{
+		{Height: 1, Round: 0, Step: SignStepBlock, BlockHash: hashA, Header: header},
+		{Height: 1, Round: 0, Step: SignStepProposal, POLRound: -1, BlockHash: hashA},
+		{Height: 1, Round: 0, Step: SignStepPrevote, BlockHash: hashA},
+	}
+	for _, req := range steps {
+		if _, err := server.Sign(req); err != nil {
+			t.Fatalf("step %d 서명 실패: %v", req.Step, err)
+		}
+	}
+
+	// 같은 메시지는 같은 서명, 같은 단계의 다른 메시지는 거부
+	prevote := steps[2]
+	sig, err := server.Sign(prevote)
+	if err != nil || sig != server.State().Signature {
+		t.Fatalf("같은 prevote의 서명이 재사용되지 않음: %v", err)
+	}
+	if _, err := server.Sign(&SignRequest{Height: 1, Round: 0, Step: SignStepPrevote, BlockHash: hashB}); err == nil {
+		t.Fatalf("충돌하는 prevote에 서명함")
+	}
+	if _, err := server.Sign(&SignRequest{Height: 1, Round: 0, Step: SignStepPrevote}); err == nil {
+		t.Fatalf("충돌하는 nil prevote에 서명함")
+	}
+
+	// 단계 / 라운드 / 높이 역행 거부
+	if _, err := server.Sign(steps[1]); err == nil {
+		t.Fatalf("이전 단계(제안)에 서명함")
+	}
+	if _, err := server.Sign(&SignRequest{Height: 1, Round: 1, Step: SignStepPrevote}); err != nil {
+		t.Fatalf("다음 라운드 nil prevote 서명 실패: %v", err)
+	}
+	if _, err := server.Sign(&SignRequest{Height: 1, Round: 0, Step: SignStepPrecommit, BlockHash: hashA}); err == nil {
+		t.Fatalf("이전 라운드 precommit에 서명함")
+	}
+
+	// 재시작 후에도 상태 파일로 보호 유지
+	server, err = NewSignerServer(privKey, statePath, nil)
+	if err != nil {
+		t.Fatalf("failed to reload signer server: %v", err)
+	}
+	if state := server.State(); state.Height != 1 || state.Round != 1 || state.Step != SignStepPrevote {
+		t.Fatalf("재시작 후 상태 오류: %+v", state)
+	}
+	if _, err := server.Sign(&SignRequest{Height: 1, Round: 1, Step: SignStepPrevote, BlockHash: hashB}); err == nil {
+		t.Fatalf("재시작 후 충돌하는 prevote에 서명함")
+	}
+
+	// 원격 서명자 (unix 소켓, 비밀 없음)
+	listener, err := ListenSigner("unix://"+filepath.Join(dir, "signer.sock"), nil)
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+	go server.Serve(listener)
+	if info, err := os.Stat(filepath.Join(dir, "signer.sock")); err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("소켓 권한이 소유자 전용이 아님: %v", err)
+	}
+
+	remote, err := NewRemoteSigner("unix://"+filepath.Join(dir, "signer.sock"), nil)
+	if err != nil {
+		t.Fatalf("failed to create remote signer: %v", err)
+	}
+	publicKey, err := remote.PublicKey()
+	if err != nil {
+		t.Fatalf("원격 공개키 조회 실패: %v", err)
+	}
+
+	req := &SignRequest{Height: 1, Round: 1, Step: SignStepPrecommit}
+	sig, err = remote.Sign(req)
+	if err != nil {
+		t.Fatalf("원격 precommit 서명 실패: %v", err)
+	}
+	signHash, _ := req.SignHash()
+	if ok, err := crypto.VerifySignatureWithBytes(publicKey, utils.HashToBytes(signHash), sig); err != nil || !ok {
+		t.Fatalf("원격 서명 검증 실패: %v", err)
+	}
+	if _, err := remote.Sign(&SignRequest{Height: 1, Round: 1, Step: SignStepPrecommit, BlockHash: hashA}); err == nil {
+		t.Fatalf("원격 서명자가 충돌하는 precommit에 서명함")
+	}
+	next := &core.BlockHeader{Height: 2, Round: 0, PrevHash: hashA, Timestamp: 1001}
+	next.Hash = utils.Hash(*next)
+	if _, err := remote.Sign(&SignRequest{Height: 2, Round: 0, Step: SignStepBlock, BlockHash: next.Hash, Header: next}); err != nil {
+		t.Fatalf("다음 높이 원격 서명 실패: %v", err)
+	}
+
+	// 잘못된 주소 형식 / 비밀 없는 tcp / 짧은 비밀
+	if _, err := NewRemoteSigner("localhost:26659", nil); err == nil {
+		t.Fatalf("잘못된 서명자 주소를 허용함")
+	}
+	if _, err := ListenSigner("tcp://127.0.0.1:0", nil); err == nil {
+		t.Fatalf("비밀 없는 tcp 서명자를 허용함")
+	}
+	if _, err := NewRemoteSigner("tcp://127.0.0.1:26659", []byte("short")); err == nil {
+		t.Fatalf("짧은 비밀을 허용함")
+	}
+}
+
+// tcp 원격 서명자의 공유 비밀 인증 테스트
+func TestRemoteSignerAuthentication(t *testing.T) {
+	initTestLogger(t)
+	secret := []byte("0123456789abcdef-secret")
+
+	server, err := NewSignerServer(newTestKey(t), filepath.Join(t.TempDir(), "signer_state.json"), secret)
+	if err != nil {
+		t.Fatalf("failed to create signer server: %v", err)
+	}
+	listener, err := ListenSigner("tcp://127.0.0.1:0", secret)
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+	go server.Serve(listener)
+	addr := "tcp://" + listener.Addr().String()
+
+	// 다른 비밀로는 서명을 받을 수 없음
+	wrong, err := NewRemoteSigner(addr, []byte("fedcba9876543210-secret"))
+	if err != nil {
+		t.Fatalf("failed to create remote signer: %v", err)
+	}
+	req := &SignRequest{Height: 1, Round: 0, Step: SignStepPrevote}
+	if _, err := wrong.Sign(req); err == nil {
+		t.Fatalf("다른 비밀의 요청에 서명함")
+	}
+	if state := server.State(); state.Step != 0 {
+		t.Fatalf("인증 실패 요청이 서명 상태를 바꿈: %+v", state)
+	}
+
+	// 같은 비밀이면 서명
+	remote, err := NewRemoteSigner(addr, secret)
+	if err != nil {
+		t.Fatalf("failed to create remote signer: %v", err)
+	}
+	if _, err := remote.Sign(req); err != nil {
+		t.Fatalf("인증된 요청 서명 실패: %v", err)
+	}
+	if _, err := remote.Sign(&SignRequest{Height: 1, Round: 0, Step: SignStepPrecommit}); err != nil {
+		t.Fatalf("같은 연결의 다음 요청 서명 실패: %v", err)
+	}
+}
+
+// 투표 / 제안 서명 해시는 도메인으로 구분 (같은 내용의 투표와 제안이 같은 해시가 되지 않음)
+func TestSignHashDomains(t *testing.T) {
+	blockHash := prt.Hash{0xa}
+	prevote := core.VoteSignHash(1, 0, core.VoteTypePrevote, blockHash)
+	precommit := core.VoteSignHash(1, 0, core.VoteTypePrecommit, blockHash)
+	proposal := core.ProposalSignHash(1, 0, 0, blockHash)
+	if prevote == precommit || prevote == proposal || precommit == proposal || proposal == blockHash {
+		t.Fatalf("서명 해시가 구분되지 않음")
+	}
+	if prevote == utils.Hash(struct {
+		Height    uint64   `json:"height"`
+		Round     uint32   `json:"round"`
+		Type      uint8    `json:"type"`
+		BlockHash prt.Hash `json:"blockHash"`
+	}{1, 0, core.VoteTypePrevote, blockHash}) {
+		t.Fatalf("투표 서명 해시에 도메인이 없음")
+	}
+}
+
+// 엔진 테스트 네트워크: 노드 사이 메시지를 무작위로 지연해 순서를 뒤섞음 (일부는 라운드 타임아웃보다 늦게 전달)
+type engineNetwork struct {
+	t        *testing.T
+	engines  []*ConsensusEngine
+	chains   []*core.BlockChain
+	maxDelay time.Duration
+	late     time.Duration
+	drop     func(to int, height uint64, round uint32) bool // true면 제안을 전달하지 않음
+
+	mu        sync.Mutex
+	rng       *rand.Rand
+	stopped   bool
+	pending   sync.WaitGroup
+	commits   []map[uint64]prt.Hash // 노드별 합의로 확정한 블록 해시
+	conflicts []string
+	maxRound  uint32 // 브로드캐스트된 제안의 최대 라운드
+}
+
+// 노드 from의 P2P 브로드캐스터
+type engineBroadcaster struct {
+	net  *engineNetwork
+	from int
+}
+
+// 노드 node의 블록 동기화 (다른 노드의 체인에서 다음 블록을 가져옴)
+type engineSyncer struct {
+	net  *engineNetwork
+	node int
+}
+
+// 투표력이 같은 검증자 n명의 엔진 (타이밍은 제네시스에 기록)
+func newEngineNetwork(t *testing.T, n int) *engineNetwork {
+	cfg := &config.Config{}
+	cfg.Common.Mode = "boot"
+	cfg.Common.NetworkID = "abcfe-test"
+	cfg.Version.Protocol = "1.0"
+	cfg.Version.Transaction = "1.0"
+	cfg.Fee.MinFee = 1
+	cfg.Fee.BlockReward = 50
+	cfg.Genesis.Timestamp = time.Now().Unix() - 1000
+	cfg.Consensus.BlockProduceMs = 20
+	cfg.Consensus.BlockIntervalMs = 20
+	cfg.Consensus.ProposingMs = 10
+	cfg.Consensus.VotingMs = 30
+	cfg.Consensus.CommittingMs = 10
+	cfg.Consensus.RoundTimeoutMs = 300
+	cfg.Consensus.RoundTimeoutDeltaMs = 100
+
+	type validatorKey struct {
+		address prt.Address
+		private []byte
+		public  []byte
+	}
+	keys := make([]validatorKey, n)
+	for i := range keys {
+		priv, pub, err := crypto.GenerateKeyPair()
+		if err != nil {
+			t.Fatalf("failed to generate key: %v", err)
+		}
+		keys[i].private, _ = crypto.PrivateKeyToBytes(priv)
+		keys[i].public, _ = crypto.PublicKeyToBytes(pub)
+		keys[i].address, _ = crypto.PublicKeyToAddress(pub)
+		cfg.Validators.List = append(cfg.Validators.List, config.ValidatorConfig{
+			Address:     utils.AddressToString(keys[i].address),
+			PublicKey:   hex.EncodeToString(keys[i].public),
+			VotingPower: 10,
+		})
+	}
+
+	net := &engineNetwork{
+		t:        t,
+		maxDelay: 60 * time.Millisecond,
+		late:     time.Duration(cfg.Consensus.RoundTimeoutMs) * time.Millisecond,
+		rng:      rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	for i, key := range keys {
+		db, err := leveldb.Open(storage.NewMemStorage(), nil)
+		if err != nil {
+			t.Fatalf("failed to open db: %v", err)
+		}
+		bc, err := core.NewChainState(db, cfg)
+		if err != nil {
+			t.Fatalf("failed to init chain: %v", err)
+		}
+		cons, err := NewConsensus(cfg, db)
+		if err != nil {
+			t.Fatalf("failed to init consensus: %v", err)
+		}
+		signer, err := NewLocalSigner(key.private)
+		if err != nil {
+			t.Fatalf("failed to create signer: %v", err)
+		}
+		if err := cons.RegisterValidator(key.address, key.public, signer); err != nil {
+			t.Fatalf("failed to register validator: %v", err)
+		}
+		bc.SetProposerValidator(cons)
+		if err := cons.SyncValidators(bc); err != nil {
+			t.Fatalf("failed to sync validators: %v", err)
+		}
+
+		node := i
+		engine := NewConsensusEngine(cons, bc)
+		engine.SetP2PBroadcaster(&engineBroadcaster{net: net, from: node})
+		engine.SetBlockSyncer(&engineSyncer{net: net, node: node})
+		engine.SetBlockCommitCallback(func(block *core.Block) {
+			net.commit(node, block)
+		})
+		net.engines = append(net.engines, engine)
+		net.chains = append(net.chains, bc)
+		net.commits = append(net.commits, make(map[uint64]prt.Hash))
+	}
+	return net
+}
+
+// send delivers to every other node after a random delay
+func (net *engineNetwork) send(from int, deliver func(to int)) {
+	net.mu.Lock()
+	defer net.mu.Unlock()
+
+	if net.stopped {
+		return
+	}
+	for to := range net.engines {
+		if to == from {
+			continue
+		}
+		delay := time.Duration(net.rng.Int63n(int64(net.maxDelay)))
+		if net.rng.Intn(5) == 0 {
+			delay += net.late
+		}
+		to := to
+		net.pending.Add(1)
+		time.AfterFunc(delay, func() {
+			defer net.pending.Done()
+			net.mu.Lock()
+			stopped := net.stopped
+			net.mu.Unlock()
+			if !stopped {
+				deliver(to)
+			}
+		})
+	}
+}
+
+// commit records the block committed by node and gossips it to the others
+func (net *engineNetwork) commit(node int, block *core.Block) {
+	net.mu.Lock()
+	height, hash := block.Header.Height, block.Header.Hash
+	for other, commits := range net.commits {
+		if committed, ok := commits[height]; ok && committed != hash {
+			net.conflicts = append(net.conflicts, fmt.Sprintf("height %d: node %d %s, node %d %s",
+				height, other, utils.HashToString(committed)[:16], node, utils.HashToString(hash)[:16]))
+		}
+	}
+	net.commits[node][height] = hash
+	net.mu.Unlock()
+
+	data, err := utils.SerializeData(block, utils.SerializationFormatGob)
+	if err != nil {
+		net.t.Errorf("failed to serialize block: %v", err)
+		return
+	}
+	net.send(node, func(to int) {
+		var received core.Block
+		if err := utils.DeserializeData(data, &received, utils.SerializationFormatGob); err != nil {
+			net.t.Errorf("failed to deserialize block: %v", err)
+			return
+		}
+		net.importBlock(to, &received)
+	})
+}
+
+// importBlock adds the next block received from a peer (under the engine lock, not racing a commit of the height)
+func (net *engineNetwork) importBlock(node int, block *core.Block) bool {
+	engine, bc := net.engines[node], net.chains[node]
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+
+	height, _ := bc.GetLatestHeight()
+	if block.Header.Height != height+1 {
+		return false
+	}
+	if err := bc.ValidateBlock(*block, true); err != nil {
+		return false
+	}
+	if success, err := bc.AddBlock(*block); !success || err != nil {
+		return false
+	}
+	engine.consensus.UpdateHeight(block.Header.Height + 1)
+	return true
+}
+
+func (net *engineNetwork) start() {
+	for _, engine := range net.engines {
+		if err := engine.Start(); err != nil {
+			net.t.Fatalf("failed to start engine: %v", err)
+		}
+	}
+}
+
+// stop stops engines and waits for messages in flight
+func (net *engineNetwork) stop() {
+	for _, engine := range net.engines {
+		engine.Stop()
+	}
+	net.mu.Lock()
+	net.stopped = true
+	net.mu.Unlock()
+	net.pending.Wait()
+	for _, engine := range net.engines {
+		engine.stopRoundTimer()
+	}
+}
+
+// minHeight lowest chain height among nodes
+func (net *engineNetwork) minHeight() uint64 {
+	var lowest uint64
+	for i, bc := range net.chains {
+		height, _ := bc.GetLatestHeight()
+		if i == 0 || height < lowest {
+			lowest = height
+		}
+	}
+	return lowest
+}
+
+func (b *engineBroadcaster) BroadcastProposal(height uint64, round uint32, polRound int32, blockHash prt.Hash, block *core.Block, proposerID string, signature prt.Signature) error {
+	data, err := utils.SerializeData(block, utils.SerializationFormatGob)
+	if err != nil {
+		return fmt.Errorf("failed to serialize block: %w", err)
+	}
+	proposer, err := utils.StringToAddress(proposerID)
+	if err != nil {
+		return fmt.Errorf("invalid proposer id: %w", err)
+	}
+
+	b.net.mu.Lock()
+	if round > b.net.maxRound {
+		b.net.maxRound = round
+	}
+	b.net.mu.Unlock()
+
+	b.net.send(b.from, func(to int) {
+		if b.net.drop != nil && b.net.drop(to, height, round) {
+			return
+		}
+		var received core.Block
+		if err := utils.DeserializeData(data, &received, utils.SerializationFormatGob); err != nil {
+			b.net.t.Errorf("failed to deserialize block: %v", err)
+			return
+		}
+		b.net.engines[to].HandleProposal(&Proposal{
+			Height:    height,
+			Round:     round,
+			POLRound:  polRound,
+			BlockHash: blockHash,
+			Block:     &received,
+			Proposer:  proposer,
+			Signature: signature,
+		})
+	})
+	return nil
+}
+
+func (b *engineBroadcaster) BroadcastVote(height uint64, round uint32, blockHash prt.Hash, voteType uint8, voterID string, signature prt.Signature) error {
+	voter, err := utils.StringToAddress(voterID)
+	if err != nil {
+		return fmt.Errorf("invalid voter id: %w", err)
+	}
+	b.net.send(b.from, func(to int) {
+		b.net.engines[to].HandleVote(&Vote{
+			Height:    height,
+			Round:     round,
+			Type:      VoteType(voteType),
+			BlockHash: blockHash,
+			VoterID:   voter,
+			Signature: signature,
+		})
+	})
+	return nil
+}
+
+func (b *engineBroadcaster) BroadcastTx(tx *core.Transaction) error {
+	return nil
+}
+
+func (s *engineSyncer) SyncBlocks() error {
+	for {
+		height, _ := s.net.chains[s.node].GetLatestHeight()
+		synced := false
+		for peer, bc := range s.net.chains {
+			if peer == s.node {
+				continue
+			}
+			if block, err := bc.GetBlockByHeight(height + 1); err == nil && s.net.importBlock(s.node, block) {
+				synced = true
+				break
+			}
+		}
+		if !synced {
+			return nil
+		}
+	}
+}
+
+func (s *engineSyncer) GetPeerCount() int {
+	return len(s.net.engines) - 1
+}
+
+// 여러 엔진을 지연 / 순서가 뒤바뀌는 네트워크로 연결해도 같은 높이에 서로 다른 블록이 확정되지 않는지 테스트
+func TestConsensusEngineSafetyUnderDelay(t *testing.T) {
+	initTestLogger(t)
+	net := newEngineNetwork(t, 4)
+
+	// 높이 2 라운드 0 제안은 한 노드에만 전달: 2/3 prevote가 모이지 않아 라운드 타임아웃 후 다음 라운드에서 확정
+	net.drop = func(to int, height uint64, round uint32) bool {
+		return height == 2 && round == 0 && to != 1
+	}
+
+	const target = 5
+	net.start()
+	deadline := time.Now().Add(60 * time.Second)
+	for net.minHeight() < target && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	net.stop()
+
+	for _, conflict := range net.conflicts {
+		t.Errorf("같은 높이에 다른 블록 확정: %s", conflict)
+	}
+
+	// 모든 노드의 체인이 같은 블록으로 이어짐
+	reached := net.minHeight()
+	if reached < target {
+		t.Fatalf("높이 %d까지 진행하지 못함 (최저 높이 %d)", target, reached)
+	}
+	for height := uint64(1); height <= reached; height++ {
+		first, err := net.chains[0].GetBlockByHeight(height)
+		if err != nil {
+			t.Fatalf("블록 %d 조회 실패: %v", height, err)
+		}
+		for node, bc := range net.chains[1:] {
+			block, err := bc.GetBlockByHeight(height)
+			if err != nil || block.Header.Hash != first.Header.Hash {
+				t.Fatalf("노드 %d의 블록 %d이 노드 0과 다름", node+1, height)
+			}
+		}
+	}
+
+	// 합의로 확정한 블록이 있고, 라운드 타임아웃 후 다음 라운드의 제안이 있었음
+	committed := 0
+	for _, commits := range net.commits {
+		committed += len(commits)
+	}
+	if committed == 0 {
+		t.Fatalf("합의로 확정된 블록이 없음")
+	}
+	if net.maxRound == 0 {
+		t.Fatalf("라운드 타임아웃 후 다음 라운드로 진행하지 않음")
+	}
+}
@@ -13,18 +13,12 @@ import (
 	"github.com/syndtr/goleveldb/leveldb"
 )
 
-// Consensus constants
+// MinStakeAmount minimum stake amount to become validator (applied by core when storing epochs)
+const MinStakeAmount = core.MinValidatorStake
+
+// Consensus constants (phase durations and round timeout are configurable, see core.ConsensusTiming)
 const (
-	MinStakeAmount     = 1000  // Minimum stake amount
-	MaxValidators      = 100   // Maximum validators
-	BlockProduceTimeMs = 1000  // Block production check interval (milliseconds)
-	BlockIntervalMs    = 1000  // Minimum interval between blocks (milliseconds)
-	RoundTimeoutMs     = 20000 // Round timeout (milliseconds)
-
-	// Phase duration constants (for observable state changes via WebSocket)
-	ProposingDurationMs  = 2000 // Proposing phase duration (milliseconds)
-	VotingDurationMs     = 3000 // Voting phase duration (milliseconds)
-	CommittingDurationMs = 2000 // Committing phase duration (milliseconds)
+	MaxValidators = 100 // Maximum validators
 )
 
 // ConsensusState consensus state
@@ -84,21 +78,18 @@ type Consensus struct {
 
 	// Proposer selection mode: "roundrobin", "vrf", "hybrid"
 	ProposerSelectionMode string
+
+	// Epoch of the current validator set
+	Epoch *core.Epoch
+
+	// Tip block hash staker/validator sets were last synced at
+	syncedBlockHash string
 }
 
-// NewConsensus creates new consensus engine
+// NewConsensus creates new consensus engine.
+// Starts with genesis validators only; the epoch validator set is loaded from chain data by SyncValidators.
 func NewConsensus(cfg *conf.Config, db *leveldb.DB) (*Consensus, error) {
-	// Load StakerSet
-	stakerSet, err := LoadStakerSet(db)
-	if err != nil {
-		return nil, fmt.Errorf("failed to load staker set: %w", err)
-	}
-
-	// Load or create ValidatorSet
-	validatorSet, err := LoadValidatorSet(db)
-	if err != nil {
-		return nil, fmt.Errorf("failed to load validator set: %w", err)
-	}
+	validatorSet := NewValidatorSet()
 
 	// Load genesis validators (PoA)
 	if len(cfg.Validators.List) > 0 {
@@ -107,9 +98,6 @@ func NewConsensus(cfg *conf.Config, db *leveldb.DB) (*Consensus, error) {
 			return nil, fmt.Errorf("failed to load genesis validators: %w", err)
 		}
 		logger.Info("[Consensus] Genesis validators loaded, total voting power: ", validatorSet.TotalVotingPower)
-	} else {
-		// If no genesis validators, update validators based on stakers
-		validatorSet.UpdateFromStakerSet(stakerSet, MinStakeAmount)
 	}
 
 	selector := NewProposerSelector(validatorSet)
@@ -128,7 +116,7 @@ func NewConsensus(cfg *conf.Config, db *leveldb.DB) (*Consensus, error) {
 		State:                 StateIdle,
 		CurrentHeight:         0,
 		CurrentRound:          0,
-		StakerSet:             stakerSet,
+		StakerSet:             NewStakerSet(),
 		ValidatorSet:          validatorSet,
 		Selector:              selector,
 		ProposerSelectionMode: proposerMode,
@@ -173,8 +161,8 @@ func loadGenesisValidators(vs *ValidatorSet, validators []conf.ValidatorConfig)
 	return nil
 }
 
-// RegisterValidator registers local node as validator
-func (c *Consensus) RegisterValidator(address prt.Address, publicKey []byte, privateKey []byte) error {
+// RegisterValidator registers local node as validator signing with signer
+func (c *Consensus) RegisterValidator(address prt.Address, publicKey []byte, signer Signer) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -197,59 +185,74 @@ func (c *Consensus) RegisterValidator(address prt.Address, publicKey []byte, pri
 	}
 
 	c.LocalValidator = validator
-	c.LocalProposer = NewProposer(validator, privateKey)
+	c.LocalProposer = NewProposer(validator, signer)
 
 	return nil
 }
 
-// Stake staking
-func (c *Consensus) Stake(address prt.Address, amount uint64, publicKey []byte) error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+// SyncValidators loads the validator set of the epoch of the next block and stakes as of the chain tip.
+// The set changes only at epoch boundaries; stakes of the tip are kept for status.
+func (c *Consensus) SyncValidators(bc *core.BlockChain) error {
+	c.mu.RLock()
+	synced := c.syncedBlockHash
+	c.mu.RUnlock()
 
-	if err := c.StakerSet.AddStaker(address, amount, publicKey); err != nil {
+	tipHash, err := bc.GetLatestBlockHash()
+	if err != nil || tipHash == synced {
+		return nil // No chain yet or tip unchanged
+	}
+	height, err := bc.GetLatestHeight()
+	if err != nil {
 		return err
 	}
 
-	// Update ValidatorSet
-	c.ValidatorSet.UpdateFromStakerSet(c.StakerSet, MinStakeAmount)
-
-	// Save to DB
-	if err := SaveStakerSet(c.DB, c.StakerSet); err != nil {
-		return fmt.Errorf("failed to save staker set: %w", err)
+	epoch, err := bc.GetEpoch(height + 1)
+	if err != nil {
+		return fmt.Errorf("failed to get epoch: %w", err)
 	}
-
-	if err := SaveValidatorSet(c.DB, c.ValidatorSet); err != nil {
-		return fmt.Errorf("failed to save validator set: %w", err)
+	snapshot, err := bc.GetStakeSnapshot()
+	if err != nil {
+		return fmt.Errorf("failed to get stake snapshot: %w", err)
 	}
-
-	return nil
-}
-
-// Unstake unstaking
-func (c *Consensus) Unstake(address prt.Address, amount uint64) error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	if err := c.StakerSet.RemoveStaker(address, amount); err != nil {
+	stakerSet, err := NewStakerSetFromSnapshot(bc, snapshot)
+	if err != nil {
 		return err
 	}
+	validatorSet := NewValidatorSetFromEpoch(epoch)
 
-	// Update ValidatorSet
-	c.ValidatorSet.UpdateFromStakerSet(c.StakerSet, MinStakeAmount)
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-	// Save to DB
-	if err := SaveStakerSet(c.DB, c.StakerSet); err != nil {
-		return fmt.Errorf("failed to save staker set: %w", err)
+	if c.Epoch == nil || c.Epoch.StartHeight != epoch.StartHeight || c.Epoch.BoundaryHash != epoch.BoundaryHash {
+		logger.Info("[Consensus] Epoch ", epoch.Number, " validator set from height ", epoch.StartHeight, ": ", len(validatorSet.Validators), " validators, total power ", validatorSet.TotalVotingPower)
 	}
 
-	if err := SaveValidatorSet(c.DB, c.ValidatorSet); err != nil {
-		return fmt.Errorf("failed to save validator set: %w", err)
+	c.Epoch = epoch
+	c.StakerSet = stakerSet
+	c.ValidatorSet = validatorSet
+	c.Selector = NewProposerSelector(validatorSet)
+	c.syncedBlockHash = snapshot.BlockHash
+
+	// Voting power of local validator follows the set
+	if c.LocalValidator != nil {
+		c.LocalValidator.VotingPower = 0
+		if v := validatorSet.GetValidator(c.LocalValidator.Address); v != nil {
+			c.LocalValidator.VotingPower = v.VotingPower
+		}
 	}
 
 	return nil
 }
 
+// validatorSetFor validator set of epoch (current set if the epoch is unknown, lock must be held).
+// Built from the epoch even when it is the current one, since jailing within the epoch depends on the height read.
+func (c *Consensus) validatorSetFor(epoch *core.Epoch) *ValidatorSet {
+	if epoch == nil {
+		return c.ValidatorSet
+	}
+	return NewValidatorSetFromEpoch(epoch)
+}
+
 // GetCurrentProposer gets proposer of current block
 func (c *Consensus) GetCurrentProposer() *Validator {
 	c.mu.RLock()
@@ -328,13 +331,13 @@ func (c *Consensus) Stop() {
 }
 
 // ValidateProposerSignature validates proposer signature (implements core.ProposerValidator interface)
-func (c *Consensus) ValidateProposerSignature(proposer prt.Address, blockHash prt.Hash, signature prt.Signature) bool {
+func (c *Consensus) ValidateProposerSignature(epoch *core.Epoch, proposer prt.Address, blockHash prt.Hash, signature prt.Signature) bool {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
 	// Find validator
 	addrStr := utils.AddressToString(proposer)
-	validator, exists := c.ValidatorSet.Validators[addrStr]
+	validator, exists := c.validatorSetFor(epoch).Validators[addrStr]
 	if !exists || !validator.IsActive {
 		return false
 	}
@@ -346,13 +349,13 @@ func (c *Consensus) ValidateProposerSignature(proposer prt.Address, blockHash pr
 // IsValidProposer checks if valid proposer for the height (implements core.ProposerValidator interface)
 // In BFT mode, proposer changes by round, so only check if in validator list
 // Round-based proposer validation is done in HandleProposal in consensus/engine.go
-func (c *Consensus) IsValidProposer(proposer prt.Address, height uint64) bool {
+func (c *Consensus) IsValidProposer(epoch *core.Epoch, proposer prt.Address, height uint64) bool {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	// Check if in validator list
+	// Check if in validator list of the block's epoch
 	addrStr := utils.AddressToString(proposer)
-	validator, exists := c.ValidatorSet.Validators[addrStr]
+	validator, exists := c.validatorSetFor(epoch).Validators[addrStr]
 	if !exists || !validator.IsActive {
 		return false
 	}
@@ -362,17 +365,20 @@ func (c *Consensus) IsValidProposer(proposer prt.Address, height uint64) bool {
 	return true
 }
 
-// ValidateCommitSignatures validates BFT commit signatures (2/3+ majority)
-func (c *Consensus) ValidateCommitSignatures(blockHash prt.Hash, commitSigs []core.CommitSignature) error {
+// ValidateCommitSignatures validates BFT commit signatures (2/3+ majority) against the validator set of the block's epoch.
+// Each signature is a precommit of the block at height and the round it carries (core.VoteSignHash).
+func (c *Consensus) ValidateCommitSignatures(epoch *core.Epoch, height uint64, blockHash prt.Hash, commitSigs []core.CommitSignature) error {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
+	validatorSet := c.validatorSetFor(epoch)
+
 	// If no validators in the set (e.g. solo mode), skip validation
-	if len(c.ValidatorSet.Validators) == 0 {
+	if len(validatorSet.Validators) == 0 {
 		return nil
 	}
 
-	totalPower := c.ValidatorSet.TotalVotingPower
+	totalPower := validatorSet.TotalVotingPower
 	var votedPower uint64
 	seenValidators := make(map[string]bool)
 
@@ -382,13 +388,13 @@ func (c *Consensus) ValidateCommitSignatures(blockHash prt.Hash, commitSigs []co
 			continue // Prevent double voting in evidence
 		}
 
-		validator, exists := c.ValidatorSet.Validators[addrStr]
+		validator, exists := validatorSet.Validators[addrStr]
 		if !exists || !validator.IsActive {
-			continue // Not an active validator in our set
+			continue // Not an active validator in the epoch's set
 		}
 
 		// Verify signature
-		if !validator.ValidateBlockSignature(blockHash, sig.Signature) {
+		if !validator.ValidateBlockSignature(core.VoteSignHash(height, sig.Round, core.VoteTypePrecommit, blockHash), sig.Signature) {
 			return fmt.Errorf("invalid commit signature from validator %s", addrStr)
 		}
 
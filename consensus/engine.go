@@ -14,8 +14,9 @@ import (
 
 // P2PBroadcaster P2P broadcast interface
 type P2PBroadcaster interface {
-	BroadcastProposal(height uint64, round uint32, blockHash prt.Hash, block *core.Block, proposerID string, signature prt.Signature) error
+	BroadcastProposal(height uint64, round uint32, polRound int32, blockHash prt.Hash, block *core.Block, proposerID string, signature prt.Signature) error
 	BroadcastVote(height uint64, round uint32, blockHash prt.Hash, voteType uint8, voterID string, signature prt.Signature) error
+	BroadcastTx(tx *core.Transaction) error
 }
 
 // BlockSyncer block sync interface
@@ -42,12 +43,26 @@ type ConsensusEngine struct {
 	// Block sync (P2PService)
 	syncer BlockSyncer
 
-	// Vote management
+	// Vote management (votes of the current round, kept in state)
 	prevotes   *VoteSet
 	precommits *VoteSet
 
+	// Votes, proposed blocks and lock of the height being decided (all rounds)
+	state *heightState
+
 	// Currently proposed block
-	proposedBlock *core.Block
+	proposedBlock    *core.Block
+	proposalPOLRound int32 // POL round of the proposed block (-1 for a new block)
+
+	// Double-sign detection
+	proposals map[string]*core.Block // First signed proposal per height:round of the next height
+	evidence  *EvidencePool
+
+	// Write-ahead log of signed proposals and votes (nil: not persisted)
+	wal *WAL
+
+	// Timing of the network (nil until the genesis block is known)
+	netTiming *core.ConsensusTiming
 
 	// Callback
 	onBlockCommit func(*core.Block) // Called on block commit (for P2P broadcast)
@@ -74,6 +89,8 @@ func NewConsensusEngine(consensus *Consensus, blockchain *core.BlockChain) *Cons
 		consensus:  consensus,
 		blockchain: blockchain,
 		stopCh:     make(chan struct{}),
+		proposals:  make(map[string]*core.Block),
+		evidence:   NewEvidencePool(),
 	}
 }
 
@@ -91,6 +108,13 @@ func (e *ConsensusEngine) SetP2PBroadcaster(p2p P2PBroadcaster) {
 	e.p2p = p2p
 }
 
+// SetWAL sets write-ahead log of signed proposals and votes (replayed on Start)
+func (e *ConsensusEngine) SetWAL(wal *WAL) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.wal = wal
+}
+
 // SetBlockSyncer sets block syncer
 func (e *ConsensusEngine) SetBlockSyncer(syncer BlockSyncer) {
 	e.mu.Lock()
@@ -105,6 +129,27 @@ func (e *ConsensusEngine) SetStateBroadcaster(broadcaster StateBroadcaster) {
 	e.stateBroadcaster = broadcaster
 }
 
+// timing returns consensus timing of the network (phase durations and round timeout).
+// Only called once resolveTiming succeeded: rounds, proposals and votes wait for it.
+func (e *ConsensusEngine) timing() core.ConsensusTiming {
+	return *e.netTiming
+}
+
+// resolveTiming loads timing of the network recorded in the genesis block (false until the genesis block is known).
+// The timing is fixed from then on, so a height never switches timing between rounds.
+func (e *ConsensusEngine) resolveTiming() bool {
+	if e.netTiming != nil {
+		return true
+	}
+	timing, err := e.blockchain.GetConsensusTiming()
+	if err != nil {
+		logger.Debug("[Consensus] Waiting for consensus timing: ", err)
+		return false
+	}
+	e.netTiming = &timing
+	return true
+}
+
 // broadcastState broadcasts current consensus state via WebSocket
 func (e *ConsensusEngine) broadcastState(proposerAddr string) {
 	if e.stateBroadcaster == nil {
@@ -118,8 +163,6 @@ func (e *ConsensusEngine) broadcastState(proposerAddr string) {
 	)
 }
 
-
-
 // Start starts the consensus engine
 func (e *ConsensusEngine) Start() error {
 	e.mu.Lock()
@@ -134,6 +177,7 @@ func (e *ConsensusEngine) Start() error {
 	// Initialize to current blockchain height
 	height, _ := e.blockchain.GetLatestHeight()
 	e.consensus.UpdateHeight(height + 1)
+	e.syncValidators()
 
 	go e.runConsensusLoop()
 
@@ -157,7 +201,27 @@ func (e *ConsensusEngine) Stop() {
 
 // runConsensusLoop consensus main loop
 func (e *ConsensusEngine) runConsensusLoop() {
-	ticker := time.NewTicker(time.Duration(BlockProduceTimeMs) * time.Millisecond)
+	// Rounds start once the timing of the network is known (genesis block synced)
+	for {
+		e.mu.Lock()
+		ready := e.resolveTiming()
+		if ready {
+			// Restore round state signed before a restart
+			e.replayWAL()
+		}
+		e.mu.Unlock()
+		if ready {
+			break
+		}
+
+		select {
+		case <-e.stopCh:
+			return
+		case <-time.After(time.Second):
+		}
+	}
+
+	ticker := time.NewTicker(time.Duration(e.timing().BlockProduceMs) * time.Millisecond)
 	defer ticker.Stop()
 
 	for {
@@ -175,11 +239,19 @@ func (e *ConsensusEngine) runRound() {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
+	// Load validator set of the next block's epoch (blocks may have been synced since the last round)
+	e.syncValidators()
+
+	// Resubmit evidence not committed yet
+	if e.evidence.Size() > 0 {
+		e.submitEvidence()
+	}
+
 	// Check minimum block interval
 	now := time.Now().UnixMilli()
 	if e.lastBlockTime > 0 {
 		elapsed := now - e.lastBlockTime
-		if elapsed < BlockIntervalMs {
+		if elapsed < int64(e.timing().BlockIntervalMs) {
 			// Not enough time has passed since last block
 			return
 		}
@@ -211,6 +283,17 @@ func (e *ConsensusEngine) runRound() {
 		}
 	}
 
+	// Discard proposal built on a block that is no longer the tip (chain reorganized)
+	if e.proposedBlock != nil && e.proposedBlock.Header.Height == nextBlockHeight {
+		tipBlock, err := e.blockchain.GetBlockByHeight(currentHeight)
+		if err == nil && tipBlock.Header.Hash != e.proposedBlock.Header.PrevHash {
+			logger.Warn("[Consensus] Proposed block parent is no longer the tip, discarding proposal")
+			e.proposedBlock = nil
+			e.prevotes = nil
+			e.precommits = nil
+		}
+	}
+
 	// Sync consensus height (based on blockchain height)
 	if e.consensus.CurrentHeight != nextBlockHeight {
 		e.consensus.mu.Lock()
@@ -254,14 +337,39 @@ func (e *ConsensusEngine) runRound() {
 		// Non-proposer: Wait for proposal
 		// Keep existing VoteSet if exists (might be collecting votes)
 		if e.prevotes == nil || e.prevotes.Height != nextBlockHeight {
-			e.prevotes = NewVoteSet(nextBlockHeight, 0, VoteTypePrevote)
-			e.precommits = NewVoteSet(nextBlockHeight, 0, VoteTypePrecommit)
+			e.enterRound(e.currentState(), e.consensus.CurrentRound)
 			e.startRoundTimer()
 		}
 		// Keep timeout timer if already running
 	}
 }
 
+// currentState returns locking state of the next block height (reset when the tip changed)
+func (e *ConsensusEngine) currentState() *heightState {
+	height, _ := e.blockchain.GetLatestHeight()
+	var tipHash prt.Hash
+	if tip, err := e.blockchain.GetBlockByHeight(height); err == nil {
+		tipHash = tip.Header.Hash
+	}
+	if e.state == nil || e.state.height != height+1 || e.state.prevHash != tipHash {
+		e.state = newHeightState(height+1, tipHash)
+	}
+	return e.state
+}
+
+// enterRound points vote progress at votes of round (votes of earlier rounds stay in state)
+func (e *ConsensusEngine) enterRound(state *heightState, round uint32) {
+	e.prevotes = state.voteSet(VoteTypePrevote, round)
+	e.precommits = state.voteSet(VoteTypePrecommit, round)
+}
+
+// syncValidators loads validator set of the next block's epoch when the tip changed
+func (e *ConsensusEngine) syncValidators() {
+	if err := e.consensus.SyncValidators(e.blockchain); err != nil {
+		logger.Error("[Consensus] Failed to sync validators: ", err)
+	}
+}
+
 // produceBlockSolo creates block in solo node mode
 func (e *ConsensusEngine) produceBlockSolo() {
 	// Check current height
@@ -295,7 +403,7 @@ func (e *ConsensusEngine) produceBlockSolo() {
 
 	// Sign block (if local proposer exists)
 	if e.consensus.LocalProposer != nil {
-		sig, err := e.consensus.LocalProposer.signBlockHash(newBlock.Header.Hash)
+		sig, err := e.consensus.LocalProposer.signBlock(&newBlock.Header)
 		if err != nil {
 			logger.Error("[Consensus] Failed to sign block: ", err)
 		} else {
@@ -340,7 +448,7 @@ func (e *ConsensusEngine) proposeBlock() {
 	e.broadcastState(proposerAddrStr)
 
 	// Wait for proposing phase duration (observable via WebSocket)
-	time.Sleep(time.Duration(ProposingDurationMs) * time.Millisecond)
+	time.Sleep(time.Duration(e.timing().ProposingMs) * time.Millisecond)
 
 	// Check current height
 	currentHeight, err := e.blockchain.GetLatestHeight()
@@ -355,11 +463,27 @@ func (e *ConsensusEngine) proposeBlock() {
 		prevHash = prevBlock.Header.Hash
 	}
 
+	// Block signed for this round before a restart is proposed again (signing another would be double-signing)
+	if signed := e.signedProposal(currentHeight+1, e.consensus.CurrentRound, prevHash); signed != nil {
+		e.proposedBlock = signed.block
+		e.proposalPOLRound = signed.polRound
+		logger.Info("[Consensus] Proposing block signed before restart ", currentHeight+1, " (hash: ", utils.HashToString(signed.block.Header.Hash)[:16], ")")
+		return
+	}
+
+	// Re-propose the block with the latest POL of the height (validators locked on it prevote it again)
+	if validBlock, polRound := e.currentState().proposal(); validBlock != nil && validBlock.Header.PrevHash == prevHash {
+		e.proposedBlock = validBlock
+		e.proposalPOLRound = polRound
+		logger.Info("[Consensus] Re-proposing block ", validBlock.Header.Height, " (hash: ", utils.HashToString(validBlock.Header.Hash)[:16], ", POL round: ", polRound, ")")
+		return
+	}
+
 	// Block timestamp (used consistently across all nodes)
 	blockTimestamp := time.Now().Unix()
 
-	// Create new block
-	newBlock := e.blockchain.SetBlock(prevHash, currentHeight+1, proposerAddr, blockTimestamp)
+	// Create new block (the round is signed with it)
+	newBlock := e.blockchain.SetBlockAtRound(prevHash, currentHeight+1, e.consensus.CurrentRound, proposerAddr, blockTimestamp)
 	if newBlock == nil {
 		logger.Error("[Consensus] Failed to create proposed block")
 		return
@@ -367,7 +491,7 @@ func (e *ConsensusEngine) proposeBlock() {
 
 	// Block signature
 	if e.consensus.LocalProposer != nil {
-		sig, err := e.consensus.LocalProposer.signBlockHash(newBlock.Header.Hash)
+		sig, err := e.consensus.LocalProposer.signBlock(&newBlock.Header)
 		if err != nil {
 			logger.Error("[Consensus] Failed to sign proposed block: ", err)
 		} else {
@@ -376,6 +500,7 @@ func (e *ConsensusEngine) proposeBlock() {
 	}
 
 	e.proposedBlock = newBlock
+	e.proposalPOLRound = -1
 
 	logger.Info("[Consensus] Proposed block ", newBlock.Header.Height, " (hash: ", utils.HashToString(newBlock.Header.Hash)[:16], ", proposer: ", proposerAddrStr[:16], ")")
 }
@@ -392,27 +517,37 @@ func (e *ConsensusEngine) broadcastProposal() {
 
 	height := e.consensus.CurrentHeight
 	round := e.consensus.CurrentRound
+	polRound := e.proposalPOLRound
 	blockHash := e.proposedBlock.Header.Hash
 	proposerID := utils.AddressToString(e.consensus.LocalValidator.Address)
 
+	// Sign proposal (height, round and POL round are signed with the block hash)
+	signature, err := e.signProposal(height, round, polRound, e.proposedBlock)
+	if err != nil {
+		logger.Error("[Consensus] Failed to sign proposal: ", err)
+		return
+	}
+
 	// Initialize VoteSet
-	e.prevotes = NewVoteSet(height, round, VoteTypePrevote)
-	e.precommits = NewVoteSet(height, round, VoteTypePrecommit)
+	state := e.currentState()
+	state.addBlock(e.proposedBlock)
+	e.enterRound(state, round)
 
 	// Broadcast Proposal via P2P
 	if err := e.p2p.BroadcastProposal(
 		height,
 		round,
+		polRound,
 		blockHash,
 		e.proposedBlock,
 		proposerID,
-		e.proposedBlock.Signature,
+		signature,
 	); err != nil {
 		logger.Error("[Consensus] Failed to broadcast proposal: ", err)
 		return
 	}
 
-	logger.Info("[Consensus] Broadcast proposal at height ", height, " round ", round)
+	logger.Info("[Consensus] Broadcast proposal at height ", height, " round ", round, " (POL round ", polRound, ")")
 
 	// Set state to PREVOTING and broadcast
 	e.consensus.mu.Lock()
@@ -424,14 +559,22 @@ func (e *ConsensusEngine) broadcastProposal() {
 	e.startRoundTimer()
 
 	// Local prevote (proposer also participates in voting)
-	e.castVote(VoteTypePrevote, blockHash)
+	e.prevote(round, polRound, e.proposedBlock)
 }
 
 // HandleProposal handles proposal message (from P2P)
-func (e *ConsensusEngine) HandleProposal(height uint64, round uint32, blockHash prt.Hash, block *core.Block) {
+func (e *ConsensusEngine) HandleProposal(p *Proposal) {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
+	height, round, block := p.Height, p.Round, p.Block
+
+	// Rounds wait for the timing of the network
+	if e.netTiming == nil {
+		logger.Debug("[Consensus] Consensus timing not known yet, ignoring proposal")
+		return
+	}
+
 	// Check if block at this height is already committed
 	currentHeight, _ := e.blockchain.GetLatestHeight()
 	if height <= currentHeight {
@@ -463,8 +606,61 @@ func (e *ConsensusEngine) HandleProposal(height uint64, round uint32, blockHash
 		logger.Error("[Consensus] No expected proposer for height ", height, " round ", round)
 		return
 	}
-	if block.Proposer != expectedProposer.Address {
-		logger.Debug("[Consensus] Invalid proposer for round ", round, ": expected ", utils.AddressToString(expectedProposer.Address)[:16], ", got ", utils.AddressToString(block.Proposer)[:16])
+	if p.Proposer != expectedProposer.Address {
+		logger.Debug("[Consensus] Invalid proposer for round ", round, ": expected ", utils.AddressToString(expectedProposer.Address)[:16], ", got ", utils.AddressToString(p.Proposer)[:16])
+		return
+	}
+	if block == nil || block.Header.Height != height || block.Header.Hash != p.BlockHash {
+		logger.Debug("[Consensus] Proposal does not match its height/hash, ignoring")
+		return
+	}
+	if !e.consensus.ValidateProposerSignature(nil, p.Proposer, core.ProposalSignHash(height, round, p.POLRound, p.BlockHash), p.Signature) {
+		logger.Warn("[Consensus] Invalid proposal signature from: ", utils.AddressToString(p.Proposer)[:16])
+		return
+	}
+
+	if p.POLRound < 0 {
+		// New block: built and signed by the proposer of the round
+		if block.Proposer != p.Proposer || block.Header.Round != round {
+			logger.Debug("[Consensus] Proposal does not match its proposer/round, ignoring")
+			return
+		}
+
+		// A second signed block of the proposer for the same round is double-signing
+		if core.ValidateBlockHash(block) == nil && e.consensus.ValidateProposerSignature(nil, block.Proposer, block.Header.Hash, block.Signature) {
+			key := fmt.Sprintf("%d:%d", height, round)
+			first, exists := e.proposals[key]
+			if exists && first.Header.Hash != block.Header.Hash {
+				e.reportEvidence(core.NewDuplicateProposalEvidence(first, block))
+				return
+			}
+			if !exists {
+				for k, proposal := range e.proposals {
+					if proposal.Header.Height != height {
+						delete(e.proposals, k) // Committed heights
+					}
+				}
+				e.proposals[key] = block
+			}
+		}
+	} else if uint32(p.POLRound) >= round {
+		// Re-proposed block: its POL must be from an earlier round
+		logger.Debug("[Consensus] Proposal POL round ", p.POLRound, " is not before round ", round, ", ignoring")
+		return
+	}
+
+	// Validate block (including signature)
+	if err := e.blockchain.ValidateBlock(*block, false); err != nil {
+		logger.Error("[Consensus] Invalid proposed block: ", err)
+		return
+	}
+
+	// Proposal of an earlier round: keep the block, its round's votes may still commit it
+	state := e.currentState()
+	state.addBlock(block)
+	if e.consensus.CurrentHeight == height && round < e.consensus.CurrentRound {
+		logger.Debug("[Consensus] Proposal for earlier round ", round, " recorded (current ", e.consensus.CurrentRound, ")")
+		e.checkVotes(round)
 		return
 	}
 
@@ -477,19 +673,13 @@ func (e *ConsensusEngine) HandleProposal(height uint64, round uint32, blockHash
 		e.consensus.mu.Unlock()
 	}
 
-	// Validate block (including signature)
-	if err := e.blockchain.ValidateBlock(*block, false); err != nil {
-		logger.Error("[Consensus] Invalid proposed block: ", err)
-		return
-	}
-
-	logger.Info("[Consensus] Received valid proposal at height ", height, " round ", round, " from ", utils.AddressToString(block.Proposer)[:16])
+	logger.Info("[Consensus] Received valid proposal at height ", height, " round ", round, " (POL round ", p.POLRound, ") from ", utils.AddressToString(p.Proposer)[:16])
 
 	e.proposedBlock = block
-	e.prevotes = NewVoteSet(height, round, VoteTypePrevote)
-	e.precommits = NewVoteSet(height, round, VoteTypePrecommit)
+	e.proposalPOLRound = p.POLRound
+	e.enterRound(state, round)
 
-	proposerAddr := utils.AddressToString(block.Proposer)
+	proposerAddr := utils.AddressToString(p.Proposer)
 
 	// Broadcast PROPOSING state first (so frontend can see proposer)
 	e.consensus.mu.Lock()
@@ -498,7 +688,7 @@ func (e *ConsensusEngine) HandleProposal(height uint64, round uint32, blockHash
 	e.broadcastState(proposerAddr)
 
 	// Wait for proposing phase duration (same as proposer node)
-	time.Sleep(time.Duration(ProposingDurationMs) * time.Millisecond)
+	time.Sleep(time.Duration(e.timing().ProposingMs) * time.Millisecond)
 
 	// Then transition to PREVOTING
 	e.consensus.mu.Lock()
@@ -509,10 +699,26 @@ func (e *ConsensusEngine) HandleProposal(height uint64, round uint32, blockHash
 	// Start round timeout timer
 	e.startRoundTimer()
 
-	// Send Prevote (if local validator)
-	if e.consensus.LocalValidator != nil {
-		e.castVote(VoteTypePrevote, blockHash)
+	// Send Prevote (if local validator), then apply votes received before the proposal
+	e.prevote(round, p.POLRound, block)
+	e.checkVotes(round)
+}
+
+// prevote casts prevote for block proposed in round following the locking rules
+func (e *ConsensusEngine) prevote(round uint32, polRound int32, block *core.Block) {
+	if e.consensus.LocalValidator == nil {
+		return
+	}
+
+	state := e.currentState()
+	blockHash, ok := state.prevote(round, polRound, block, e.consensus.ValidatorSet.TotalVotingPower)
+	if !ok {
+		return
+	}
+	if blockHash != block.Header.Hash {
+		logger.Info("[Consensus] Locked on block ", utils.HashToString(state.lockedBlock.Header.Hash)[:16], " since round ", state.lockedRound, ", prevoting nil")
 	}
+	e.castVote(VoteTypePrevote, blockHash)
 }
 
 // HandleVote handles vote message (from P2P)
@@ -520,6 +726,11 @@ func (e *ConsensusEngine) HandleVote(vote *Vote) {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
+	// Rounds wait for the timing of the network
+	if e.netTiming == nil {
+		return
+	}
+
 	// Ignore votes for already committed height
 	currentHeight, _ := e.blockchain.GetLatestHeight()
 	if vote.Height <= currentHeight {
@@ -532,69 +743,78 @@ func (e *ConsensusEngine) HandleVote(vote *Vote) {
 		return
 	}
 
-	// Ignore if different from current round (votes synced with proposal)
-	// However, process if VoteSet exists and round matches
-	if vote.Round != e.consensus.CurrentRound {
-		logger.Debug("[Consensus] Vote round mismatch: current ", e.consensus.CurrentRound, ", got ", vote.Round)
-		return
-	}
-
 	// Get voter's voting power
 	validator := e.consensus.ValidatorSet.GetValidator(vote.VoterID)
-	if validator == nil {
-		logger.Debug("[Consensus] Unknown voter: ", utils.AddressToString(vote.VoterID)[:16])
+	if validator == nil || !validator.IsActive {
+		logger.Debug("[Consensus] Unknown or inactive voter: ", utils.AddressToString(vote.VoterID)[:16])
 		return
 	}
 
-	// Verify vote signature
-	if !validator.ValidateBlockSignature(vote.BlockHash, vote.Signature) {
+	// Verify vote signature (height, round and type are signed with the block hash)
+	if !validator.ValidateBlockSignature(core.VoteSignHash(vote.Height, vote.Round, uint8(vote.Type), vote.BlockHash), vote.Signature) {
 		logger.Warn("[Consensus] Invalid vote signature from: ", utils.AddressToString(vote.VoterID)[:16])
 		return
 	}
 
-	totalPower := e.consensus.ValidatorSet.TotalVotingPower
-	voterAddr := utils.AddressToString(vote.VoterID)[:16]
-
-	switch vote.Type {
-	case VoteTypePrevote:
-		if e.prevotes != nil {
-			added := e.prevotes.AddVote(vote, validator.VotingPower)
-			if added {
-				logger.Debug("[Consensus] Prevote received from ", voterAddr, " (", e.prevotes.VotedPower, "/", totalPower, " = ", len(e.prevotes.Votes), " votes)")
-			}
+	// Votes of every round are kept: a POL or commit of another round affects the lock
+	state := e.currentState()
+	added, conflict := state.addVote(vote, validator.VotingPower)
+	if conflict != nil {
+		e.reportDuplicateVote(conflict, vote)
+	}
+	if added {
+		votes := state.voteSet(vote.Type, vote.Round)
+		logger.Debug("[Consensus] Vote (type ", vote.Type, ", round ", vote.Round, ") received from ", utils.AddressToString(vote.VoterID)[:16],
+			" (", votes.VotedPower, "/", e.consensus.ValidatorSet.TotalVotingPower, " = ", len(votes.Votes), " votes)")
+		e.checkVotes(vote.Round)
+	}
+}
 
-			// Proceed to precommit if 2/3+
-			if e.prevotes.HasTwoThirdsMajority(totalPower) {
-				logger.Debug("[Consensus] Prevote 2/3+ reached at height ", vote.Height, " (", e.prevotes.VotedPower, "/", totalPower, ")")
+// checkVotes applies votes of round: records a newer POL as valid block, commits a block with 2/3+ precommits
+// of any round and precommits once 2/3+ prevoted in the current round
+func (e *ConsensusEngine) checkVotes(round uint32) {
+	state := e.currentState()
+	totalPower := e.consensus.ValidatorSet.TotalVotingPower
 
-				// Transition to PRECOMMITTING state
-				e.consensus.mu.Lock()
-				if e.consensus.State == StatePrevoting {
-					e.consensus.State = StatePrecommitting
-					e.consensus.mu.Unlock()
-					e.broadcastState("")
-				} else {
-					e.consensus.mu.Unlock()
-				}
+	state.updateValid(round, totalPower)
 
-				e.castVote(VoteTypePrecommit, vote.BlockHash)
-			}
-		}
+	if block, precommits := state.decided(totalPower); block != nil {
+		logger.Debug("[Consensus] Precommit 2/3+ reached at height ", block.Header.Height, " round ", precommits.Round, " (", precommits.BlockPower[block.Header.Hash], "/", totalPower, "), committing block")
+		e.commitBlockWithSignatures(block, precommits)
+		return
+	}
 
-	case VoteTypePrecommit:
-		if e.precommits != nil {
-			added := e.precommits.AddVote(vote, validator.VotingPower)
-			if added {
-				logger.Debug("[Consensus] Precommit received from ", voterAddr, " (", e.precommits.VotedPower, "/", totalPower, " = ", len(e.precommits.Votes), " votes)")
-			}
+	if round != e.consensus.CurrentRound || e.consensus.LocalValidator == nil {
+		return
+	}
+	// Precommit only after our own prevote is signed (a signer refuses steps going backwards)
+	localAddr := e.consensus.LocalValidator.Address
+	if _, exists := state.voteSet(VoteTypePrevote, round).Votes[string(localAddr[:])]; !exists {
+		return
+	}
+	blockHash, ok := state.precommit(round, totalPower)
+	if !ok {
+		return
+	}
+	logger.Debug("[Consensus] Prevote 2/3+ reached at height ", state.height, " round ", round)
 
-			// Commit if 2/3+
-			if e.precommits.HasTwoThirdsMajority(totalPower) && e.proposedBlock != nil {
-				logger.Debug("[Consensus] Precommit 2/3+ reached at height ", vote.Height, " (", e.precommits.VotedPower, "/", totalPower, "), committing block")
-				e.commitBlockWithSignatures(e.proposedBlock)
-			}
-		}
+	// Transition to PRECOMMITTING state
+	e.consensus.mu.Lock()
+	if e.consensus.State == StatePrevoting {
+		e.consensus.State = StatePrecommitting
+		e.consensus.mu.Unlock()
+		e.broadcastState("")
+	} else {
+		e.consensus.mu.Unlock()
 	}
+
+	e.castVote(VoteTypePrecommit, blockHash)
+}
+
+// reportDuplicateVote reports two votes of a validator for different blocks in the same height/round
+func (e *ConsensusEngine) reportDuplicateVote(first, second *Vote) {
+	e.reportEvidence(core.NewDuplicateVoteEvidence(second.VoterID, second.Height, second.Round, uint8(second.Type),
+		first.BlockHash, first.Signature, second.BlockHash, second.Signature))
 }
 
 // castVote creates and sends vote with random delay
@@ -603,91 +823,8 @@ func (e *ConsensusEngine) castVote(voteType VoteType, blockHash prt.Hash) {
 		return
 	}
 
-	// Capture current state for the goroutine
-	height := e.consensus.CurrentHeight
-	round := e.consensus.CurrentRound
-	voterID := e.consensus.LocalValidator.Address
-	votingPower := e.consensus.LocalValidator.VotingPower
-	totalPower := e.consensus.ValidatorSet.TotalVotingPower
-
-	// Random delay within VotingDurationMs to spread out votes across validators
-	// Run in goroutine to avoid blocking mutex
-	go func() {
-		randomDelay := rand.Intn(VotingDurationMs)
-		time.Sleep(time.Duration(randomDelay) * time.Millisecond)
-
-		e.mu.Lock()
-		defer e.mu.Unlock()
-
-		// Check if still valid (height/round might have changed)
-		if e.consensus.CurrentHeight != height || e.consensus.CurrentRound != round {
-			return
-		}
-
-		// Create signature
-		sig, err := e.consensus.LocalProposer.signBlockHash(blockHash)
-		if err != nil {
-			logger.Error("[Consensus] Failed to sign vote: ", err)
-			return
-		}
-
-		vote := &Vote{
-			Height:    height,
-			Round:     round,
-			Type:      voteType,
-			BlockHash: blockHash,
-			VoterID:   voterID,
-			Signature: sig,
-			Timestamp: time.Now().Unix(),
-		}
-
-		// Broadcast vote via P2P
-		if e.p2p != nil {
-			voterIDStr := utils.AddressToString(voterID)
-			if err := e.p2p.BroadcastVote(
-				vote.Height,
-				vote.Round,
-				vote.BlockHash,
-				uint8(voteType),
-				voterIDStr,
-				vote.Signature,
-			); err != nil {
-				logger.Error("[Consensus] Failed to broadcast vote: ", err)
-			}
-		}
-
-		// Handle local vote
-		switch voteType {
-		case VoteTypePrevote:
-			if e.prevotes != nil {
-				e.prevotes.AddVote(vote, votingPower)
-
-				if e.prevotes.HasTwoThirdsMajority(totalPower) {
-					// Transition to PRECOMMITTING state
-					e.consensus.mu.Lock()
-					if e.consensus.State == StatePrevoting {
-						e.consensus.State = StatePrecommitting
-						e.consensus.mu.Unlock()
-						e.broadcastState("")
-					} else {
-						e.consensus.mu.Unlock()
-					}
-
-					// Cast precommit (will run in new goroutine with its own delay)
-					go e.castVoteInternal(VoteTypePrecommit, blockHash, height, round)
-				}
-			}
-		case VoteTypePrecommit:
-			if e.precommits != nil {
-				e.precommits.AddVote(vote, votingPower)
-
-				if e.precommits.HasTwoThirdsMajority(totalPower) && e.proposedBlock != nil {
-					logger.Info("[Consensus] Local precommit triggered 2/3+ at height ", height)
-					e.commitBlockWithSignatures(e.proposedBlock)
-				}
-			}
-		}
-	}()
+	// Run in goroutine to avoid blocking mutex (current state captured for the goroutine)
+	go e.castVoteInternal(voteType, blockHash, e.consensus.CurrentHeight, e.consensus.CurrentRound)
 }
 
 // castVoteInternal casts vote with delay (called from goroutine, needs to acquire lock)
@@ -696,23 +833,23 @@ func (e *ConsensusEngine) castVoteInternal(voteType VoteType, blockHash prt.Hash
 		return
 	}
 
-	randomDelay := rand.Intn(VotingDurationMs)
+	// Random delay within the voting phase to spread out votes across validators
+	randomDelay := rand.Intn(int(e.timing().VotingMs))
 	time.Sleep(time.Duration(randomDelay) * time.Millisecond)
 
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
-	// Check if still valid
+	// Check if still valid (height/round might have changed)
 	if e.consensus.CurrentHeight != expectedHeight || e.consensus.CurrentRound != expectedRound {
 		return
 	}
 
 	votingPower := e.consensus.LocalValidator.VotingPower
-	totalPower := e.consensus.ValidatorSet.TotalVotingPower
 	voterID := e.consensus.LocalValidator.Address
 
-	// Create signature
-	sig, err := e.consensus.LocalProposer.signBlockHash(blockHash)
+	// Create signature (logged in WAL before broadcast)
+	sig, err := e.signVote(expectedHeight, expectedRound, voteType, blockHash)
 	if err != nil {
 		logger.Error("[Consensus] Failed to sign vote: ", err)
 		return
@@ -743,14 +880,9 @@ func (e *ConsensusEngine) castVoteInternal(voteType VoteType, blockHash prt.Hash
 		}
 	}
 
-	// Handle local vote (only precommit here)
-	if e.precommits != nil {
-		e.precommits.AddVote(vote, votingPower)
-
-		if e.precommits.HasTwoThirdsMajority(totalPower) && e.proposedBlock != nil {
-			logger.Info("[Consensus] Local precommit triggered 2/3+ at height ", expectedHeight)
-			e.commitBlockWithSignatures(e.proposedBlock)
-		}
+	// Handle local vote
+	if added, _ := e.currentState().addVote(vote, votingPower); added {
+		e.checkVotes(expectedRound)
 	}
 }
 
@@ -781,15 +913,17 @@ func (e *ConsensusEngine) commitBlock(block *core.Block) {
 		e.onBlockCommit(block)
 	}
 
-	// To next height
+	// To next height (validator set changes if this block ends an epoch)
 	e.consensus.UpdateHeight(block.Header.Height + 1)
+	e.syncValidators()
 	e.proposedBlock = nil
 	e.prevotes = nil
 	e.precommits = nil
+	e.state = nil
 }
 
-// commitBlockWithSignatures commits block after BFT consensus (with validator signatures)
-func (e *ConsensusEngine) commitBlockWithSignatures(block *core.Block) {
+// commitBlockWithSignatures commits block after BFT consensus (with precommit signatures of the deciding round)
+func (e *ConsensusEngine) commitBlockWithSignatures(block *core.Block, precommits *VoteSet) {
 	// Cancel timeout timer
 	e.stopRoundTimer()
 
@@ -806,14 +940,18 @@ func (e *ConsensusEngine) commitBlockWithSignatures(block *core.Block) {
 	e.broadcastState(proposerAddr)
 
 	// Wait for committing phase duration (observable via WebSocket)
-	time.Sleep(time.Duration(CommittingDurationMs) * time.Millisecond)
+	time.Sleep(time.Duration(e.timing().CommittingMs) * time.Millisecond)
 
-	// Create CommitSignatures from precommits
-	if e.precommits != nil {
+	// Create CommitSignatures from precommits for the block (nil precommits are not commit signatures)
+	if precommits != nil {
 		var commitSigs []core.CommitSignature
-		for _, vote := range e.precommits.Votes {
+		for _, vote := range precommits.Votes {
+			if vote.BlockHash != block.Header.Hash {
+				continue
+			}
 			commitSigs = append(commitSigs, core.CommitSignature{
 				ValidatorAddress: vote.VoterID,
+				Round:            vote.Round,
 				Signature:        vote.Signature,
 				Timestamp:        vote.Timestamp,
 			})
@@ -847,11 +985,13 @@ func (e *ConsensusEngine) commitBlockWithSignatures(block *core.Block) {
 		e.onBlockCommit(block)
 	}
 
-	// To next height
+	// To next height (validator set changes if this block ends an epoch)
 	e.consensus.UpdateHeight(block.Header.Height + 1)
+	e.syncValidators()
 	e.proposedBlock = nil
 	e.prevotes = nil
 	e.precommits = nil
+	e.state = nil
 
 	// Broadcast IDLE state after commit
 	e.broadcastState("")
@@ -870,11 +1010,13 @@ func (e *ConsensusEngine) startRoundTimer() {
 	height := e.consensus.CurrentHeight
 	round := e.consensus.CurrentRound
 
-	e.roundTimer = time.AfterFunc(time.Duration(RoundTimeoutMs)*time.Millisecond, func() {
+	// Timeout grows with the round so that validators eventually share a round long enough to decide
+	timeout := e.timing().RoundTimeout(round)
+	e.roundTimer = time.AfterFunc(timeout, func() {
 		e.handleRoundTimeout(height, round)
 	})
 
-	logger.Debug("[Consensus] Round timer started for height ", height, " round ", round, " (", RoundTimeoutMs, "ms)")
+	logger.Debug("[Consensus] Round timer started for height ", height, " round ", round, " (", timeout.Milliseconds(), "ms)")
 }
 
 // stopRoundTimer cancels round timeout timer
@@ -907,8 +1049,9 @@ func (e *ConsensusEngine) handleRoundTimeout(height uint64, round uint32) {
 		logger.Warn("[Consensus] Too many consecutive timeouts, attempting block sync...")
 		e.consecutiveTimeouts = 0
 
-		// Check current blockchain height
+		// Check current blockchain tip
 		currentHeight, _ := e.blockchain.GetLatestHeight()
+		currentHash, _ := e.blockchain.GetLatestBlockHash()
 
 		// Attempt block sync (in separate goroutine)
 		if e.syncer != nil && e.syncer.GetPeerCount() > 0 {
@@ -921,10 +1064,11 @@ func (e *ConsensusEngine) handleRoundTimeout(height uint64, round uint32) {
 			}()
 		}
 
-		// Update consensus height if blockchain height changed after sync
+		// Update consensus height if blockchain tip changed after sync (advanced or reorganized)
 		newHeight, _ := e.blockchain.GetLatestHeight()
-		if newHeight > currentHeight {
-			logger.Info("[Consensus] Blockchain advanced from ", currentHeight, " to ", newHeight, ", updating consensus")
+		newHash, _ := e.blockchain.GetLatestBlockHash()
+		if newHeight != currentHeight || newHash != currentHash {
+			logger.Info("[Consensus] Blockchain tip changed from ", currentHeight, " to ", newHeight, ", updating consensus")
 			e.consensus.mu.Lock()
 			e.consensus.CurrentHeight = newHeight + 1
 			e.consensus.CurrentRound = 0
@@ -932,15 +1076,15 @@ func (e *ConsensusEngine) handleRoundTimeout(height uint64, round uint32) {
 			e.proposedBlock = nil
 			e.prevotes = nil
 			e.precommits = nil
+			e.state = nil
 			return
 		}
 	}
 
-	// Increment round -> next proposer's turn
+	// Increment round -> next proposer's turn (lock and votes of the height are kept)
 	e.consensus.IncrementRound()
 	e.proposedBlock = nil
-	e.prevotes = nil
-	e.precommits = nil
+	e.enterRound(e.currentState(), e.consensus.CurrentRound)
 
 	// Get previous block hash for VRF-based selection
 	var prevBlockHash prt.Hash
@@ -977,19 +1121,27 @@ func (e *ConsensusEngine) broadcastProposalInternal() {
 
 	height := e.consensus.CurrentHeight
 	round := e.consensus.CurrentRound
+	polRound := e.proposalPOLRound
 	blockHash := e.proposedBlock.Header.Hash
 	proposerID := utils.AddressToString(e.consensus.LocalValidator.Address)
 
-	e.prevotes = NewVoteSet(height, round, VoteTypePrevote)
-	e.precommits = NewVoteSet(height, round, VoteTypePrecommit)
+	signature, err := e.signProposal(height, round, polRound, e.proposedBlock)
+	if err != nil {
+		logger.Error("[Consensus] Failed to sign proposal: ", err)
+		return
+	}
+
+	state := e.currentState()
+	state.addBlock(e.proposedBlock)
+	e.enterRound(state, round)
 
-	if err := e.p2p.BroadcastProposal(height, round, blockHash, e.proposedBlock, proposerID, e.proposedBlock.Signature); err != nil {
+	if err := e.p2p.BroadcastProposal(height, round, polRound, blockHash, e.proposedBlock, proposerID, signature); err != nil {
 		logger.Error("[Consensus] Failed to broadcast proposal: ", err)
 		return
 	}
 
-	logger.Info("[Consensus] Broadcast proposal at height ", height, " round ", round)
-	e.castVote(VoteTypePrevote, blockHash)
+	logger.Info("[Consensus] Broadcast proposal at height ", height, " round ", round, " (POL round ", polRound, ")")
+	e.prevote(round, polRound, e.proposedBlock)
 }
 
 // GetStatus returns current status
@@ -1003,6 +1155,12 @@ func (e *ConsensusEngine) GetStatus() map[string]interface{} {
 		proposerAddr = utils.AddressToString(e.proposedBlock.Proposer)
 	}
 
+	// Lock of the height being decided (-1 if none)
+	lockedRound, validRound := int32(-1), int32(-1)
+	if e.state != nil {
+		lockedRound, validRound = e.state.lockedRound, e.state.validRound
+	}
+
 	return map[string]interface{}{
 		"running":       e.running,
 		"state":         e.consensus.State,
@@ -1011,6 +1169,9 @@ func (e *ConsensusEngine) GetStatus() map[string]interface{} {
 		"validators":    e.consensus.GetValidatorCount(),
 		"totalStaked":   e.consensus.GetTotalStaked(),
 		"proposerAddr":  proposerAddr,
+		"lockedRound":   lockedRound,
+		"validRound":    validRound,
+		"timing":        e.netTiming, // nil until the genesis block is known
 	}
 }
 
@@ -0,0 +1,108 @@
+package consensus
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/abcfe/abcfe-node/common/logger"
+	"github.com/abcfe/abcfe-node/common/utils"
+	"github.com/abcfe/abcfe-node/core"
+)
+
+// EvidencePool double-sign evidence detected by the engine, kept until the offender is jailed
+type EvidencePool struct {
+	mu      sync.Mutex
+	pending map[string]*core.Evidence // key: validator:height
+}
+
+// NewEvidencePool creates a new evidence pool
+func NewEvidencePool() *EvidencePool {
+	return &EvidencePool{pending: make(map[string]*core.Evidence)}
+}
+
+// Add adds evidence (one per validator and height), returns false if already known
+func (ep *EvidencePool) Add(ev *core.Evidence) bool {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+
+	key := fmt.Sprintf("%s:%d", utils.AddressToString(ev.Validator), ev.Height)
+	if _, exists := ep.pending[key]; exists {
+		return false
+	}
+	ep.pending[key] = ev
+	return true
+}
+
+// List returns pending evidence
+func (ep *EvidencePool) List() []*core.Evidence {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+
+	list := make([]*core.Evidence, 0, len(ep.pending))
+	for _, ev := range ep.pending {
+		list = append(list, ev)
+	}
+	return list
+}
+
+// Remove drops evidence
+func (ep *EvidencePool) Remove(ev *core.Evidence) {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+
+	delete(ep.pending, fmt.Sprintf("%s:%d", utils.AddressToString(ev.Validator), ev.Height))
+}
+
+// Size returns pending evidence count
+func (ep *EvidencePool) Size() int {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+
+	return len(ep.pending)
+}
+
+// reportEvidence records detected double-sign and submits it
+func (e *ConsensusEngine) reportEvidence(ev *core.Evidence) {
+	if !e.evidence.Add(ev) {
+		return
+	}
+	logger.Warn("[Evidence] Double-sign detected: validator ", utils.AddressToString(ev.Validator), " height ", ev.Height, " round ", ev.Round, " type ", ev.Type)
+	e.submitEvidence()
+}
+
+// submitEvidence puts an evidence tx for each pending evidence into mempool and broadcasts it.
+// Evidence stays pending until its offender is jailed, so it is resubmitted if the tx is dropped.
+func (e *ConsensusEngine) submitEvidence() {
+	for _, ev := range e.evidence.List() {
+		jail, err := e.blockchain.GetJail(ev.Validator)
+		if err != nil {
+			logger.Error("[Evidence] Failed to get jail: ", err)
+			continue
+		}
+		if jail != nil && jail.Reason == core.JailReasonDoubleSign {
+			e.evidence.Remove(ev)
+			continue
+		}
+		if e.blockchain.HasPendingEvidence(ev.Validator) {
+			continue
+		}
+
+		tx, err := e.blockchain.BuildEvidenceTx(ev)
+		if err != nil {
+			logger.Warn("[Evidence] Dropping evidence against ", utils.AddressToString(ev.Validator), ": ", err)
+			e.evidence.Remove(ev)
+			continue
+		}
+		if _, err := e.blockchain.AddTxToMempool(tx); err != nil {
+			logger.Warn("[Evidence] Evidence tx rejected: ", err)
+			continue
+		}
+		logger.Info("[Evidence] Evidence tx submitted: ", utils.HashToString(tx.ID)[:16])
+
+		if e.p2p != nil {
+			if err := e.p2p.BroadcastTx(tx); err != nil {
+				logger.Error("[Evidence] Failed to broadcast evidence tx: ", err)
+			}
+		}
+	}
+}
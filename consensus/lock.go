@@ -0,0 +1,150 @@
+package consensus
+
+import (
+	"github.com/abcfe/abcfe-node/core"
+	prt "github.com/abcfe/abcfe-node/protocol"
+)
+
+// Locking rules (Tendermint proof-of-lock), kept for the height being decided:
+//   - 2/3+ prevotes for a block in a round are its proof-of-lock (POL). A validator precommits a block only
+//     with a POL of the current round and is then locked on it: in later rounds it prevotes nil for other blocks.
+//   - The lock is released only by a POL for another block in a round not older than the lock: a proposal
+//     re-proposing a block with such a POL round is prevoted.
+//   - The block with the latest POL seen is the valid block; a proposer re-proposes it instead of building a new one.
+//   - Nil votes (zero block hash) count towards the votes of a round but never commit.
+//
+// With less than 1/3 of the voting power faulty, two rounds of a height cannot commit different blocks:
+// once 2/3+ precommitted a block, 2/3+ are locked on it and no other block gets a POL in later rounds.
+
+// heightState votes, blocks and lock of the validator at one height (all rounds)
+type heightState struct {
+	height   uint64
+	prevHash prt.Hash // Parent the blocks of the height build on
+
+	lockedBlock *core.Block // Block precommitted by this validator (nil if not locked)
+	lockedRound int32       // Round of the lock (-1 if not locked)
+	validBlock  *core.Block // Block with the latest POL seen (nil if none)
+	validRound  int32       // Round of the valid block's POL (-1 if none)
+
+	blocks       map[prt.Hash]*core.Block // Valid blocks proposed at the height
+	prevotes     map[uint32]*VoteSet
+	precommits   map[uint32]*VoteSet
+	prevoted     map[uint32]bool // Rounds this validator prevoted in
+	precommitted map[uint32]bool // Rounds this validator precommitted in
+}
+
+// newHeightState creates state of a height building on prevHash
+func newHeightState(height uint64, prevHash prt.Hash) *heightState {
+	return &heightState{
+		height:       height,
+		prevHash:     prevHash,
+		lockedRound:  -1,
+		validRound:   -1,
+		blocks:       make(map[prt.Hash]*core.Block),
+		prevotes:     make(map[uint32]*VoteSet),
+		precommits:   make(map[uint32]*VoteSet),
+		prevoted:     make(map[uint32]bool),
+		precommitted: make(map[uint32]bool),
+	}
+}
+
+// voteSet returns votes of type in round (created on first use)
+func (hs *heightState) voteSet(voteType VoteType, round uint32) *VoteSet {
+	sets := hs.prevotes
+	if voteType == VoteTypePrecommit {
+		sets = hs.precommits
+	}
+	vs, exists := sets[round]
+	if !exists {
+		vs = NewVoteSet(hs.height, round, voteType)
+		sets[round] = vs
+	}
+	return vs
+}
+
+// addBlock records a valid block proposed at the height (it may be committed by votes of any round)
+func (hs *heightState) addBlock(block *core.Block) {
+	hs.blocks[block.Header.Hash] = block
+}
+
+// addVote adds vote of any round of the height, returns the earlier vote of the voter if it conflicts
+func (hs *heightState) addVote(vote *Vote, votingPower uint64) (bool, *Vote) {
+	return hs.voteSet(vote.Type, vote.Round).AddVote(vote, votingPower)
+}
+
+// hasPOL checks 2/3+ prevotes for blockHash in round
+func (hs *heightState) hasPOL(round uint32, blockHash prt.Hash, totalPower uint64) bool {
+	polHash, ok := hs.voteSet(VoteTypePrevote, round).TwoThirdsMajority(totalPower)
+	return ok && polHash == blockHash
+}
+
+// prevote returns prevote of the validator for block proposed in round (nil if locked on another block
+// without a POL releasing the lock), false if it already prevoted in the round
+func (hs *heightState) prevote(round uint32, polRound int32, block *core.Block, totalPower uint64) (prt.Hash, bool) {
+	if hs.prevoted[round] {
+		return prt.Hash{}, false
+	}
+	hs.prevoted[round] = true
+
+	blockHash := block.Header.Hash
+	if hs.lockedBlock == nil || hs.lockedBlock.Header.Hash == blockHash {
+		return blockHash, true
+	}
+	if polRound >= hs.lockedRound && polRound >= 0 && uint32(polRound) < round && hs.hasPOL(uint32(polRound), blockHash, totalPower) {
+		return blockHash, true
+	}
+	return prt.Hash{}, true
+}
+
+// updateValid makes block with a POL in round the valid block if the POL is newer
+func (hs *heightState) updateValid(round uint32, totalPower uint64) {
+	blockHash, ok := hs.voteSet(VoteTypePrevote, round).TwoThirdsMajority(totalPower)
+	if !ok || int32(round) <= hs.validRound {
+		return
+	}
+	if block := hs.blocks[blockHash]; block != nil {
+		hs.validBlock, hs.validRound = block, int32(round)
+	}
+}
+
+// precommit returns precommit of the validator in round once it prevoted and 2/3+ prevoted the same block
+// or nil (false before that or if it already precommitted). Precommitting a block locks the validator on it.
+func (hs *heightState) precommit(round uint32, totalPower uint64) (prt.Hash, bool) {
+	if !hs.prevoted[round] || hs.precommitted[round] {
+		return prt.Hash{}, false
+	}
+	blockHash, ok := hs.voteSet(VoteTypePrevote, round).TwoThirdsMajority(totalPower)
+	if !ok {
+		return prt.Hash{}, false
+	}
+	hs.precommitted[round] = true
+
+	block := hs.blocks[blockHash]
+	if block == nil {
+		return prt.Hash{}, true // Nil POL, or POL for a block not received
+	}
+	hs.lockedBlock, hs.lockedRound = block, int32(round)
+	return blockHash, true
+}
+
+// decided returns block with 2/3+ precommits in some round of the height and those precommits (nil if none)
+func (hs *heightState) decided(totalPower uint64) (*core.Block, *VoteSet) {
+	for _, precommits := range hs.precommits {
+		blockHash, ok := precommits.TwoThirdsMajority(totalPower)
+		if !ok {
+			continue
+		}
+		if block := hs.blocks[blockHash]; block != nil {
+			return block, precommits
+		}
+	}
+	return nil, nil
+}
+
+// proposal returns block the proposer of a round re-proposes and the round of its POL (nil, -1 for a new block)
+func (hs *heightState) proposal() (*core.Block, int32) {
+	if hs.validBlock == nil {
+		return nil, -1
+	}
+	return hs.validBlock, hs.validRound
+}
@@ -3,8 +3,7 @@ package consensus
 import (
 	"fmt"
 
-	"github.com/abcfe/abcfe-node/common/crypto"
-	"github.com/abcfe/abcfe-node/common/utils"
+	"github.com/abcfe/abcfe-node/core"
 	prt "github.com/abcfe/abcfe-node/protocol"
 )
 
@@ -21,35 +20,35 @@ type BlockProposal struct {
 // Proposer block proposer
 type Proposer struct {
 	Validator    *Validator
-	PrivateKey   []byte // Private key for signing
+	Signer       Signer // Signs blocks, proposals and votes (local key or remote signer)
 	CurrentRound uint32
 }
 
 // NewProposer creates new proposer
-func NewProposer(validator *Validator, privateKey []byte) *Proposer {
+func NewProposer(validator *Validator, signer Signer) *Proposer {
 	return &Proposer{
 		Validator:    validator,
-		PrivateKey:   privateKey,
+		Signer:       signer,
 		CurrentRound: 0,
 	}
 }
 
-// ProposeBlock creates block proposal
-func (p *Proposer) ProposeBlock(height uint64, blockHash prt.Hash, timestamp int64) (*BlockProposal, error) {
+// ProposeBlock creates block proposal of block header
+func (p *Proposer) ProposeBlock(header *core.BlockHeader, timestamp int64) (*BlockProposal, error) {
 	if p.Validator == nil {
 		return nil, fmt.Errorf("validator not set")
 	}
 
-	// Sign block hash
-	sig, err := p.signBlockHash(blockHash)
+	// Sign block
+	sig, err := p.signBlock(header)
 	if err != nil {
 		return nil, fmt.Errorf("failed to sign block: %w", err)
 	}
 
 	proposal := &BlockProposal{
-		Height:     height,
-		Round:      p.CurrentRound,
-		BlockHash:  blockHash,
+		Height:     header.Height,
+		Round:      header.Round,
+		BlockHash:  header.Hash,
 		ProposerID: p.Validator.Address,
 		Signature:  sig,
 		Timestamp:  timestamp,
@@ -58,27 +57,9 @@ func (p *Proposer) ProposeBlock(height uint64, blockHash prt.Hash, timestamp int
 	return proposal, nil
 }
 
-// signBlockHash signs block hash
-func (p *Proposer) signBlockHash(blockHash prt.Hash) (prt.Signature, error) {
-	privateKey, err := crypto.BytesToPrivateKey(p.PrivateKey)
-	if err != nil {
-		return prt.Signature{}, fmt.Errorf("failed to parse private key: %w", err)
-	}
-
-	hashBytes := utils.HashToBytes(blockHash)
-	sig, err := crypto.SignData(privateKey, hashBytes)
-	if err != nil {
-		return prt.Signature{}, err
-	}
-
-	// Check actual signature length
-	sigLen := len(sig)
-	for sigLen > 0 && sig[sigLen-1] == 0 {
-		sigLen--
-	}
-	fmt.Printf("[DEBUG] signBlockHash: hash=%s sig_len=%d actual_len=%d sig=%x\n",
-		utils.HashToString(blockHash), len(sig), sigLen, sig[:sigLen])
-	return sig, nil
+// signBlock signs block of header (at the height and round of the header)
+func (p *Proposer) signBlock(header *core.BlockHeader) (prt.Signature, error) {
+	return p.Signer.Sign(&SignRequest{Height: header.Height, Round: header.Round, Step: SignStepBlock, BlockHash: header.Hash, Header: header})
 }
 
 // VerifyProposal verifies block proposal
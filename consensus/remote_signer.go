@@ -0,0 +1,486 @@
+package consensus
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/abcfe/abcfe-node/common/logger"
+	prt "github.com/abcfe/abcfe-node/protocol"
+)
+
+const (
+	SignerTimeout      = 5 * time.Second // Remote signer request timeout
+	SignerMinSecretLen = 16              // Minimum shared secret length (required for tcp)
+)
+
+// Remote signer protocol (one JSON object per line):
+//   - On connect the signer sends a random challenge (signerHello).
+//   - With a shared secret, every request and response carries HMAC-SHA256 keyed by HMAC(secret, challenge)
+//     over its direction, sequence number and payload. Both sides drop the connection on a bad MAC, so
+//     only holders of the secret can request signatures and responses cannot be forged or replayed.
+//   - tcp requires a secret; a unix socket without secret relies on its file permissions (0600).
+
+// signerHello first message of the signer on a connection
+type signerHello struct {
+	Challenge []byte `json:"challenge"`
+}
+
+// signerMessage authenticated request or response
+type signerMessage struct {
+	Payload json.RawMessage `json:"payload"`
+	MAC     []byte          `json:"mac,omitempty"`
+}
+
+// signerRequest request to a remote signer (one JSON object per line)
+type signerRequest struct {
+	Method string       `json:"method"` // "publicKey" or "sign"
+	Sign   *SignRequest `json:"sign,omitempty"`
+}
+
+// signerResponse response of a remote signer (one JSON object per line)
+type signerResponse struct {
+	PublicKey []byte        `json:"publicKey,omitempty"`
+	Signature prt.Signature `json:"signature"`
+	Error     string        `json:"error,omitempty"`
+}
+
+// parseSignerAddress splits signer address ("unix:///path/signer.sock" or "tcp://host:port").
+// A tcp address requires a shared secret of at least SignerMinSecretLen bytes.
+func parseSignerAddress(addr string, secret []byte) (string, string, error) {
+	var network, address string
+	switch {
+	case strings.HasPrefix(addr, "unix://"):
+		network, address = "unix", strings.TrimPrefix(addr, "unix://")
+	case strings.HasPrefix(addr, "tcp://"):
+		network, address = "tcp", strings.TrimPrefix(addr, "tcp://")
+	default:
+		return "", "", fmt.Errorf("invalid signer address %q (expected unix:// or tcp://)", addr)
+	}
+	if len(secret) > 0 && len(secret) < SignerMinSecretLen {
+		return "", "", fmt.Errorf("signer secret must be at least %d bytes", SignerMinSecretLen)
+	}
+	if network == "tcp" && len(secret) == 0 {
+		return "", "", fmt.Errorf("tcp signer address requires a shared secret")
+	}
+	return network, address, nil
+}
+
+// Directions of signer messages (part of the MAC)
+const (
+	signerDirRequest  byte = 'q'
+	signerDirResponse byte = 'r'
+)
+
+// signerSession authenticates messages of one connection (no MAC without secret)
+type signerSession struct {
+	key     []byte // HMAC(secret, challenge), nil without secret
+	sendDir byte
+	sendSeq uint64
+	recvSeq uint64
+}
+
+// newSignerSession creates session of connection with challenge (server: sends responses)
+func newSignerSession(secret, challenge []byte, server bool) *signerSession {
+	s := &signerSession{sendDir: signerDirRequest}
+	if server {
+		s.sendDir = signerDirResponse
+	}
+	if len(secret) > 0 {
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(challenge)
+		s.key = mac.Sum(nil)
+	}
+	return s
+}
+
+// mac returns MAC of payload sent in direction with sequence number
+func (s *signerSession) mac(dir byte, seq uint64, payload []byte) []byte {
+	mac := hmac.New(sha256.New, s.key)
+	var header [9]byte
+	header[0] = dir
+	binary.BigEndian.PutUint64(header[1:], seq)
+	mac.Write(header[:])
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// seal returns message line of v
+func (s *signerSession) seal(v interface{}) ([]byte, error) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal signer message: %w", err)
+	}
+	msg := signerMessage{Payload: payload}
+	if s.key != nil {
+		msg.MAC = s.mac(s.sendDir, s.sendSeq, payload)
+	}
+	s.sendSeq++
+
+	line, err := json.Marshal(&msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal signer message: %w", err)
+	}
+	return append(line, '\n'), nil
+}
+
+// open verifies message line and unmarshals its payload into v
+func (s *signerSession) open(line []byte, v interface{}) error {
+	var msg signerMessage
+	if err := json.Unmarshal(line, &msg); err != nil {
+		return fmt.Errorf("failed to unmarshal signer message: %w", err)
+	}
+	recvDir := signerDirResponse
+	if s.sendDir == signerDirResponse {
+		recvDir = signerDirRequest
+	}
+	if s.key != nil && !hmac.Equal(msg.MAC, s.mac(recvDir, s.recvSeq, msg.Payload)) {
+		return fmt.Errorf("signer message authentication failed")
+	}
+	s.recvSeq++
+
+	if err := json.Unmarshal(msg.Payload, v); err != nil {
+		return fmt.Errorf("failed to unmarshal signer message: %w", err)
+	}
+	return nil
+}
+
+// RemoteSigner signs through a signer process (cmd/signer) holding the validator key
+type RemoteSigner struct {
+	network string
+	address string
+	secret  []byte
+
+	mu      sync.Mutex
+	conn    net.Conn
+	reader  *bufio.Reader
+	session *signerSession
+}
+
+// NewRemoteSigner creates signer connecting to addr on first use, authenticating with secret
+func NewRemoteSigner(addr string, secret []byte) (*RemoteSigner, error) {
+	network, address, err := parseSignerAddress(addr, secret)
+	if err != nil {
+		return nil, err
+	}
+	return &RemoteSigner{network: network, address: address, secret: secret}, nil
+}
+
+// PublicKey returns public key of the remote signer
+func (r *RemoteSigner) PublicKey() ([]byte, error) {
+	resp, err := r.call(&signerRequest{Method: "publicKey"})
+	if err != nil {
+		return nil, err
+	}
+	return resp.PublicKey, nil
+}
+
+// Sign signs message (refused by the signer if it could be double-signing)
+func (r *RemoteSigner) Sign(req *SignRequest) (prt.Signature, error) {
+	resp, err := r.call(&signerRequest{Method: "sign", Sign: req})
+	if err != nil {
+		return prt.Signature{}, err
+	}
+	return resp.Signature, nil
+}
+
+// call sends request, reconnecting once if the connection was lost.
+// Resending a sign request is safe: the signer answers the same message with the same signature.
+func (r *RemoteSigner) call(req *signerRequest) (*signerResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	resp, err := r.roundTrip(req)
+	if err != nil && r.conn == nil {
+		resp, err = r.roundTrip(req)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("signer refused: %s", resp.Error)
+	}
+	return resp, nil
+}
+
+// connect dials the signer and reads its challenge
+func (r *RemoteSigner) connect() error {
+	conn, err := net.DialTimeout(r.network, r.address, SignerTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to connect to signer: %w", err)
+	}
+	r.conn = conn
+	r.reader = bufio.NewReader(conn)
+
+	conn.SetDeadline(time.Now().Add(SignerTimeout))
+	helloLine, err := r.reader.ReadBytes('\n')
+	if err != nil {
+		r.close()
+		return fmt.Errorf("failed to read signer challenge: %w", err)
+	}
+	var hello signerHello
+	if err := json.Unmarshal(helloLine, &hello); err != nil {
+		r.close()
+		return fmt.Errorf("failed to unmarshal signer challenge: %w", err)
+	}
+	r.session = newSignerSession(r.secret, hello.Challenge, false)
+	return nil
+}
+
+// roundTrip writes request and reads its response (the connection is dropped on I/O and authentication errors)
+func (r *RemoteSigner) roundTrip(req *signerRequest) (*signerResponse, error) {
+	if r.conn == nil {
+		if err := r.connect(); err != nil {
+			return nil, err
+		}
+	}
+
+	line, err := r.session.seal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	r.conn.SetDeadline(time.Now().Add(SignerTimeout))
+	if _, err := r.conn.Write(line); err != nil {
+		r.close()
+		return nil, fmt.Errorf("failed to send signer request: %w", err)
+	}
+	respLine, err := r.reader.ReadBytes('\n')
+	if err != nil {
+		r.close()
+		return nil, fmt.Errorf("failed to read signer response: %w", err)
+	}
+
+	var resp signerResponse
+	if err := r.session.open(respLine, &resp); err != nil {
+		r.close()
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// close drops the connection
+func (r *RemoteSigner) close() {
+	if r.conn != nil {
+		r.conn.Close()
+		r.conn = nil
+		r.reader = nil
+		r.session = nil
+	}
+}
+
+// SignerServer signs for a validator node with its own key (cmd/signer).
+// It enforces double-sign protection on its own: the last signed height/round/step is synced to
+// its state file before a signature is returned, so it never moves backwards even across restarts.
+type SignerServer struct {
+	mu        sync.Mutex
+	signer    *LocalSigner
+	secret    []byte // Shared secret authenticating nodes (nil: unix socket permissions only)
+	statePath string
+	state     SignState
+}
+
+// NewSignerServer creates signer server of private key, loading the last signed message from statePath.
+// Nodes must authenticate with secret unless it is empty.
+func NewSignerServer(privateKey []byte, statePath string, secret []byte) (*SignerServer, error) {
+	signer, err := NewLocalSigner(privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &SignerServer{signer: signer, secret: secret, statePath: statePath}
+	stateBytes, err := os.ReadFile(statePath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read signer state: %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(stateBytes, &s.state); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal signer state: %w", err)
+		}
+	}
+	return s, nil
+}
+
+// State returns the last signed message
+func (s *SignerServer) State() SignState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state
+}
+
+// Sign signs message unless it could be double-signing
+func (s *SignerServer) Sign(req *SignRequest) (prt.Signature, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	signHash, err := req.SignHash()
+	if err != nil {
+		return prt.Signature{}, err
+	}
+	signature, signed, err := s.state.Check(req, signHash)
+	if err != nil {
+		return prt.Signature{}, err
+	}
+	if signed {
+		return signature, nil
+	}
+
+	signature, err = s.signer.Sign(req)
+	if err != nil {
+		return prt.Signature{}, err
+	}
+
+	state := s.state
+	state.Update(req, signHash, signature)
+	if err := s.saveState(&state); err != nil {
+		return prt.Signature{}, err
+	}
+	s.state = state
+	return signature, nil
+}
+
+// saveState writes state file (synced, replaced atomically)
+func (s *SignerServer) saveState(state *SignState) error {
+	stateBytes, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to marshal signer state: %w", err)
+	}
+
+	tmpPath := s.statePath + ".tmp"
+	file, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create signer state: %w", err)
+	}
+	if _, err := file.Write(stateBytes); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write signer state: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to sync signer state: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to close signer state: %w", err)
+	}
+	if err := os.Rename(tmpPath, s.statePath); err != nil {
+		return fmt.Errorf("failed to replace signer state: %w", err)
+	}
+	return nil
+}
+
+// ListenSigner listens on signer address served with secret (a tcp address requires one).
+// A stale unix socket file is removed and the new one is accessible by its owner only.
+func ListenSigner(addr string, secret []byte) (net.Listener, error) {
+	network, address, err := parseSignerAddress(addr, secret)
+	if err != nil {
+		return nil, err
+	}
+	if network == "unix" {
+		if err := os.Remove(address); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to remove stale socket: %w", err)
+		}
+	}
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	if network == "unix" {
+		if err := os.Chmod(address, 0600); err != nil {
+			listener.Close()
+			return nil, fmt.Errorf("failed to restrict socket permissions: %w", err)
+		}
+	}
+	return listener, nil
+}
+
+// Serve handles node connections until listener is closed
+func (s *SignerServer) Serve(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go s.handleConn(conn)
+	}
+}
+
+// handleConn sends a challenge and answers authenticated requests of a node connection
+func (s *SignerServer) handleConn(conn net.Conn) {
+	defer conn.Close()
+	logger.Info("[Signer] Node connected: ", conn.RemoteAddr())
+
+	challenge := make([]byte, 32)
+	if _, err := rand.Read(challenge); err != nil {
+		logger.Error("[Signer] Failed to create challenge: ", err)
+		return
+	}
+	helloLine, err := json.Marshal(&signerHello{Challenge: challenge})
+	if err != nil {
+		logger.Error("[Signer] Failed to marshal challenge: ", err)
+		return
+	}
+	if _, err := conn.Write(append(helloLine, '\n')); err != nil {
+		logger.Error("[Signer] Failed to send challenge: ", err)
+		return
+	}
+	session := newSignerSession(s.secret, challenge, true)
+
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			logger.Info("[Signer] Node disconnected: ", conn.RemoteAddr())
+			return
+		}
+
+		var req signerRequest
+		if err := session.open(line, &req); err != nil {
+			logger.Warn("[Signer] Dropping connection ", conn.RemoteAddr(), ": ", err)
+			return
+		}
+		var resp signerResponse
+		s.handleRequest(&req, &resp)
+
+		respLine, err := session.seal(&resp)
+		if err != nil {
+			logger.Error("[Signer] Failed to marshal response: ", err)
+			return
+		}
+		if _, err := conn.Write(respLine); err != nil {
+			logger.Error("[Signer] Failed to send response: ", err)
+			return
+		}
+	}
+}
+
+// handleRequest fills response of request
+func (s *SignerServer) handleRequest(req *signerRequest, resp *signerResponse) {
+	switch req.Method {
+	case "publicKey":
+		resp.PublicKey, _ = s.signer.PublicKey()
+	case "sign":
+		if req.Sign == nil {
+			resp.Error = "missing sign request"
+			return
+		}
+		signature, err := s.Sign(req.Sign)
+		if err != nil {
+			logger.Warn("[Signer] Refused to sign height ", req.Sign.Height, " round ", req.Sign.Round, " step ", req.Sign.Step, ": ", err)
+			resp.Error = err.Error()
+			return
+		}
+		resp.Signature = signature
+		logger.Info("[Signer] Signed height ", req.Sign.Height, " round ", req.Sign.Round, " step ", req.Sign.Step)
+	default:
+		resp.Error = fmt.Sprintf("unknown method %q", req.Method)
+	}
+}
@@ -0,0 +1,151 @@
+package consensus
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+
+	"github.com/abcfe/abcfe-node/common/crypto"
+	"github.com/abcfe/abcfe-node/common/utils"
+	"github.com/abcfe/abcfe-node/core"
+	prt "github.com/abcfe/abcfe-node/protocol"
+)
+
+// SignStep step of a round a consensus message is signed at (height, round and step only move forward)
+type SignStep uint8
+
+const (
+	SignStepBlock     SignStep = iota + 1 // Block signed by its proposer (hash computed from the header)
+	SignStepProposal                      // Proposal of a round (core.ProposalSignHash)
+	SignStepPrevote                       // Prevote (core.VoteSignHash)
+	SignStepPrecommit                     // Precommit (core.VoteSignHash)
+)
+
+// SignRequest consensus message to sign
+type SignRequest struct {
+	Height    uint64            `json:"height"`
+	Round     uint32            `json:"round"`
+	Step      SignStep          `json:"step"`
+	POLRound  int32             `json:"polRound"`         // Proposals only
+	BlockHash prt.Hash          `json:"blockHash"`        // Zero for nil votes
+	Header    *core.BlockHeader `json:"header,omitempty"` // Blocks only
+}
+
+// SignHash returns hash signed for the message. A block hash is computed from the block header, so a
+// signer never signs an arbitrary 32-byte hash (such as a tx ID) handed to it as a block.
+func (r *SignRequest) SignHash() (prt.Hash, error) {
+	switch r.Step {
+	case SignStepBlock:
+		if r.Header == nil {
+			return prt.Hash{}, fmt.Errorf("block header is required to sign a block")
+		}
+		if r.Header.Height != r.Height || r.Header.Round != r.Round {
+			return prt.Hash{}, fmt.Errorf("block header is at height %d round %d, not %d/%d", r.Header.Height, r.Header.Round, r.Height, r.Round)
+		}
+		header := *r.Header
+		header.Hash = prt.Hash{}
+		blockHash := utils.Hash(header)
+		if blockHash != r.BlockHash {
+			return prt.Hash{}, fmt.Errorf("block hash does not match block header")
+		}
+		return blockHash, nil
+	case SignStepProposal:
+		return core.ProposalSignHash(r.Height, r.Round, r.POLRound, r.BlockHash), nil
+	case SignStepPrevote:
+		return core.VoteSignHash(r.Height, r.Round, core.VoteTypePrevote, r.BlockHash), nil
+	case SignStepPrecommit:
+		return core.VoteSignHash(r.Height, r.Round, core.VoteTypePrecommit, r.BlockHash), nil
+	}
+	return prt.Hash{}, fmt.Errorf("unknown sign step %d", r.Step)
+}
+
+// voteSignStep returns sign step of vote type
+func voteSignStep(voteType VoteType) SignStep {
+	if voteType == VoteTypePrecommit {
+		return SignStepPrecommit
+	}
+	return SignStepPrevote
+}
+
+// Signer signs block hashes, proposals and votes of the local validator
+type Signer interface {
+	PublicKey() ([]byte, error)
+	Sign(req *SignRequest) (prt.Signature, error)
+}
+
+// LocalSigner signs with the validator private key held by the node process
+type LocalSigner struct {
+	privateKey *ecdsa.PrivateKey
+	publicKey  []byte
+}
+
+// NewLocalSigner creates signer of private key bytes
+func NewLocalSigner(privateKey []byte) (*LocalSigner, error) {
+	priv, err := crypto.BytesToPrivateKey(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+	pub, err := crypto.PublicKeyToBytes(&priv.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode public key: %w", err)
+	}
+	return &LocalSigner{privateKey: priv, publicKey: pub}, nil
+}
+
+// PublicKey returns public key of the signer
+func (s *LocalSigner) PublicKey() ([]byte, error) {
+	return s.publicKey, nil
+}
+
+// Sign signs message
+func (s *LocalSigner) Sign(req *SignRequest) (prt.Signature, error) {
+	signHash, err := req.SignHash()
+	if err != nil {
+		return prt.Signature{}, err
+	}
+	return crypto.SignData(s.privateKey, utils.HashToBytes(signHash))
+}
+
+// SignState last message signed by a signer. A message of a lower height/round/step than the last one
+// is refused; the last message itself is answered with its signature, any other message at its step is refused.
+type SignState struct {
+	Height    uint64        `json:"height"`
+	Round     uint32        `json:"round"`
+	Step      SignStep      `json:"step"`
+	SignHash  prt.Hash      `json:"signHash"`
+	Signature prt.Signature `json:"signature"`
+}
+
+// Check returns signature of the last message if req is that message (true), an error if signing req
+// could be double-signing
+func (s *SignState) Check(req *SignRequest, signHash prt.Hash) (prt.Signature, bool, error) {
+	if s.Step == 0 {
+		return prt.Signature{}, false, nil // Nothing signed yet
+	}
+
+	switch {
+	case req.Height != s.Height:
+		if req.Height < s.Height {
+			return prt.Signature{}, false, fmt.Errorf("height regression: %d < %d", req.Height, s.Height)
+		}
+	case req.Round != s.Round:
+		if req.Round < s.Round {
+			return prt.Signature{}, false, fmt.Errorf("round regression at height %d: %d < %d", req.Height, req.Round, s.Round)
+		}
+	case req.Step != s.Step:
+		if req.Step < s.Step {
+			return prt.Signature{}, false, fmt.Errorf("step regression at height %d round %d: %d < %d", req.Height, req.Round, req.Step, s.Step)
+		}
+	default:
+		if signHash != s.SignHash {
+			return prt.Signature{}, false, fmt.Errorf("conflicting message at height %d round %d step %d", req.Height, req.Round, req.Step)
+		}
+		return s.Signature, true, nil
+	}
+	return prt.Signature{}, false, nil
+}
+
+// Update records req as the last signed message
+func (s *SignState) Update(req *SignRequest, signHash prt.Hash, signature prt.Signature) {
+	s.Height, s.Round, s.Step = req.Height, req.Round, req.Step
+	s.SignHash, s.Signature = signHash, signature
+}
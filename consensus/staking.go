@@ -5,8 +5,8 @@ import (
 	"time"
 
 	"github.com/abcfe/abcfe-node/common/utils"
+	"github.com/abcfe/abcfe-node/core"
 	prt "github.com/abcfe/abcfe-node/protocol"
-	"github.com/syndtr/goleveldb/leveldb"
 )
 
 // Staker information
@@ -103,51 +103,28 @@ func (s *StakerSet) GetActiveStakers() []*Staker {
 	return active
 }
 
-// SaveStakerSet saves staker set to DB
-func SaveStakerSet(db *leveldb.DB, stakerSet *StakerSet) error {
-	key := []byte(prt.PrefixStakerInfo)
-	data, err := utils.SerializeData(stakerSet, utils.SerializationFormatGob)
-	if err != nil {
-		return fmt.Errorf("failed to serialize staker set: %w", err)
-	}
-
-	if err := db.Put(key, data, nil); err != nil {
-		return fmt.Errorf("failed to save staker set: %w", err)
-	}
-
-	return nil
-}
-
-// LoadStakerSet loads staker set from DB
-func LoadStakerSet(db *leveldb.DB) (*StakerSet, error) {
-	key := []byte(prt.PrefixStakerInfo)
-	data, err := db.Get(key, nil)
-	if err != nil {
-		if err == leveldb.ErrNotFound {
-			return NewStakerSet(), nil
+// NewStakerSetFromSnapshot builds staker set from stakes committed on chain.
+// StartTime is the timestamp of the block the first staked output was committed in; jailed stakers are inactive.
+func NewStakerSetFromSnapshot(bc *core.BlockChain, snapshot *core.StakeSnapshot) (*StakerSet, error) {
+	stakerSet := NewStakerSet()
+	for _, stake := range snapshot.Stakes {
+		blk, err := bc.GetBlockByHeight(stake.Height)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get stake block %d: %w", stake.Height, err)
+		}
+		jail, err := bc.GetJail(stake.Address)
+		if err != nil {
+			return nil, err
 		}
-		return nil, fmt.Errorf("failed to load staker set: %w", err)
-	}
-
-	var stakerSet StakerSet
-	if err := utils.DeserializeData(data, &stakerSet, utils.SerializationFormatGob); err != nil {
-		return nil, fmt.Errorf("failed to deserialize staker set: %w", err)
-	}
-
-	return &stakerSet, nil
-}
-
-// GetStakers returns list of staker addresses (backward compatibility)
-func GetStakers(db *leveldb.DB) ([]string, error) {
-	stakerSet, err := LoadStakerSet(db)
-	if err != nil {
-		return nil, err
-	}
 
-	var addresses []string
-	for addr := range stakerSet.Stakers {
-		addresses = append(addresses, addr)
+		stakerSet.Stakers[utils.AddressToString(stake.Address)] = &Staker{
+			Address:   stake.Address,
+			PublicKey: stake.PublicKey,
+			Amount:    stake.Amount,
+			StartTime: blk.Header.Timestamp,
+			IsActive:  jail == nil,
+		}
+		stakerSet.TotalStaked += stake.Amount
 	}
-
-	return addresses, nil
+	return stakerSet, nil
 }
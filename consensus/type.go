@@ -1,6 +1,7 @@
 package consensus
 
 import (
+	"github.com/abcfe/abcfe-node/core"
 	prt "github.com/abcfe/abcfe-node/protocol"
 )
 
@@ -23,6 +24,18 @@ type Vote struct {
 	Timestamp  int64         `json:"timestamp"`
 }
 
+// Proposal block proposed for a round, signed by the round's proposer (core.ProposalSignHash).
+// The block is new (POLRound -1) or a block proposed in an earlier round re-proposed with its proof-of-lock.
+type Proposal struct {
+	Height    uint64
+	Round     uint32
+	POLRound  int32
+	BlockHash prt.Hash
+	Block     *core.Block
+	Proposer  prt.Address
+	Signature prt.Signature
+}
+
 // VoteType vote type
 type VoteType uint8
 
@@ -36,8 +49,9 @@ type VoteSet struct {
 	Height     uint64
 	Round      uint32
 	Type       VoteType
-	Votes      map[string]*Vote // key: voter address string
-	VotedPower uint64           // Sum of voted voting power
+	Votes      map[string]*Vote    // key: voter address string
+	VotedPower uint64              // Sum of voted voting power
+	BlockPower map[prt.Hash]uint64 // Voted power per block hash (zero hash: nil votes)
 }
 
 // NewVoteSet creates a new vote set
@@ -48,26 +62,42 @@ func NewVoteSet(height uint64, round uint32, voteType VoteType) *VoteSet {
 		Type:       voteType,
 		Votes:      make(map[string]*Vote),
 		VotedPower: 0,
+		BlockPower: make(map[prt.Hash]uint64),
 	}
 }
 
-// AddVote adds a vote
-func (vs *VoteSet) AddVote(vote *Vote, votingPower uint64) bool {
+// AddVote adds a vote.
+// A second vote of the voter for another block is not added; the earlier vote is returned as conflict (double-sign).
+func (vs *VoteSet) AddVote(vote *Vote, votingPower uint64) (bool, *Vote) {
 	if vote.Height != vs.Height || vote.Round != vs.Round || vote.Type != vs.Type {
-		return false
+		return false, nil
 	}
 
 	key := string(vote.VoterID[:])
-	if _, exists := vs.Votes[key]; exists {
-		return false // Already voted
+	if existing, exists := vs.Votes[key]; exists {
+		if existing.BlockHash != vote.BlockHash {
+			return false, existing
+		}
+		return false, nil // Already voted
 	}
 
 	vs.Votes[key] = vote
 	vs.VotedPower += votingPower
-	return true
+	vs.BlockPower[vote.BlockHash] += votingPower
+	return true, nil
 }
 
-// HasTwoThirdsMajority checks if 2/3 majority reached
+// HasTwoThirdsMajority checks if 2/3 majority reached (votes for any block or nil)
 func (vs *VoteSet) HasTwoThirdsMajority(totalPower uint64) bool {
 	return vs.VotedPower*3 > totalPower*2
 }
+
+// TwoThirdsMajority returns block hash (zero for nil) with 2/3+ of the voting power
+func (vs *VoteSet) TwoThirdsMajority(totalPower uint64) (prt.Hash, bool) {
+	for blockHash, power := range vs.BlockPower {
+		if power*3 > totalPower*2 {
+			return blockHash, true
+		}
+	}
+	return prt.Hash{}, false
+}
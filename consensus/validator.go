@@ -5,8 +5,8 @@ import (
 
 	"github.com/abcfe/abcfe-node/common/crypto"
 	"github.com/abcfe/abcfe-node/common/utils"
+	"github.com/abcfe/abcfe-node/core"
 	prt "github.com/abcfe/abcfe-node/protocol"
-	"github.com/syndtr/goleveldb/leveldb"
 )
 
 // Validator information
@@ -91,18 +91,22 @@ func (v *Validator) ValidateBlockSignature(blockHash prt.Hash, sig prt.Signature
 	return result
 }
 
-// AddValidator adds a validator
+// AddValidator adds a validator (only active validators count towards total voting power)
 func (vs *ValidatorSet) AddValidator(validator *Validator) {
 	addrStr := utils.AddressToString(validator.Address)
 	vs.Validators[addrStr] = validator
-	vs.TotalVotingPower += validator.VotingPower
+	if validator.IsActive {
+		vs.TotalVotingPower += validator.VotingPower
+	}
 }
 
 // RemoveValidator removes a validator
 func (vs *ValidatorSet) RemoveValidator(address prt.Address) {
 	addrStr := utils.AddressToString(address)
 	if v, exists := vs.Validators[addrStr]; exists {
-		vs.TotalVotingPower -= v.VotingPower
+		if v.IsActive {
+			vs.TotalVotingPower -= v.VotingPower
+		}
 		delete(vs.Validators, addrStr)
 	}
 }
@@ -124,6 +128,20 @@ func (vs *ValidatorSet) GetActiveValidators() []*Validator {
 	return active
 }
 
+// NewValidatorSetFromEpoch creates validator set of an epoch stored on chain (jailed validators are inactive)
+func NewValidatorSetFromEpoch(epoch *core.Epoch) *ValidatorSet {
+	vs := NewValidatorSet()
+	for _, v := range epoch.Validators {
+		vs.AddValidator(&Validator{
+			Address:     v.Address,
+			PublicKey:   v.PublicKey,
+			VotingPower: v.VotingPower,
+			IsActive:    !v.Jailed,
+		})
+	}
+	return vs
+}
+
 // UpdateFromStakerSet updates validator set from staker set
 func (vs *ValidatorSet) UpdateFromStakerSet(stakerSet *StakerSet, minStake uint64) {
 	// Initialize existing validators
@@ -143,37 +161,3 @@ func (vs *ValidatorSet) UpdateFromStakerSet(stakerSet *StakerSet, minStake uint6
 		}
 	}
 }
-
-// SaveValidatorSet saves validator set to DB
-func SaveValidatorSet(db *leveldb.DB, validatorSet *ValidatorSet) error {
-	key := []byte("consensus:validators")
-	data, err := utils.SerializeData(validatorSet, utils.SerializationFormatGob)
-	if err != nil {
-		return fmt.Errorf("failed to serialize validator set: %w", err)
-	}
-
-	if err := db.Put(key, data, nil); err != nil {
-		return fmt.Errorf("failed to save validator set: %w", err)
-	}
-
-	return nil
-}
-
-// LoadValidatorSet loads validator set from DB
-func LoadValidatorSet(db *leveldb.DB) (*ValidatorSet, error) {
-	key := []byte("consensus:validators")
-	data, err := db.Get(key, nil)
-	if err != nil {
-		if err == leveldb.ErrNotFound {
-			return NewValidatorSet(), nil
-		}
-		return nil, fmt.Errorf("failed to load validator set: %w", err)
-	}
-
-	var validatorSet ValidatorSet
-	if err := utils.DeserializeData(data, &validatorSet, utils.SerializationFormatGob); err != nil {
-		return nil, fmt.Errorf("failed to deserialize validator set: %w", err)
-	}
-
-	return &validatorSet, nil
-}
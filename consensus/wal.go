@@ -0,0 +1,361 @@
+package consensus
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/abcfe/abcfe-node/common/logger"
+	"github.com/abcfe/abcfe-node/common/utils"
+	"github.com/abcfe/abcfe-node/core"
+	prt "github.com/abcfe/abcfe-node/protocol"
+)
+
+// WALRecordType kind of signed consensus message
+type WALRecordType uint8
+
+const (
+	WALRecordProposal WALRecordType = iota // Proposal (signed core.ProposalSignHash)
+	WALRecordVote                          // Prevote or precommit (signed core.VoteSignHash)
+)
+
+// WALRecord proposal or vote signed by the local validator
+type WALRecord struct {
+	Type      WALRecordType `json:"type"`
+	Height    uint64        `json:"height"`
+	Round     uint32        `json:"round"`
+	VoteType  VoteType      `json:"voteType"`            // Votes only
+	POLRound  int32         `json:"polRound"`            // Proposals only
+	BlockHash prt.Hash      `json:"blockHash"`           // Zero for nil votes
+	BlockData []byte        `json:"blockData,omitempty"` // Gob-serialized block of proposals and precommits for a block
+	Signature prt.Signature `json:"signature"`
+}
+
+// key identifies the message a validator may sign only once
+func (r *WALRecord) key() string {
+	if r.Type == WALRecordProposal {
+		return fmt.Sprintf("proposal:%d:%d", r.Height, r.Round)
+	}
+	return fmt.Sprintf("vote:%d:%d:%d", r.Height, r.Round, r.VoteType)
+}
+
+// conflicts checks if r signs other content than signed for the same key
+func (r *WALRecord) conflicts(signed *WALRecord) bool {
+	return r.BlockHash != signed.BlockHash || (r.Type == WALRecordProposal && r.POLRound != signed.POLRound)
+}
+
+// block returns block of the record (nil if none)
+func (r *WALRecord) block() (*core.Block, error) {
+	if len(r.BlockData) == 0 {
+		return nil, nil
+	}
+	var block core.Block
+	if err := utils.DeserializeData(r.BlockData, &block, utils.SerializationFormatGob); err != nil {
+		return nil, fmt.Errorf("failed to deserialize block: %w", err)
+	}
+	if block.Header.Hash != r.BlockHash {
+		return nil, fmt.Errorf("block hash mismatch")
+	}
+	return &block, nil
+}
+
+// WAL consensus write-ahead log (one JSON record per line).
+// Every proposal and vote is synced to disk before it is broadcast, so a validator restarted mid-round
+// restores its round state and never signs a message conflicting with one signed before the crash.
+// Only the records of the latest height are kept: the file is truncated when a higher height is signed.
+type WAL struct {
+	mu      sync.Mutex
+	file    *os.File
+	height  uint64
+	records []*WALRecord
+	signed  map[string]*WALRecord // key: WALRecord.key
+}
+
+// OpenWAL opens (or creates) WAL file and loads its records.
+// A torn last record (crash while writing) is dropped.
+func OpenWAL(path string) (*WAL, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open wal: %w", err)
+	}
+
+	w := &WAL{file: file, signed: make(map[string]*WALRecord)}
+	if err := w.load(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// load reads records and truncates the file after the last complete record
+func (w *WAL) load() error {
+	reader := bufio.NewReader(w.file)
+	var offset int64
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			if len(line) > 0 {
+				logger.Warn("[WAL] Dropping torn record at offset ", offset)
+			}
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read wal: %w", err)
+		}
+
+		var rec WALRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			logger.Warn("[WAL] Dropping corrupted record at offset ", offset, ": ", err)
+			break
+		}
+		w.add(&rec)
+		offset += int64(len(line))
+	}
+
+	if err := w.file.Truncate(offset); err != nil {
+		return fmt.Errorf("failed to truncate wal: %w", err)
+	}
+	if _, err := w.file.Seek(offset, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek wal: %w", err)
+	}
+	return nil
+}
+
+// add indexes record (records of a lower height than the latest are ignored)
+func (w *WAL) add(rec *WALRecord) {
+	if rec.Height < w.height {
+		return
+	}
+	if rec.Height > w.height {
+		w.height = rec.Height
+		w.records = nil
+		w.signed = make(map[string]*WALRecord)
+	}
+	w.records = append(w.records, rec)
+	w.signed[rec.key()] = rec
+}
+
+// Signed returns record signed before for the message of rec (nil if none).
+// Returns an error if the signed message conflicts with rec (signing rec would be double-signing).
+func (w *WAL) Signed(rec *WALRecord) (*WALRecord, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if rec.Height < w.height {
+		return nil, fmt.Errorf("height %d is below wal height %d", rec.Height, w.height)
+	}
+	signed, exists := w.signed[rec.key()]
+	if !exists {
+		return nil, nil
+	}
+	if rec.conflicts(signed) {
+		return nil, fmt.Errorf("conflicts with %s signed for block %s", rec.key(), utils.HashToString(signed.BlockHash))
+	}
+	return signed, nil
+}
+
+// Write appends signed record and syncs it to disk (call before broadcasting the message)
+func (w *WAL) Write(rec *WALRecord) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if rec.Height < w.height {
+		return fmt.Errorf("height %d is below wal height %d", rec.Height, w.height)
+	}
+	if signed, exists := w.signed[rec.key()]; exists && rec.Height == w.height && rec.conflicts(signed) {
+		return fmt.Errorf("conflicts with %s signed for block %s", rec.key(), utils.HashToString(signed.BlockHash))
+	}
+
+	// Records of committed heights are no longer needed
+	if rec.Height > w.height && len(w.records) > 0 {
+		if err := w.file.Truncate(0); err != nil {
+			return fmt.Errorf("failed to truncate wal: %w", err)
+		}
+		if _, err := w.file.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("failed to seek wal: %w", err)
+		}
+	}
+
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to marshal wal record: %w", err)
+	}
+	if _, err := w.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write wal: %w", err)
+	}
+	if err := w.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync wal: %w", err)
+	}
+
+	w.add(rec)
+	return nil
+}
+
+// Records returns records of height in signing order
+func (w *WAL) Records(height uint64) []*WALRecord {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if height != w.height {
+		return nil
+	}
+	return append([]*WALRecord{}, w.records...)
+}
+
+// Close closes WAL file
+func (w *WAL) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.file.Close()
+}
+
+// signVote signs vote of the local validator unless it signed a conflicting vote before (WAL).
+// The signed vote is logged before it is broadcast; a vote signed before is returned as is.
+func (e *ConsensusEngine) signVote(height uint64, round uint32, voteType VoteType, blockHash prt.Hash) (prt.Signature, error) {
+	rec := &WALRecord{Type: WALRecordVote, Height: height, Round: round, VoteType: voteType, BlockHash: blockHash}
+
+	// Precommitted block is logged to restore the lock
+	if voteType == VoteTypePrecommit && e.state != nil {
+		if block := e.state.blocks[blockHash]; block != nil {
+			blockData, err := utils.SerializeData(block, utils.SerializationFormatGob)
+			if err != nil {
+				return prt.Signature{}, fmt.Errorf("failed to serialize block: %w", err)
+			}
+			rec.BlockData = blockData
+		}
+	}
+
+	return e.signRecord(rec, &SignRequest{Height: height, Round: round, Step: voteSignStep(voteType), BlockHash: blockHash})
+}
+
+// signProposal signs proposal of round with local proposer key unless it signed a conflicting proposal
+// before (WAL). The signed proposal is logged with its block before it is broadcast.
+func (e *ConsensusEngine) signProposal(height uint64, round uint32, polRound int32, block *core.Block) (prt.Signature, error) {
+	blockData, err := utils.SerializeData(block, utils.SerializationFormatGob)
+	if err != nil {
+		return prt.Signature{}, fmt.Errorf("failed to serialize block: %w", err)
+	}
+	rec := &WALRecord{Type: WALRecordProposal, Height: height, Round: round, POLRound: polRound, BlockHash: block.Header.Hash, BlockData: blockData}
+
+	return e.signRecord(rec, &SignRequest{Height: height, Round: round, Step: SignStepProposal, POLRound: polRound, BlockHash: block.Header.Hash})
+}
+
+// signRecord signs req for rec and logs it in WAL
+func (e *ConsensusEngine) signRecord(rec *WALRecord, req *SignRequest) (prt.Signature, error) {
+	if e.consensus.LocalProposer == nil {
+		return prt.Signature{}, fmt.Errorf("local proposer is not set")
+	}
+
+	if e.wal != nil {
+		signed, err := e.wal.Signed(rec)
+		if err != nil {
+			return prt.Signature{}, fmt.Errorf("refusing to sign: %w", err)
+		}
+		if signed != nil {
+			return signed.Signature, nil
+		}
+	}
+
+	sig, err := e.consensus.LocalProposer.Signer.Sign(req)
+	if err != nil {
+		return prt.Signature{}, err
+	}
+
+	if e.wal != nil {
+		rec.Signature = sig
+		if err := e.wal.Write(rec); err != nil {
+			return prt.Signature{}, fmt.Errorf("failed to log signed message: %w", err)
+		}
+	}
+	return sig, nil
+}
+
+// walProposal proposal signed by the local validator before a restart
+type walProposal struct {
+	block    *core.Block
+	polRound int32
+}
+
+// signedProposal returns proposal logged for height/round building on prevHash (nil if none)
+func (e *ConsensusEngine) signedProposal(height uint64, round uint32, prevHash prt.Hash) *walProposal {
+	if e.wal == nil {
+		return nil
+	}
+	for _, rec := range e.wal.Records(height) {
+		if rec.Type != WALRecordProposal || rec.Round != round {
+			continue
+		}
+		block, err := rec.block()
+		if err != nil || block == nil || block.Header.PrevHash != prevHash {
+			return nil
+		}
+		return &walProposal{block: block, polRound: rec.POLRound}
+	}
+	return nil
+}
+
+// replayWAL restores round, own votes, lock and proposed blocks of the height being decided from
+// messages signed before a restart
+func (e *ConsensusEngine) replayWAL() {
+	if e.wal == nil || e.consensus.LocalValidator == nil {
+		return
+	}
+
+	state := e.currentState()
+	records := e.wal.Records(state.height)
+	if len(records) == 0 {
+		return
+	}
+
+	var round uint32
+	for _, rec := range records {
+		block, err := rec.block()
+		if err != nil {
+			logger.Warn("[WAL] Skipping block of record ", rec.key(), ": ", err)
+		}
+		if block != nil && block.Header.PrevHash == state.prevHash {
+			state.addBlock(block)
+		} else {
+			block = nil
+		}
+
+		if rec.Type == WALRecordVote {
+			vote := &Vote{
+				Height:    rec.Height,
+				Round:     rec.Round,
+				Type:      rec.VoteType,
+				BlockHash: rec.BlockHash,
+				VoterID:   e.consensus.LocalValidator.Address,
+				Signature: rec.Signature,
+			}
+			state.addVote(vote, e.consensus.LocalValidator.VotingPower)
+
+			if rec.VoteType == VoteTypePrevote {
+				state.prevoted[rec.Round] = true
+			} else {
+				state.precommitted[rec.Round] = true
+				// A precommitted block is a lock (and valid block) with the POL of its round
+				if block != nil && int32(rec.Round) > state.lockedRound {
+					state.lockedBlock, state.lockedRound = block, int32(rec.Round)
+					state.validBlock, state.validRound = block, int32(rec.Round)
+				}
+			}
+		}
+
+		if rec.Round > round {
+			round = rec.Round
+		}
+	}
+
+	e.consensus.mu.Lock()
+	e.consensus.CurrentRound = round
+	e.consensus.mu.Unlock()
+	e.enterRound(state, round)
+	e.startRoundTimer()
+
+	logger.Info("[WAL] Replayed ", len(records), " signed messages at height ", state.height, " round ", round, " (locked round ", state.lockedRound, ")")
+}
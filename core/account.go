@@ -7,6 +7,7 @@ import (
 	"github.com/abcfe/abcfe-node/common/utils"
 	prt "github.com/abcfe/abcfe-node/protocol"
 	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
 )
 
 // Account status constants
@@ -235,70 +236,67 @@ func (p *BlockChain) UpdateAccountStatus(address prt.Address, status string) err
 	return p.saveAccount(account)
 }
 
-// GetAccountTxList gets all transactions of account
+// GetAccountTxList gets all transactions of account (chain order)
 func (p *BlockChain) GetAccountTxList(address prt.Address) ([]prt.Hash, error) {
-	p.mu.RLock()
-	defer p.mu.RUnlock()
-
-	key := []byte(prt.PrefixAddressTxs + utils.AddressToString(address))
-	data, err := p.db.Get(key, nil)
-	if err != nil {
-		if err == leveldb.ErrNotFound {
-			return []prt.Hash{}, nil
-		}
-		return nil, fmt.Errorf("failed to get tx list: %w", err)
-	}
-
-	var txList AccountTxList
-	if err := utils.DeserializeData(data, &txList, utils.SerializationFormatGob); err != nil {
-		return nil, fmt.Errorf("failed to deserialize tx list: %w", err)
-	}
-
-	return txList.TxHashes, nil
+	return p.getAccountTxHashes(address, "", prt.PrefixAddressTxs)
 }
 
 // GetAccountReceivedTxList gets received transaction list
 func (p *BlockChain) GetAccountReceivedTxList(address prt.Address) ([]prt.Hash, error) {
-	p.mu.RLock()
-	defer p.mu.RUnlock()
-
-	key := []byte(prt.PrefixAddressReceived + utils.AddressToString(address))
-	data, err := p.db.Get(key, nil)
-	if err != nil {
-		if err == leveldb.ErrNotFound {
-			return []prt.Hash{}, nil
-		}
-		return nil, fmt.Errorf("failed to get received tx list: %w", err)
-	}
-
-	var txList AccountTxList
-	if err := utils.DeserializeData(data, &txList, utils.SerializationFormatGob); err != nil {
-		return nil, fmt.Errorf("failed to deserialize received tx list: %w", err)
-	}
-
-	return txList.TxHashes, nil
+	return p.getAccountTxHashes(address, AddressTxReceived, prt.PrefixAddressReceived)
 }
 
 // GetAccountSentTxList gets sent transaction list
 func (p *BlockChain) GetAccountSentTxList(address prt.Address) ([]prt.Hash, error) {
+	return p.getAccountTxHashes(address, AddressTxSent, prt.PrefixAddressSent)
+}
+
+// getAccountTxHashes reads tx hashes of address history entries ("" = all types),
+// followed by hashes added to the account tx list (listPrefix) that are not indexed
+func (p *BlockChain) getAccountTxHashes(address prt.Address, txType string, listPrefix string) ([]prt.Hash, error) {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 
-	key := []byte(prt.PrefixAddressSent + utils.AddressToString(address))
+	iter := p.db.NewIterator(util.BytesPrefix(utils.GetAddressHistoryPrefix(address)), nil)
+	defer iter.Release()
+
+	txHashes := []prt.Hash{}
+	indexed := make(map[prt.Hash]bool)
+	for iter.Next() {
+		var info AddressTxInfo
+		if err := utils.DeserializeData(iter.Value(), &info, utils.SerializationFormatGob); err != nil {
+			return nil, fmt.Errorf("failed to deserialize address tx entry: %w", err)
+		}
+		if txType == "" || info.Type == txType {
+			txHashes = append(txHashes, info.TxID)
+			indexed[info.TxID] = true
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return nil, fmt.Errorf("failed to get tx list: %w", err)
+	}
+
+	key := []byte(listPrefix + utils.AddressToString(address))
 	data, err := p.db.Get(key, nil)
 	if err != nil {
 		if err == leveldb.ErrNotFound {
-			return []prt.Hash{}, nil
+			return txHashes, nil
 		}
-		return nil, fmt.Errorf("failed to get sent tx list: %w", err)
+		return nil, fmt.Errorf("failed to get tx list: %w", err)
 	}
 
 	var txList AccountTxList
 	if err := utils.DeserializeData(data, &txList, utils.SerializationFormatGob); err != nil {
-		return nil, fmt.Errorf("failed to deserialize sent tx list: %w", err)
+		return nil, fmt.Errorf("failed to deserialize tx list: %w", err)
+	}
+	for _, txHash := range txList.TxHashes {
+		if !indexed[txHash] {
+			txHashes = append(txHashes, txHash)
+			indexed[txHash] = true
+		}
 	}
 
-	return txList.TxHashes, nil
+	return txHashes, nil
 }
 
 // GetBalance gets balance based on UTXO
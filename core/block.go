@@ -19,88 +19,55 @@ type Block struct {
 
 	// BFT consensus info (2/3 vote evidence)
 	CommitSignatures []CommitSignature `json:"commitSignatures,omitempty"` // Validators' commit signatures
+
+	// Commit signatures of the parent block (sorted by validator, hashed into the header) used for downtime tracking
+	LastCommit []CommitSignature `json:"lastCommit,omitempty"`
 }
 
 // CommitSignature validator's commit signature info
 type CommitSignature struct {
 	ValidatorAddress prt.Address   `json:"validatorAddress"` // Validator address
-	Signature        prt.Signature `json:"signature"`        // Signature for block hash
+	Signature        prt.Signature `json:"signature"`        // Precommit signature (VoteSignHash of the block hash)
 	Timestamp        int64         `json:"timestamp"`        // Signature time
+	Round            uint32        `json:"round"`            // Round of the precommit
 }
 
 type BlockHeader struct {
-	Hash       prt.Hash `json:"hash"`       // Block hash
-	PrevHash   prt.Hash `json:"prevHash"`   // Previous block hash
-	Version    string   `json:"version"`    // Blockchain protocol version
-	Height     uint64   `json:"height"`     // Block height (changed to uint64)
-	MerkleRoot prt.Hash `json:"merkleRoot"` // Transaction Merkle root
-	Timestamp  int64    `json:"timestamp"`  // Block creation time (Unix timestamp)
+	Hash       prt.Hash `json:"hash"`            // Block hash
+	PrevHash   prt.Hash `json:"prevHash"`        // Previous block hash
+	Version    string   `json:"version"`         // Blockchain protocol version
+	Height     uint64   `json:"height"`          // Block height (changed to uint64)
+	MerkleRoot prt.Hash `json:"merkleRoot"`      // Transaction Merkle root
+	Timestamp  int64    `json:"timestamp"`       // Block creation time (Unix timestamp)
+	Round      uint32   `json:"round,omitempty"` // Consensus round the block was proposed in
+
+	LastCommitHash *prt.Hash `json:"lastCommitHash,omitempty"` // Hash of the block's LastCommit (nil if none)
 	// StateRoot  Hash   `json:"stateRoot"`  // State Merkle root (UTXO or account state)
 }
 
 func (p *BlockChain) SetBlock(prevHash prt.Hash, height uint64, proposer prt.Address, blockTimestamp int64) *Block {
-	// Get transactions from mempool
-	candidateTxs := p.Mempool.GetTxs()
-
-	// logger.Info("[SetBlock] Mempool has ", len(candidateTxs), " candidate TXs")
-
-	// Filter only valid transactions (including signature verification)
-	var validTxs []*Transaction
-	var invalidTxIds []prt.Hash
-	var totalFees uint64 = 0
-
-	// Track UTXOs used in this block to prevent double-spending
-	usedUTXOs := make(map[string]bool)
-
-	for _, tx := range candidateTxs {
-		if err := p.ValidateTransaction(tx); err != nil {
-			// Invalid transactions will be removed from mempool
-			logger.Warn("[SetBlock] TX validation failed: ", utils.HashToString(tx.ID)[:16], " error: ", err)
-			invalidTxIds = append(invalidTxIds, tx.ID)
-			continue
-		}
-
-		// Check for duplicate UTXO usage within this block
-		hasDoubleSpend := false
-		for _, input := range tx.Inputs {
-			utxoKey := fmt.Sprintf("%s:%d", utils.HashToString(input.TxID), input.OutputIndex)
-			if usedUTXOs[utxoKey] {
-				// This TX uses a UTXO already claimed by another TX in this block
-				logger.Warn("[SetBlock] Double-spend detected, removing TX: ", utils.HashToString(tx.ID)[:16], " UTXO: ", utxoKey)
-				invalidTxIds = append(invalidTxIds, tx.ID)
-				hasDoubleSpend = true
-				break
-			}
-		}
-		if hasDoubleSpend {
-			continue
-		}
-
-		// Calculate fee
-		fee, err := p.CalculateTxFee(tx)
-		if err != nil {
-			logger.Warn("[SetBlock] Failed to calculate fee for TX: ", utils.HashToString(tx.ID)[:16], " error: ", err)
-			invalidTxIds = append(invalidTxIds, tx.ID)
-			continue
-		}
+	return p.SetBlockAtRound(prevHash, height, 0, proposer, blockTimestamp)
+}
 
-		// Mark UTXOs as used
-		for _, input := range tx.Inputs {
-			utxoKey := fmt.Sprintf("%s:%d", utils.HashToString(input.TxID), input.OutputIndex)
-			usedUTXOs[utxoKey] = true
-		}
+// SetBlockAtRound creates block proposed in consensus round (the round is part of the block hash,
+// so a proposer signing two blocks for the same height and round can be proven)
+func (p *BlockChain) SetBlockAtRound(prevHash prt.Hash, height uint64, round uint32, proposer prt.Address, blockTimestamp int64) *Block {
+	// Select transactions from mempool by fee rate within block limits (parents before children)
+	template := p.BuildBlockTemplate(height, blockTimestamp)
 
-		totalFees += fee
-		logger.Info("[SetBlock] TX validated: ", utils.HashToString(tx.ID)[:16], " fee: ", fee)
-		validTxs = append(validTxs, tx)
+	for _, tx := range template.Included {
+		logger.Info("[SetBlock] TX validated: ", utils.HashToString(tx.TxID)[:16], " fee: ", tx.Fee, " size: ", tx.Size)
 	}
 
-	// Remove invalid transactions from mempool
-	for _, txId := range invalidTxIds {
+	// Remove invalid transactions (and mempool children spending their outputs) from mempool
+	for _, txId := range template.InvalidTxIds {
 		logger.Warn("[SetBlock] Removing invalid TX from mempool: ", utils.HashToString(txId)[:16])
-		p.Mempool.DelTx(txId)
+		p.Mempool.RemoveTxWithDescendants(txId)
 	}
 
+	validTxs := template.Txs
+	totalFees := template.TotalFees
+
 	// Create Coinbase TX (Block reward + fees) - Use block timestamp
 	var emptyProposer prt.Address
 	if proposer != emptyProposer {
@@ -122,6 +89,7 @@ func (p *BlockChain) SetBlock(prevHash prt.Hash, height uint64, proposer prt.Add
 		PrevHash:   prevHash,
 		Timestamp:  blockTimestamp,
 		MerkleRoot: merkleRoot,
+		Round:      round,
 	}
 
 	blk := &Block{
@@ -130,6 +98,13 @@ func (p *BlockChain) SetBlock(prevHash prt.Hash, height uint64, proposer prt.Add
 		Proposer:     proposer,
 	}
 
+	// Carry commit signatures of the parent so that every node counts the same signers
+	if lastCommit := p.lastCommitOf(prevHash, height); len(lastCommit) > 0 {
+		lastCommitHash := utils.Hash(lastCommit)
+		blk.LastCommit = lastCommit
+		blk.Header.LastCommitHash = &lastCommitHash
+	}
+
 	// Block hash is calculated only with Header (Header already includes MerkleRoot so transaction integrity is guaranteed)
 	blkHash := utils.Hash(blk.Header)
 	blk.Header.Hash = blkHash
@@ -137,10 +112,14 @@ func (p *BlockChain) SetBlock(prevHash prt.Hash, height uint64, proposer prt.Add
 	return blk
 }
 
-// createCoinbaseTx creates Coinbase transaction (Pay block reward + fees to proposer)
+// createCoinbaseTx creates Coinbase transaction (Pay block reward + fees to proposer).
+// Delegators' shares of the reward are not paid here but credited to their reward balances at commit.
 func (p *BlockChain) createCoinbaseTx(proposer prt.Address, height uint64, totalFees uint64, blockTimestamp int64) *Transaction {
 	blockReward := p.GetBlockReward()
 	totalReward := blockReward + totalFees
+	if epoch, err := p.GetEpoch(height); err == nil {
+		totalReward, _ = splitReward(epoch, proposer, totalReward)
+	}
 
 	coinbaseTx := &Transaction{
 		Version:   p.cfg.Version.Transaction,
@@ -186,6 +165,11 @@ func (p *BlockChain) AddBlock(blk Block) (bool, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
+	return p.addBlockNoLock(blk)
+}
+
+// addBlockNoLock adds block to chain without lock (internal use)
+func (p *BlockChain) addBlockNoLock(blk Block) (bool, error) {
 	// db batch process ready
 	batch := new(leveldb.Batch)
 
@@ -207,21 +191,37 @@ func (p *BlockChain) AddBlock(blk Block) (bool, error) {
 		return false, fmt.Errorf("failed to save utxo into db: %w", err)
 	}
 
+	// chain status update (same batch, so the stored tip always matches stored block data)
+	updateTip := blk.Header.Height > p.LatestHeight || blk.Header.Height == 0
+	if updateTip {
+		putChainState(batch, blk.Header.Height, utils.HashToString(blk.Header.Hash))
+	}
+
 	// batch excute
 	if err := p.db.Write(batch, nil); err != nil {
 		return false, fmt.Errorf("failed to write batch: %w", err)
 	}
+	if updateTip {
+		p.LatestHeight = blk.Header.Height
+		p.LatestBlockHash = utils.HashToString(blk.Header.Hash)
+	}
 
 	// mempool update
 	for _, tx := range blk.Transactions {
 		p.Mempool.DelTx(tx.ID)
 	}
 
-	// chain status update
-	if blk.Header.Height > p.LatestHeight || blk.Header.Height == 0 {
-		if err := p.UpdateChainState(blk.Header.Height, utils.HashToString(blk.Header.Hash)); err != nil {
-			return false, fmt.Errorf("failed to update chain status: %w", err)
-		}
+	// validator set of the next epoch
+	if err := p.updateEpochNoLock(&blk); err != nil {
+		return false, fmt.Errorf("failed to update epoch: %w", err)
+	}
+
+	// mempool revalidation against the new tip (height, validator set)
+	p.RevalidateMempool()
+
+	// fee estimator update
+	if err := p.recordBlockFeesNoLock(&blk); err != nil {
+		logger.Warn("[FeeEstimator] Failed to record block ", blk.Header.Height, ": ", err)
 	}
 
 	return true, nil
@@ -300,6 +300,11 @@ func (p *BlockChain) saveTxData(batch *leveldb.Batch, blk Block) error {
 		}
 	}
 
+	// address -> sent / received tx history
+	if err := p.saveAddressHistory(batch, blk); err != nil {
+		return fmt.Errorf("failed to save address history: %w", err)
+	}
+
 	return nil
 }
 
@@ -4,17 +4,19 @@ import (
 	"fmt"
 	"strconv"
 	"sync"
+	"time"
 
 	"github.com/abcfe/abcfe-node/config"
 	proto "github.com/abcfe/abcfe-node/protocol"
 	"github.com/syndtr/goleveldb/leveldb"
 )
 
-// ProposerValidator interface for proposer signature verification
+// ProposerValidator interface for proposer signature verification.
+// epoch is the validator set active at the block height (nil if unknown: the current set applies).
 type ProposerValidator interface {
-	ValidateProposerSignature(proposer proto.Address, blockHash proto.Hash, signature proto.Signature) bool
-	IsValidProposer(proposer proto.Address, height uint64) bool
-	ValidateCommitSignatures(blockHash proto.Hash, commitSigs []CommitSignature) error
+	ValidateProposerSignature(epoch *Epoch, proposer proto.Address, blockHash proto.Hash, signature proto.Signature) bool
+	IsValidProposer(epoch *Epoch, proposer proto.Address, height uint64) bool
+	ValidateCommitSignatures(epoch *Epoch, height uint64, blockHash proto.Hash, commitSigs []CommitSignature) error
 }
 
 type BlockChain struct {
@@ -27,19 +29,57 @@ type BlockChain struct {
 
 	// Callback for PoA verification (set in consensus package)
 	proposerValidator ProposerValidator
+
+	// Blocks received before their parent (block hash -> block)
+	orphans map[proto.Hash]*Block
+
+	// Fee rates of recent blocks
+	feeEstimator *FeeEstimator
+
+	// Consensus timing of the genesis block (nil until the genesis block is known)
+	timing   *ConsensusTiming
+	timingMu sync.Mutex
+
+	// Consensus params of the genesis block (nil until the genesis block is known)
+	params   *ConsensusParams
+	paramsMu sync.Mutex
 }
 
 func NewChainState(db *leveldb.DB, cfg *config.Config) (*BlockChain, error) {
 	bc := &BlockChain{
-		db:      db,
-		cfg:     cfg,
-		Mempool: NewMempool(),
+		db:           db,
+		cfg:          cfg,
+		Mempool:      NewMempoolWithLimits(cfg.Mempool.MaxTxs, cfg.Mempool.MaxBytes, time.Duration(cfg.Mempool.MaxAgeSec)*time.Second),
+		orphans:      make(map[proto.Hash]*Block),
+		feeEstimator: NewFeeEstimator(),
 	}
 
 	if err := bc.LoadChainDB(); err != nil {
 		return nil, err
 	}
 
+	// Roll back a reorg interrupted by a crash
+	if err := bc.recoverReorg(); err != nil {
+		return nil, err
+	}
+
+	// Index blocks committed before the address history index existed
+	if err := bc.rebuildAddressHistory(); err != nil {
+		return nil, err
+	}
+
+	// Index staked outputs committed before the stake index existed
+	if err := bc.rebuildStakeIndex(); err != nil {
+		return nil, err
+	}
+
+	// Store validator sets of epochs committed before epoch history existed
+	if err := bc.rebuildEpochs(); err != nil {
+		return nil, err
+	}
+
+	bc.loadFeeHistoryNoLock()
+
 	// Only boot node or block producer creates genesis block
 	// sync-only nodes receive genesis block via P2P
 	shouldCreateGenesis := (cfg.Common.Mode == "boot" || cfg.Common.BlockProducer) &&
@@ -155,7 +195,14 @@ func (p *BlockChain) UpdateChainState(height uint64, blockHash string) error {
 
 	// db batch update
 	batch := new(leveldb.Batch)
+	putChainState(batch, height, blockHash)
+
+	// batch write excute
+	return p.db.Write(batch, nil)
+}
 
+// putChainState writes latest height / hash into batch
+func putChainState(batch *leveldb.Batch, height uint64, blockHash string) {
 	heightKey := []byte(proto.PrefixMetaHeight)
 	batch.Put(heightKey, []byte(fmt.Sprintf("%d", height)))
 
@@ -165,9 +212,6 @@ func (p *BlockChain) UpdateChainState(height uint64, blockHash string) error {
 	// height - hash mapping
 	heightToHashKey := []byte(fmt.Sprintf("%s%d", proto.PrefixMetaHeight, height))
 	batch.Put(heightToHashKey, []byte(blockHash))
-
-	// batch write excute
-	return p.db.Write(batch, nil)
 }
 
 // SetProposerValidator sets interface for PoA verification
@@ -0,0 +1,203 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"sort"
+)
+
+// Coin selection strategies
+const (
+	CoinSelectLargestFirst   = "largest"  // Fewest inputs
+	CoinSelectSmallestFirst  = "smallest" // Consolidates dust (more inputs)
+	CoinSelectBranchAndBound = "bnb"      // Exact match without change, falls back to largest first
+	CoinSelectRandom         = "random"   // Random order (does not reveal wallet UTXO ordering)
+
+	DefaultCoinSelection = CoinSelectBranchAndBound
+
+	bnbMaxTries = 100000 // Search steps before branch and bound gives up
+)
+
+// CoinSelector picks UTXOs worth at least target.
+// maxExcess is the largest surplus which is added to fee instead of creating a change output.
+// Returns nil if the strategy could not find a selection.
+type CoinSelector func(utxos []*UTXO, target uint64, maxExcess uint64) []*UTXO
+
+var coinSelectors = map[string]CoinSelector{
+	CoinSelectLargestFirst:   selectLargestFirst,
+	CoinSelectSmallestFirst:  selectSmallestFirst,
+	CoinSelectBranchAndBound: selectBranchAndBound,
+	CoinSelectRandom:         selectRandom,
+}
+
+// RegisterCoinSelector adds (or replaces) a coin selection strategy
+func RegisterCoinSelector(name string, selector CoinSelector) {
+	coinSelectors[name] = selector
+}
+
+// CoinSelection result of coin selection
+type CoinSelection struct {
+	Strategy string  // Strategy which produced the selection
+	Utxos    []*UTXO // Selected inputs
+	Total    uint64  // Sum of selected inputs
+	Change   uint64  // Returned to sender (0 = no change output)
+	Excess   uint64  // Surplus added to fee instead of change
+}
+
+// SelectCoins selects inputs worth target (amount + fee) using strategy ("" = DefaultCoinSelection).
+// Surplus up to maxExcess is left to fee instead of creating a dust change output.
+func SelectCoins(strategy string, utxos []*UTXO, target uint64, maxExcess uint64) (*CoinSelection, error) {
+	if strategy == "" {
+		strategy = DefaultCoinSelection
+	}
+	selector, ok := coinSelectors[strategy]
+	if !ok {
+		return nil, fmt.Errorf("unknown coin selection strategy: %s", strategy)
+	}
+
+	var spendable []*UTXO
+	var balance uint64
+	for _, utxo := range utxos {
+		if !utxo.Spent {
+			spendable = append(spendable, utxo)
+			balance += utxo.TxOut.Amount
+		}
+	}
+	if balance < target {
+		return nil, fmt.Errorf("not enough balance: required %d, have %d", target, balance)
+	}
+
+	selected := selector(spendable, target, maxExcess)
+	if selected == nil && strategy == CoinSelectBranchAndBound {
+		// No exact match, minimize inputs instead
+		strategy = CoinSelectLargestFirst
+		selected = selectLargestFirst(spendable, target, maxExcess)
+	}
+	if selected == nil {
+		return nil, fmt.Errorf("coin selection %s failed for amount %d", strategy, target)
+	}
+
+	selection := &CoinSelection{Strategy: strategy, Utxos: selected}
+	for _, utxo := range selected {
+		selection.Total += utxo.TxOut.Amount
+	}
+	if selection.Total < target {
+		return nil, fmt.Errorf("coin selection %s returned %d, required %d", strategy, selection.Total, target)
+	}
+
+	if surplus := selection.Total - target; surplus <= maxExcess {
+		selection.Excess = surplus
+	} else {
+		selection.Change = surplus
+	}
+
+	return selection, nil
+}
+
+// sortUtxos sorts by amount (ties by outpoint so the result does not depend on map order)
+func sortUtxos(utxos []*UTXO, descending bool) []*UTXO {
+	sorted := append([]*UTXO{}, utxos...)
+	sort.Slice(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if a.TxOut.Amount != b.TxOut.Amount {
+			if descending {
+				return a.TxOut.Amount > b.TxOut.Amount
+			}
+			return a.TxOut.Amount < b.TxOut.Amount
+		}
+		if c := bytes.Compare(a.TxId[:], b.TxId[:]); c != 0 {
+			return c < 0
+		}
+		return a.OutputIndex < b.OutputIndex
+	})
+	return sorted
+}
+
+// accumulate takes utxos in order until target is reached
+func accumulate(utxos []*UTXO, target uint64) []*UTXO {
+	var selected []*UTXO
+	var total uint64
+	for _, utxo := range utxos {
+		if total >= target {
+			break
+		}
+		selected = append(selected, utxo)
+		total += utxo.TxOut.Amount
+	}
+	if total < target {
+		return nil
+	}
+	return selected
+}
+
+func selectLargestFirst(utxos []*UTXO, target uint64, maxExcess uint64) []*UTXO {
+	return accumulate(sortUtxos(utxos, true), target)
+}
+
+func selectSmallestFirst(utxos []*UTXO, target uint64, maxExcess uint64) []*UTXO {
+	return accumulate(sortUtxos(utxos, false), target)
+}
+
+func selectRandom(utxos []*UTXO, target uint64, maxExcess uint64) []*UTXO {
+	shuffled := append([]*UTXO{}, utxos...)
+	rand.Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+	return accumulate(shuffled, target)
+}
+
+// selectBranchAndBound depth first search for the input set with the smallest surplus within [target, target+maxExcess]
+func selectBranchAndBound(utxos []*UTXO, target uint64, maxExcess uint64) []*UTXO {
+	sorted := sortUtxos(utxos, true)
+
+	// remaining[i] = sum of sorted[i:]
+	remaining := make([]uint64, len(sorted)+1)
+	for i := len(sorted) - 1; i >= 0; i-- {
+		remaining[i] = remaining[i+1] + sorted[i].TxOut.Amount
+	}
+
+	var best []int
+	bestExcess := maxExcess + 1
+	current := make([]int, 0, len(sorted))
+	tries := 0
+
+	var search func(idx int, total uint64) bool
+	search = func(idx int, total uint64) bool {
+		tries++
+		if tries > bnbMaxTries {
+			return true
+		}
+		if total >= target {
+			if excess := total - target; excess < bestExcess {
+				bestExcess = excess
+				best = append(best[:0], current...)
+			}
+			return bestExcess == 0 // Exact match, stop searching
+		}
+		if idx == len(sorted) || total+remaining[idx] < target {
+			return false // Cannot reach target on this branch
+		}
+
+		// Include sorted[idx] unless it overshoots the allowed surplus
+		if amount := sorted[idx].TxOut.Amount; total+amount <= target+maxExcess {
+			current = append(current, idx)
+			done := search(idx+1, total+amount)
+			current = current[:len(current)-1]
+			if done {
+				return true
+			}
+		}
+		return search(idx+1, total)
+	}
+	search(0, 0)
+
+	if best == nil {
+		return nil
+	}
+	selected := make([]*UTXO, len(best))
+	for i, idx := range best {
+		selected[i] = sorted[idx]
+	}
+	return selected
+}
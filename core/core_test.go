@@ -1,12 +1,20 @@
 package core
 
 import (
+	"encoding/hex"
 	"fmt"
+	"path/filepath"
+	"reflect"
 	"testing"
 	"time"
 
+	"github.com/abcfe/abcfe-node/common/crypto"
+	"github.com/abcfe/abcfe-node/common/logger"
 	"github.com/abcfe/abcfe-node/common/utils"
+	"github.com/abcfe/abcfe-node/config"
 	prt "github.com/abcfe/abcfe-node/protocol"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/storage"
 )
 
 // 트랜잭션 생성 헬퍼 함수
@@ -495,3 +503,2560 @@ func TestSetGenesisBlock(t *testing.T) {
 
 // 	return txs, nil
 // }
+
+// ===== 체인 상태 테스트 (메모리 DB) =====
+
+// 테스트 계정 (키 + 주소)
+type testAccount struct {
+	Address    prt.Address
+	PrivateKey []byte
+	PublicKey  []byte
+}
+
+func newTestAccount(t *testing.T) *testAccount {
+	priv, pub, err := crypto.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	addr, err := crypto.PublicKeyToAddress(pub)
+	if err != nil {
+		t.Fatalf("failed to derive address: %v", err)
+	}
+	privBytes, _ := crypto.PrivateKeyToBytes(priv)
+	pubBytes, _ := crypto.PublicKeyToBytes(pub)
+	return &testAccount{Address: addr, PrivateKey: privBytes, PublicKey: pubBytes}
+}
+
+// 제네시스에 system 계정 잔액을 넣은 메모리 체인 생성
+func newTestChain(t *testing.T, system *testAccount, balance uint64) *BlockChain {
+	return newTestChainWith(t, system, balance, nil)
+}
+
+// newTestChainWith 제네시스 생성 전에 설정(합의 파라미터 등)을 바꾼 테스트 체인
+func newTestChainWith(t *testing.T, system *testAccount, balance uint64, configure func(cfg *config.Config)) *BlockChain {
+	cfg := &config.Config{}
+	cfg.LogInfo.Path = filepath.Join(t.TempDir(), "test")
+	cfg.LogInfo.MaxAgeHour = 1
+	cfg.LogInfo.RotateHour = 1
+	if err := logger.InitLogger(cfg); err != nil {
+		t.Fatalf("failed to init logger: %v", err)
+	}
+
+	cfg.Common.Mode = "boot"
+	cfg.Common.NetworkID = "abcfe-test"
+	cfg.Version.Protocol = "1.0"
+	cfg.Version.Transaction = "1.0"
+	cfg.Fee.MinFee = 1
+	cfg.Fee.BlockReward = 50
+	cfg.Genesis.Timestamp = time.Now().Unix() - 1000
+	cfg.Genesis.SystemAddresses = []string{utils.AddressToString(system.Address)}
+	cfg.Genesis.SystemBalances = []uint64{balance}
+	if configure != nil {
+		configure(cfg)
+	}
+
+	db, err := leveldb.Open(storage.NewMemStorage(), nil)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	bc, err := NewChainState(db, cfg)
+	if err != nil {
+		t.Fatalf("failed to init chain: %v", err)
+	}
+	return bc
+}
+
+// 포크 선택 & 재구성 테스트
+func TestImportBlock_Reorg(t *testing.T) {
+	system := newTestAccount(t)
+	minerA := newTestAccount(t)
+	minerB := newTestAccount(t)
+	receiver := newTestAccount(t)
+
+	bc := newTestChain(t, system, 100000)
+	genesis, _ := bc.GetBlockByHeight(0)
+	ts := genesis.Header.Timestamp
+
+	// 메인 체인: A1 (system -> receiver 전송 포함)
+	tx, err := bc.CreateSignedTx(system.Address, receiver.Address, 1000, 1, "", nil, TxTypeGeneral, system.PrivateKey, system.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to create tx: %v", err)
+	}
+	if err := bc.Mempool.NewTransactionWithFee(tx, 1); err != nil {
+		t.Fatalf("failed to add tx: %v", err)
+	}
+	blkA1 := bc.SetBlock(genesis.Header.Hash, 1, minerA.Address, ts+1)
+	if status, err := bc.ImportBlock(*blkA1); err != nil || status != BlockImportExtended {
+		t.Fatalf("A1 import: status=%v err=%v", status, err)
+	}
+
+	// 사이드 체인: B1 <- B2 (전송 없음)
+	blkB1 := bc.SetBlock(genesis.Header.Hash, 1, minerB.Address, ts+2)
+	blkB2 := bc.SetBlock(blkB1.Header.Hash, 2, minerB.Address, ts+3)
+
+	// 부모보다 먼저 도착한 블록은 orphan
+	if status, err := bc.ImportBlock(*blkB2); err != nil || status != BlockImportOrphan {
+		t.Fatalf("B2 import: status=%v err=%v", status, err)
+	}
+
+	// B1 도착 -> B2 연결 -> 더 긴 체인으로 재구성
+	if status, err := bc.ImportBlock(*blkB1); err != nil || status != BlockImportReorged {
+		t.Fatalf("B1 import: status=%v err=%v", status, err)
+	}
+
+	if bc.LatestHeight != 2 || bc.LatestBlockHash != utils.HashToString(blkB2.Header.Hash) {
+		t.Fatalf("unexpected tip: height=%d hash=%s", bc.LatestHeight, bc.LatestBlockHash)
+	}
+
+	// A1 전송은 롤백되어 멤풀로 복귀, receiver 잔액 0
+	if bc.Mempool.GetTx(tx.ID) == nil {
+		t.Error("reverted tx should be back in mempool")
+	}
+	if balance, _ := bc.GetBalance(receiver.Address); balance != 0 {
+		t.Errorf("receiver balance should be 0 after reorg, got %d", balance)
+	}
+	if balance, _ := bc.GetBalance(system.Address); balance != 100000 {
+		t.Errorf("system balance should be restored, got %d", balance)
+	}
+	if balance, _ := bc.GetBalance(minerA.Address); balance != 0 {
+		t.Errorf("abandoned coinbase should be removed, got %d", balance)
+	}
+	if balance, _ := bc.GetBalance(minerB.Address); balance != 100 {
+		t.Errorf("minerB should have 2 block rewards, got %d", balance)
+	}
+
+	// A1은 사이드 체인 저장소로 이동
+	if _, err := bc.GetSideBlock(blkA1.Header.Hash); err != nil {
+		t.Errorf("A1 should be kept as side block: %v", err)
+	}
+}
+
+// 중단된 재구성 복구 테스트 (재시작 시 원래 메인 체인 복원)
+func TestReorgRecovery(t *testing.T) {
+	system := newTestAccount(t)
+	minerA := newTestAccount(t)
+	minerB := newTestAccount(t)
+	receiver := newTestAccount(t)
+
+	bc := newTestChain(t, system, 100000)
+	genesis, _ := bc.GetBlockByHeight(0)
+	ts := genesis.Header.Timestamp
+
+	// 메인 체인: A1 (전송) <- A2
+	tx, err := bc.CreateSignedTx(system.Address, receiver.Address, 1000, 1, "", nil, TxTypeGeneral, system.PrivateKey, system.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to create tx: %v", err)
+	}
+	if err := bc.Mempool.NewTransactionWithFee(tx, 1); err != nil {
+		t.Fatalf("failed to add tx: %v", err)
+	}
+	blkA1 := bc.SetBlock(genesis.Header.Hash, 1, minerA.Address, ts+1)
+	if _, err := bc.AddBlock(*blkA1); err != nil {
+		t.Fatalf("failed to add A1: %v", err)
+	}
+	blkA2 := bc.SetBlock(blkA1.Header.Hash, 2, minerA.Address, ts+2)
+	if _, err := bc.AddBlock(*blkA2); err != nil {
+		t.Fatalf("failed to add A2: %v", err)
+	}
+
+	// 사이드 체인 B1 연결 도중 중단: A2, A1 롤백 + B1 연결 후 크래시
+	blkB1 := bc.SetBlock(genesis.Header.Hash, 1, minerB.Address, ts+3)
+	bc.mu.Lock()
+	if err := bc.saveReorgJournalNoLock(blkB1, []*Block{blkA2, blkA1}); err != nil {
+		t.Fatalf("failed to save journal: %v", err)
+	}
+	for _, blk := range []*Block{blkA2, blkA1} {
+		if err := bc.disconnectBlockNoLock(blk, true); err != nil {
+			t.Fatalf("failed to disconnect block %d: %v", blk.Header.Height, err)
+		}
+	}
+	if _, err := bc.addBlockNoLock(*blkB1); err != nil {
+		t.Fatalf("failed to connect B1: %v", err)
+	}
+	bc.mu.Unlock()
+
+	// 재시작: 원래 메인 체인(A1, A2) 복원, 저널 삭제
+	restarted, err := NewChainState(bc.db, bc.cfg)
+	if err != nil {
+		t.Fatalf("failed to restart chain: %v", err)
+	}
+	if restarted.LatestHeight != 2 || restarted.LatestBlockHash != utils.HashToString(blkA2.Header.Hash) {
+		t.Fatalf("unexpected tip after recovery: height=%d hash=%s", restarted.LatestHeight, restarted.LatestBlockHash)
+	}
+	if balance, _ := restarted.GetBalance(receiver.Address); balance != 1000 {
+		t.Errorf("receiver balance should be restored, got %d", balance)
+	}
+	if balance, _ := restarted.GetBalance(minerB.Address); balance != 0 {
+		t.Errorf("abandoned B1 coinbase should be removed, got %d", balance)
+	}
+	if _, err := restarted.GetSideBlock(blkB1.Header.Hash); err != nil {
+		t.Errorf("B1 should be kept as side block: %v", err)
+	}
+	if _, err := restarted.GetSideBlock(blkA1.Header.Hash); err == nil {
+		t.Error("A1 should be back on main chain")
+	}
+	if _, err := bc.db.Get([]byte(prt.PrefixMetaReorg), nil); err == nil {
+		t.Error("reorg journal should be deleted after recovery")
+	}
+}
+
+// 언두 레코드 기반 롤백 테스트
+func TestRollbackToHeight(t *testing.T) {
+	system := newTestAccount(t)
+	miner := newTestAccount(t)
+	receiver := newTestAccount(t)
+
+	bc := newTestChain(t, system, 100000)
+	genesis, _ := bc.GetBlockByHeight(0)
+	ts := genesis.Header.Timestamp
+
+	// 블록 1: system -> receiver 전송
+	tx, err := bc.CreateSignedTx(system.Address, receiver.Address, 1000, 1, "", nil, TxTypeGeneral, system.PrivateKey, system.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to create tx: %v", err)
+	}
+	if err := bc.Mempool.NewTransactionWithFee(tx, 1); err != nil {
+		t.Fatalf("failed to add tx: %v", err)
+	}
+	blk1 := bc.SetBlock(genesis.Header.Hash, 1, miner.Address, ts+1)
+	if _, err := bc.AddBlock(*blk1); err != nil {
+		t.Fatalf("failed to add block 1: %v", err)
+	}
+
+	// 블록 1의 언두 레코드 확인
+	undo, err := bc.GetBlockUndo(blk1.Header.Hash)
+	if err != nil {
+		t.Fatalf("undo record should exist: %v", err)
+	}
+	if len(undo.SpentUtxos) != 1 || len(undo.CreatedUtxos) != 3 {
+		t.Fatalf("unexpected undo record: spent=%d created=%d", len(undo.SpentUtxos), len(undo.CreatedUtxos))
+	}
+
+	// 블록 2: 빈 블록
+	blk2 := bc.SetBlock(blk1.Header.Hash, 2, miner.Address, ts+2)
+	if _, err := bc.AddBlock(*blk2); err != nil {
+		t.Fatalf("failed to add block 2: %v", err)
+	}
+
+	// 현재 높이 이상으로는 롤백 불가
+	if err := bc.RollbackToHeight(2); err == nil {
+		t.Error("rollback to current height should fail")
+	}
+
+	if err := bc.RollbackToHeight(0); err != nil {
+		t.Fatalf("rollback failed: %v", err)
+	}
+
+	if bc.LatestHeight != 0 || bc.LatestBlockHash != utils.HashToString(genesis.Header.Hash) {
+		t.Fatalf("unexpected tip: height=%d hash=%s", bc.LatestHeight, bc.LatestBlockHash)
+	}
+
+	// meta:height / meta:hash 가 DB에서도 되감겼는지 확인
+	if err := bc.LoadChainDB(); err != nil {
+		t.Fatalf("failed to reload chain db: %v", err)
+	}
+	if bc.LatestHeight != 0 || bc.LatestBlockHash != utils.HashToString(genesis.Header.Hash) {
+		t.Fatalf("meta not rewound: height=%d hash=%s", bc.LatestHeight, bc.LatestBlockHash)
+	}
+
+	if balance, _ := bc.GetBalance(system.Address); balance != 100000 {
+		t.Errorf("system balance should be restored, got %d", balance)
+	}
+	if balance, _ := bc.GetBalance(receiver.Address); balance != 0 {
+		t.Errorf("receiver balance should be 0, got %d", balance)
+	}
+	if balance, _ := bc.GetBalance(miner.Address); balance != 0 {
+		t.Errorf("coinbase should be removed, got %d", balance)
+	}
+	if _, err := bc.GetBlockUndo(blk1.Header.Hash); err == nil {
+		t.Error("undo record should be deleted after rollback")
+	}
+
+	// 롤백된 전송은 멤풀로 복귀, 블록은 다시 가져올 수 있음
+	if bc.Mempool.GetTx(tx.ID) == nil {
+		t.Error("rolled back tx should be back in mempool")
+	}
+	if status, err := bc.ImportBlock(*blk1); err != nil || status != BlockImportExtended {
+		t.Fatalf("re-import block 1: status=%v err=%v", status, err)
+	}
+}
+
+// 주소 히스토리 인덱스 테스트 (보낸/받은 TX, 높이 범위, 커서)
+func TestAddressHistory(t *testing.T) {
+	system := newTestAccount(t)
+	miner := newTestAccount(t)
+	receiver := newTestAccount(t)
+
+	bc := newTestChain(t, system, 100000)
+	genesis, _ := bc.GetBlockByHeight(0)
+	prevHash := genesis.Header.Hash
+
+	// 블록 1, 2: system -> receiver 전송
+	amounts := []uint64{1000, 500}
+	for i, amount := range amounts {
+		tx, err := bc.CreateSignedTx(system.Address, receiver.Address, amount, 1, "", nil, TxTypeGeneral, system.PrivateKey, system.PublicKey)
+		if err != nil {
+			t.Fatalf("failed to create tx: %v", err)
+		}
+		if err := bc.Mempool.NewTransactionWithFee(tx, 1); err != nil {
+			t.Fatalf("failed to add tx: %v", err)
+		}
+		blk := bc.SetBlock(prevHash, uint64(i+1), miner.Address, genesis.Header.Timestamp+int64(i+1))
+		if _, err := bc.AddBlock(*blk); err != nil {
+			t.Fatalf("failed to add block %d: %v", i+1, err)
+		}
+		prevHash = blk.Header.Hash
+	}
+
+	// system: 제네시스 수신 + 2건 송신 (최신 순)
+	infos, cursor, err := bc.GetAddressTransactions(system.Address, AddressTxQuery{Desc: true})
+	if err != nil {
+		t.Fatalf("failed to get history: %v", err)
+	}
+	if len(infos) != 3 || cursor != "" {
+		t.Fatalf("expected 3 entries without cursor, got %d cursor=%q", len(infos), cursor)
+	}
+	if infos[0].Height != 2 || infos[0].Type != AddressTxSent || infos[0].NetAmount != -501 {
+		t.Errorf("unexpected newest entry: %+v", infos[0])
+	}
+	if infos[2].Height != 0 || infos[2].Type != AddressTxReceived || infos[2].NetAmount != 100000 {
+		t.Errorf("unexpected genesis entry: %+v", infos[2])
+	}
+	// 기존 응답 필드 (amount / spent / index) 유지: 제네시스 출력은 블록 1에서 사용됨
+	if infos[2].Amount != 100000 || !infos[2].Spent || infos[2].Index != 0 {
+		t.Errorf("unexpected genesis amount / spent / index: %+v", infos[2])
+	}
+
+	// 높이 범위 + 커서 페이징 (내림차순)
+	page1, cursor, err := bc.GetAddressTransactions(system.Address, AddressTxQuery{FromHeight: 1, Limit: 1, Desc: true})
+	if err != nil || len(page1) != 1 || cursor == "" || page1[0].Height != 2 {
+		t.Fatalf("page1: entries=%v cursor=%q err=%v", page1, cursor, err)
+	}
+	page2, cursor, err := bc.GetAddressTransactions(system.Address, AddressTxQuery{FromHeight: 1, Limit: 1, Desc: true, Cursor: cursor})
+	if err != nil || len(page2) != 1 || cursor != "" || page2[0].Height != 1 {
+		t.Fatalf("page2: entries=%v cursor=%q err=%v", page2, cursor, err)
+	}
+	// 오름차순
+	asc, _, _ := bc.GetAddressTransactions(system.Address, AddressTxQuery{ToHeight: 1})
+	if len(asc) != 2 || asc[0].Height != 0 || asc[1].Height != 1 {
+		t.Fatalf("unexpected ascending range result: %v", asc)
+	}
+
+	// receiver: 받은 TX 2건
+	received, err := bc.GetAccountReceivedTxList(receiver.Address)
+	if err != nil || len(received) != 2 {
+		t.Fatalf("receiver should have 2 received txs, got %d err=%v", len(received), err)
+	}
+	recvInfos, _, _ := bc.GetAddressTransactions(receiver.Address, AddressTxQuery{})
+	if len(recvInfos) != 2 || recvInfos[0].Amount != 1000 || recvInfos[0].Spent {
+		t.Errorf("unexpected receiver entries: %v", recvInfos)
+	}
+
+	// AddAccountTxReceived 로 추가한 TX도 목록에 포함
+	extra := utils.Hash("extra")
+	if err := bc.AddAccountTxReceived(receiver.Address, extra); err != nil {
+		t.Fatalf("failed to add account tx: %v", err)
+	}
+	received, _ = bc.GetAccountReceivedTxList(receiver.Address)
+	if len(received) != 3 || received[2] != extra {
+		t.Errorf("account tx list should be appended, got %v", received)
+	}
+
+	// 롤백 시 인덱스도 삭제
+	if err := bc.RollbackToHeight(1); err != nil {
+		t.Fatalf("rollback failed: %v", err)
+	}
+	sent, _ := bc.GetAccountSentTxList(system.Address)
+	if len(sent) != 1 {
+		t.Errorf("system should have 1 sent tx after rollback, got %d", len(sent))
+	}
+}
+
+// RBF (replace-by-fee) 테스트
+func TestReplaceByFee(t *testing.T) {
+	system := newTestAccount(t)
+	receiver := newTestAccount(t)
+
+	bc := newTestChain(t, system, 100000)
+
+	// RBF 미신호 TX는 교체 불가
+	finalTx, err := bc.CreateSignedTx(system.Address, receiver.Address, 1000, 1, "", nil, TxTypeGeneral, system.PrivateKey, system.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to create tx: %v", err)
+	}
+	if _, err := bc.AddTxToMempool(finalTx); err != nil {
+		t.Fatalf("failed to add tx: %v", err)
+	}
+	if _, err := bc.BumpTxFee(finalTx.ID, 10, system.PrivateKey, system.PublicKey); err == nil {
+		t.Fatal("non-replaceable tx should not be bumped")
+	}
+	bc.Mempool.Clear()
+
+	// RBF 신호 TX 생성
+	tx, err := bc.CreateReplaceableSignedTx(system.Address, receiver.Address, 1000, 1, "", nil, TxTypeGeneral, system.PrivateKey, system.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to create tx: %v", err)
+	}
+	if _, err := bc.AddTxToMempool(tx); err != nil {
+		t.Fatalf("failed to add tx: %v", err)
+	}
+
+	// 같은 입력을 쓰는 RBF 미신호 충돌 TX 거부 (수수료 동일)
+	if _, err := bc.AddTxToMempool(finalTx); err == nil {
+		t.Fatal("conflicting tx with same fee should be rejected")
+	}
+
+	// 수수료 인상 TX로 교체
+	bumped, err := bc.BumpTxFee(tx.ID, 5, system.PrivateKey, system.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to bump fee: %v", err)
+	}
+	replaced, err := bc.AddTxToMempool(bumped)
+	if err != nil {
+		t.Fatalf("failed to add replacement: %v", err)
+	}
+	if len(replaced) != 1 || replaced[0].ID != tx.ID {
+		t.Fatalf("original tx should be replaced, got %v", replaced)
+	}
+	if bc.Mempool.GetTx(tx.ID) != nil || bc.Mempool.GetTxCount() != 1 {
+		t.Fatal("original tx should be evicted from mempool")
+	}
+	if entry := bc.Mempool.GetTxWithFee(bumped.ID); entry == nil || entry.Fee != 5 {
+		t.Fatalf("replacement should be in mempool with fee 5, got %+v", entry)
+	}
+
+	// 수신자 금액은 유지
+	if bumped.Outputs[0].Address != receiver.Address || bumped.Outputs[0].Amount != 1000 {
+		t.Errorf("recipient output changed: %+v", bumped.Outputs[0])
+	}
+}
+
+// CPFP (child-pays-for-parent) 및 미확인 출력 사용 테스트
+func TestChildPaysForParent(t *testing.T) {
+	system := newTestAccount(t)
+	other := newTestAccount(t)
+	receiver := newTestAccount(t)
+	miner := newTestAccount(t)
+
+	bc := newTestChain(t, system, 100000)
+	genesis, _ := bc.GetBlockByHeight(0)
+
+	// 블록 1: other 계정 자금 확보
+	fundTx, err := bc.CreateSignedTx(system.Address, other.Address, 10000, 1, "", nil, TxTypeGeneral, system.PrivateKey, system.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to create tx: %v", err)
+	}
+	if _, err := bc.AddTxToMempool(fundTx); err != nil {
+		t.Fatalf("failed to add tx: %v", err)
+	}
+	blk1 := bc.SetBlock(genesis.Header.Hash, 1, miner.Address, genesis.Header.Timestamp+1)
+	if _, err := bc.AddBlock(*blk1); err != nil {
+		t.Fatalf("failed to add block 1: %v", err)
+	}
+
+	// 저수수료 부모 TX (system -> receiver)
+	parent, err := bc.CreateSignedTx(system.Address, receiver.Address, 5000, 1, "", nil, TxTypeGeneral, system.PrivateKey, system.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to create parent: %v", err)
+	}
+	if _, err := bc.AddTxToMempool(parent); err != nil {
+		t.Fatalf("failed to add parent: %v", err)
+	}
+
+	// 중간 수수료 경쟁 TX (other -> miner)
+	competitor, err := bc.CreateSignedTx(other.Address, miner.Address, 100, 5, "", nil, TxTypeGeneral, other.PrivateKey, other.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to create competitor: %v", err)
+	}
+	if _, err := bc.AddTxToMempool(competitor); err != nil {
+		t.Fatalf("failed to add competitor: %v", err)
+	}
+
+	// 미확인 부모 출력을 사용하는 고수수료 자식 TX (receiver -> miner)
+	child, err := bc.CreateSignedTx(receiver.Address, miner.Address, 1000, 50, "", nil, TxTypeGeneral, receiver.PrivateKey, receiver.PublicKey)
+	if err != nil {
+		t.Fatalf("receiver should spend unconfirmed output: %v", err)
+	}
+	if child.Inputs[0].TxID != parent.ID {
+		t.Fatalf("child should spend parent output")
+	}
+	if _, err := bc.AddTxToMempool(child); err != nil {
+		t.Fatalf("failed to add child: %v", err)
+	}
+
+	// 부모+자식 패키지가 경쟁 TX보다 먼저 선택되고 부모가 자식보다 앞
+	txs := bc.Mempool.GetTxs()
+	if len(txs) != 3 || txs[0].ID != parent.ID || txs[1].ID != child.ID || txs[2].ID != competitor.ID {
+		t.Fatalf("unexpected selection order")
+	}
+
+	// 블록 2: 같은 블록 안에서 부모 출력을 자식이 사용
+	blk2 := bc.SetBlock(blk1.Header.Hash, 2, miner.Address, genesis.Header.Timestamp+2)
+	if len(blk2.Transactions) != 4 {
+		t.Fatalf("block should include coinbase + 3 txs, got %d", len(blk2.Transactions))
+	}
+	if _, err := bc.AddBlock(*blk2); err != nil {
+		t.Fatalf("failed to add block 2: %v", err)
+	}
+	if bc.Mempool.GetTxCount() != 0 {
+		t.Errorf("mempool should be empty, got %d", bc.Mempool.GetTxCount())
+	}
+
+	// receiver: 5000 - 1000 - 50 = 3950
+	utxos, err := bc.GetUtxoList(receiver.Address, false)
+	if err != nil {
+		t.Fatalf("failed to get utxos: %v", err)
+	}
+	if balance := bc.CalBalanceUtxo(utxos); balance != 3950 {
+		t.Errorf("receiver balance should be 3950, got %d", balance)
+	}
+
+	// 롤백 시 블록 내 생성/사용 UTXO도 정리
+	if err := bc.RollbackToHeight(1); err != nil {
+		t.Fatalf("rollback failed: %v", err)
+	}
+	if _, err := bc.GetUtxoByTxIdAndIdx(parent.ID, 0); err == nil {
+		t.Error("parent output should be removed after rollback")
+	}
+}
+
+// 멤풀 크기 제한 / 만료 / 커밋 후 재검증 테스트
+func TestMempoolLimits(t *testing.T) {
+	// 서로 다른 입력을 쓰는 같은 크기의 TX 생성
+	newTx := func(i byte) *Transaction {
+		tx := setTestTransaction()
+		tx.Inputs[0].TxID[3] = i
+		tx.ID = prt.Hash{}
+		tx.ID = utils.Hash(tx)
+		return tx
+	}
+
+	mp := NewMempoolWithLimits(2, 0, time.Hour)
+	low, high, mid := newTx(1), newTx(2), newTx(3)
+	if err := mp.NewTransactionWithFee(low, 1); err != nil {
+		t.Fatalf("failed to add tx: %v", err)
+	}
+	if err := mp.NewTransactionWithFee(high, 5); err != nil {
+		t.Fatalf("failed to add tx: %v", err)
+	}
+
+	// 가득 찬 상태: 더 높은 수수료율 TX가 최저 수수료율 TX를 밀어냄
+	if err := mp.NewTransactionWithFee(mid, 3); err != nil {
+		t.Fatalf("higher fee rate tx should be accepted: %v", err)
+	}
+	if mp.GetTx(low.ID) != nil || mp.GetTxCount() != 2 {
+		t.Fatal("lowest fee rate tx should be evicted")
+	}
+
+	// 최저 수수료율 이하 TX는 거부
+	if err := mp.NewTransactionWithFee(newTx(4), 1); err == nil {
+		t.Fatal("low fee rate tx should be rejected when mempool is full")
+	}
+
+	// 만료
+	if expired := mp.ExpireTxs(time.Now().Add(2 * time.Hour)); len(expired) != 2 {
+		t.Fatalf("expected 2 expired txs, got %d", len(expired))
+	}
+
+	stats := mp.GetStats()
+	if stats.TxCount != 0 || stats.Bytes != 0 || stats.EvictedFull != 1 || stats.EvictedExpired != 2 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+
+	// 커밋된 블록과 입력이 충돌하는 TX는 제거
+	system := newTestAccount(t)
+	receiver := newTestAccount(t)
+	miner := newTestAccount(t)
+
+	bc := newTestChain(t, system, 100000)
+	genesis, _ := bc.GetBlockByHeight(0)
+
+	committed, err := bc.CreateSignedTx(system.Address, receiver.Address, 1000, 1, "", nil, TxTypeGeneral, system.PrivateKey, system.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to create tx: %v", err)
+	}
+	conflicting, err := bc.CreateSignedTx(system.Address, receiver.Address, 2000, 1, "", nil, TxTypeGeneral, system.PrivateKey, system.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to create tx: %v", err)
+	}
+
+	if _, err := bc.AddTxToMempool(committed); err != nil {
+		t.Fatalf("failed to add tx: %v", err)
+	}
+	blk := bc.SetBlock(genesis.Header.Hash, 1, miner.Address, genesis.Header.Timestamp+1)
+
+	// 다른 노드에서 받은 충돌 TX가 멤풀에 있는 상황
+	bc.Mempool.Clear()
+	if err := bc.Mempool.NewTransactionWithFee(conflicting, 1); err != nil {
+		t.Fatalf("failed to add tx: %v", err)
+	}
+	if _, err := bc.AddBlock(*blk); err != nil {
+		t.Fatalf("failed to add block: %v", err)
+	}
+	if bc.Mempool.GetTxCount() != 0 || bc.Mempool.GetStats().EvictedConflict != 1 {
+		t.Errorf("conflicting tx should be dropped, stats: %+v", bc.Mempool.GetStats())
+	}
+}
+
+// 수수료율(바이트당) 기준 블록 템플릿 선택 테스트
+func TestSelectTxsByFeeRate(t *testing.T) {
+	small := setTestTransaction()
+	big := setTestTransaction()
+	big.Inputs[0].TxID[3] = 1
+	big.Data = make([]byte, 1000)
+	big.ID = prt.Hash{}
+	big.ID = utils.Hash(big)
+
+	mp := NewMempool()
+	if err := mp.NewTransactionWithFee(small, 10); err != nil {
+		t.Fatalf("failed to add tx: %v", err)
+	}
+	if err := mp.NewTransactionWithFee(big, 11); err != nil {
+		t.Fatalf("failed to add tx: %v", err)
+	}
+
+	// 절대 수수료가 낮아도 바이트당 수수료가 높은 작은 TX가 먼저
+	selected, skipped := mp.SelectTxs(1, prt.MaxBlockSize)
+	if len(selected) != 1 || selected[0].Tx.ID != small.ID {
+		t.Fatal("small tx should be selected first")
+	}
+	if len(skipped) != 1 || skipped[0].TxID != big.ID || skipped[0].Reason != TemplateSkipTxCount {
+		t.Fatalf("big tx should be skipped by tx count: %+v", skipped)
+	}
+
+	// 바이트 제한
+	selected, skipped = mp.SelectTxs(10, TxSize(small)+TxSize(big)-1)
+	if len(selected) != 1 || len(skipped) != 1 || skipped[0].Reason != TemplateSkipBlockSize {
+		t.Fatalf("big tx should be skipped by block size: %+v", skipped)
+	}
+
+	// 부모 선택 후 남은 자식의 점수 갱신: 부모(저수수료) + 자식 2개, 독립 TX
+	parent := setTestTransaction()
+	parent.Inputs[0].TxID[3] = 10
+	parent.Outputs = append(parent.Outputs, &TxOutput{Address: parent.Outputs[0].Address, Amount: 1000, TxType: TxTypeGeneral})
+	parent.ID = prt.Hash{}
+	parent.ID = utils.Hash(parent)
+	children := make([]*Transaction, 2)
+	for i := range children {
+		children[i] = setTestTransaction()
+		children[i].Inputs[0].TxID = parent.ID
+		children[i].Inputs[0].OutputIndex = uint64(i)
+		children[i].ID = prt.Hash{}
+		children[i].ID = utils.Hash(children[i])
+	}
+	other := setTestTransaction()
+	other.Inputs[0].TxID[3] = 11
+	other.ID = prt.Hash{}
+	other.ID = utils.Hash(other)
+
+	mp = NewMempool()
+	for _, added := range []struct {
+		tx  *Transaction
+		fee uint64
+	}{{parent, 1}, {children[0], 100}, {children[1], 30}, {other, 20}} {
+		if err := mp.NewTransactionWithFee(added.tx, added.fee); err != nil {
+			t.Fatalf("failed to add tx: %v", err)
+		}
+	}
+	// 부모가 선택된 뒤 두 번째 자식은 자신의 수수료율(30)로 독립 TX(20)보다 먼저
+	selected, _ = mp.SelectTxs(10, prt.MaxBlockSize)
+	order := []prt.Hash{parent.ID, children[0].ID, children[1].ID, other.ID}
+	if len(selected) != len(order) {
+		t.Fatalf("expected %d selected txs, got %d", len(order), len(selected))
+	}
+	for i, txId := range order {
+		if selected[i].Tx.ID != txId {
+			t.Fatalf("unexpected selection order at %d", i)
+		}
+	}
+
+	// 블록 크기 검증
+	if err := ValidateBlockSize([]*Transaction{small, big}); err != nil {
+		t.Errorf("block within size limit rejected: %v", err)
+	}
+	huge := setTestTransaction()
+	huge.Data = make([]byte, prt.MaxBlockSize)
+	if err := ValidateBlockSize([]*Transaction{huge}); err == nil {
+		t.Error("oversized block should be rejected")
+	}
+}
+
+// 수수료 추정 테스트
+func TestEstimateFees(t *testing.T) {
+	system := newTestAccount(t)
+	receiver := newTestAccount(t)
+	miner := newTestAccount(t)
+
+	bc := newTestChain(t, system, 100000)
+	genesis, _ := bc.GetBlockByHeight(0)
+
+	// 멤풀이 비어 있으면 최소 수수료
+	estimate := bc.EstimateFees(0)
+	if estimate.NextBlock.Fee != 1 || estimate.Economy.Fee != 1 || estimate.TxSize != DefaultEstimateTxSize {
+		t.Fatalf("unexpected estimate on empty mempool: %+v", estimate)
+	}
+
+	// 블록에 포함된 TX 수수료율 기록
+	tx, err := bc.CreateSignedTx(system.Address, receiver.Address, 1000, 100, "", nil, TxTypeGeneral, system.PrivateKey, system.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to create tx: %v", err)
+	}
+	if _, err := bc.AddTxToMempool(tx); err != nil {
+		t.Fatalf("failed to add tx: %v", err)
+	}
+	blk := bc.SetBlock(genesis.Header.Hash, 1, miner.Address, genesis.Header.Timestamp+1)
+	if _, err := bc.AddBlock(*blk); err != nil {
+		t.Fatalf("failed to add block: %v", err)
+	}
+	recent := bc.feeEstimator.GetRecentBlocks(1)
+	if len(recent) != 1 || recent[0].Height != 1 || recent[0].TxCount != 1 || recent[0].MinRate != 100/float64(TxSize(tx)) {
+		t.Fatalf("unexpected block fee stats: %+v", recent)
+	}
+
+	// 한 블록에 들어가지 않는 멤풀 적체: 다음 블록은 밀려나는 TX보다 높은 수수료율 필요
+	for i := 0; i < prt.MaxTxsPerBlock; i++ {
+		pending := setTestTransaction()
+		pending.Inputs[0].TxID[3] = byte(i)
+		pending.ID = prt.Hash{}
+		pending.ID = utils.Hash(pending)
+		if err := bc.Mempool.NewTransactionWithFee(pending, uint64(i+1)); err != nil {
+			t.Fatalf("failed to add tx: %v", err)
+		}
+	}
+	estimate = bc.EstimateFees(0)
+	if estimate.NextBlock.FeeRate <= 0 || estimate.NextBlock.Fee <= 1 {
+		t.Errorf("next block should require higher fee under backlog: %+v", estimate.NextBlock)
+	}
+	if estimate.Within3Blocks.FeeRate != 0 || estimate.Economy.Fee != 1 {
+		t.Errorf("slower targets should use minimum fee: %+v %+v", estimate.Within3Blocks, estimate.Economy)
+	}
+
+	// 멤풀 수수료율은 팁 / 멤풀 변경 전까지 캐시
+	revision := bc.feeEstimator.Revision()
+	if bc.feeEstimator.cachedRates(revision, bc.Mempool.Revision()) == nil {
+		t.Error("mempool rates should be cached after estimate")
+	}
+	extra := setTestTransaction()
+	extra.Inputs[0].TxID[4] = 1
+	extra.ID = prt.Hash{}
+	extra.ID = utils.Hash(extra)
+	if err := bc.Mempool.NewTransactionWithFee(extra, 1); err != nil {
+		t.Fatalf("failed to add tx: %v", err)
+	}
+	if bc.feeEstimator.cachedRates(revision, bc.Mempool.Revision()) != nil {
+		t.Error("cached rates should be stale after mempool change")
+	}
+	if cached := bc.EstimateFees(0); cached.NextBlock.FeeRate < estimate.NextBlock.FeeRate {
+		t.Errorf("estimate after mempool change should not drop: %+v", cached.NextBlock)
+	}
+
+	// 롤백 시 기록 삭제
+	if err := bc.RollbackToHeight(0); err != nil {
+		t.Fatalf("rollback failed: %v", err)
+	}
+	if recent := bc.feeEstimator.GetRecentBlocks(FeeHistoryBlocks); len(recent) != 1 || recent[0].Height != 0 {
+		t.Errorf("rolled back block stats should be removed: %+v", recent)
+	}
+}
+
+// 멤풀 스냅샷 저장 / 복원 테스트
+func TestMempoolSnapshot(t *testing.T) {
+	system := newTestAccount(t)
+	receiver := newTestAccount(t)
+
+	bc := newTestChain(t, system, 100000)
+
+	// 유효 TX + 자식 TX (미확인 출력 사용)
+	parent, err := bc.CreateSignedTx(system.Address, receiver.Address, 1000, 1, "", nil, TxTypeGeneral, system.PrivateKey, system.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to create tx: %v", err)
+	}
+	if _, err := bc.AddTxToMempool(parent); err != nil {
+		t.Fatalf("failed to add tx: %v", err)
+	}
+	child, err := bc.CreateSignedTx(receiver.Address, system.Address, 500, 1, "", nil, TxTypeGeneral, receiver.PrivateKey, receiver.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to create child: %v", err)
+	}
+	if _, err := bc.AddTxToMempool(child); err != nil {
+		t.Fatalf("failed to add child: %v", err)
+	}
+	addedAt := bc.Mempool.GetTxWithFee(parent.ID).AddedAt
+
+	// 무효 TX (존재하지 않는 UTXO)
+	if err := bc.Mempool.NewTransactionWithFee(setTestTransaction(), 1); err != nil {
+		t.Fatalf("failed to add tx: %v", err)
+	}
+
+	if err := bc.SaveMempool(); err != nil {
+		t.Fatalf("failed to save snapshot: %v", err)
+	}
+
+	// 재시작 (빈 멤풀)
+	bc.Mempool.Clear()
+	loaded, dropped, err := bc.LoadMempool()
+	if err != nil {
+		t.Fatalf("failed to load snapshot: %v", err)
+	}
+	if loaded != 2 || dropped != 1 {
+		t.Fatalf("expected 2 loaded / 1 dropped, got %d / %d", loaded, dropped)
+	}
+	entry := bc.Mempool.GetTxWithFee(parent.ID)
+	if entry == nil || entry.Fee != 1 || entry.AddedAt != addedAt {
+		t.Errorf("restored entry mismatch: %+v", entry)
+	}
+	if bc.Mempool.GetTx(child.ID) == nil {
+		t.Error("child tx should be restored")
+	}
+}
+
+// 다중 수신자 TX 테스트 (수신자별 출력 + 단일 수수료/잔돈)
+func TestMultiRecipientTx(t *testing.T) {
+	system := newTestAccount(t)
+	bc := newTestChain(t, system, 100000)
+	genesis, err := bc.GetBlockByHeight(0)
+	if err != nil {
+		t.Fatalf("failed to get genesis: %v", err)
+	}
+
+	var recipients []TxRecipient
+	for i := 0; i < 3; i++ {
+		recipients = append(recipients, TxRecipient{Address: newTestAccount(t).Address, Amount: uint64(1000 * (i + 1))})
+	}
+
+	// 잘못된 수신자 목록 거부
+	if _, err := bc.CreateSignedMultiTx(system.Address, nil, 1, "", nil, TxTypeGeneral, system.PrivateKey, system.PublicKey); err == nil {
+		t.Error("empty recipient list should be rejected")
+	}
+	zero := []TxRecipient{recipients[0], {Address: recipients[1].Address, Amount: 0}}
+	if _, err := bc.CreateSignedMultiTx(system.Address, zero, 1, "", nil, TxTypeGeneral, system.PrivateKey, system.PublicKey); err == nil {
+		t.Error("zero amount recipient should be rejected")
+	}
+	overflow := []TxRecipient{{Address: recipients[0].Address, Amount: ^uint64(0)}, recipients[1]}
+	if _, err := bc.CreateSignedMultiTx(system.Address, overflow, 1, "", nil, TxTypeGeneral, system.PrivateKey, system.PublicKey); err == nil {
+		t.Error("overflowing total should be rejected")
+	}
+
+	tx, err := bc.CreateReplaceableSignedMultiTx(system.Address, recipients, 5, "payroll", nil, TxTypeGeneral, system.PrivateKey, system.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to create multi tx: %v", err)
+	}
+
+	// 수신자 순서대로 출력, 잔돈은 마지막
+	if len(tx.Outputs) != len(recipients)+1 {
+		t.Fatalf("expected %d outputs, got %d", len(recipients)+1, len(tx.Outputs))
+	}
+	for i, recipient := range recipients {
+		if tx.Outputs[i].Address != recipient.Address || tx.Outputs[i].Amount != recipient.Amount {
+			t.Errorf("output[%d] mismatch: %+v", i, tx.Outputs[i])
+		}
+	}
+	if change := tx.Outputs[len(recipients)]; change.Address != system.Address || change.Amount != 100000-6000-5 {
+		t.Errorf("unexpected change output: %+v", change)
+	}
+
+	if _, err := bc.AddTxToMempool(tx); err != nil {
+		t.Fatalf("failed to add tx: %v", err)
+	}
+	if entry := bc.Mempool.GetTxWithFee(tx.ID); entry == nil || entry.Fee != 5 {
+		t.Fatalf("multi tx should pay single fee 5, got %+v", entry)
+	}
+
+	// 수수료 인상 시 수신자 출력 유지, 잔돈에서 차감
+	bumped, err := bc.BumpTxFee(tx.ID, 20, system.PrivateKey, system.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to bump fee: %v", err)
+	}
+	for i, recipient := range recipients {
+		if bumped.Outputs[i].Address != recipient.Address || bumped.Outputs[i].Amount != recipient.Amount {
+			t.Errorf("bumped output[%d] changed: %+v", i, bumped.Outputs[i])
+		}
+	}
+	if _, err := bc.AddTxToMempool(bumped); err != nil {
+		t.Fatalf("failed to add replacement: %v", err)
+	}
+
+	blk := bc.SetBlock(genesis.Header.Hash, 1, system.Address, time.Now().Unix())
+	if len(blk.Transactions) != 2 {
+		t.Fatalf("expected coinbase + multi tx, got %d txs", len(blk.Transactions))
+	}
+	if _, err := bc.AddBlock(*blk); err != nil {
+		t.Fatalf("failed to add block: %v", err)
+	}
+
+	for i, recipient := range recipients {
+		if balance, _ := bc.GetBalance(recipient.Address); balance != recipient.Amount {
+			t.Errorf("recipient[%d] balance: expected %d, got %d", i, recipient.Amount, balance)
+		}
+	}
+	// 잔돈 + 블록 보상 + 수수료 (제안자 = system)
+	if balance, _ := bc.GetBalance(system.Address); balance != 100000-6000+50 {
+		t.Errorf("unexpected sender balance: %d", balance)
+	}
+}
+
+// 코인 선택 전략 테스트
+func TestSelectCoins(t *testing.T) {
+	var utxos []*UTXO
+	for i, amount := range []uint64{20, 5, 100, 10, 50} {
+		utxos = append(utxos, &UTXO{TxId: prt.Hash{byte(i + 1)}, TxOut: TxOutput{Amount: amount}})
+	}
+	amounts := func(selection *CoinSelection) []uint64 {
+		var result []uint64
+		for _, utxo := range selection.Utxos {
+			result = append(result, utxo.TxOut.Amount)
+		}
+		return result
+	}
+
+	// largest: 입력 수 최소
+	selection, err := SelectCoins(CoinSelectLargestFirst, utxos, 60, 0)
+	if err != nil || !reflect.DeepEqual(amounts(selection), []uint64{100}) || selection.Change != 40 {
+		t.Errorf("largest: unexpected selection %v, err=%v", selection, err)
+	}
+
+	// smallest: 작은 UTXO부터 정리
+	selection, err = SelectCoins(CoinSelectSmallestFirst, utxos, 30, 0)
+	if err != nil || !reflect.DeepEqual(amounts(selection), []uint64{5, 10, 20}) || selection.Change != 5 {
+		t.Errorf("smallest: unexpected selection %v, err=%v", selection, err)
+	}
+
+	// bnb: 정확히 일치 -> 잔돈 없음
+	selection, err = SelectCoins(CoinSelectBranchAndBound, utxos, 70, 0)
+	if err != nil || selection.Strategy != CoinSelectBranchAndBound || selection.Total != 70 || selection.Change != 0 {
+		t.Errorf("bnb: unexpected selection %v, err=%v", selection, err)
+	}
+
+	// bnb: 허용 초과분 이내면 수수료로 흡수
+	selection, err = SelectCoins(CoinSelectBranchAndBound, utxos, 74, 1)
+	if err != nil || selection.Total != 75 || selection.Excess != 1 || selection.Change != 0 {
+		t.Errorf("bnb with excess: unexpected selection %v, err=%v", selection, err)
+	}
+
+	// bnb: 일치 조합이 없으면 largest로 대체
+	selection, err = SelectCoins("", utxos, 36, 0)
+	if err != nil || selection.Strategy != CoinSelectLargestFirst || selection.Change != 64 {
+		t.Errorf("bnb fallback: unexpected selection %v, err=%v", selection, err)
+	}
+
+	// random: 금액만 충족
+	selection, err = SelectCoins(CoinSelectRandom, utxos, 150, 0)
+	if err != nil || selection.Total < 150 || selection.Total-150 != selection.Change {
+		t.Errorf("random: unexpected selection %v, err=%v", selection, err)
+	}
+
+	// 사용된 UTXO 제외, 잔액 부족 / 알 수 없는 전략 거부
+	utxos[2].Spent = true
+	if _, err := SelectCoins(CoinSelectLargestFirst, utxos, 100, 0); err == nil {
+		t.Error("spent utxo should not be selected")
+	}
+	if _, err := SelectCoins("unknown", utxos, 10, 0); err == nil {
+		t.Error("unknown strategy should be rejected")
+	}
+
+	// 체인: 정확한 금액 전송은 잔돈 출력 없이 생성
+	system := newTestAccount(t)
+	receiver := newTestAccount(t)
+	bc := newTestChain(t, system, 100000)
+
+	recipients := []TxRecipient{{Address: receiver.Address, Amount: 99999}}
+	tx, selection, err := bc.BuildSignedTx(system.Address, recipients, 1, "", nil, TxTypeGeneral, TxBuildOptions{}, system.PrivateKey, system.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to build tx: %v", err)
+	}
+	if selection.Strategy != CoinSelectBranchAndBound || len(tx.Outputs) != 1 {
+		t.Errorf("exact match should not create change: strategy=%s outputs=%d", selection.Strategy, len(tx.Outputs))
+	}
+	if _, err := bc.AddTxToMempool(tx); err != nil {
+		t.Fatalf("failed to add tx: %v", err)
+	}
+}
+
+// UTXO 통합 (sweep) 테스트
+func TestSweepUtxos(t *testing.T) {
+	system := newTestAccount(t)
+	receiver := newTestAccount(t)
+	bc := newTestChain(t, system, 100000)
+	genesis, err := bc.GetBlockByHeight(0)
+	if err != nil {
+		t.Fatalf("failed to get genesis: %v", err)
+	}
+
+	// receiver에게 작은 UTXO 30개 지급
+	var recipients []TxRecipient
+	for i := 0; i < 30; i++ {
+		recipients = append(recipients, TxRecipient{Address: receiver.Address, Amount: 100})
+	}
+	payout, err := bc.CreateSignedMultiTx(system.Address, recipients, 1, "", nil, TxTypeGeneral, system.PrivateKey, system.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to create payout: %v", err)
+	}
+	if _, err := bc.AddTxToMempool(payout); err != nil {
+		t.Fatalf("failed to add payout: %v", err)
+	}
+	ts := time.Now().Unix()
+	blk1 := bc.SetBlock(genesis.Header.Hash, 1, system.Address, ts)
+	if _, err := bc.AddBlock(*blk1); err != nil {
+		t.Fatalf("failed to add block: %v", err)
+	}
+
+	// 10개씩 최대 2개 TX -> 10개 남음
+	result, err := bc.SweepUtxos(receiver.Address, SweepOptions{MaxInputsPerTx: 10, MaxTxs: 2, Fee: 5}, receiver.PrivateKey, receiver.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to sweep: %v", err)
+	}
+	if len(result.Txs) != 2 || result.Swept != 20 || result.Remaining != 10 || result.Amount != 2*(1000-5) || result.Fees != 10 {
+		t.Fatalf("unexpected sweep result: %+v", result)
+	}
+	for _, tx := range result.Txs {
+		if len(tx.Inputs) != 10 || len(tx.Outputs) != 1 || tx.Outputs[0].Address != receiver.Address {
+			t.Errorf("unexpected sweep tx shape: %d inputs, %d outputs", len(tx.Inputs), len(tx.Outputs))
+		}
+		if bc.Mempool.GetTx(tx.ID) == nil {
+			t.Error("sweep tx should be in mempool")
+		}
+	}
+
+	// 미확인 출력(통합 결과)은 다시 통합하지 않음, 금액 상한 필터
+	result, err = bc.SweepUtxos(receiver.Address, SweepOptions{MaxUtxoAmount: 100}, receiver.PrivateKey, receiver.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to sweep remaining: %v", err)
+	}
+	if len(result.Txs) != 1 || result.Swept != 10 || result.Remaining != 0 || result.Fees != bc.GetMinFee() {
+		t.Fatalf("unexpected second sweep result: %+v", result)
+	}
+
+	blk2 := bc.SetBlock(blk1.Header.Hash, 2, system.Address, ts+1)
+	if _, err := bc.AddBlock(*blk2); err != nil {
+		t.Fatalf("failed to add block: %v", err)
+	}
+	utxos, err := bc.GetUtxoList(receiver.Address, false)
+	if err != nil {
+		t.Fatalf("failed to get utxos: %v", err)
+	}
+	if len(utxos) != 3 {
+		t.Errorf("expected 3 consolidated UTXOs, got %d", len(utxos))
+	}
+	if balance, _ := bc.GetBalance(receiver.Address); balance != 3000-10-bc.GetMinFee() {
+		t.Errorf("unexpected balance after sweep: %d", balance)
+	}
+
+	// 마지막 배치에 UTXO가 하나뿐이면 같은 주소로 보내는 TX는 만들지 않음
+	result, err = bc.SweepUtxos(receiver.Address, SweepOptions{MaxInputsPerTx: 2}, receiver.PrivateKey, receiver.PublicKey)
+	if err != nil || len(result.Txs) != 1 || result.Swept != 2 {
+		t.Fatalf("expected single sweep of two UTXOs, got %+v err=%v", result, err)
+	}
+}
+
+// 타임락 출력 테스트 (절대 높이 / 절대 시간 / 상대 높이)
+func TestTimelockedOutputs(t *testing.T) {
+	system := newTestAccount(t)
+	heightLocked := newTestAccount(t)
+	relativeLocked := newTestAccount(t)
+	timeLocked := newTestAccount(t)
+	bc := newTestChain(t, system, 100000)
+	genesis, err := bc.GetBlockByHeight(0)
+	if err != nil {
+		t.Fatalf("failed to get genesis: %v", err)
+	}
+
+	now := time.Now().Unix()
+	recipients := []TxRecipient{
+		{Address: heightLocked.Address, Amount: 1000, LockHeight: 3},
+		{Address: relativeLocked.Address, Amount: 2000, RelativeLockHeight: 2},
+		{Address: timeLocked.Address, Amount: 3000, LockTime: now + 3600},
+	}
+	payout, err := bc.CreateSignedMultiTx(system.Address, recipients, 1, "vesting", nil, TxTypeGeneral, system.PrivateKey, system.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to create payout: %v", err)
+	}
+	if _, err := bc.AddTxToMempool(payout); err != nil {
+		t.Fatalf("failed to add payout: %v", err)
+	}
+
+	// 미확인 상대 타임락 출력은 사용 불가
+	if utxos, _ := bc.GetUtxoList(relativeLocked.Address, true); len(utxos) != 0 {
+		t.Error("unconfirmed relative locked output should not be spendable")
+	}
+
+	blk1 := bc.SetBlock(genesis.Header.Hash, 1, system.Address, now)
+	if _, err := bc.AddBlock(*blk1); err != nil {
+		t.Fatalf("failed to add block: %v", err)
+	}
+
+	// 잠긴 출력은 잔액에서 제외, locked 잔액으로 집계
+	for i, recipient := range recipients {
+		if balance, _ := bc.GetBalance(recipient.Address); balance != 0 {
+			t.Errorf("recipient[%d]: locked output counted as balance %d", i, balance)
+		}
+		if locked, _ := bc.GetLockedBalance(recipient.Address); locked != recipient.Amount {
+			t.Errorf("recipient[%d]: expected locked balance %d, got %d", i, recipient.Amount, locked)
+		}
+	}
+
+	// 잠긴 출력을 직접 사용하는 TX 생성
+	spendOutput := func(owner *testAccount, outputIndex uint64, amount uint64) *Transaction {
+		tx := &Transaction{
+			Version:   bc.cfg.Version.Transaction,
+			NetworkID: bc.cfg.Common.NetworkID,
+			Timestamp: now,
+			Inputs:    []*TxInput{{TxID: payout.ID, OutputIndex: outputIndex, PublicKey: owner.PublicKey}},
+			Outputs:   []*TxOutput{{Address: system.Address, Amount: amount - 1}},
+			Data:      []byte{},
+		}
+		if err := signTx(tx, owner.PrivateKey); err != nil {
+			t.Fatalf("failed to sign tx: %v", err)
+		}
+		return tx
+	}
+	heightSpend := spendOutput(heightLocked, 0, 1000)
+	relativeSpend := spendOutput(relativeLocked, 1, 2000)
+	timeSpend := spendOutput(timeLocked, 2, 3000)
+
+	// 다음 블록 높이 2: 모두 잠김
+	for i, tx := range []*Transaction{heightSpend, relativeSpend, timeSpend} {
+		if _, err := bc.AddTxToMempool(tx); err == nil {
+			t.Errorf("spend[%d]: locked output should be rejected", i)
+		}
+	}
+
+	blk2 := bc.SetBlock(blk1.Header.Hash, 2, system.Address, now+1)
+	if _, err := bc.AddBlock(*blk2); err != nil {
+		t.Fatalf("failed to add block: %v", err)
+	}
+
+	// 다음 블록 높이 3: 높이 락 / 상대 락(1 + 2) 해제, 시간 락은 유지
+	if balance, _ := bc.GetBalance(heightLocked.Address); balance != 1000 {
+		t.Errorf("height lock should be released, balance %d", balance)
+	}
+	if balance, _ := bc.GetBalance(relativeLocked.Address); balance != 2000 {
+		t.Errorf("relative lock should be released, balance %d", balance)
+	}
+	if _, err := bc.AddTxToMempool(heightSpend); err != nil {
+		t.Errorf("height unlocked spend rejected: %v", err)
+	}
+	if _, err := bc.AddTxToMempool(relativeSpend); err != nil {
+		t.Errorf("relative unlocked spend rejected: %v", err)
+	}
+	if _, err := bc.AddTxToMempool(timeSpend); err == nil {
+		t.Error("time locked spend should be rejected")
+	}
+
+	// 블록 검증은 블록 타임스탬프 기준
+	if err := bc.validateTransaction(timeSpend, nil, false, spendContext{Height: 3, Time: now + 3600}); err != nil {
+		t.Errorf("time lock should be released at block time: %v", err)
+	}
+	if err := bc.validateTransaction(heightSpend, nil, false, spendContext{Height: 2, Time: now}); err == nil {
+		t.Error("height lock should apply to block at height 2")
+	}
+}
+
+// 멀티시그 (2-of-3) 주소 생성, 서명 수집, 검증 테스트
+func TestMultisig(t *testing.T) {
+	system := newTestAccount(t)
+	parties := []*testAccount{newTestAccount(t), newTestAccount(t), newTestAccount(t)}
+	recipient := newTestAccount(t)
+	bc := newTestChain(t, system, 100000)
+	genesis, err := bc.GetBlockByHeight(0)
+	if err != nil {
+		t.Fatalf("failed to get genesis: %v", err)
+	}
+
+	keys := [][]byte{parties[0].PublicKey, parties[1].PublicKey, parties[2].PublicKey}
+	script, err := NewMultiSigScript(2, keys)
+	if err != nil {
+		t.Fatalf("failed to create script: %v", err)
+	}
+	multisigAddr, err := script.Address()
+	if err != nil {
+		t.Fatalf("failed to derive address: %v", err)
+	}
+
+	// 키 순서와 무관하게 같은 주소, 임계값이 다르면 다른 주소
+	reordered, _ := NewMultiSigScript(2, [][]byte{keys[2], keys[0], keys[1]})
+	if addr, _ := reordered.Address(); addr != multisigAddr {
+		t.Error("address should not depend on key order")
+	}
+	other, _ := NewMultiSigScript(3, keys)
+	if addr, _ := other.Address(); addr == multisigAddr {
+		t.Error("different threshold should give different address")
+	}
+	if _, err := NewMultiSigScript(4, keys); err == nil {
+		t.Error("threshold above key count should be rejected")
+	}
+	if _, err := NewMultiSigScript(1, [][]byte{keys[0], keys[0]}); err == nil {
+		t.Error("duplicate keys should be rejected")
+	}
+
+	// 멀티시그 주소로 입금 후 확정
+	fund, err := bc.CreateSignedTx(system.Address, multisigAddr, 5000, 1, "treasury", nil, TxTypeGeneral, system.PrivateKey, system.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to create funding tx: %v", err)
+	}
+	if _, err := bc.AddTxToMempool(fund); err != nil {
+		t.Fatalf("failed to add funding tx: %v", err)
+	}
+	now := time.Now().Unix()
+	blk1 := bc.SetBlock(genesis.Header.Hash, 1, system.Address, now)
+	if _, err := bc.AddBlock(*blk1); err != nil {
+		t.Fatalf("failed to add block: %v", err)
+	}
+
+	tx, _, err := bc.CreateMultisigTx(script, []TxRecipient{{Address: recipient.Address, Amount: 1200}}, 1, "", nil, TxTypeGeneral)
+	if err != nil {
+		t.Fatalf("failed to create multisig tx: %v", err)
+	}
+	if GetMultisigStatus(tx).Complete {
+		t.Error("unsigned tx should not be complete")
+	}
+
+	// 각 서명자는 인코딩된 TX 사본에 서명 (서명은 TX 해시에 포함되지 않음)
+	encoded, err := EncodeTxHex(tx)
+	if err != nil {
+		t.Fatalf("failed to encode tx: %v", err)
+	}
+	signCopy := func(party *testAccount) *Transaction {
+		copyTx, err := DecodeTxHex(encoded)
+		if err != nil {
+			t.Fatalf("failed to decode tx: %v", err)
+		}
+		if _, err := SignMultisigTx(copyTx, party.PrivateKey, party.PublicKey); err != nil {
+			t.Fatalf("failed to sign: %v", err)
+		}
+		return copyTx
+	}
+	copyA := signCopy(parties[0])
+	copyC := signCopy(parties[2])
+
+	// 서명 1개는 임계값 미달
+	if _, err := bc.AddTxToMempool(copyA); err == nil {
+		t.Error("tx with 1 of 2 signatures should be rejected")
+	}
+
+	// 참여자가 아닌 키는 서명 불가
+	if _, err := SignMultisigTx(signCopy(parties[1]), recipient.PrivateKey, recipient.PublicKey); err == nil {
+		t.Error("non member key should not sign")
+	}
+
+	// 키 수보다 많은 서명 슬롯은 서명 / 디코딩 전에 거부 (패닉 없음)
+	oversized, _ := DecodeTxHex(encoded)
+	oversized.Inputs[0].Signatures = make([]prt.Signature, len(script.PublicKeys)+1)
+	if _, err := SignMultisigTx(oversized, parties[0].PrivateKey, parties[0].PublicKey); err == nil {
+		t.Error("input with more signature slots than keys should not be signed")
+	}
+	if _, err := CombineMultisigTxs([]*Transaction{copyA, oversized}); err == nil {
+		t.Error("input with more signature slots than keys should not be combined")
+	}
+	oversizedHex, _ := EncodeTxHex(oversized)
+	if _, err := DecodeTxHex(oversizedHex); err == nil {
+		t.Error("input with more signature slots than keys should not decode")
+	}
+
+	combined, err := CombineMultisigTxs([]*Transaction{copyA, copyC})
+	if err != nil {
+		t.Fatalf("failed to combine: %v", err)
+	}
+	if status := GetMultisigStatus(combined); !status.Complete || status.Signed[0] != 2 {
+		t.Errorf("expected complete 2 of 2, got %+v", status)
+	}
+
+	// 서명 후 출력 변조 시 거부
+	tampered, _ := DecodeTxHex(encoded)
+	tampered.Inputs = copyA.Inputs
+	tampered.Outputs[0].Address = system.Address
+	if _, err := bc.AddTxToMempool(tampered); err == nil {
+		t.Error("tampered multisig tx should be rejected")
+	}
+
+	if _, err := bc.AddTxToMempool(combined); err != nil {
+		t.Fatalf("combined multisig tx rejected: %v", err)
+	}
+	blk2 := bc.SetBlock(blk1.Header.Hash, 2, system.Address, now+1)
+	if _, err := bc.AddBlock(*blk2); err != nil {
+		t.Fatalf("failed to add block with multisig tx: %v", err)
+	}
+
+	if balance, _ := bc.GetBalance(recipient.Address); balance != 1200 {
+		t.Errorf("expected recipient balance 1200, got %d", balance)
+	}
+	if balance, _ := bc.GetBalance(multisigAddr); balance != 5000-1200-1 {
+		t.Errorf("expected multisig change %d, got %d", 5000-1200-1, balance)
+	}
+}
+
+// 부분 서명 TX (오프라인 서명) 테스트
+func TestPartialTx(t *testing.T) {
+	system := newTestAccount(t)
+	owner := newTestAccount(t)
+	recipient := newTestAccount(t)
+	bc := newTestChain(t, system, 100000)
+	genesis, err := bc.GetBlockByHeight(0)
+	if err != nil {
+		t.Fatalf("failed to get genesis: %v", err)
+	}
+
+	// owner 계정에 UTXO 2개 입금
+	fund, err := bc.CreateSignedMultiTx(system.Address, []TxRecipient{
+		{Address: owner.Address, Amount: 700},
+		{Address: owner.Address, Amount: 800},
+	}, 1, "", nil, TxTypeGeneral, system.PrivateKey, system.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to create funding tx: %v", err)
+	}
+	if _, err := bc.AddTxToMempool(fund); err != nil {
+		t.Fatalf("failed to add funding tx: %v", err)
+	}
+	now := time.Now().Unix()
+	blk1 := bc.SetBlock(genesis.Header.Hash, 1, system.Address, now)
+	if _, err := bc.AddBlock(*blk1); err != nil {
+		t.Fatalf("failed to add block: %v", err)
+	}
+
+	// 노드는 공개키만으로 미서명 TX 생성
+	spender := TxSpender{PublicKey: owner.PublicKey}
+	ptx, _, err := bc.CreatePartialTx(spender, []TxRecipient{{Address: recipient.Address, Amount: 1200}}, 5, "offline", nil, TxTypeGeneral, TxBuildOptions{})
+	if err != nil {
+		t.Fatalf("failed to create partial tx: %v", err)
+	}
+	if len(ptx.Inputs) != 2 || len(ptx.Tx.Inputs) != 2 {
+		t.Fatalf("expected 2 inputs, got %d", len(ptx.Inputs))
+	}
+	if fee, err := ptx.Fee(); err != nil || fee != 5 {
+		t.Errorf("expected fee 5, got %d (%v)", fee, err)
+	}
+	if err := bc.CheckPartialTxInputs(ptx); err != nil {
+		t.Errorf("spent outputs should match chain: %v", err)
+	}
+	if _, err := ptx.Finalize(); err == nil {
+		t.Error("unsigned partial tx should not finalize")
+	}
+
+	// 오프라인 서명자는 인코딩된 PSBT만 받음
+	encoded, err := EncodePartialTx(ptx)
+	if err != nil {
+		t.Fatalf("failed to encode partial tx: %v", err)
+	}
+	offline, err := DecodePartialTx(encoded)
+	if err != nil {
+		t.Fatalf("failed to decode partial tx: %v", err)
+	}
+
+	// 입력 금액 변조 시 (수수료 속이기) 검증 실패
+	tampered, _ := DecodePartialTx(encoded)
+	tampered.Inputs[0].Output.Address = recipient.Address
+	if err := tampered.Validate(); err == nil {
+		t.Error("spent output owned by another address should be rejected")
+	}
+	tampered, _ = DecodePartialTx(encoded)
+	tampered.Inputs[1].TxID = prt.Hash{}
+	if err := tampered.Validate(); err == nil {
+		t.Error("spent output not matching input should be rejected")
+	}
+	tampered, _ = DecodePartialTx(encoded)
+	tampered.Inputs[0].Output.Amount = 100000
+	if err := bc.CheckPartialTxInputs(tampered); err == nil {
+		t.Error("spent output amount not matching chain should be rejected")
+	}
+
+	// 오프라인에서도 이전 TX로 금액 확인 (이전 TX까지 변조하면 TX ID가 맞지 않음)
+	if err := tampered.Validate(); err == nil {
+		t.Error("spent output amount not matching previous tx should be rejected offline")
+	}
+	if _, err := tampered.Fee(); err == nil {
+		t.Error("fee should not be computed from unverified amounts")
+	}
+	tampered.Inputs[0].PrevTx.Outputs[tampered.Inputs[0].OutputIndex].Amount = 100000
+	if err := tampered.Validate(); err == nil {
+		t.Error("previous tx not matching input tx id should be rejected")
+	}
+	tampered, _ = DecodePartialTx(encoded)
+	tampered.Inputs[1].PrevTx = nil
+	if err := tampered.Validate(); err == nil {
+		t.Error("partial tx without previous tx should be rejected")
+	}
+
+	if _, err := offline.Sign(recipient.PrivateKey, recipient.PublicKey); err == nil {
+		t.Error("key not owning any input should not sign")
+	}
+	if signed, err := offline.Sign(owner.PrivateKey, owner.PublicKey); err != nil || signed != 2 {
+		t.Fatalf("expected 2 inputs signed, got %d (%v)", signed, err)
+	}
+
+	// 서명본 병합 (서명되지 않은 사본 + 서명본)
+	combined, err := CombinePartialTxs([]*PartialTx{ptx, offline})
+	if err != nil {
+		t.Fatalf("failed to combine: %v", err)
+	}
+	if !combined.Status().Complete {
+		t.Fatalf("combined partial tx should be complete: %+v", combined.Status())
+	}
+
+	final, err := combined.Finalize()
+	if err != nil {
+		t.Fatalf("failed to finalize: %v", err)
+	}
+	if final.ID != ptx.Tx.ID {
+		t.Error("signing should not change tx id")
+	}
+	if _, err := bc.AddTxToMempool(final); err != nil {
+		t.Fatalf("finalized tx rejected: %v", err)
+	}
+
+	blk2 := bc.SetBlock(blk1.Header.Hash, 2, system.Address, now+1)
+	if _, err := bc.AddBlock(*blk2); err != nil {
+		t.Fatalf("failed to add block: %v", err)
+	}
+	if balance, _ := bc.GetBalance(recipient.Address); balance != 1200 {
+		t.Errorf("expected recipient balance 1200, got %d", balance)
+	}
+	if balance, _ := bc.GetBalance(owner.Address); balance != 1500-1200-5 {
+		t.Errorf("expected owner change %d, got %d", 1500-1200-5, balance)
+	}
+	if err := bc.CheckPartialTxInputs(ptx); err == nil {
+		t.Error("spent inputs should fail chain check")
+	}
+}
+
+func TestHTLC(t *testing.T) {
+	system := newTestAccount(t)
+	alice := newTestAccount(t) // 잠금 / 환불 측
+	bob := newTestAccount(t)   // preimage로 클레임하는 측
+	bc := newTestChain(t, system, 100000)
+	genesis, err := bc.GetBlockByHeight(0)
+	if err != nil {
+		t.Fatalf("failed to get genesis: %v", err)
+	}
+
+	preimage := []byte("atomic swap secret")
+	lock := &HashLock{Hash: HashPreimage(preimage), Recipient: bob.Address, Refund: alice.Address, Expiry: 3}
+
+	// 같은 조건의 HTLC 출력 2개 (하나는 클레임, 하나는 환불)
+	lockTx, err := bc.CreateSignedMultiTx(system.Address, []TxRecipient{
+		{Amount: 1000, HTLC: lock},
+		{Amount: 2000, HTLC: lock},
+	}, 1, "", nil, TxTypeGeneral, system.PrivateKey, system.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to create lock tx: %v", err)
+	}
+	if lockTx.Outputs[0].Address != lock.Address() || lockTx.Outputs[0].TxType != TxTypeHTLC {
+		t.Fatal("HTLC output should be paid to contract address")
+	}
+	if _, err := bc.AddTxToMempool(lockTx); err != nil {
+		t.Fatalf("failed to add lock tx: %v", err)
+	}
+
+	now := time.Now().Unix()
+	blk1 := bc.SetBlock(genesis.Header.Hash, 1, system.Address, now)
+	if _, err := bc.AddBlock(*blk1); err != nil {
+		t.Fatalf("failed to add block: %v", err)
+	}
+
+	// 잘못된 preimage / 서명자 / 만료 전 환불은 거부
+	if _, err := bc.BuildHTLCClaimTx(lockTx.ID, 0, []byte("wrong"), bob.Address, 1, bob.PrivateKey, bob.PublicKey); err == nil {
+		t.Error("claim with wrong preimage should fail")
+	}
+	if _, err := bc.BuildHTLCClaimTx(lockTx.ID, 0, preimage, alice.Address, 1, alice.PrivateKey, alice.PublicKey); err == nil {
+		t.Error("claim signed by refund address should fail")
+	}
+	if _, err := bc.BuildHTLCRefundTx(lockTx.ID, 1, alice.Address, 1, alice.PrivateKey, alice.PublicKey); err == nil {
+		t.Error("refund before expiry should fail")
+	}
+	forged := &Transaction{
+		Version:   bc.cfg.Version.Transaction,
+		NetworkID: bc.cfg.Common.NetworkID,
+		Timestamp: now,
+		Inputs:    []*TxInput{{TxID: lockTx.ID, OutputIndex: 0, PublicKey: bob.PublicKey, Preimage: []byte("wrong")}},
+		Outputs:   []*TxOutput{{Address: bob.Address, Amount: 999}},
+		Data:      []byte{},
+	}
+	if err := signTx(forged, bob.PrivateKey); err != nil {
+		t.Fatalf("failed to sign tx: %v", err)
+	}
+	if _, err := bc.AddTxToMempool(forged); err == nil {
+		t.Error("mempool should reject wrong preimage")
+	}
+
+	// 만료 전 (다음 블록 높이 2) 클레임 성공, preimage 공개
+	claim, err := bc.BuildHTLCClaimTx(lockTx.ID, 0, preimage, bob.Address, 1, bob.PrivateKey, bob.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to create claim: %v", err)
+	}
+	if _, err := bc.AddTxToMempool(claim); err != nil {
+		t.Fatalf("claim rejected: %v", err)
+	}
+	if status, err := bc.GetHTLC(lockTx.ID, 0); err != nil || string(status.Preimage) != string(preimage) {
+		t.Errorf("pending claim should reveal preimage: %+v, %v", status, err)
+	}
+
+	blk2 := bc.SetBlock(blk1.Header.Hash, 2, system.Address, now+1)
+	if _, err := bc.AddBlock(*blk2); err != nil {
+		t.Fatalf("failed to add block: %v", err)
+	}
+	if balance, _ := bc.GetBalance(bob.Address); balance != 999 {
+		t.Errorf("expected bob balance 999, got %d", balance)
+	}
+	status, err := bc.GetHTLC(lockTx.ID, 0)
+	if err != nil {
+		t.Fatalf("failed to get HTLC: %v", err)
+	}
+	if !status.Confirmed || status.Refunded || string(status.Preimage) != string(preimage) {
+		t.Errorf("confirmed claim should reveal preimage: %+v", status)
+	}
+
+	// 만료 후 (다음 블록 높이 3) 클레임 거부, 환불 성공
+	if _, err := bc.BuildHTLCClaimTx(lockTx.ID, 1, preimage, bob.Address, 1, bob.PrivateKey, bob.PublicKey); err == nil {
+		t.Error("claim after expiry should fail")
+	}
+	refund, err := bc.BuildHTLCRefundTx(lockTx.ID, 1, alice.Address, 1, alice.PrivateKey, alice.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to create refund: %v", err)
+	}
+	if _, err := bc.AddTxToMempool(refund); err != nil {
+		t.Fatalf("refund rejected: %v", err)
+	}
+
+	blk3 := bc.SetBlock(blk2.Header.Hash, 3, system.Address, now+2)
+	if _, err := bc.AddBlock(*blk3); err != nil {
+		t.Fatalf("failed to add block: %v", err)
+	}
+	if balance, _ := bc.GetBalance(alice.Address); balance != 1999 {
+		t.Errorf("expected alice balance 1999, got %d", balance)
+	}
+	if status, _ := bc.GetHTLC(lockTx.ID, 1); status == nil || !status.Refunded || !status.Expired {
+		t.Errorf("expected refunded HTLC: %+v", status)
+	}
+}
+
+// 스테이킹 / 언스테이킹 테스트 (스테이크 인덱스, 언본딩, 롤백 후 재계산)
+func TestStaking(t *testing.T) {
+	system := newTestAccount(t)
+	miner := newTestAccount(t)
+	bob := newTestAccount(t)
+	bc := newTestChainWith(t, system, 100000, func(cfg *config.Config) {
+		cfg.Consensus.UnbondingBlocks = 2
+	})
+	genesis, err := bc.GetBlockByHeight(0)
+	if err != nil {
+		t.Fatalf("failed to get genesis: %v", err)
+	}
+	now := time.Now().Unix()
+
+	// 다른 주소로 가는 스테이킹 출력은 거부
+	otherStake, _, err := bc.BuildSignedTx(system.Address, []TxRecipient{{Address: bob.Address, Amount: 1000}}, 1, "", nil, TxTypeStaking, TxBuildOptions{}, system.PrivateKey, system.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to create tx: %v", err)
+	}
+	if _, err := bc.AddTxToMempool(otherStake); err == nil {
+		t.Error("staking output paid to another address should be rejected")
+	}
+
+	// 블록 1: system 5000 스테이킹 (거스름돈은 일반 출력)
+	stakeTx, _, err := bc.BuildStakeTx(system.Address, 5000, 1, system.PrivateKey, system.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to create stake tx: %v", err)
+	}
+	if stakeTx.Outputs[1].TxType != TxTypeGeneral {
+		t.Errorf("change of stake tx should be general, got type %d", stakeTx.Outputs[1].TxType)
+	}
+	if _, err := bc.AddTxToMempool(stakeTx); err != nil {
+		t.Fatalf("stake tx rejected: %v", err)
+	}
+	blk1 := bc.SetBlock(genesis.Header.Hash, 1, miner.Address, now)
+	if _, err := bc.AddBlock(*blk1); err != nil {
+		t.Fatalf("failed to add block: %v", err)
+	}
+
+	if balance, _ := bc.GetBalance(system.Address); balance != 94999 {
+		t.Errorf("staked output should not be spendable: balance %d", balance)
+	}
+	if staked, _ := bc.GetStakedBalance(system.Address); staked != 5000 {
+		t.Errorf("expected staked 5000, got %d", staked)
+	}
+	snapshot, err := bc.GetStakeSnapshot()
+	if err != nil {
+		t.Fatalf("failed to get stake snapshot: %v", err)
+	}
+	if len(snapshot.Stakes) != 1 || snapshot.Stakes[0].Amount != 5000 || snapshot.Stakes[0].Height != 1 ||
+		string(snapshot.Stakes[0].PublicKey) != string(system.PublicKey) || snapshot.Height != 1 {
+		t.Fatalf("unexpected stake snapshot: %+v", snapshot)
+	}
+
+	// 스테이킹 출력을 일반 전송으로 사용하는 TX는 거부
+	direct := &Transaction{
+		Version:   bc.cfg.Version.Transaction,
+		NetworkID: bc.cfg.Common.NetworkID,
+		Timestamp: now,
+		Inputs:    []*TxInput{{TxID: stakeTx.ID, OutputIndex: 0, PublicKey: system.PublicKey}},
+		Outputs:   []*TxOutput{{Address: bob.Address, Amount: 4999}},
+		Data:      []byte{},
+	}
+	if err := signTx(direct, system.PrivateKey); err != nil {
+		t.Fatalf("failed to sign tx: %v", err)
+	}
+	if _, err := bc.AddTxToMempool(direct); err == nil {
+		t.Error("spending staked output directly should be rejected")
+	}
+
+	// 블록 2: 2000 언스테이킹 (수수료는 스테이크에서, 나머지 2999는 재스테이킹)
+	unstakeTx, err := bc.BuildUnstakeTx(system.Address, 2000, 1, system.PrivateKey, system.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to create unstake tx: %v", err)
+	}
+	if _, err := bc.AddTxToMempool(unstakeTx); err != nil {
+		t.Fatalf("unstake tx rejected: %v", err)
+	}
+	blk2 := bc.SetBlock(blk1.Header.Hash, 2, miner.Address, now+1)
+	if _, err := bc.AddBlock(*blk2); err != nil {
+		t.Fatalf("failed to add block: %v", err)
+	}
+
+	if staked, _ := bc.GetStakedBalance(system.Address); staked != 2999 {
+		t.Errorf("expected staked 2999 after unstake, got %d", staked)
+	}
+	if locked, _ := bc.GetLockedBalance(system.Address); locked != 2000 {
+		t.Errorf("unstaked funds should be locked for unbonding, got %d", locked)
+	}
+
+	// 언본딩 기간 (높이 2 + 2) 이후 사용 가능
+	blk3 := bc.SetBlock(blk2.Header.Hash, 3, miner.Address, now+2)
+	if _, err := bc.AddBlock(*blk3); err != nil {
+		t.Fatalf("failed to add block: %v", err)
+	}
+	if balance, _ := bc.GetBalance(system.Address); balance != 96999 {
+		t.Errorf("unstaked funds should be spendable after unbonding, balance %d", balance)
+	}
+
+	// 인덱스를 지우고 재구성해도 같은 스테이크
+	if err := bc.db.Delete([]byte(prt.PrefixMetaStakeIx), nil); err != nil {
+		t.Fatalf("failed to delete index version: %v", err)
+	}
+	if err := bc.rebuildStakeIndex(); err != nil {
+		t.Fatalf("failed to rebuild stake index: %v", err)
+	}
+	if stake, _ := bc.GetStake(system.Address); stake == nil || stake.Amount != 2999 || stake.Height != 2 {
+		t.Errorf("unexpected stake after rebuild: %+v", stake)
+	}
+
+	// 롤백하면 해당 높이의 스테이크로 복구
+	if err := bc.RollbackToHeight(1); err != nil {
+		t.Fatalf("rollback failed: %v", err)
+	}
+	if staked, _ := bc.GetStakedBalance(system.Address); staked != 5000 {
+		t.Errorf("expected staked 5000 after rollback, got %d", staked)
+	}
+	if err := bc.RollbackToHeight(0); err != nil {
+		t.Fatalf("rollback failed: %v", err)
+	}
+	if snapshot, _ := bc.GetStakeSnapshot(); snapshot == nil || len(snapshot.Stakes) != 0 {
+		t.Errorf("no stakes expected at genesis: %+v", snapshot)
+	}
+}
+
+// 검증 시 전달된 에포크를 기록하는 ProposerValidator
+type epochRecorder struct {
+	epochs []*Epoch
+}
+
+func (r *epochRecorder) ValidateProposerSignature(epoch *Epoch, proposer prt.Address, blockHash prt.Hash, signature prt.Signature) bool {
+	return true
+}
+
+func (r *epochRecorder) IsValidProposer(epoch *Epoch, proposer prt.Address, height uint64) bool {
+	return true
+}
+
+func (r *epochRecorder) ValidateCommitSignatures(epoch *Epoch, height uint64, blockHash prt.Hash, commitSigs []CommitSignature) error {
+	r.epochs = append(r.epochs, epoch)
+	return nil
+}
+
+// 에포크 경계에서만 바뀌는 검증자 세트와 높이별 이력 테스트
+func TestEpochs(t *testing.T) {
+	system := newTestAccount(t)
+	miner := newTestAccount(t)
+	bc := newTestChainWith(t, system, 100000, func(cfg *config.Config) {
+		cfg.Consensus.EpochBlocks = 2
+	})
+	bc.cfg.Validators.List = []config.ValidatorConfig{
+		{Address: utils.AddressToString(miner.Address), PublicKey: hex.EncodeToString(miner.PublicKey), VotingPower: 10},
+	}
+	recorder := &epochRecorder{}
+	bc.SetProposerValidator(recorder)
+	genesis, err := bc.GetBlockByHeight(0)
+	if err != nil {
+		t.Fatalf("failed to get genesis: %v", err)
+	}
+	now := time.Now().Unix()
+
+	// 블록 1: system 5000 스테이킹, 블록 2: 에포크 경계
+	stakeTx, _, err := bc.BuildStakeTx(system.Address, 5000, 1, system.PrivateKey, system.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to create stake tx: %v", err)
+	}
+	if _, err := bc.AddTxToMempool(stakeTx); err != nil {
+		t.Fatalf("stake tx rejected: %v", err)
+	}
+	blk1 := bc.SetBlock(genesis.Header.Hash, 1, miner.Address, now)
+	if _, err := bc.AddBlock(*blk1); err != nil {
+		t.Fatalf("failed to add block: %v", err)
+	}
+
+	// 에포크 중간에는 스테이크가 반영되지 않음
+	if epoch, err := bc.GetEpoch(2); err != nil || epoch.Number != 0 || len(epoch.Validators) != 0 {
+		t.Fatalf("epoch 0 should have no validators: %+v, %v", epoch, err)
+	}
+
+	blk2 := bc.SetBlock(blk1.Header.Hash, 2, miner.Address, now+1)
+	if _, err := bc.AddBlock(*blk2); err != nil {
+		t.Fatalf("failed to add block: %v", err)
+	}
+
+	// 에포크 1 (높이 3, 4) = 제네시스 검증자 + 스테이커
+	epoch1, err := bc.GetEpoch(3)
+	if err != nil {
+		t.Fatalf("failed to get epoch 1: %v", err)
+	}
+	if epoch1.Number != 1 || epoch1.StartHeight != 3 || epoch1.BoundaryHash != blk2.Header.Hash ||
+		len(epoch1.Validators) != 2 || epoch1.TotalVotingPower != 5010 {
+		t.Fatalf("unexpected epoch 1: %+v", epoch1)
+	}
+	if epoch, _ := bc.GetEpoch(100); epoch == nil || epoch.Number != 1 {
+		t.Errorf("future height should get latest epoch: %+v", epoch)
+	}
+
+	// 과거 블록은 그 블록의 에포크로 검증
+	if err := bc.ValidateBlock(*blk2, true); err != nil {
+		t.Fatalf("block 2 validation failed: %v", err)
+	}
+	blk3 := bc.SetBlock(blk2.Header.Hash, 3, miner.Address, now+2)
+	if err := bc.ValidateBlock(*blk3, true); err != nil {
+		t.Fatalf("block 3 validation failed: %v", err)
+	}
+	if len(recorder.epochs) != 2 || recorder.epochs[0].Number != 0 || recorder.epochs[1].Number != 1 {
+		t.Fatalf("commit signatures should be checked against the block's epoch: %+v", recorder.epochs)
+	}
+
+	// 경계 블록 롤백 시 다음 에포크 삭제
+	if err := bc.RollbackToHeight(1); err != nil {
+		t.Fatalf("rollback failed: %v", err)
+	}
+	if epochs, _ := bc.GetEpochs(0, 100); len(epochs) != 1 {
+		t.Errorf("only epoch 0 should remain after rollback, got %d", len(epochs))
+	}
+
+	// 다시 연결 후 이력을 재구성해도 같은 세트
+	if status, err := bc.ImportBlock(*blk2); err != nil || status != BlockImportExtended {
+		t.Fatalf("re-import block 2: status=%v err=%v", status, err)
+	}
+	if err := bc.db.Delete([]byte(prt.PrefixMetaEpochIx), nil); err != nil {
+		t.Fatalf("failed to delete epoch history version: %v", err)
+	}
+	if err := bc.rebuildEpochs(); err != nil {
+		t.Fatalf("failed to rebuild epochs: %v", err)
+	}
+	rebuilt, err := bc.GetEpoch(3)
+	if err != nil {
+		t.Fatalf("failed to get rebuilt epoch: %v", err)
+	}
+	if !reflect.DeepEqual(rebuilt, epoch1) {
+		t.Errorf("rebuilt epoch differs:\n%+v\n%+v", rebuilt, epoch1)
+	}
+}
+
+// 검증자 위임, 투표력 반영, 블록 보상 분배와 보상 청구 테스트
+func TestDelegation(t *testing.T) {
+	system := newTestAccount(t)
+	alice := newTestAccount(t)
+	bc := newTestChainWith(t, system, 100000, func(cfg *config.Config) {
+		cfg.Consensus.EpochBlocks = 2
+		cfg.Consensus.UnbondingBlocks = 2
+	})
+	bc.cfg.Fee.BlockReward = 6000
+	genesis, err := bc.GetBlockByHeight(0)
+	if err != nil {
+		t.Fatalf("failed to get genesis: %v", err)
+	}
+	now := time.Now().Unix()
+
+	// 블록 1: system 5000 스테이킹 (검증자), alice에게 3001 전송
+	stakeTx, _, err := bc.BuildStakeTx(system.Address, 5000, 1, system.PrivateKey, system.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to create stake tx: %v", err)
+	}
+	if _, err := bc.AddTxToMempool(stakeTx); err != nil {
+		t.Fatalf("stake tx rejected: %v", err)
+	}
+	blk1 := bc.SetBlock(genesis.Header.Hash, 1, system.Address, now)
+	if _, err := bc.AddBlock(*blk1); err != nil {
+		t.Fatalf("failed to add block: %v", err)
+	}
+	fundTx, _, err := bc.BuildSignedTx(system.Address, []TxRecipient{{Address: alice.Address, Amount: 3001}}, 1, "", nil, TxTypeGeneral, TxBuildOptions{}, system.PrivateKey, system.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to create tx: %v", err)
+	}
+	if _, err := bc.AddTxToMempool(fundTx); err != nil {
+		t.Fatalf("fund tx rejected: %v", err)
+	}
+	blk2 := bc.SetBlock(blk1.Header.Hash, 2, system.Address, now+1)
+	if _, err := bc.AddBlock(*blk2); err != nil {
+		t.Fatalf("failed to add block: %v", err)
+	}
+
+	// 자기 자신에게 위임은 거부
+	if _, _, err := bc.BuildDelegateTx(system.Address, system.Address, 100, 1, system.PrivateKey, system.PublicKey); err == nil {
+		t.Error("delegating to own address should be rejected")
+	}
+
+	// 블록 3, 4: alice가 system에 1000 위임 (블록 4가 에포크 경계)
+	delegateTx, _, err := bc.BuildDelegateTx(alice.Address, system.Address, 1000, 1, alice.PrivateKey, alice.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to create delegate tx: %v", err)
+	}
+	if delegateTx.Outputs[0].TxType != TxTypeDelegate || *delegateTx.Outputs[0].Validator != system.Address || delegateTx.Outputs[1].TxType != TxTypeGeneral {
+		t.Fatalf("unexpected delegate tx outputs: %+v", delegateTx.Outputs)
+	}
+	if _, err := bc.AddTxToMempool(delegateTx); err != nil {
+		t.Fatalf("delegate tx rejected: %v", err)
+	}
+	blk3 := bc.SetBlock(blk2.Header.Hash, 3, system.Address, now+2)
+	if _, err := bc.AddBlock(*blk3); err != nil {
+		t.Fatalf("failed to add block: %v", err)
+	}
+	blk4 := bc.SetBlock(blk3.Header.Hash, 4, system.Address, now+3)
+	if _, err := bc.AddBlock(*blk4); err != nil {
+		t.Fatalf("failed to add block: %v", err)
+	}
+
+	if balance, _ := bc.GetBalance(alice.Address); balance != 2000 {
+		t.Errorf("delegated output should not be spendable: balance %d", balance)
+	}
+	if delegated, _ := bc.GetDelegatedBalance(alice.Address); delegated != 1000 {
+		t.Errorf("expected delegated 1000, got %d", delegated)
+	}
+	delegations, err := bc.GetDelegations(system.Address)
+	if err != nil || len(delegations) != 1 || delegations[0].Delegator != alice.Address || delegations[0].Amount != 1000 {
+		t.Fatalf("unexpected delegations: %+v, %v", delegations, err)
+	}
+
+	// 에포크 2 (높이 5, 6): 투표력 = 자기 스테이크 + 위임
+	epoch, err := bc.GetEpoch(5)
+	if err != nil {
+		t.Fatalf("failed to get epoch: %v", err)
+	}
+	if len(epoch.Validators) != 1 || epoch.Validators[0].VotingPower != 6000 || epoch.Validators[0].Delegated != 1000 ||
+		len(epoch.Validators[0].Delegations) != 1 || epoch.CommissionRate != DefaultCommissionRate {
+		t.Fatalf("unexpected epoch: %+v", epoch)
+	}
+
+	// 블록 5: 보상 6000 중 위임 몫 1000, 수수료 10% 제외 900이 alice에게 적립
+	blk5 := bc.SetBlock(blk4.Header.Hash, 5, system.Address, now+4)
+	if blk5.Transactions[0].Outputs[0].Amount != 5100 {
+		t.Errorf("proposer should get own share + commission 5100, got %d", blk5.Transactions[0].Outputs[0].Amount)
+	}
+
+	// 위임자 몫까지 가져가는 코인베이스는 거부
+	greedy := *blk5
+	greedyCoinbase := *blk5.Transactions[0]
+	greedyCoinbase.Outputs = []*TxOutput{{Address: system.Address, Amount: 6000, TxType: TxTypeCoinbase}}
+	greedyCoinbase.ID = utils.Hash(&greedyCoinbase)
+	greedy.Transactions = []*Transaction{&greedyCoinbase}
+	greedy.Header.MerkleRoot = calculateMerkleRoot(greedy.Transactions)
+	greedy.Header.Hash = utils.Hash(greedy.Header)
+	if err := bc.ValidateBlock(greedy, false); err == nil {
+		t.Error("coinbase paying the delegators' share should be rejected")
+	}
+
+	if _, err := bc.AddBlock(*blk5); err != nil {
+		t.Fatalf("failed to add block: %v", err)
+	}
+	if reward, _ := bc.GetRewardBalance(alice.Address); reward != 900 {
+		t.Fatalf("expected claimable reward 900, got %d", reward)
+	}
+
+	// 적립액을 넘는 청구는 거부
+	claimTx, err := bc.BuildClaimRewardTx(alice.Address, 1, alice.PrivateKey, alice.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to create claim tx: %v", err)
+	}
+	overClaim := *claimTx
+	overClaim.Claim = 901
+	overClaim.Outputs = []*TxOutput{{Address: alice.Address, Amount: 2900, TxType: TxTypeGeneral}}
+	overClaim.Inputs = []*TxInput{{TxID: claimTx.Inputs[0].TxID, OutputIndex: claimTx.Inputs[0].OutputIndex, PublicKey: alice.PublicKey}}
+	if err := signTx(&overClaim, alice.PrivateKey); err != nil {
+		t.Fatalf("failed to sign tx: %v", err)
+	}
+	if _, err := bc.AddTxToMempool(&overClaim); err == nil {
+		t.Error("claim above reward balance should be rejected")
+	}
+
+	// 블록 6: 보상 청구 + 500 위임 해제 (나머지 499는 재위임)
+	if claimTx.Claim != 900 || claimTx.Outputs[0].Amount != 2899 {
+		t.Fatalf("unexpected claim tx: claim=%d outputs=%+v", claimTx.Claim, claimTx.Outputs)
+	}
+	if _, err := bc.AddTxToMempool(claimTx); err != nil {
+		t.Fatalf("claim tx rejected: %v", err)
+	}
+	again, err := bc.BuildClaimRewardTx(alice.Address, 1, alice.PrivateKey, alice.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to create claim tx: %v", err)
+	}
+	if _, err := bc.AddTxToMempool(again); err == nil {
+		t.Error("second claim of the same reward should be rejected")
+	}
+	undelegateTx, err := bc.BuildUndelegateTx(alice.Address, system.Address, 500, 1, alice.PrivateKey, alice.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to create undelegate tx: %v", err)
+	}
+	if _, err := bc.AddTxToMempool(undelegateTx); err != nil {
+		t.Fatalf("undelegate tx rejected: %v", err)
+	}
+	blk6 := bc.SetBlock(blk5.Header.Hash, 6, system.Address, now+5)
+	if err := bc.ValidateBlock(*blk6, false); err != nil {
+		t.Fatalf("block 6 validation failed: %v", err)
+	}
+	if _, err := bc.AddBlock(*blk6); err != nil {
+		t.Fatalf("failed to add block: %v", err)
+	}
+
+	// 블록 6 보상 (6000 + 수수료 2)의 위임 몫 1000 중 900 적립
+	if reward, _ := bc.GetRewardBalance(alice.Address); reward != 900 {
+		t.Errorf("expected new reward 900 after claim, got %d", reward)
+	}
+	if balance, _ := bc.GetBalance(alice.Address); balance != 2899 {
+		t.Errorf("claimed reward should be spendable: balance %d", balance)
+	}
+	if delegated, _ := bc.GetDelegatedBalance(alice.Address); delegated != 499 {
+		t.Errorf("expected delegated 499 after undelegate, got %d", delegated)
+	}
+	if locked, _ := bc.GetLockedBalance(alice.Address); locked != 500 {
+		t.Errorf("undelegated funds should be locked for unbonding, got %d", locked)
+	}
+
+	// 인덱스를 지우고 재구성해도 같은 위임
+	if err := bc.db.Delete([]byte(prt.PrefixMetaStakeIx), nil); err != nil {
+		t.Fatalf("failed to delete index version: %v", err)
+	}
+	if err := bc.rebuildStakeIndex(); err != nil {
+		t.Fatalf("failed to rebuild stake index: %v", err)
+	}
+	if delegations, _ := bc.GetDelegations(system.Address); len(delegations) != 1 || delegations[0].Amount != 499 {
+		t.Errorf("unexpected delegations after rebuild: %+v", delegations)
+	}
+
+	// 롤백하면 보상 잔액과 위임 복구
+	if err := bc.RollbackToHeight(5); err != nil {
+		t.Fatalf("rollback failed: %v", err)
+	}
+	if reward, _ := bc.GetRewardBalance(alice.Address); reward != 900 {
+		t.Errorf("expected reward 900 after rollback, got %d", reward)
+	}
+	if delegated, _ := bc.GetDelegatedBalance(alice.Address); delegated != 1000 {
+		t.Errorf("expected delegated 1000 after rollback, got %d", delegated)
+	}
+	if err := bc.RollbackToHeight(4); err != nil {
+		t.Fatalf("rollback failed: %v", err)
+	}
+	if reward, _ := bc.GetRewardBalance(alice.Address); reward != 0 {
+		t.Errorf("expected no reward before block 5, got %d", reward)
+	}
+}
+
+// 이중 서명 증거, 스테이크 슬래싱과 검증자 감금 테스트
+func TestEvidence(t *testing.T) {
+	system := newTestAccount(t)
+	miner := newTestAccount(t)
+	bob := newTestAccount(t)
+	bc := newTestChainWith(t, system, 100000, func(cfg *config.Config) {
+		cfg.Consensus.EpochBlocks = 2
+		cfg.Consensus.UnbondingBlocks = 10
+	})
+	genesis, err := bc.GetBlockByHeight(0)
+	if err != nil {
+		t.Fatalf("failed to get genesis: %v", err)
+	}
+	now := time.Now().Unix()
+
+	sign := func(account *testAccount, hash prt.Hash) prt.Signature {
+		privateKey, err := crypto.BytesToPrivateKey(account.PrivateKey)
+		if err != nil {
+			t.Fatalf("failed to parse private key: %v", err)
+		}
+		sig, err := crypto.SignData(privateKey, utils.HashToBytes(hash))
+		if err != nil {
+			t.Fatalf("failed to sign: %v", err)
+		}
+		return sig
+	}
+
+	// 블록 1: system 5000 스테이킹, 블록 2: 에포크 경계 (에포크 1 검증자 = system)
+	stakeTx, _, err := bc.BuildStakeTx(system.Address, 5000, 1, system.PrivateKey, system.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to create stake tx: %v", err)
+	}
+	if _, err := bc.AddTxToMempool(stakeTx); err != nil {
+		t.Fatalf("stake tx rejected: %v", err)
+	}
+	blk1 := bc.SetBlock(genesis.Header.Hash, 1, miner.Address, now)
+	if _, err := bc.AddBlock(*blk1); err != nil {
+		t.Fatalf("failed to add block: %v", err)
+	}
+	blk2 := bc.SetBlock(blk1.Header.Hash, 2, miner.Address, now+1)
+	if _, err := bc.AddBlock(*blk2); err != nil {
+		t.Fatalf("failed to add block: %v", err)
+	}
+
+	// 높이 3 라운드 0에서 서로 다른 블록에 대한 두 프리커밋
+	hashA, hashB := utils.Hash("block-a"), utils.Hash("block-b")
+	sigA := sign(system, VoteSignHash(3, 0, VoteTypePrecommit, hashA))
+	sigB := sign(system, VoteSignHash(3, 0, VoteTypePrecommit, hashB))
+	ev := NewDuplicateVoteEvidence(system.Address, 3, 0, VoteTypePrecommit, hashB, sigB, hashA, sigA)
+	if string(ev.First.BlockHash[:]) >= string(ev.Second.BlockHash[:]) {
+		t.Fatalf("evidence should be ordered by block hash: %+v", ev)
+	}
+
+	// 서명이 맞지 않거나 검증자가 아니면 증거로 인정하지 않음
+	forged := *ev
+	forged.Second.Signature = forged.First.Signature
+	if _, err := bc.BuildEvidenceTx(&forged); err == nil {
+		t.Error("evidence with invalid signature should be rejected")
+	}
+	bobEv := NewDuplicateVoteEvidence(bob.Address, 3, 0, VoteTypePrecommit,
+		hashA, sign(bob, VoteSignHash(3, 0, VoteTypePrecommit, hashA)), hashB, sign(bob, VoteSignHash(3, 0, VoteTypePrecommit, hashB)))
+	if _, err := bc.BuildEvidenceTx(bobEv); err == nil {
+		t.Error("evidence against non-validator should be rejected")
+	}
+	otherRound := NewDuplicateVoteEvidence(system.Address, 3, 1, VoteTypePrecommit, hashA, sigA, hashB, sigB)
+	if _, err := bc.BuildEvidenceTx(otherRound); err == nil {
+		t.Error("votes signed for another round should not be evidence")
+	}
+
+	// 증거 TX: 스테이크 5000을 입력으로, 5% 슬래싱 후 4750을 다시 스테이킹
+	evidenceTx, err := bc.BuildEvidenceTx(ev)
+	if err != nil {
+		t.Fatalf("failed to create evidence tx: %v", err)
+	}
+	if len(evidenceTx.Inputs) != 1 || len(evidenceTx.Outputs) != 1 || evidenceTx.Outputs[0].Amount != 4750 ||
+		evidenceTx.Outputs[0].TxType != TxTypeStaking || evidenceTx.Outputs[0].Address != system.Address {
+		t.Fatalf("unexpected evidence tx: inputs=%d outputs=%+v", len(evidenceTx.Inputs), evidenceTx.Outputs)
+	}
+
+	// 슬래싱 없이 돌려주는 증거 TX는 거부
+	greedy := *evidenceTx
+	greedy.Outputs = []*TxOutput{{Address: system.Address, Amount: 5000, TxType: TxTypeStaking}}
+	greedy.ID = utils.Hash(&greedy)
+	if _, err := bc.AddTxToMempool(&greedy); err == nil {
+		t.Error("evidence tx without slashing should be rejected")
+	}
+
+	// 서명 검증만 거쳐도 증거가 고정한 입력 / 출력이 아니면 거부
+	if err := bc.ValidateTxSignatures(&greedy); err == nil {
+		t.Error("evidence tx without slashing should fail signature validation")
+	}
+	if err := bc.ValidateTxSignatures(evidenceTx); err != nil {
+		t.Errorf("evidence tx should pass signature validation: %v", err)
+	}
+
+	if _, err := bc.AddTxToMempool(evidenceTx); err != nil {
+		t.Fatalf("evidence tx rejected: %v", err)
+	}
+	if !bc.HasPendingEvidence(system.Address) {
+		t.Error("evidence should be pending in mempool")
+	}
+
+	// 블록 3: 증거 반영 (수수료 없음, 차액 250 소각)
+	blk3 := bc.SetBlock(blk2.Header.Hash, 3, miner.Address, now+2)
+	if len(blk3.Transactions) != 2 {
+		t.Fatalf("evidence tx should be included, got %d txs", len(blk3.Transactions))
+	}
+	if blk3.Transactions[0].Outputs[0].Amount != 50 {
+		t.Errorf("evidence tx should pay no fee, coinbase %d", blk3.Transactions[0].Outputs[0].Amount)
+	}
+	if _, err := bc.AddBlock(*blk3); err != nil {
+		t.Fatalf("failed to add block: %v", err)
+	}
+	if staked, _ := bc.GetStakedBalance(system.Address); staked != 4750 {
+		t.Errorf("expected staked 4750 after slashing, got %d", staked)
+	}
+	jail, err := bc.GetJail(system.Address)
+	if err != nil || jail == nil || jail.Height != 3 || jail.Reason != JailReasonDoubleSign || jail.Evidence != evidenceTx.ID {
+		t.Fatalf("unexpected jail: %+v, %v", jail, err)
+	}
+
+	// 감금은 다음 블록부터 적용, 다음 에포크에서는 제외
+	if epoch, _ := bc.GetEpoch(3); epoch == nil || len(epoch.Validators) != 1 || epoch.Validators[0].Jailed {
+		t.Errorf("validator should still sign block 3: %+v", epoch)
+	}
+	if epoch, _ := bc.GetEpoch(4); epoch == nil || len(epoch.Validators) != 1 || !epoch.Validators[0].Jailed {
+		t.Errorf("validator should be jailed from block 4: %+v", epoch)
+	}
+	if _, err := bc.BuildEvidenceTx(ev); err == nil {
+		t.Error("evidence against jailed validator should be rejected")
+	}
+	blk4 := bc.SetBlock(blk3.Header.Hash, 4, miner.Address, now+3)
+	if _, err := bc.AddBlock(*blk4); err != nil {
+		t.Fatalf("failed to add block: %v", err)
+	}
+	epoch2, err := bc.GetEpoch(5)
+	if err != nil || len(epoch2.Validators) != 0 {
+		t.Fatalf("jailed validator should be left out of epoch 2: %+v, %v", epoch2, err)
+	}
+
+	// 이력을 재구성해도 감금된 검증자는 제외
+	if err := bc.db.Delete([]byte(prt.PrefixMetaEpochIx), nil); err != nil {
+		t.Fatalf("failed to delete epoch history version: %v", err)
+	}
+	if err := bc.rebuildEpochs(); err != nil {
+		t.Fatalf("failed to rebuild epochs: %v", err)
+	}
+	if rebuilt, _ := bc.GetEpoch(5); !reflect.DeepEqual(rebuilt, epoch2) {
+		t.Errorf("rebuilt epoch differs:\n%+v\n%+v", rebuilt, epoch2)
+	}
+
+	// 롤백하면 감금 해제, 증거 TX는 멤풀로 복귀
+	if err := bc.RollbackToHeight(2); err != nil {
+		t.Fatalf("rollback failed: %v", err)
+	}
+	if jail, _ := bc.GetJail(system.Address); jail != nil {
+		t.Errorf("jail should be reverted: %+v", jail)
+	}
+	if staked, _ := bc.GetStakedBalance(system.Address); staked != 5000 {
+		t.Errorf("expected staked 5000 after rollback, got %d", staked)
+	}
+	if !bc.HasPendingEvidence(system.Address) {
+		t.Error("reverted evidence tx should return to mempool")
+	}
+
+	// 같은 높이/라운드에 서로 다른 두 블록 제안도 증거
+	proposalA := bc.SetBlockAtRound(blk2.Header.Hash, 3, 1, system.Address, now+2)
+	proposalB := bc.SetBlockAtRound(blk2.Header.Hash, 3, 1, system.Address, now+3)
+	proposalA.SignBlock(sign(system, proposalA.Header.Hash))
+	proposalB.SignBlock(sign(system, proposalB.Header.Hash))
+	proposalEv := NewDuplicateProposalEvidence(proposalA, proposalB)
+	proposalTx, err := bc.BuildEvidenceTx(proposalEv)
+	if err != nil {
+		t.Fatalf("failed to create proposal evidence tx: %v", err)
+	}
+	if _, err := bc.AddTxToMempool(proposalTx); err == nil {
+		t.Error("second evidence against the same validator should be rejected while one is pending")
+	}
+	laterRound := bc.SetBlockAtRound(blk2.Header.Hash, 3, 2, system.Address, now+3)
+	laterRound.SignBlock(sign(system, laterRound.Header.Hash))
+	if _, err := bc.BuildEvidenceTx(NewDuplicateProposalEvidence(proposalA, laterRound)); err == nil {
+		t.Error("blocks proposed in different rounds should not be evidence")
+	}
+}
+
+// 증거 반영 전에 언스테이킹한 스테이크도 언본딩 중이면 슬래싱
+func TestEvidenceSlashesUnbonding(t *testing.T) {
+	system := newTestAccount(t)
+	miner := newTestAccount(t)
+	bc := newTestChainWith(t, system, 100000, func(cfg *config.Config) {
+		cfg.Consensus.EpochBlocks = 2
+		cfg.Consensus.UnbondingBlocks = 10
+	})
+	genesis, err := bc.GetBlockByHeight(0)
+	if err != nil {
+		t.Fatalf("failed to get genesis: %v", err)
+	}
+	now := time.Now().Unix()
+
+	privateKey, err := crypto.BytesToPrivateKey(system.PrivateKey)
+	if err != nil {
+		t.Fatalf("failed to parse private key: %v", err)
+	}
+	sign := func(hash prt.Hash) prt.Signature {
+		sig, err := crypto.SignData(privateKey, utils.HashToBytes(hash))
+		if err != nil {
+			t.Fatalf("failed to sign: %v", err)
+		}
+		return sig
+	}
+
+	// 블록 1: 5000 스테이킹, 블록 2: 에포크 경계, 블록 3: 2000 언스테이킹 (수수료 1은 스테이크에서)
+	stakeTx, _, err := bc.BuildStakeTx(system.Address, 5000, 1, system.PrivateKey, system.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to create stake tx: %v", err)
+	}
+	if _, err := bc.AddTxToMempool(stakeTx); err != nil {
+		t.Fatalf("stake tx rejected: %v", err)
+	}
+	prevHash := genesis.Header.Hash
+	addBlock := func(height uint64) *Block {
+		blk := bc.SetBlock(prevHash, height, miner.Address, now+int64(height))
+		if _, err := bc.AddBlock(*blk); err != nil {
+			t.Fatalf("failed to add block %d: %v", height, err)
+		}
+		prevHash = blk.Header.Hash
+		return blk
+	}
+	addBlock(1)
+	addBlock(2)
+	unstakeTx, err := bc.BuildUnstakeTx(system.Address, 2000, 1, system.PrivateKey, system.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to create unstake tx: %v", err)
+	}
+	if _, err := bc.AddTxToMempool(unstakeTx); err != nil {
+		t.Fatalf("unstake tx rejected: %v", err)
+	}
+	addBlock(3)
+	if staked, _ := bc.GetStakedBalance(system.Address); staked != 2999 {
+		t.Fatalf("expected staked 2999 after unstaking, got %d", staked)
+	}
+
+	// 높이 3의 이중 서명 증거: 남은 스테이크와 언본딩 출력 모두 입력
+	hashA, hashB := utils.Hash("block-a"), utils.Hash("block-b")
+	ev := NewDuplicateVoteEvidence(system.Address, 3, 0, VoteTypePrecommit,
+		hashA, sign(VoteSignHash(3, 0, VoteTypePrecommit, hashA)), hashB, sign(VoteSignHash(3, 0, VoteTypePrecommit, hashB)))
+	evidenceTx, err := bc.BuildEvidenceTx(ev)
+	if err != nil {
+		t.Fatalf("failed to create evidence tx: %v", err)
+	}
+	if len(evidenceTx.Inputs) != 2 || len(evidenceTx.Outputs) != 2 {
+		t.Fatalf("evidence tx should slash stake and unbonding output: inputs=%d outputs=%+v", len(evidenceTx.Inputs), evidenceTx.Outputs)
+	}
+	staked, unbonding := evidenceTx.Outputs[0], evidenceTx.Outputs[1]
+	if staked.TxType != TxTypeStaking || staked.Amount != 2850 {
+		t.Errorf("unexpected slashed stake: %+v", staked)
+	}
+	if unbonding.TxType != TxTypeUnStaking || unbonding.Amount != 1900 || unbonding.LockHeight != 13 || unbonding.RelativeLockHeight != 0 {
+		t.Errorf("slashed unbonding output should keep its unlock height: %+v", unbonding)
+	}
+
+	// 언본딩 출력을 빼먹은 증거 TX는 거부
+	partial := *evidenceTx
+	partial.Inputs = evidenceTx.Inputs[:1]
+	partial.Outputs = evidenceTx.Outputs[:1]
+	partial.ID = prt.Hash{}
+	partial.ID = utils.Hash(&partial)
+	if _, err := bc.AddTxToMempool(&partial); err == nil {
+		t.Error("evidence tx leaving unbonding output unslashed should be rejected")
+	}
+
+	// 인덱스를 재구성해도 같은 언본딩 출력
+	if err := bc.db.Delete([]byte(prt.PrefixMetaStakeIx), nil); err != nil {
+		t.Fatalf("failed to delete stake index version: %v", err)
+	}
+	if err := bc.rebuildStakeIndex(); err != nil {
+		t.Fatalf("failed to rebuild stake index: %v", err)
+	}
+	if rebuilt, err := bc.BuildEvidenceTx(ev); err != nil || len(rebuilt.Inputs) != 2 {
+		t.Fatalf("rebuilt index should keep unbonding output: %v", err)
+	}
+
+	if _, err := bc.AddTxToMempool(evidenceTx); err != nil {
+		t.Fatalf("evidence tx rejected: %v", err)
+	}
+	addBlock(4)
+	if balance, _ := bc.GetStakedBalance(system.Address); balance != 2850 {
+		t.Errorf("expected staked 2850 after slashing, got %d", balance)
+	}
+	if locked, _ := bc.GetLockedBalance(system.Address); locked != 1900 {
+		t.Errorf("expected 1900 unbonding after slashing, got %d", locked)
+	}
+
+	// 잠금이 끝난 언본딩 출력은 슬래싱 대상이 아님
+	if unbonding, err := bc.collectUnbonding(system.Address, 13); err != nil || len(unbonding) != 0 {
+		t.Errorf("unlocked unbonding output should not be slashable: %d, %v", len(unbonding), err)
+	}
+}
+
+// 커밋 서명 기반 다운타임 추적, 감금과 감금 해제 테스트
+func TestDowntime(t *testing.T) {
+	system := newTestAccount(t)
+	miner := newTestAccount(t)
+	bc := newTestChainWith(t, system, 100000, func(cfg *config.Config) {
+		cfg.Consensus.EpochBlocks = 2
+		cfg.Consensus.SignedBlocksWindow = 4
+		cfg.Consensus.MinSignedPerWindow = 50 // 창 4개 중 2개까지 누락 허용
+		cfg.Consensus.DowntimeJailBlocks = 3
+	})
+	bc.cfg.Validators.List = []config.ValidatorConfig{
+		{Address: utils.AddressToString(miner.Address), PublicKey: hex.EncodeToString(miner.PublicKey), VotingPower: 10},
+	}
+	genesis, err := bc.GetBlockByHeight(0)
+	if err != nil {
+		t.Fatalf("failed to get genesis: %v", err)
+	}
+	now := time.Now().Unix()
+
+	// miner만 커밋에 서명하는 블록 추가
+	commit := func(prev *Block, height uint64) *Block {
+		blk := bc.SetBlock(prev.Header.Hash, height, miner.Address, now+int64(height))
+		blk.CommitSignatures = []CommitSignature{{ValidatorAddress: miner.Address, Timestamp: now}}
+		if _, err := bc.AddBlock(*blk); err != nil {
+			t.Fatalf("failed to add block %d: %v", height, err)
+		}
+		return blk
+	}
+
+	// 블록 1: system 5000 스테이킹, 에포크 1 (높이 3, 4)부터 miner + system
+	stakeTx, _, err := bc.BuildStakeTx(system.Address, 5000, 1, system.PrivateKey, system.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to create stake tx: %v", err)
+	}
+	if _, err := bc.AddTxToMempool(stakeTx); err != nil {
+		t.Fatalf("stake tx rejected: %v", err)
+	}
+	blocks := []*Block{genesis}
+	for height := uint64(1); height <= 5; height++ {
+		blocks = append(blocks, commit(blocks[height-1], height))
+	}
+
+	// 블록은 부모의 커밋 서명을 담고 헤더에 그 해시를 기록
+	if len(blocks[1].LastCommit) != 0 || blocks[1].Header.LastCommitHash != nil {
+		t.Errorf("block 1 should carry no last commit: %+v", blocks[1].LastCommit)
+	}
+	if len(blocks[5].LastCommit) != 1 || blocks[5].LastCommit[0].ValidatorAddress != miner.Address ||
+		blocks[5].Header.LastCommitHash == nil || *blocks[5].Header.LastCommitHash != utils.Hash(blocks[5].LastCommit) {
+		t.Fatalf("unexpected last commit of block 5: %+v", blocks[5].LastCommit)
+	}
+	stripped := *blocks[5]
+	stripped.LastCommit = nil
+	if err := bc.ValidateBlock(stripped, false); err == nil {
+		t.Error("block without the last commit its header commits to should be rejected")
+	}
+
+	// 높이 3, 4 누락 = 허용 한도
+	if uptime, _ := bc.GetUptime(system.Address); uptime == nil || len(uptime.Missed) != 2 || len(uptime.Signed) != 0 {
+		t.Fatalf("unexpected uptime of system: %+v", uptime)
+	}
+	if jail, _ := bc.GetJail(system.Address); jail != nil {
+		t.Fatalf("validator within the limit should not be jailed: %+v", jail)
+	}
+
+	// 블록 6: 높이 5까지 3번 누락 -> 다운타임 감금 (에포크 경계, 다음 에포크에서 제외)
+	blocks = append(blocks, commit(blocks[5], 6))
+	jail, err := bc.GetJail(system.Address)
+	if err != nil || jail == nil || jail.Height != 6 || jail.Reason != JailReasonDowntime {
+		t.Fatalf("unexpected jail: %+v, %v", jail, err)
+	}
+	if uptime, _ := bc.GetUptime(miner.Address); len(uptime.Signed) != 3 || len(uptime.Missed) != 0 {
+		t.Errorf("unexpected uptime of miner: %+v", uptime)
+	}
+	if epoch, _ := bc.GetEpoch(7); epoch == nil || len(epoch.Validators) != 1 || epoch.Validators[0].Address != miner.Address {
+		t.Fatalf("jailed validator should be left out of epoch 3: %+v", epoch)
+	}
+
+	// 감금 기간(3블록)이 지나기 전에는 해제 불가
+	if _, err := bc.BuildUnjailTx(system.Address, 1, system.PrivateKey, system.PublicKey); err == nil {
+		t.Error("unjail before the jail period should be rejected")
+	}
+	blocks = append(blocks, commit(blocks[6], 7))
+	blocks = append(blocks, commit(blocks[7], 8))
+
+	// 높이 9부터 해제 가능, 같은 검증자의 두 번째 해제 TX는 거부
+	unjailTx, err := bc.BuildUnjailTx(system.Address, 1, system.PrivateKey, system.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to create unjail tx: %v", err)
+	}
+	if _, err := bc.AddTxToMempool(unjailTx); err != nil {
+		t.Fatalf("unjail tx rejected: %v", err)
+	}
+	if second, err := bc.BuildUnjailTx(system.Address, 2, system.PrivateKey, system.PublicKey); err == nil {
+		if _, err := bc.AddTxToMempool(second); err == nil {
+			t.Error("second unjail of the same validator should be rejected")
+		}
+	}
+
+	// 블록 9: 감금 해제 + 가동 기록 초기화, 에포크 5 (높이 11부터) 복귀
+	blocks = append(blocks, commit(blocks[8], 9))
+	if len(blocks[9].Transactions) != 2 {
+		t.Fatalf("unjail tx should be included, got %d txs", len(blocks[9].Transactions))
+	}
+	if jail, _ := bc.GetJail(system.Address); jail != nil {
+		t.Fatalf("validator should be unjailed: %+v", jail)
+	}
+	if uptime, _ := bc.GetUptime(system.Address); len(uptime.Signed)+len(uptime.Missed) != 0 {
+		t.Errorf("uptime should be reset by unjail: %+v", uptime)
+	}
+	blocks = append(blocks, commit(blocks[9], 10))
+	epoch5, err := bc.GetEpoch(11)
+	if err != nil || len(epoch5.Validators) != 2 {
+		t.Fatalf("unjailed validator should return in epoch 5: %+v, %v", epoch5, err)
+	}
+
+	// 이력을 재구성해도 같은 세트
+	if err := bc.db.Delete([]byte(prt.PrefixMetaEpochIx), nil); err != nil {
+		t.Fatalf("failed to delete epoch history version: %v", err)
+	}
+	if err := bc.rebuildEpochs(); err != nil {
+		t.Fatalf("failed to rebuild epochs: %v", err)
+	}
+	if rebuilt, _ := bc.GetEpoch(11); !reflect.DeepEqual(rebuilt, epoch5) {
+		t.Errorf("rebuilt epoch differs:\n%+v\n%+v", rebuilt, epoch5)
+	}
+	if rebuilt, _ := bc.GetEpoch(7); rebuilt == nil || len(rebuilt.Validators) != 1 {
+		t.Errorf("rebuilt epoch 3 should leave out the jailed validator: %+v", rebuilt)
+	}
+
+	// 롤백하면 감금과 가동 기록 복원
+	if err := bc.RollbackToHeight(8); err != nil {
+		t.Fatalf("rollback failed: %v", err)
+	}
+	if jail, _ := bc.GetJail(system.Address); jail == nil || jail.Reason != JailReasonDowntime || jail.Height != 6 {
+		t.Errorf("jail should be restored: %+v", jail)
+	}
+	if uptime, _ := bc.GetUptime(system.Address); len(uptime.Missed) != 3 {
+		t.Errorf("uptime should be restored: %+v", uptime)
+	}
+	if err := bc.RollbackToHeight(5); err != nil {
+		t.Fatalf("rollback failed: %v", err)
+	}
+	if jail, _ := bc.GetJail(system.Address); jail != nil {
+		t.Errorf("downtime jail should be reverted: %+v", jail)
+	}
+	if uptime, _ := bc.GetUptime(system.Address); len(uptime.Missed) != 2 {
+		t.Errorf("uptime should be reverted: %+v", uptime)
+	}
+}
+
+// 컨센서스 타이밍: 기본값 / 라운드별 타임아웃 증가 / 제네시스 기록 우선 테스트
+func TestConsensusTiming(t *testing.T) {
+	system := newTestAccount(t)
+	bc := newTestChain(t, system, 100000)
+
+	// 설정이 없으면 기본값, 제네시스에는 기록하지 않음 (기존 제네시스 해시 유지)
+	if timing, err := bc.GetConsensusTiming(); err != nil || timing != DefaultConsensusTiming() {
+		t.Fatalf("기본 타이밍이 아님: %+v", timing)
+	}
+	genesis, err := bc.GetBlockByHeight(0)
+	if err != nil {
+		t.Fatalf("failed to get genesis: %v", err)
+	}
+	if len(genesis.Transactions[0].Data) != 0 {
+		t.Fatalf("기본 타이밍이 제네시스에 기록됨")
+	}
+
+	// 라운드 타임아웃 = base + delta*round
+	timing := DefaultConsensusTiming()
+	timing.RoundTimeoutMs, timing.RoundTimeoutDeltaMs = 2000, 500
+	if timeout := timing.RoundTimeout(3); timeout != 3500*time.Millisecond {
+		t.Fatalf("라운드 3 타임아웃 오류: %v", timeout)
+	}
+
+	// 단계 합보다 짧은 라운드 타임아웃은 거부
+	timing.RoundTimeoutMs = timing.ProposingMs + 2*timing.VotingMs + timing.CommittingMs
+	if err := timing.Validate(); err == nil {
+		t.Fatalf("단계보다 짧은 라운드 타임아웃을 허용함")
+	}
+
+	// 제네시스 이후 설정을 바꿔도 제네시스(기본) 타이밍 사용
+	bc.cfg.Consensus.VotingMs = 300
+	if timing, _ := bc.GetConsensusTiming(); timing.VotingMs != DefaultVotingMs {
+		t.Fatalf("제네시스 대신 설정 타이밍 사용: %+v", timing)
+	}
+
+	// 기본값과 다른 타이밍은 제네시스 TX data에 기록
+	bc.cfg.Consensus.ProposingMs = 200
+	bc.cfg.Consensus.CommittingMs = 200
+	bc.cfg.Consensus.RoundTimeoutMs = 2000
+	bc.cfg.Consensus.RoundTimeoutDeltaMs = 500
+	custom, err := bc.SetGenesisBlock()
+	if err != nil {
+		t.Fatalf("failed to create genesis: %v", err)
+	}
+	if custom.Header.Hash == genesis.Header.Hash {
+		t.Fatalf("타이밍이 제네시스 해시에 반영되지 않음")
+	}
+
+	db, err := leveldb.Open(storage.NewMemStorage(), nil)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	defer db.Close()
+	fast, err := NewChainState(db, bc.cfg)
+	if err != nil {
+		t.Fatalf("failed to init chain: %v", err)
+	}
+	want := ConfigConsensusTiming(bc.cfg)
+	if timing, err := fast.GetConsensusTiming(); err != nil || timing != want || timing.VotingMs != 300 {
+		t.Fatalf("제네시스 타이밍 오류: %+v", timing)
+	}
+
+	// 같은 DB를 다른 설정으로 다시 열어도 제네시스에 기록된 타이밍 사용
+	cfg := *bc.cfg
+	cfg.Consensus.VotingMs = 0
+	cfg.Consensus.RoundTimeoutMs = 0
+	reopened, err := NewChainState(db, &cfg)
+	if err != nil {
+		t.Fatalf("failed to reopen chain: %v", err)
+	}
+	if timing, err := reopened.GetConsensusTiming(); err != nil || timing != want {
+		t.Fatalf("재시작 후 제네시스 타이밍이 아님: %+v", timing)
+	}
+
+	// 제네시스를 받기 전에는 설정 타이밍으로 대신하지 않음
+	syncCfg := cfg
+	syncCfg.Common.Mode = ""
+	emptyDB, err := leveldb.Open(storage.NewMemStorage(), nil)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	defer emptyDB.Close()
+	syncing, err := NewChainState(emptyDB, &syncCfg)
+	if err != nil {
+		t.Fatalf("failed to init chain: %v", err)
+	}
+	if _, err := syncing.GetConsensusTiming(); err == nil {
+		t.Fatalf("제네시스 없이 타이밍을 반환함")
+	}
+
+	// 잘못된 타이밍으로는 제네시스를 만들지 않음
+	cfg.Consensus.RoundTimeoutMs = 100
+	bc.cfg = &cfg
+	if _, err := bc.SetGenesisBlock(); err == nil {
+		t.Fatalf("잘못된 타이밍으로 제네시스를 생성함")
+	}
+}
+
+// 합의 파라미터는 제네시스에 기록된 값 사용 (설정과 달라도)
+func TestConsensusParams(t *testing.T) {
+	system := newTestAccount(t)
+
+	// 기본 파라미터는 제네시스에 기록하지 않음
+	bc := newTestChain(t, system, 100000)
+	if params := bc.GetConsensusParams(); params != DefaultConsensusParams() {
+		t.Fatalf("unexpected default params: %+v", params)
+	}
+	genesis, _ := bc.GetBlockByHeight(0)
+	if len(genesis.Transactions[0].Data) != 0 {
+		t.Fatalf("기본 파라미터가 제네시스에 기록됨")
+	}
+
+	// 제네시스 이후 설정을 바꿔도 제네시스(기본) 파라미터 사용
+	bc.cfg.Consensus.EpochBlocks = 2
+	if bc.GetEpochBlocks() != DefaultEpochBlocks {
+		t.Fatalf("제네시스 대신 설정 파라미터 사용: %d", bc.GetEpochBlocks())
+	}
+
+	// 기본값과 다른 파라미터는 제네시스 TX data에 기록
+	custom := newTestChainWith(t, system, 100000, func(cfg *config.Config) {
+		cfg.Consensus.EpochBlocks = 2
+		cfg.Consensus.SlashRate = 20
+		cfg.Consensus.UnbondingBlocks = 7
+	})
+	want := ConfigConsensusParams(custom.cfg)
+	if params := custom.GetConsensusParams(); params != want || params.SlashRate != 20 {
+		t.Fatalf("제네시스 파라미터 오류: %+v", params)
+	}
+	if timing, err := custom.GetConsensusTiming(); err != nil || timing != DefaultConsensusTiming() {
+		t.Fatalf("파라미터 기록이 타이밍을 바꿈: %+v %v", timing, err)
+	}
+
+	// 같은 DB를 다른 설정으로 다시 열어도 제네시스에 기록된 파라미터 사용
+	cfg := *custom.cfg
+	cfg.Consensus.EpochBlocks = 0
+	cfg.Consensus.SlashRate = 50
+	reopened, err := NewChainState(custom.db, &cfg)
+	if err != nil {
+		t.Fatalf("failed to reopen chain: %v", err)
+	}
+	if params := reopened.GetConsensusParams(); params != want {
+		t.Fatalf("재시작 후 제네시스 파라미터가 아님: %+v", params)
+	}
+	if reopened.GetSlashRate() != 20 || reopened.GetUnbondingBlocks() != 7 {
+		t.Errorf("getters should follow genesis params: slash=%d unbonding=%d", reopened.GetSlashRate(), reopened.GetUnbondingBlocks())
+	}
+}
@@ -0,0 +1,463 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"math/bits"
+	"sort"
+	"time"
+
+	"github.com/abcfe/abcfe-node/common/utils"
+	prt "github.com/abcfe/abcfe-node/protocol"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// Delegation on chain:
+//   - Delegate tx: outputs of TxTypeDelegate paid back to the delegator, naming the validator they back in Validator.
+//     They follow the staking rules (staking.go): excluded from spendable balance, returned only through unbonding.
+//   - Delegations to a validator of an epoch add to its voting power (epoch.go).
+//   - Block reward (reward + fees) of the proposer is split by voting power. The delegators' part minus the
+//     validator's commission is credited to their reward balances in proportion to each delegation,
+//     the proposer's coinbase output gets the rest.
+//   - Reward balances are withdrawn with Transaction.Claim, counted as input of a tx signed by the delegator.
+//
+// Reward balance changes are recorded in the block undo record, so they are reverted with the block.
+
+const (
+	DefaultCommissionRate = 10 // Commission percent when not configured
+)
+
+// Delegation delegated outputs of a delegator to one validator
+type Delegation struct {
+	Delegator prt.Address
+	Validator prt.Address
+	Amount    uint64  // Sum of delegated outputs
+	Height    uint64  // Earliest confirmation height of delegated outputs
+	Utxos     []*UTXO // Delegated outputs
+}
+
+// RewardBalance reward amount of an address
+type RewardBalance struct {
+	Address prt.Address
+	Amount  uint64
+}
+
+// GetCommissionRate percent of the delegators' reward share kept by the validator
+func (p *BlockChain) GetCommissionRate() uint64 {
+	return p.GetConsensusParams().CommissionRate
+}
+
+// GetDelegations returns confirmed delegations to validator (sorted by delegator)
+func (p *BlockChain) GetDelegations(validator prt.Address) ([]*Delegation, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.collectDelegations(utils.GetDelegationPrefix(validator))
+}
+
+// GetDelegatorDelegations returns confirmed delegations of delegator to every validator
+func (p *BlockChain) GetDelegatorDelegations(delegator prt.Address) ([]*Delegation, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	delegations, err := p.collectDelegations([]byte(prt.PrefixDelegation))
+	if err != nil {
+		return nil, err
+	}
+	result := delegations[:0]
+	for _, delegation := range delegations {
+		if delegation.Delegator == delegator {
+			result = append(result, delegation)
+		}
+	}
+	return result, nil
+}
+
+// GetDelegatedBalance sum of confirmed delegated outputs of delegator
+func (p *BlockChain) GetDelegatedBalance(delegator prt.Address) (uint64, error) {
+	delegations, err := p.GetDelegatorDelegations(delegator)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get delegated balance: %w", err)
+	}
+	var total uint64
+	for _, delegation := range delegations {
+		total += delegation.Amount
+	}
+	return total, nil
+}
+
+// collectDelegations groups indexed delegated outputs under prefix by validator and delegator
+func (p *BlockChain) collectDelegations(prefix []byte) ([]*Delegation, error) {
+	iter := p.db.NewIterator(util.BytesPrefix(prefix), nil)
+	defer iter.Release()
+
+	var utxos []*UTXO
+	for iter.Next() {
+		utxo, err := p.loadUtxo(iter.Value())
+		if err != nil {
+			return nil, err
+		}
+		utxos = append(utxos, utxo)
+	}
+	if err := iter.Error(); err != nil {
+		return nil, fmt.Errorf("failed to iterate delegation index: %w", err)
+	}
+	return groupDelegations(utxos), nil
+}
+
+// groupDelegations groups delegated outputs by validator and delegator (sorted in that order)
+func groupDelegations(utxos []*UTXO) []*Delegation {
+	type pair struct{ validator, delegator prt.Address }
+	grouped := make(map[pair]*Delegation)
+	var delegations []*Delegation
+	for _, utxo := range utxos {
+		if utxo.TxOut.Validator == nil {
+			continue
+		}
+		key := pair{*utxo.TxOut.Validator, utxo.TxOut.Address}
+		delegation, exists := grouped[key]
+		if !exists {
+			delegation = &Delegation{Delegator: key.delegator, Validator: key.validator, Height: utxo.Height}
+			grouped[key] = delegation
+			delegations = append(delegations, delegation)
+		}
+		delegation.Amount += utxo.TxOut.Amount
+		if utxo.Height < delegation.Height {
+			delegation.Height = utxo.Height
+		}
+		delegation.Utxos = append(delegation.Utxos, utxo)
+	}
+
+	sort.Slice(delegations, func(i, j int) bool {
+		if c := bytes.Compare(delegations[i].Validator[:], delegations[j].Validator[:]); c != 0 {
+			return c < 0
+		}
+		return bytes.Compare(delegations[i].Delegator[:], delegations[j].Delegator[:]) < 0
+	})
+	return delegations
+}
+
+// BuildDelegateTx creates signed tx delegating amount of address to validator
+func (p *BlockChain) BuildDelegateTx(address, validator prt.Address, amount uint64, fee uint64, privateKeyBytes, publicKeyBytes []byte) (*Transaction, *CoinSelection, error) {
+	if address == validator {
+		return nil, nil, fmt.Errorf("cannot delegate to own address (stake instead)")
+	}
+	if fee == 0 {
+		fee = p.GetMinFee()
+	}
+	recipients := []TxRecipient{{Address: address, Amount: amount, Validator: &validator}}
+	return p.BuildSignedTx(address, recipients, fee, "", nil, TxTypeDelegate, TxBuildOptions{}, privateKeyBytes, publicKeyBytes)
+}
+
+// BuildUndelegateTx creates signed tx undelegating amount of address from validator.
+// Fee is paid from the delegation; undelegated funds are spendable after the unbonding period, the rest stays delegated.
+func (p *BlockChain) BuildUndelegateTx(address, validator prt.Address, amount uint64, fee uint64, privateKeyBytes, publicKeyBytes []byte) (*Transaction, error) {
+	delegations, err := p.GetDelegations(validator)
+	if err != nil {
+		return nil, err
+	}
+	for _, delegation := range delegations {
+		if delegation.Delegator == address {
+			rest := TxOutput{Address: address, TxType: TxTypeDelegate, Validator: &validator}
+			return p.buildUnbondTx(address, delegation.Utxos, amount, fee, rest, privateKeyBytes, publicKeyBytes)
+		}
+	}
+	return nil, fmt.Errorf("address %s has no delegation to %s", utils.AddressToString(address), utils.AddressToString(validator))
+}
+
+// GetRewardBalance returns claimable delegation reward of address as of the current tip
+func (p *BlockChain) GetRewardBalance(address prt.Address) (uint64, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.rewardBalance(address)
+}
+
+// rewardBalance reads reward balance of address (0 if it has none)
+func (p *BlockChain) rewardBalance(address prt.Address) (uint64, error) {
+	balanceBytes, err := p.db.Get(utils.GetRewardKey(address), nil)
+	if err == leveldb.ErrNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get reward balance: %w", err)
+	}
+	var balance uint64
+	if err := utils.DeserializeData(balanceBytes, &balance, utils.SerializationFormatGob); err != nil {
+		return 0, fmt.Errorf("failed to deserialize reward balance: %w", err)
+	}
+	return balance, nil
+}
+
+// putRewardBalance writes reward balance of address into batch (deleted when empty)
+func putRewardBalance(batch *leveldb.Batch, address prt.Address, balance uint64) error {
+	if balance == 0 {
+		batch.Delete(utils.GetRewardKey(address))
+		return nil
+	}
+	balanceBytes, err := utils.SerializeData(balance, utils.SerializationFormatGob)
+	if err != nil {
+		return fmt.Errorf("failed to serialize reward balance: %w", err)
+	}
+	batch.Put(utils.GetRewardKey(address), balanceBytes)
+	return nil
+}
+
+// BuildClaimRewardTx creates signed tx withdrawing the whole reward balance of address (minus fee) to it.
+// A spendable output of the address (or else one of its delegated outputs, delegated again) signs the claim.
+func (p *BlockChain) BuildClaimRewardTx(address prt.Address, fee uint64, privateKeyBytes, publicKeyBytes []byte) (*Transaction, error) {
+	if fee == 0 {
+		fee = p.GetMinFee()
+	}
+	claim, err := p.GetRewardBalance(address)
+	if err != nil {
+		return nil, err
+	}
+	if claim <= fee {
+		return nil, fmt.Errorf("claimable reward %d does not cover fee %d", claim, fee)
+	}
+
+	utxo, err := p.claimSignerUtxo(address)
+	if err != nil {
+		return nil, err
+	}
+
+	var txOuts []*TxOutput
+	if utxo.TxOut.TxType == TxTypeDelegate {
+		delegated := utxo.TxOut
+		txOuts = []*TxOutput{&delegated, {Address: address, Amount: claim - fee, TxType: TxTypeGeneral}}
+	} else {
+		txOuts = []*TxOutput{{Address: address, Amount: utxo.TxOut.Amount + claim - fee, TxType: TxTypeGeneral}}
+	}
+
+	publicKey := publicKeyBytes
+	if publicKey == nil {
+		publicKey = []byte{}
+	}
+	tx := &Transaction{
+		Version:   p.cfg.Version.Transaction,
+		NetworkID: p.cfg.Common.NetworkID,
+		Timestamp: time.Now().Unix(),
+		Inputs:    []*TxInput{{TxID: utxo.TxId, OutputIndex: utxo.OutputIndex, PublicKey: publicKey}},
+		Outputs:   txOuts,
+		Data:      []byte{},
+		Claim:     claim,
+	}
+	if err := signTx(tx, privateKeyBytes); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// claimSignerUtxo output of address spent by its claim tx (proves the claimant signed it)
+func (p *BlockChain) claimSignerUtxo(address prt.Address) (*UTXO, error) {
+	utxos, err := p.GetUtxoList(address, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get UTXO list: %w", err)
+	}
+	if len(utxos) > 0 {
+		return utxos[0], nil
+	}
+
+	delegations, err := p.GetDelegatorDelegations(address)
+	if err != nil {
+		return nil, err
+	}
+	for _, delegation := range delegations {
+		for _, utxo := range delegation.Utxos {
+			if !p.isOnMempool(utxo.TxId, utxo.OutputIndex) {
+				return utxo, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("address %s has no output to sign the claim with", utils.AddressToString(address))
+}
+
+// claimantOf address withdrawing the reward claimed by tx (key signing its inputs)
+func claimantOf(tx *Transaction) (prt.Address, error) {
+	if len(tx.Inputs) == 0 {
+		return prt.Address{}, fmt.Errorf("reward claim has no inputs")
+	}
+	return TxSpender{PublicKey: tx.Inputs[0].PublicKey}.Address()
+}
+
+// validateRewardClaim validates reward withdrawn by tx: every input is signed by the claimant,
+// whose balance covers the claim and the claims of other mempool txs (useMempool)
+func (p *BlockChain) validateRewardClaim(tx *Transaction, inputUtxos []*UTXO, useMempool bool) error {
+	if tx.Claim == 0 {
+		return nil
+	}
+
+	claimant, err := singleKeySigner(tx, inputUtxos)
+	if err != nil {
+		return fmt.Errorf("reward claim %w", err)
+	}
+	balance, err := p.rewardBalance(claimant)
+	if err != nil {
+		return err
+	}
+
+	claimed := tx.Claim
+	if useMempool && p.Mempool != nil {
+		claimed += p.pendingClaims(claimant, tx)
+	}
+	if claimed > balance {
+		return fmt.Errorf("reward claim %d exceeds reward balance %d of %s", claimed, balance, utils.AddressToString(claimant))
+	}
+	return nil
+}
+
+// pendingClaims sum of rewards claimed by mempool txs of claimant other than tx (and txs it replaces)
+func (p *BlockChain) pendingClaims(claimant prt.Address, tx *Transaction) uint64 {
+	spent := make(map[string]bool)
+	for _, input := range tx.Inputs {
+		spent[outpointKey(input.TxID, input.OutputIndex)] = true
+	}
+
+	var claimed uint64
+	for _, pendingTx := range p.Mempool.GetAllTxs() {
+		if pendingTx.Claim == 0 || pendingTx.ID == tx.ID {
+			continue
+		}
+		if address, err := claimantOf(pendingTx); err != nil || address != claimant {
+			continue
+		}
+		conflict := false
+		for _, input := range pendingTx.Inputs {
+			if spent[outpointKey(input.TxID, input.OutputIndex)] {
+				conflict = true
+				break
+			}
+		}
+		if !conflict {
+			claimed += pendingTx.Claim
+		}
+	}
+	return claimed
+}
+
+// addBlockClaim adds claim of tx to claims of a block and checks them against the claimant's reward balance
+func (p *BlockChain) addBlockClaim(claims map[prt.Address]uint64, tx *Transaction) error {
+	if tx.Claim == 0 {
+		return nil
+	}
+	claimant, err := claimantOf(tx)
+	if err != nil {
+		return err
+	}
+	balance, err := p.rewardBalance(claimant)
+	if err != nil {
+		return err
+	}
+	if claims[claimant]+tx.Claim > balance {
+		return fmt.Errorf("reward claims of %s in block exceed reward balance %d", utils.AddressToString(claimant), balance)
+	}
+	claims[claimant] += tx.Claim
+	return nil
+}
+
+// splitReward splits block reward of proposer between the proposer (own share + commission)
+// and its delegators of the epoch (in proportion to each delegation)
+func splitReward(epoch *Epoch, proposer prt.Address, reward uint64) (uint64, []RewardBalance) {
+	var validator *EpochValidator
+	for i := range epoch.Validators {
+		if epoch.Validators[i].Address == proposer {
+			validator = &epoch.Validators[i]
+			break
+		}
+	}
+	if validator == nil || validator.Delegated == 0 || validator.VotingPower == 0 {
+		return reward, nil
+	}
+
+	delegatorsPart := mulDiv(reward, validator.Delegated, validator.VotingPower)
+	distributed := delegatorsPart - mulDiv(delegatorsPart, epoch.CommissionRate, 100)
+
+	var shares []RewardBalance
+	var paid uint64
+	for _, delegation := range validator.Delegations {
+		amount := mulDiv(distributed, delegation.Amount, validator.Delegated)
+		if amount == 0 {
+			continue
+		}
+		shares = append(shares, RewardBalance{Address: delegation.Delegator, Amount: amount})
+		paid += amount
+	}
+	// Rounding remainder stays with the proposer
+	return reward - paid, shares
+}
+
+// mulDiv returns a*b/c without intermediate overflow (b <= c)
+func mulDiv(a, b, c uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	quo, _ := bits.Div64(hi, lo, c)
+	return quo
+}
+
+// applyRewards withdraws reward claims of block txs and credits the delegators' shares of the block reward.
+// Balances before the block are recorded in undo.
+func (p *BlockChain) applyRewards(batch *leveldb.Batch, blk *Block, fees uint64, undo *BlockUndo) error {
+	balances := make(map[prt.Address]uint64)
+	load := func(address prt.Address) (uint64, error) {
+		if balance, exists := balances[address]; exists {
+			return balance, nil
+		}
+		balance, err := p.rewardBalance(address)
+		if err != nil {
+			return 0, err
+		}
+		balances[address] = balance
+		undo.Rewards = append(undo.Rewards, RewardBalance{Address: address, Amount: balance})
+		return balance, nil
+	}
+
+	for _, tx := range blk.Transactions {
+		if tx.Claim == 0 {
+			continue
+		}
+		claimant, err := claimantOf(tx)
+		if err != nil {
+			return err
+		}
+		balance, err := load(claimant)
+		if err != nil {
+			return err
+		}
+		if tx.Claim > balance {
+			return fmt.Errorf("reward claim %d exceeds reward balance %d of %s", tx.Claim, balance, utils.AddressToString(claimant))
+		}
+		balances[claimant] = balance - tx.Claim
+	}
+
+	var emptyProposer prt.Address
+	if blk.Header.Height > 0 && blk.Proposer != emptyProposer {
+		if epoch := p.epochOrNilNoLock(blk.Header.Height); epoch != nil {
+			_, shares := splitReward(epoch, blk.Proposer, p.GetBlockReward()+fees)
+			for _, share := range shares {
+				balance, err := load(share.Address)
+				if err != nil {
+					return err
+				}
+				balances[share.Address] = balance + share.Amount
+			}
+		}
+	}
+
+	for _, prev := range undo.Rewards {
+		if err := putRewardBalance(batch, prev.Address, balances[prev.Address]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// revertRewards restores reward balances recorded in undo
+func revertRewards(batch *leveldb.Batch, undo *BlockUndo) error {
+	for _, prev := range undo.Rewards {
+		if err := putRewardBalance(batch, prev.Address, prev.Amount); err != nil {
+			return err
+		}
+	}
+	return nil
+}
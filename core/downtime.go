@@ -0,0 +1,384 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/abcfe/abcfe-node/common/logger"
+	"github.com/abcfe/abcfe-node/common/utils"
+	prt "github.com/abcfe/abcfe-node/protocol"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// Downtime tracking:
+//   - Every block carries the commit signatures of its parent (LastCommit, hashed into the header), so every
+//     node counts the same signers for the parent height.
+//   - Committing block h records, for each validator of the epoch of h-1 not jailed, whether it signed h-1.
+//     Only the last SignedBlocksWindow heights are kept.
+//   - A validator which missed more than allowed by MinSignedPerWindow within the window is jailed for downtime:
+//     inactive from the next block on and left out of later sets, like a double-signer, but not slashed.
+//   - After DowntimeJailBlocks the validator may release itself with an unjail tx (a self-transfer signed by its
+//     key with Unjail set), which also resets its uptime record.
+//
+// Uptime records are reverted with the block undo record.
+
+const (
+	DefaultSignedBlocksWindow = 100
+	DefaultMinSignedPerWindow = 50 // %
+	DefaultDowntimeJailBlocks = 100
+)
+
+// ValidatorUptime heights of the window the validator signed or missed (ascending)
+type ValidatorUptime struct {
+	Address prt.Address
+	Signed  []uint64
+	Missed  []uint64
+}
+
+// GetSignedBlocksWindow number of recent heights the uptime of a validator is tracked over
+func (p *BlockChain) GetSignedBlocksWindow() uint64 {
+	return p.GetConsensusParams().SignedBlocksWindow
+}
+
+// GetMinSignedPerWindow minimum percentage of the window a validator must sign
+func (p *BlockChain) GetMinSignedPerWindow() uint64 {
+	return p.GetConsensusParams().MinSignedPerWindow
+}
+
+// GetDowntimeJailBlocks blocks a validator jailed for downtime has to wait before unjailing
+func (p *BlockChain) GetDowntimeJailBlocks() uint64 {
+	return p.GetConsensusParams().DowntimeJailBlocks
+}
+
+// maxMissedPerWindow heights of the window a validator may miss without being jailed
+func (p *BlockChain) maxMissedPerWindow() uint64 {
+	window := p.GetSignedBlocksWindow()
+	return window - window*p.GetMinSignedPerWindow()/100
+}
+
+// lastCommitOf commit signatures of the parent block to carry in the block at height (sorted by validator)
+func (p *BlockChain) lastCommitOf(prevHash prt.Hash, height uint64) []CommitSignature {
+	if height < 2 {
+		return nil
+	}
+	parent, err := p.GetBlockByHash(prevHash)
+	if err != nil || len(parent.CommitSignatures) == 0 {
+		return nil
+	}
+
+	seen := make(map[prt.Address]bool)
+	lastCommit := make([]CommitSignature, 0, len(parent.CommitSignatures))
+	for _, sig := range parent.CommitSignatures {
+		if seen[sig.ValidatorAddress] {
+			continue
+		}
+		seen[sig.ValidatorAddress] = true
+		lastCommit = append(lastCommit, sig)
+	}
+	sort.Slice(lastCommit, func(i, j int) bool {
+		return bytes.Compare(lastCommit[i].ValidatorAddress[:], lastCommit[j].ValidatorAddress[:]) < 0
+	})
+	return lastCommit
+}
+
+// validateLastCommit validates commit signatures of the parent carried by block (lock must be held)
+func (p *BlockChain) validateLastCommit(block *Block) error {
+	if block.Header.LastCommitHash == nil {
+		if len(block.LastCommit) > 0 {
+			return fmt.Errorf("block carries last commit without its hash")
+		}
+		return nil
+	}
+	if len(block.LastCommit) == 0 {
+		return fmt.Errorf("block has last commit hash but no last commit")
+	}
+	if block.Header.Height < 2 {
+		return fmt.Errorf("block at height %d cannot carry last commit", block.Header.Height)
+	}
+	if utils.Hash(block.LastCommit) != *block.Header.LastCommitHash {
+		return fmt.Errorf("last commit hash mismatch")
+	}
+	for i := 1; i < len(block.LastCommit); i++ {
+		if bytes.Compare(block.LastCommit[i-1].ValidatorAddress[:], block.LastCommit[i].ValidatorAddress[:]) >= 0 {
+			return fmt.Errorf("last commit must be sorted by validator without duplicates")
+		}
+	}
+
+	if p.proposerValidator != nil {
+		height := block.Header.Height - 1
+		if err := p.proposerValidator.ValidateCommitSignatures(p.epochOrNilNoLock(height), height, block.Header.PrevHash, block.LastCommit); err != nil {
+			return fmt.Errorf("invalid last commit: %w", err)
+		}
+	}
+	return nil
+}
+
+// applyUptime records which validators signed the parent of block (LastCommit) and jails the ones
+// missing too many heights of the window. Records before the block are kept in undo.
+func (p *BlockChain) applyUptime(batch *leveldb.Batch, blk *Block, undo *BlockUndo) error {
+	if len(blk.LastCommit) == 0 || blk.Header.Height < 2 {
+		return nil
+	}
+	height := blk.Header.Height - 1
+	epoch, err := p.getEpochNoLock(height)
+	if err != nil {
+		return err
+	}
+
+	signed := make(map[prt.Address]bool, len(blk.LastCommit))
+	for _, sig := range blk.LastCommit {
+		signed[sig.ValidatorAddress] = true
+	}
+	// Validators jailed or unjailed by the block itself are left for the next block
+	changed := make(map[prt.Address]bool)
+	for _, tx := range blk.Transactions {
+		if validator, ok := jailChangeOf(tx); ok {
+			changed[validator] = true
+		}
+	}
+	for _, address := range undo.Jailed {
+		changed[address] = true
+	}
+
+	window := p.GetSignedBlocksWindow()
+	maxMissed := p.maxMissedPerWindow()
+	for _, validator := range epoch.Validators {
+		if validator.Jailed || changed[validator.Address] {
+			continue
+		}
+		jail, err := p.getJailNoLock(validator.Address)
+		if err != nil {
+			return err
+		}
+		if jail != nil {
+			continue
+		}
+
+		uptime, err := p.getUptimeNoLock(validator.Address)
+		if err != nil {
+			return err
+		}
+		undo.Uptimes = append(undo.Uptimes, *uptime)
+
+		if signed[validator.Address] {
+			uptime.Signed = append(uptime.Signed, height)
+		} else {
+			uptime.Missed = append(uptime.Missed, height)
+		}
+		if height >= window {
+			uptime.Signed = pruneHeights(uptime.Signed, height-window)
+			uptime.Missed = pruneHeights(uptime.Missed, height-window)
+		}
+		if err := putUptime(batch, uptime); err != nil {
+			return err
+		}
+
+		if uint64(len(uptime.Missed)) > maxMissed {
+			jail := &Jail{
+				Address: validator.Address,
+				Height:  blk.Header.Height,
+				Reason:  JailReasonDowntime,
+			}
+			if err := p.putJail(batch, jail, undo); err != nil {
+				return err
+			}
+			logger.Warn("[Downtime] Validator ", utils.AddressToString(validator.Address), " jailed at height ", jail.Height, " for missing ", len(uptime.Missed), " of the last ", window, " blocks")
+		}
+	}
+	return nil
+}
+
+// pruneHeights drops heights <= limit (heights ascending)
+func pruneHeights(heights []uint64, limit uint64) []uint64 {
+	i := sort.Search(len(heights), func(i int) bool { return heights[i] > limit })
+	return append([]uint64{}, heights[i:]...)
+}
+
+// putUptime writes uptime record into batch (deleted if empty)
+func putUptime(batch *leveldb.Batch, uptime *ValidatorUptime) error {
+	if len(uptime.Signed) == 0 && len(uptime.Missed) == 0 {
+		batch.Delete(utils.GetUptimeKey(uptime.Address))
+		return nil
+	}
+	uptimeBytes, err := utils.SerializeData(uptime, utils.SerializationFormatGob)
+	if err != nil {
+		return fmt.Errorf("failed to serialize uptime: %w", err)
+	}
+	batch.Put(utils.GetUptimeKey(uptime.Address), uptimeBytes)
+	return nil
+}
+
+// revertUptimes restores uptime records changed by a rolled back block
+func revertUptimes(batch *leveldb.Batch, undo *BlockUndo) error {
+	for i := len(undo.Uptimes) - 1; i >= 0; i-- {
+		if err := putUptime(batch, &undo.Uptimes[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetUptime returns uptime record of validator (empty if not tracked yet)
+func (p *BlockChain) GetUptime(address prt.Address) (*ValidatorUptime, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.getUptimeNoLock(address)
+}
+
+// getUptimeNoLock returns uptime record of validator (lock must be held)
+func (p *BlockChain) getUptimeNoLock(address prt.Address) (*ValidatorUptime, error) {
+	uptimeBytes, err := p.db.Get(utils.GetUptimeKey(address), nil)
+	if err == leveldb.ErrNotFound {
+		return &ValidatorUptime{Address: address}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get uptime: %w", err)
+	}
+	var uptime ValidatorUptime
+	if err := utils.DeserializeData(uptimeBytes, &uptime, utils.SerializationFormatGob); err != nil {
+		return nil, fmt.Errorf("failed to deserialize uptime: %w", err)
+	}
+	return &uptime, nil
+}
+
+// validateUnjail validates unjail tx: signed by a single key whose validator is jailed for downtime
+// for at least DowntimeJailBlocks, without another unjail of it in mempool (useMempool)
+func (p *BlockChain) validateUnjail(tx *Transaction, inputUtxos []*UTXO, useMempool bool, ctx spendContext) error {
+	if !tx.Unjail {
+		return nil
+	}
+
+	validator, err := singleKeySigner(tx, inputUtxos)
+	if err != nil {
+		return fmt.Errorf("unjail %w", err)
+	}
+	if err := p.checkUnjail(validator, ctx.Height); err != nil {
+		return err
+	}
+	if useMempool && p.Mempool != nil && p.pendingUnjailTx(validator, tx) != nil {
+		return fmt.Errorf("unjail of %s is already in mempool", utils.AddressToString(validator))
+	}
+	return nil
+}
+
+// checkUnjail checks that validator may be released at height
+func (p *BlockChain) checkUnjail(validator prt.Address, height uint64) error {
+	jail, err := p.getJailNoLock(validator)
+	if err != nil {
+		return err
+	}
+	if jail == nil {
+		return fmt.Errorf("validator %s is not jailed", utils.AddressToString(validator))
+	}
+	if jail.Reason != JailReasonDowntime {
+		return fmt.Errorf("validator %s is jailed for double-signing and cannot be unjailed", utils.AddressToString(validator))
+	}
+	if releaseHeight := jail.Height + p.GetDowntimeJailBlocks(); height < releaseHeight {
+		return fmt.Errorf("validator %s cannot be unjailed before height %d", utils.AddressToString(validator), releaseHeight)
+	}
+	return nil
+}
+
+// pendingUnjailTx mempool unjail tx of validator other than tx (and txs it replaces)
+func (p *BlockChain) pendingUnjailTx(validator prt.Address, tx *Transaction) *Transaction {
+	spent := make(map[string]bool)
+	for _, input := range tx.Inputs {
+		spent[outpointKey(input.TxID, input.OutputIndex)] = true
+	}
+
+	for _, pendingTx := range p.Mempool.GetAllTxs() {
+		if !pendingTx.Unjail || pendingTx.ID == tx.ID {
+			continue
+		}
+		if address, ok := jailChangeOf(pendingTx); !ok || address != validator {
+			continue
+		}
+		conflict := false
+		for _, input := range pendingTx.Inputs {
+			if spent[outpointKey(input.TxID, input.OutputIndex)] {
+				conflict = true
+				break
+			}
+		}
+		if !conflict {
+			return pendingTx
+		}
+	}
+	return nil
+}
+
+// releaseJail releases validator of unjail tx and resets its uptime (previous records kept in undo)
+func (p *BlockChain) releaseJail(batch *leveldb.Batch, tx *Transaction, undo *BlockUndo) error {
+	validator, ok := jailChangeOf(tx)
+	if !ok {
+		return fmt.Errorf("unjail tx %s has no signer", utils.HashToString(tx.ID))
+	}
+	jail, err := p.getJailNoLock(validator)
+	if err != nil {
+		return err
+	}
+	if jail == nil {
+		return fmt.Errorf("validator %s is not jailed", utils.AddressToString(validator))
+	}
+	undo.Released = append(undo.Released, *jail)
+	batch.Delete(utils.GetJailKey(validator))
+
+	uptime, err := p.getUptimeNoLock(validator)
+	if err != nil {
+		return err
+	}
+	undo.Uptimes = append(undo.Uptimes, *uptime)
+	batch.Delete(utils.GetUptimeKey(validator))
+
+	logger.Info("[Downtime] Validator ", utils.AddressToString(validator), " unjailed")
+	return nil
+}
+
+// BuildUnjailTx builds unjail tx of the validator: one spendable output of address sent back to itself less fee
+func (p *BlockChain) BuildUnjailTx(address prt.Address, fee uint64, privateKeyBytes, publicKeyBytes []byte) (*Transaction, error) {
+	if fee == 0 {
+		fee = p.GetMinFee()
+	}
+	p.mu.RLock()
+	err := p.checkUnjail(address, p.nextSpendContext().Height)
+	p.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	utxos, err := p.GetUtxoList(address, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get UTXO list: %w", err)
+	}
+	var utxo *UTXO
+	for _, candidate := range utxos {
+		if candidate.TxOut.Amount > fee && candidate.TxOut.HTLC == nil {
+			utxo = candidate
+			break
+		}
+	}
+	if utxo == nil {
+		return nil, fmt.Errorf("address %s has no output covering unjail fee %d", utils.AddressToString(address), fee)
+	}
+
+	publicKey := publicKeyBytes
+	if publicKey == nil {
+		publicKey = []byte{}
+	}
+	tx := &Transaction{
+		Version:   p.cfg.Version.Transaction,
+		NetworkID: p.cfg.Common.NetworkID,
+		Timestamp: time.Now().Unix(),
+		Inputs:    []*TxInput{{TxID: utxo.TxId, OutputIndex: utxo.OutputIndex, PublicKey: publicKey}},
+		Outputs:   []*TxOutput{{Address: address, Amount: utxo.TxOut.Amount - fee, TxType: TxTypeGeneral}},
+		Data:      []byte{},
+		Unjail:    true,
+	}
+	if err := signTx(tx, privateKeyBytes); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
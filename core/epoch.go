@@ -0,0 +1,370 @@
+package core
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"sort"
+
+	"github.com/abcfe/abcfe-node/common/logger"
+	"github.com/abcfe/abcfe-node/common/utils"
+	prt "github.com/abcfe/abcfe-node/protocol"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// Validator epochs:
+//   - Epoch n covers heights n*EpochBlocks+1 .. (n+1)*EpochBlocks.
+//   - Its validator set is the genesis validators (config) plus stakers with at least MinValidatorStake,
+//     taken from the stakes as of the boundary block n*EpochBlocks (genesis for epoch 0).
+//     Delegations to those validators add to their voting power and share their block rewards (delegation.go).
+//   - The set is stored keyed by the epoch start height when the boundary block is committed and deleted
+//     when it is rolled back, so blocks of any height are verified against the set which signed them.
+//   - Validators jailed for double-signing (evidence.go) or downtime (downtime.go) are left out of later sets
+//     and read as Jailed (inactive) in their own epoch from the block after the jailing one.
+
+const (
+	DefaultEpochBlocks = 100  // Epoch length when not configured
+	MinValidatorStake  = 1000 // Minimum stake to become validator
+	EpochIndexVersion  = "3"  // Bump to rebuild epoch history on startup
+)
+
+// EpochValidator validator of an epoch
+type EpochValidator struct {
+	Address     prt.Address
+	PublicKey   []byte
+	VotingPower uint64            // Genesis voting power + stake + delegations
+	Delegated   uint64            // Sum of delegations
+	Delegations []EpochDelegation // Sorted by delegator
+	Jailed      bool              // Jailed during the epoch (set when read, not stored)
+}
+
+// EpochDelegation delegation to a validator of an epoch
+type EpochDelegation struct {
+	Delegator prt.Address
+	Amount    uint64
+}
+
+// Epoch validator set active for a range of heights
+type Epoch struct {
+	Number           uint64
+	StartHeight      uint64           // First height signed by this set
+	BoundaryHash     prt.Hash         // Block (StartHeight - 1) whose stakes formed the set
+	Validators       []EpochValidator // Sorted by address
+	TotalVotingPower uint64
+	CommissionRate   uint64 // Percent of the delegators' reward share kept by the proposer
+}
+
+// EndHeight last height signed by the epoch's set
+func (p *Epoch) EndHeight(epochBlocks uint64) uint64 {
+	return p.StartHeight + epochBlocks - 1
+}
+
+// GetEpochBlocks blocks per epoch
+func (p *BlockChain) GetEpochBlocks() uint64 {
+	return p.GetConsensusParams().EpochBlocks
+}
+
+// EpochOf epoch number of block height (genesis belongs to epoch 0)
+func (p *BlockChain) EpochOf(height uint64) uint64 {
+	if height == 0 {
+		return 0
+	}
+	return (height - 1) / p.GetEpochBlocks()
+}
+
+// GetEpoch returns validator set active at height.
+// Heights beyond the next known epoch get the latest stored epoch (the set expected to continue).
+func (p *BlockChain) GetEpoch(height uint64) (*Epoch, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.getEpochNoLock(height)
+}
+
+// getEpochNoLock returns validator set active at height (lock must be held)
+func (p *BlockChain) getEpochNoLock(height uint64) (*Epoch, error) {
+	startHeight := p.EpochOf(height)*p.GetEpochBlocks() + 1
+	if latestStart := p.EpochOf(p.LatestHeight+1)*p.GetEpochBlocks() + 1; startHeight > latestStart {
+		startHeight = latestStart
+	}
+
+	epochBytes, err := p.db.Get(utils.GetEpochKey(startHeight), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get epoch starting at %d: %w", startHeight, err)
+	}
+	var epoch Epoch
+	if err := utils.DeserializeData(epochBytes, &epoch, utils.SerializationFormatGob); err != nil {
+		return nil, fmt.Errorf("failed to deserialize epoch: %w", err)
+	}
+
+	// Validators jailed by an earlier block of the epoch no longer sign
+	for i := range epoch.Validators {
+		jail, err := p.getJailNoLock(epoch.Validators[i].Address)
+		if err != nil {
+			return nil, err
+		}
+		epoch.Validators[i].Jailed = jail != nil && jail.Height >= epoch.StartHeight && jail.Height < height
+	}
+	return &epoch, nil
+}
+
+// epochOrNilNoLock validator set of height for checks which may run before the epoch is known
+// (side-chain / orphan blocks); nil lets the validator use its current set
+func (p *BlockChain) epochOrNilNoLock(height uint64) *Epoch {
+	epoch, err := p.getEpochNoLock(height)
+	if err != nil {
+		return nil
+	}
+	return epoch
+}
+
+// GetEpochs returns stored epochs starting in [fromHeight, toHeight] (ascending)
+func (p *BlockChain) GetEpochs(fromHeight, toHeight uint64) ([]*Epoch, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	iter := p.db.NewIterator(&util.Range{Start: utils.GetEpochKey(fromHeight), Limit: utils.GetEpochKey(toHeight + 1)}, nil)
+	defer iter.Release()
+
+	var epochs []*Epoch
+	for iter.Next() {
+		var epoch Epoch
+		if err := utils.DeserializeData(iter.Value(), &epoch, utils.SerializationFormatGob); err != nil {
+			return nil, fmt.Errorf("failed to deserialize epoch: %w", err)
+		}
+		epochs = append(epochs, &epoch)
+	}
+	if err := iter.Error(); err != nil {
+		return nil, fmt.Errorf("failed to iterate epochs: %w", err)
+	}
+	return epochs, nil
+}
+
+// isEpochBoundary checks if block at height decides the validator set of the next epoch
+func (p *BlockChain) isEpochBoundary(height uint64) bool {
+	return height%p.GetEpochBlocks() == 0
+}
+
+// buildEpoch builds validator set of epoch from genesis validators, stakes and delegations as of its boundary block
+// (jailed validators excluded)
+func (p *BlockChain) buildEpoch(number uint64, boundaryHash prt.Hash, stakes map[prt.Address]*Stake, delegations []*Delegation, jailed map[prt.Address]bool) (*Epoch, error) {
+	validators := make(map[prt.Address]*EpochValidator)
+	for _, v := range p.cfg.Validators.List {
+		address, err := utils.StringToAddress(v.Address)
+		if err != nil {
+			return nil, fmt.Errorf("invalid validator address %s: %w", v.Address, err)
+		}
+		var publicKey []byte
+		if v.PublicKey != "" {
+			if publicKey, err = hex.DecodeString(v.PublicKey); err != nil {
+				return nil, fmt.Errorf("invalid validator public key %s: %w", v.PublicKey, err)
+			}
+		}
+		validators[address] = &EpochValidator{Address: address, PublicKey: publicKey, VotingPower: v.VotingPower}
+	}
+
+	for address, stake := range stakes {
+		if stake.Amount < MinValidatorStake {
+			continue
+		}
+		if v, exists := validators[address]; exists {
+			v.VotingPower += stake.Amount
+			if len(v.PublicKey) == 0 {
+				v.PublicKey = stake.PublicKey
+			}
+			continue
+		}
+		validators[address] = &EpochValidator{Address: address, PublicKey: stake.PublicKey, VotingPower: stake.Amount}
+	}
+
+	for address := range jailed {
+		delete(validators, address)
+	}
+
+	// Delegations to addresses which are not validators of the epoch carry no power
+	for _, delegation := range delegations {
+		v, exists := validators[delegation.Validator]
+		if !exists {
+			continue
+		}
+		v.VotingPower += delegation.Amount
+		v.Delegated += delegation.Amount
+		v.Delegations = append(v.Delegations, EpochDelegation{Delegator: delegation.Delegator, Amount: delegation.Amount})
+	}
+
+	epoch := &Epoch{
+		Number:         number,
+		StartHeight:    number*p.GetEpochBlocks() + 1,
+		BoundaryHash:   boundaryHash,
+		CommissionRate: p.GetCommissionRate(),
+	}
+	for _, v := range validators {
+		epoch.Validators = append(epoch.Validators, *v)
+		epoch.TotalVotingPower += v.VotingPower
+	}
+	sort.Slice(epoch.Validators, func(i, j int) bool {
+		return bytes.Compare(epoch.Validators[i].Address[:], epoch.Validators[j].Address[:]) < 0
+	})
+	return epoch, nil
+}
+
+// saveEpoch stores validator set of the epoch following boundary block
+func (p *BlockChain) saveEpoch(batch *leveldb.Batch, blk *Block, stakes map[prt.Address]*Stake, delegations []*Delegation, jailed map[prt.Address]bool) (*Epoch, error) {
+	epoch, err := p.buildEpoch(blk.Header.Height/p.GetEpochBlocks(), blk.Header.Hash, stakes, delegations, jailed)
+	if err != nil {
+		return nil, err
+	}
+	epochBytes, err := utils.SerializeData(epoch, utils.SerializationFormatGob)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize epoch: %w", err)
+	}
+	batch.Put(utils.GetEpochKey(epoch.StartHeight), epochBytes)
+	return epoch, nil
+}
+
+// updateEpochNoLock stores next epoch when committed main chain block is an epoch boundary.
+// Runs after the block is written so the stake index reflects it.
+func (p *BlockChain) updateEpochNoLock(blk *Block) error {
+	if !p.isEpochBoundary(blk.Header.Height) {
+		return nil
+	}
+
+	stakes, err := p.collectStakes([]byte(prt.PrefixStake))
+	if err != nil {
+		return err
+	}
+	delegations, err := p.collectDelegations([]byte(prt.PrefixDelegation))
+	if err != nil {
+		return err
+	}
+	jails, err := p.collectJails()
+	if err != nil {
+		return err
+	}
+	jailed := make(map[prt.Address]bool)
+	for _, jail := range jails {
+		jailed[jail.Address] = true
+	}
+	batch := new(leveldb.Batch)
+	epoch, err := p.saveEpoch(batch, blk, stakes, delegations, jailed)
+	if err != nil {
+		return err
+	}
+	if err := p.db.Write(batch, nil); err != nil {
+		return fmt.Errorf("failed to write epoch: %w", err)
+	}
+
+	logger.Info("[Epoch] Epoch ", epoch.Number, " from height ", epoch.StartHeight, ": ", len(epoch.Validators), " validators, total power ", epoch.TotalVotingPower)
+	return nil
+}
+
+// deleteEpoch removes epoch decided by boundary block which is rolled back
+func (p *BlockChain) deleteEpoch(batch *leveldb.Batch, blk *Block) {
+	if p.isEpochBoundary(blk.Header.Height) {
+		batch.Delete(utils.GetEpochKey(blk.Header.Height + 1))
+	}
+}
+
+// rebuildEpochs replays main chain blocks to store the validator set of every epoch
+// when history is missing, outdated or lacks the current epoch (interrupted write)
+func (p *BlockChain) rebuildEpochs() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	versionBytes, err := p.db.Get([]byte(prt.PrefixMetaEpochIx), nil)
+	if err != nil && err != leveldb.ErrNotFound {
+		return fmt.Errorf("failed to get epoch history version: %w", err)
+	}
+	if p.LatestBlockHash == "" {
+		// Epochs are stored as blocks are committed from genesis on
+		if err := p.db.Put([]byte(prt.PrefixMetaEpochIx), []byte(EpochIndexVersion), nil); err != nil {
+			return fmt.Errorf("failed to save epoch history version: %w", err)
+		}
+		return nil
+	}
+	if string(versionBytes) == EpochIndexVersion {
+		if _, err := p.getEpochNoLock(p.LatestHeight + 1); err == nil {
+			return nil
+		}
+	}
+
+	logger.Info("[Epoch] Rebuilding epoch history up to height ", p.LatestHeight)
+
+	// Drop stale entries
+	batch := new(leveldb.Batch)
+	iter := p.db.NewIterator(util.BytesPrefix([]byte(prt.PrefixEpoch)), nil)
+	for iter.Next() {
+		batch.Delete(append([]byte{}, iter.Key()...))
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		return fmt.Errorf("failed to iterate epochs: %w", err)
+	}
+
+	// Unspent staked / delegated outputs while replaying (utxo key -> output), staker keys and jailed validators
+	bonded := make(map[string]*UTXO)
+	stakerKeys := make(map[prt.Address][]byte)
+	jailed := make(map[prt.Address]bool)
+	for height := uint64(0); height <= p.LatestHeight; height++ {
+		blk, err := p.getBlockByHeightNoLock(height)
+		if err != nil {
+			return fmt.Errorf("failed to load block %d: %w", height, err)
+		}
+
+		// Jails released or replaced by the block, then jails it added
+		undo, err := p.getBlockUndoNoLock(blk)
+		if err != nil {
+			return err
+		}
+		for _, jail := range undo.Released {
+			delete(jailed, jail.Address)
+		}
+		for _, address := range undo.Jailed {
+			jailed[address] = true
+		}
+
+		for _, tx := range blk.Transactions {
+			for _, input := range tx.Inputs {
+				delete(bonded, string(utils.GetUtxoKey(input.TxID, int(input.OutputIndex))))
+			}
+			for outputIndex, output := range tx.Outputs {
+				if !isBondedType(output.TxType) || len(tx.Inputs) == 0 {
+					continue
+				}
+				bonded[string(utils.GetUtxoKey(tx.ID, outputIndex))] = &UTXO{TxId: tx.ID, OutputIndex: uint64(outputIndex), TxOut: *output, Height: height}
+				if output.TxType == TxTypeStaking {
+					stakerKeys[output.Address] = tx.Inputs[0].PublicKey
+				}
+			}
+		}
+
+		if !p.isEpochBoundary(height) {
+			continue
+		}
+		stakes := make(map[prt.Address]*Stake)
+		var delegated []*UTXO
+		for _, utxo := range bonded {
+			if utxo.TxOut.TxType == TxTypeDelegate {
+				delegated = append(delegated, utxo)
+				continue
+			}
+			address := utxo.TxOut.Address
+			stake, exists := stakes[address]
+			if !exists {
+				stake = &Stake{Address: address, PublicKey: stakerKeys[address], Height: utxo.Height}
+				stakes[address] = stake
+			}
+			stake.Amount += utxo.TxOut.Amount
+		}
+		if _, err := p.saveEpoch(batch, blk, stakes, groupDelegations(delegated), jailed); err != nil {
+			return err
+		}
+	}
+
+	batch.Put([]byte(prt.PrefixMetaEpochIx), []byte(EpochIndexVersion))
+	if err := p.db.Write(batch, nil); err != nil {
+		return fmt.Errorf("failed to write epoch history: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,527 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/abcfe/abcfe-node/common/crypto"
+	"github.com/abcfe/abcfe-node/common/logger"
+	"github.com/abcfe/abcfe-node/common/utils"
+	prt "github.com/abcfe/abcfe-node/protocol"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// Double-sign evidence and slashing:
+//   - A validator double-signs when it signs two different blocks for the same height and round, either as
+//     proposer (the round is part of the block header) or with two votes of the same type (VoteSignHash).
+//   - Evidence carries both signatures and is committed by an evidence tx. The tx spends every staked output
+//     of the offender without signatures and pays each back less SlashRate percent; the difference is burnt.
+//     Unbonding outputs of the offender still locked at the evidence block are slashed the same way and keep
+//     their unlock height, so unstaking before the evidence is committed does not escape the slash.
+//   - Committing the tx jails the offender: it is inactive in the set of its epoch from the next block on
+//     and left out of the sets of later epochs. A double-sign jail is permanent (a downtime jail, see
+//     downtime.go, is replaced by it).
+//
+// Jail records are reverted with the block undo record.
+
+const (
+	DefaultSlashRate = 5 // Slash percent when not configured
+)
+
+// EvidenceType kind of double-signing
+type EvidenceType uint8
+
+const (
+	EvidenceDuplicateVote     EvidenceType = iota // Two votes of the same type
+	EvidenceDuplicateProposal                     // Two proposed blocks
+)
+
+// Vote types signed by validators (VoteSignHash)
+const (
+	VoteTypePrevote   uint8 = iota // Prevote
+	VoteTypePrecommit              // Precommit
+)
+
+// JailReason why a validator was jailed
+type JailReason uint8
+
+const (
+	JailReasonDoubleSign JailReason = iota // Permanent
+	JailReasonDowntime                     // Released by an unjail tx (downtime.go)
+)
+
+// String returns jail reason name
+func (r JailReason) String() string {
+	switch r {
+	case JailReasonDoubleSign:
+		return "doubleSign"
+	case JailReasonDowntime:
+		return "downtime"
+	default:
+		return "unknown"
+	}
+}
+
+// EvidenceSignature one of two conflicting signatures of the offender
+type EvidenceSignature struct {
+	BlockHash prt.Hash      `json:"blockHash"`
+	Signature prt.Signature `json:"signature"`
+	Header    *BlockHeader  `json:"header,omitempty"` // Signed block header (duplicate proposal only)
+}
+
+// Evidence proof that a validator signed two different blocks for the same height and round
+type Evidence struct {
+	Type      EvidenceType      `json:"type"`
+	Validator prt.Address       `json:"validator"`
+	Height    uint64            `json:"height"`
+	Round     uint32            `json:"round"`
+	VoteType  uint8             `json:"voteType,omitempty"` // Duplicate vote only
+	First     EvidenceSignature `json:"first"`              // Signature of the lower block hash
+	Second    EvidenceSignature `json:"second"`
+}
+
+// Jail jailed validator
+type Jail struct {
+	Address  prt.Address
+	Height   uint64 // Height of the block which jailed the validator
+	Reason   JailReason
+	Evidence prt.Hash // Evidence tx (zero for downtime)
+}
+
+// Domains of consensus sign hashes. They separate votes and proposals from each other and from tx IDs
+// and block hashes signed with the same validator key.
+const (
+	VoteSignDomain     = "abcfe/vote"
+	ProposalSignDomain = "abcfe/proposal"
+)
+
+// voteSignData data signed by a prevote / precommit
+type voteSignData struct {
+	Domain    string   `json:"domain"`
+	Height    uint64   `json:"height"`
+	Round     uint32   `json:"round"`
+	Type      uint8    `json:"type"`
+	BlockHash prt.Hash `json:"blockHash"`
+}
+
+// VoteSignHash hash signed by a vote, binding the block hash to height, round and vote type
+func VoteSignHash(height uint64, round uint32, voteType uint8, blockHash prt.Hash) prt.Hash {
+	return utils.Hash(voteSignData{Domain: VoteSignDomain, Height: height, Round: round, Type: voteType, BlockHash: blockHash})
+}
+
+// proposalSignData data signed by a proposal
+type proposalSignData struct {
+	Domain    string   `json:"domain"`
+	Height    uint64   `json:"height"`
+	Round     uint32   `json:"round"`
+	POLRound  int32    `json:"polRound"`
+	BlockHash prt.Hash `json:"blockHash"`
+}
+
+// ProposalSignHash hash signed by the proposer of a round. A block proposed in an earlier round is
+// re-proposed with the round of its proof-of-lock (polRound, -1 for a new block).
+func ProposalSignHash(height uint64, round uint32, polRound int32, blockHash prt.Hash) prt.Hash {
+	return utils.Hash(proposalSignData{Domain: ProposalSignDomain, Height: height, Round: round, POLRound: polRound, BlockHash: blockHash})
+}
+
+// NewDuplicateVoteEvidence creates evidence of two votes of validator for different blocks
+func NewDuplicateVoteEvidence(validator prt.Address, height uint64, round uint32, voteType uint8, hashA prt.Hash, sigA prt.Signature, hashB prt.Hash, sigB prt.Signature) *Evidence {
+	return orderEvidence(&Evidence{
+		Type:      EvidenceDuplicateVote,
+		Validator: validator,
+		Height:    height,
+		Round:     round,
+		VoteType:  voteType,
+		First:     EvidenceSignature{BlockHash: hashA, Signature: sigA},
+		Second:    EvidenceSignature{BlockHash: hashB, Signature: sigB},
+	})
+}
+
+// NewDuplicateProposalEvidence creates evidence of two blocks proposed by the same proposer for one height and round
+func NewDuplicateProposalEvidence(a, b *Block) *Evidence {
+	headerA, headerB := a.Header, b.Header
+	return orderEvidence(&Evidence{
+		Type:      EvidenceDuplicateProposal,
+		Validator: a.Proposer,
+		Height:    a.Header.Height,
+		Round:     a.Header.Round,
+		First:     EvidenceSignature{BlockHash: a.Header.Hash, Signature: a.Signature, Header: &headerA},
+		Second:    EvidenceSignature{BlockHash: b.Header.Hash, Signature: b.Signature, Header: &headerB},
+	})
+}
+
+// orderEvidence puts the signature of the lower block hash first, so a double-sign has one encoding
+func orderEvidence(ev *Evidence) *Evidence {
+	if bytes.Compare(ev.First.BlockHash[:], ev.Second.BlockHash[:]) > 0 {
+		ev.First, ev.Second = ev.Second, ev.First
+	}
+	return ev
+}
+
+// GetSlashRate percent of the offender's stake burnt by an evidence tx
+func (p *BlockChain) GetSlashRate() uint64 {
+	return p.GetConsensusParams().SlashRate
+}
+
+// verifyEvidenceNoLock checks evidence against the validator set of its height for a block at nextHeight.
+// Evidence older than the unbonding period is rejected, the stake may have left.
+func (p *BlockChain) verifyEvidenceNoLock(ev *Evidence, nextHeight uint64) error {
+	if ev.Height == 0 || ev.Height > nextHeight {
+		return fmt.Errorf("evidence height %d is not before block %d", ev.Height, nextHeight)
+	}
+	if nextHeight-ev.Height > p.GetUnbondingBlocks() {
+		return fmt.Errorf("evidence at height %d is older than the unbonding period", ev.Height)
+	}
+	if bytes.Compare(ev.First.BlockHash[:], ev.Second.BlockHash[:]) >= 0 {
+		return fmt.Errorf("evidence block hashes must differ and be ordered")
+	}
+
+	epoch, err := p.getEpochNoLock(ev.Height)
+	if err != nil {
+		return fmt.Errorf("failed to get validator set: %w", err)
+	}
+	var publicKeyBytes []byte
+	for _, v := range epoch.Validators {
+		if v.Address == ev.Validator {
+			publicKeyBytes = v.PublicKey
+		}
+	}
+	if len(publicKeyBytes) == 0 {
+		return fmt.Errorf("%s is not a validator at height %d", utils.AddressToString(ev.Validator), ev.Height)
+	}
+	publicKey, err := crypto.BytesToPublicKey(publicKeyBytes)
+	if err != nil {
+		return fmt.Errorf("failed to parse validator public key: %w", err)
+	}
+
+	for i, sig := range []EvidenceSignature{ev.First, ev.Second} {
+		signed := sig.BlockHash
+		switch ev.Type {
+		case EvidenceDuplicateVote:
+			if ev.VoteType > VoteTypePrecommit {
+				return fmt.Errorf("unknown vote type %d", ev.VoteType)
+			}
+			if sig.Header != nil {
+				return fmt.Errorf("duplicate vote evidence carries a block header")
+			}
+			signed = VoteSignHash(ev.Height, ev.Round, ev.VoteType, sig.BlockHash)
+		case EvidenceDuplicateProposal:
+			if sig.Header == nil {
+				return fmt.Errorf("duplicate proposal evidence needs block headers")
+			}
+			if sig.Header.Height != ev.Height || sig.Header.Round != ev.Round {
+				return fmt.Errorf("proposed block %d is not at height %d round %d", i, ev.Height, ev.Round)
+			}
+			if err := ValidateBlockHash(&Block{Header: *sig.Header}); err != nil {
+				return err
+			}
+			if sig.Header.Hash != sig.BlockHash {
+				return fmt.Errorf("proposed block %d hash mismatch", i)
+			}
+		default:
+			return fmt.Errorf("unknown evidence type %d", ev.Type)
+		}
+
+		if !crypto.VerifySignature(publicKey, utils.HashToBytes(signed), sig.Signature) {
+			return fmt.Errorf("invalid signature %d of %s", i, utils.AddressToString(ev.Validator))
+		}
+	}
+	return nil
+}
+
+// slashableOutputs staked outputs of validator followed by its unbonding outputs still locked in a block at height,
+// with the staker key carried by the inputs spending them
+func (p *BlockChain) slashableOutputs(validator prt.Address, height uint64) ([]*UTXO, []byte, error) {
+	stakes, err := p.collectStakes(utils.GetStakePrefix(validator))
+	if err != nil {
+		return nil, nil, err
+	}
+	var utxos []*UTXO
+	if stake := stakes[validator]; stake != nil {
+		utxos = stake.Utxos
+	}
+	unbonding, err := p.collectUnbonding(validator, height)
+	if err != nil {
+		return nil, nil, err
+	}
+	utxos = append(utxos, unbonding...)
+	if len(utxos) == 0 {
+		return nil, nil, nil
+	}
+
+	publicKey, err := p.db.Get(utils.GetStakerKeyKey(validator), nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get staker key of %s: %w", utils.AddressToString(validator), err)
+	}
+	return utxos, publicKey, nil
+}
+
+// slashOutputs outputs paying staked / unbonding outputs back less the slash (fully slashed outputs are dropped).
+// Unbonding outputs are locked until their original unlock height.
+func (p *BlockChain) slashOutputs(utxos []*UTXO) []*TxOutput {
+	rate := p.GetSlashRate()
+	outputs := []*TxOutput{}
+	for _, utxo := range utxos {
+		kept := utxo.TxOut.Amount - mulDiv(utxo.TxOut.Amount, rate, 100)
+		if kept == 0 {
+			continue
+		}
+		output := utxo.TxOut
+		output.Amount = kept
+		if output.TxType == TxTypeUnStaking {
+			output.LockHeight = unbondingEnd(utxo)
+			output.RelativeLockHeight = 0
+		}
+		outputs = append(outputs, &output)
+	}
+	return outputs
+}
+
+// BuildEvidenceTx creates evidence tx slashing every confirmed staked and still locked unbonding output of the offender.
+// Inputs carry the staker key (not signed), so the outputs paid back stay staked under the same identity.
+func (p *BlockChain) BuildEvidenceTx(ev *Evidence) (*Transaction, error) {
+	p.mu.RLock()
+	if err := p.verifyEvidenceNoLock(ev, p.LatestHeight+1); err != nil {
+		p.mu.RUnlock()
+		return nil, err
+	}
+	jail, err := p.getJailNoLock(ev.Validator)
+	if err != nil {
+		p.mu.RUnlock()
+		return nil, err
+	}
+	slashed, publicKey, err := p.slashableOutputs(ev.Validator, p.LatestHeight+1)
+	p.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	if jail != nil && jail.Reason == JailReasonDoubleSign {
+		return nil, fmt.Errorf("validator %s is already jailed for double-signing", utils.AddressToString(ev.Validator))
+	}
+
+	tx := &Transaction{
+		Version:   p.cfg.Version.Transaction,
+		NetworkID: p.cfg.Common.NetworkID,
+		Timestamp: time.Now().Unix(),
+		Inputs:    []*TxInput{},
+		Outputs:   []*TxOutput{},
+		Memo:      fmt.Sprintf("Evidence: %s double-signed at height %d round %d", utils.AddressToString(ev.Validator), ev.Height, ev.Round),
+		Data:      []byte{},
+		Evidence:  ev,
+	}
+	for _, utxo := range slashed {
+		tx.Inputs = append(tx.Inputs, &TxInput{TxID: utxo.TxId, OutputIndex: utxo.OutputIndex, PublicKey: publicKey})
+	}
+	tx.Outputs = p.slashOutputs(slashed)
+	tx.ID = utils.Hash(tx)
+	return tx, nil
+}
+
+// validateEvidenceTx validates evidence tx: valid evidence against a validator not jailed for double-signing yet
+// (nor by another mempool tx with useMempool), spending all its confirmed staked and still locked unbonding outputs
+// and paying them back less the slash
+func (p *BlockChain) validateEvidenceTx(tx *Transaction, useMempool bool, ctx spendContext) error {
+	ev := tx.Evidence
+	if tx.Claim > 0 || tx.Unjail {
+		return fmt.Errorf("evidence tx cannot claim rewards or unjail")
+	}
+	if err := p.verifyEvidenceNoLock(ev, ctx.Height); err != nil {
+		return fmt.Errorf("invalid evidence: %w", err)
+	}
+
+	jail, err := p.getJailNoLock(ev.Validator)
+	if err != nil {
+		return err
+	}
+	if jail != nil && jail.Reason == JailReasonDoubleSign {
+		return fmt.Errorf("validator %s is already jailed for double-signing", utils.AddressToString(ev.Validator))
+	}
+	if useMempool && p.pendingEvidenceTx(ev.Validator, tx.ID) != nil {
+		return fmt.Errorf("evidence against %s is already pending", utils.AddressToString(ev.Validator))
+	}
+
+	staked, publicKey, err := p.slashableOutputs(ev.Validator, ctx.Height)
+	if err != nil {
+		return err
+	}
+
+	if len(tx.Inputs) != len(staked) {
+		return fmt.Errorf("evidence tx must spend all %d staked / unbonding outputs of the offender, got %d inputs", len(staked), len(tx.Inputs))
+	}
+	for i, input := range tx.Inputs {
+		if input.TxID != staked[i].TxId || input.OutputIndex != staked[i].OutputIndex {
+			return fmt.Errorf("input[%d]: not staked / unbonding output %s:%d of the offender", i, utils.HashToString(staked[i].TxId), staked[i].OutputIndex)
+		}
+		if !bytes.Equal(input.PublicKey, publicKey) || input.MultiSig != nil || len(input.Preimage) > 0 {
+			return fmt.Errorf("input[%d]: must carry only the staker key", i)
+		}
+	}
+
+	expected := p.slashOutputs(staked)
+	if len(tx.Outputs) != len(expected) {
+		return fmt.Errorf("evidence tx must have %d outputs, got %d", len(expected), len(tx.Outputs))
+	}
+	for i, output := range tx.Outputs {
+		if !sameOutput(output, expected[i]) {
+			return fmt.Errorf("output[%d]: must pay back staked / unbonding output less %d%%", i, p.GetSlashRate())
+		}
+	}
+	return nil
+}
+
+// pendingEvidenceTx mempool tx with evidence against validator other than txId (nil if none)
+func (p *BlockChain) pendingEvidenceTx(validator prt.Address, txId prt.Hash) *Transaction {
+	for _, pendingTx := range p.Mempool.GetAllTxs() {
+		if pendingTx.Evidence != nil && pendingTx.Evidence.Validator == validator && pendingTx.ID != txId {
+			return pendingTx
+		}
+	}
+	return nil
+}
+
+// HasPendingEvidence checks if an evidence tx against validator is in mempool
+func (p *BlockChain) HasPendingEvidence(validator prt.Address) bool {
+	return p.pendingEvidenceTx(validator, prt.Hash{}) != nil
+}
+
+// jailChangeOf validator jailed (evidence) or unjailed by tx (false if neither)
+func jailChangeOf(tx *Transaction) (prt.Address, bool) {
+	if tx.Evidence != nil {
+		return tx.Evidence.Validator, true
+	}
+	if tx.Unjail && len(tx.Inputs) > 0 {
+		validator, err := TxSpender{PublicKey: tx.Inputs[0].PublicKey}.Address()
+		return validator, err == nil
+	}
+	return prt.Address{}, false
+}
+
+// addBlockJailChange records validator of evidence / unjail tx, a block jails or unjails each validator once
+func addBlockJailChange(validators map[prt.Address]bool, tx *Transaction) error {
+	validator, ok := jailChangeOf(tx)
+	if !ok {
+		return nil
+	}
+	if validators[validator] {
+		return fmt.Errorf("duplicate evidence / unjail for %s in block", utils.AddressToString(validator))
+	}
+	validators[validator] = true
+	return nil
+}
+
+// putJail writes jail record into batch, replaced record (if any) is kept in undo
+func (p *BlockChain) putJail(batch *leveldb.Batch, jail *Jail, undo *BlockUndo) error {
+	prev, err := p.getJailNoLock(jail.Address)
+	if err != nil {
+		return err
+	}
+	if prev != nil {
+		undo.Released = append(undo.Released, *prev)
+	}
+	jailBytes, err := utils.SerializeData(jail, utils.SerializationFormatGob)
+	if err != nil {
+		return fmt.Errorf("failed to serialize jail: %w", err)
+	}
+	batch.Put(utils.GetJailKey(jail.Address), jailBytes)
+	undo.Jailed = append(undo.Jailed, jail.Address)
+	return nil
+}
+
+// applyJails jails offenders of the evidence txs of block and releases validators of its unjail txs (recorded in undo)
+func (p *BlockChain) applyJails(batch *leveldb.Batch, blk *Block, undo *BlockUndo) error {
+	for _, tx := range blk.Transactions {
+		if tx.Unjail {
+			if err := p.releaseJail(batch, tx, undo); err != nil {
+				return err
+			}
+			continue
+		}
+		if tx.Evidence == nil {
+			continue
+		}
+		jail := &Jail{
+			Address:  tx.Evidence.Validator,
+			Height:   blk.Header.Height,
+			Reason:   JailReasonDoubleSign,
+			Evidence: tx.ID,
+		}
+		if err := p.putJail(batch, jail, undo); err != nil {
+			return err
+		}
+
+		logger.Warn("[Evidence] Validator ", utils.AddressToString(jail.Address), " jailed at height ", jail.Height, " for double-signing at height ", tx.Evidence.Height)
+	}
+	return nil
+}
+
+// revertJails undoes jail changes of a rolled back block (jails it added, then records it released or replaced)
+func revertJails(batch *leveldb.Batch, undo *BlockUndo) error {
+	for _, address := range undo.Jailed {
+		batch.Delete(utils.GetJailKey(address))
+	}
+	for i := len(undo.Released) - 1; i >= 0; i-- {
+		jailBytes, err := utils.SerializeData(&undo.Released[i], utils.SerializationFormatGob)
+		if err != nil {
+			return fmt.Errorf("failed to serialize jail: %w", err)
+		}
+		batch.Put(utils.GetJailKey(undo.Released[i].Address), jailBytes)
+	}
+	return nil
+}
+
+// GetJail returns jail record of validator (nil if not jailed)
+func (p *BlockChain) GetJail(address prt.Address) (*Jail, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.getJailNoLock(address)
+}
+
+// getJailNoLock returns jail record of validator (lock must be held)
+func (p *BlockChain) getJailNoLock(address prt.Address) (*Jail, error) {
+	jailBytes, err := p.db.Get(utils.GetJailKey(address), nil)
+	if err == leveldb.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get jail: %w", err)
+	}
+	var jail Jail
+	if err := utils.DeserializeData(jailBytes, &jail, utils.SerializationFormatGob); err != nil {
+		return nil, fmt.Errorf("failed to deserialize jail: %w", err)
+	}
+	return &jail, nil
+}
+
+// GetJails returns every jailed validator (sorted by address)
+func (p *BlockChain) GetJails() ([]*Jail, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.collectJails()
+}
+
+// collectJails reads jail records (lock must be held)
+func (p *BlockChain) collectJails() ([]*Jail, error) {
+	iter := p.db.NewIterator(util.BytesPrefix([]byte(prt.PrefixJail)), nil)
+	defer iter.Release()
+
+	var jails []*Jail
+	for iter.Next() {
+		var jail Jail
+		if err := utils.DeserializeData(iter.Value(), &jail, utils.SerializationFormatGob); err != nil {
+			return nil, fmt.Errorf("failed to deserialize jail: %w", err)
+		}
+		jails = append(jails, &jail)
+	}
+	if err := iter.Error(); err != nil {
+		return nil, fmt.Errorf("failed to iterate jails: %w", err)
+	}
+	sort.Slice(jails, func(i, j int) bool {
+		return bytes.Compare(jails[i].Address[:], jails[j].Address[:]) < 0
+	})
+	return jails, nil
+}
@@ -0,0 +1,318 @@
+package core
+
+import (
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/abcfe/abcfe-node/common/logger"
+	prt "github.com/abcfe/abcfe-node/protocol"
+)
+
+const (
+	FeeHistoryBlocks      = 20   // Recent blocks kept by fee estimator
+	DefaultEstimateTxSize = 1000 // Serialized size of a typical 1 input / 2 output tx (bytes)
+
+	// Target confirmation (blocks) of suggested fee rates
+	FeeTargetNextBlock = 1
+	FeeTargetFast      = 3
+	FeeTargetEconomy   = 12
+
+	fullBlockRatio = 0.9 // Block using this much of tx count / size limit is considered full
+)
+
+// BlockFeeStats fee rates of txs included in a block (coinbase excluded)
+type BlockFeeStats struct {
+	Height     uint64
+	TxCount    int
+	Size       uint64  // Sum of tx sizes
+	MinRate    float64 // Lowest fee per byte
+	MedianRate float64
+	Full       bool // Block space was scarce (lowest included rate is meaningful)
+}
+
+// FeeSuggestion suggested fee rate for a confirmation target
+type FeeSuggestion struct {
+	TargetBlocks int
+	FeeRate      float64 // Fee per serialized byte
+	Fee          uint64  // Fee for the requested tx size (at least MinFee)
+}
+
+// FeeEstimate suggested fees and the data they are based on
+type FeeEstimate struct {
+	NextBlock     FeeSuggestion
+	Within3Blocks FeeSuggestion
+	Economy       FeeSuggestion
+	TxSize        uint64 // Tx size the fees are calculated for
+	MinFee        uint64
+	MempoolTxs    int
+	MempoolBytes  uint64
+	RecentBlocks  int // Blocks in history
+	FullBlocks    int // Full blocks in history
+}
+
+// FeeEstimator tracks fee rates of recently included txs
+type FeeEstimator struct {
+	blocks   []BlockFeeStats // Ascending height
+	revision uint64          // Bumped when the tip changes (block recorded or rolled back)
+
+	// Mempool rates of the last estimate, reused until the tip or mempool revision changes
+	rates         map[int]float64
+	ratesRevision uint64
+	ratesMempool  uint64
+
+	mu sync.RWMutex
+}
+
+func NewFeeEstimator() *FeeEstimator {
+	return &FeeEstimator{}
+}
+
+// RecordBlock adds stats of a main chain block (replacing stats at the same or higher height)
+func (p *FeeEstimator) RecordBlock(stats BlockFeeStats) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.removeFromNoLock(stats.Height)
+	p.blocks = append(p.blocks, stats)
+	p.revision++
+	if len(p.blocks) > FeeHistoryBlocks {
+		p.blocks = p.blocks[len(p.blocks)-FeeHistoryBlocks:]
+	}
+}
+
+// RemoveFrom drops stats of blocks at height or above (rolled back blocks)
+func (p *FeeEstimator) RemoveFrom(height uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.removeFromNoLock(height)
+	p.revision++
+}
+
+func (p *FeeEstimator) removeFromNoLock(height uint64) {
+	for len(p.blocks) > 0 && p.blocks[len(p.blocks)-1].Height >= height {
+		p.blocks = p.blocks[:len(p.blocks)-1]
+	}
+}
+
+// GetRecentBlocks returns stats of the last n blocks (ascending height)
+func (p *FeeEstimator) GetRecentBlocks(n int) []BlockFeeStats {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if n > len(p.blocks) {
+		n = len(p.blocks)
+	}
+	return append([]BlockFeeStats{}, p.blocks[len(p.blocks)-n:]...)
+}
+
+// Revision returns counter bumped when the tip changes
+func (p *FeeEstimator) Revision() uint64 {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.revision
+}
+
+// cachedRates returns mempool rates cached at tip revision and mempool revision (nil if stale)
+func (p *FeeEstimator) cachedRates(revision, mempoolRevision uint64) map[int]float64 {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.rates == nil || p.ratesRevision != revision || p.ratesMempool != mempoolRevision {
+		return nil
+	}
+	return p.rates
+}
+
+// setCachedRates caches mempool rates computed at tip revision and mempool revision
+func (p *FeeEstimator) setCachedRates(revision, mempoolRevision uint64, rates map[int]float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.rates, p.ratesRevision, p.ratesMempool = rates, revision, mempoolRevision
+}
+
+// historyRate median of lowest included rates of full blocks among the last n blocks (0 if none was full)
+func (p *FeeEstimator) historyRate(n int) float64 {
+	var rates []float64
+	for _, stats := range p.GetRecentBlocks(n) {
+		if stats.Full {
+			rates = append(rates, stats.MinRate)
+		}
+	}
+	return median(rates)
+}
+
+// EstimateFees suggests fee rates for next block, within 3 blocks and economy targets.
+// A rate is the higher of the rate needed to outbid mempool txs which would not fit in the target
+// blocks and the lowest rate recently accepted in full blocks. txSize 0 uses DefaultEstimateTxSize.
+func (p *BlockChain) EstimateFees(txSize uint64) *FeeEstimate {
+	if txSize == 0 {
+		txSize = DefaultEstimateTxSize
+	}
+
+	stats := p.Mempool.GetStats()
+	estimate := &FeeEstimate{
+		TxSize:       txSize,
+		MinFee:       p.GetMinFee(),
+		MempoolTxs:   stats.TxCount,
+		MempoolBytes: stats.Bytes,
+	}
+
+	history := p.feeEstimator.GetRecentBlocks(FeeHistoryBlocks)
+	estimate.RecentBlocks = len(history)
+	for _, blockStats := range history {
+		if blockStats.Full {
+			estimate.FullBlocks++
+		}
+	}
+
+	mempoolRates := p.mempoolRates()
+	suggest := func(target int, historyBlocks int) FeeSuggestion {
+		rate := mempoolRates[target]
+		if historyBlocks > 0 {
+			rate = math.Max(rate, p.feeEstimator.historyRate(historyBlocks))
+		}
+
+		fee := uint64(math.Ceil(rate * float64(txSize)))
+		if fee < estimate.MinFee {
+			fee = estimate.MinFee
+		}
+		return FeeSuggestion{TargetBlocks: target, FeeRate: rate, Fee: fee}
+	}
+
+	estimate.NextBlock = suggest(FeeTargetNextBlock, 3)
+	estimate.Within3Blocks = suggest(FeeTargetFast, 10)
+	estimate.Economy = suggest(FeeTargetEconomy, 0)
+
+	// Faster targets never suggest less than slower ones
+	if estimate.Economy.FeeRate > estimate.Within3Blocks.FeeRate {
+		estimate.Within3Blocks.FeeRate, estimate.Within3Blocks.Fee = estimate.Economy.FeeRate, estimate.Economy.Fee
+	}
+	if estimate.Within3Blocks.FeeRate > estimate.NextBlock.FeeRate {
+		estimate.NextBlock.FeeRate, estimate.NextBlock.Fee = estimate.Within3Blocks.FeeRate, estimate.Within3Blocks.Fee
+	}
+
+	return estimate
+}
+
+// mempoolRates mempool rates of all fee targets, cached per tip and mempool revision
+// so repeated estimates do not reselect the whole mempool
+func (p *BlockChain) mempoolRates() map[int]float64 {
+	revision, mempoolRevision := p.feeEstimator.Revision(), p.Mempool.Revision()
+	if rates := p.feeEstimator.cachedRates(revision, mempoolRevision); rates != nil {
+		return rates
+	}
+
+	rates := make(map[int]float64)
+	for _, target := range []int{FeeTargetNextBlock, FeeTargetFast, FeeTargetEconomy} {
+		rates[target] = p.mempoolRate(target)
+	}
+	p.feeEstimator.setCachedRates(revision, mempoolRevision, rates)
+	return rates
+}
+
+// mempoolRate fee rate needed to get ahead of mempool txs that would not fit in the next target blocks (0 = no backlog)
+func (p *BlockChain) mempoolRate(target int) float64 {
+	maxTxs := (prt.MaxTxsPerBlock - 1) * target
+	maxBytes := (prt.MaxBlockSize - CoinbaseSizeReserve) * uint64(target)
+
+	_, skipped := p.Mempool.SelectTxs(maxTxs, maxBytes)
+	if len(skipped) == 0 {
+		return 0
+	}
+	// Skipped txs are sorted by fee rate descending
+	return skipped[0].FeeRate()
+}
+
+// recordBlockFeesNoLock records fee rates of a committed main chain block using its undo record
+func (p *BlockChain) recordBlockFeesNoLock(blk *Block) error {
+	undo, err := p.getBlockUndoNoLock(blk)
+	if err != nil {
+		return err
+	}
+
+	// Spent UTXOs are recorded in tx / input order
+	spentIdx := 0
+	var rates []float64
+	stats := BlockFeeStats{Height: blk.Header.Height}
+	for _, tx := range blk.Transactions {
+		if len(tx.Inputs) == 0 {
+			continue // Coinbase
+		}
+		if blk.Header.Height == 0 || spentIdx+len(tx.Inputs) > len(undo.SpentUtxos) {
+			break
+		}
+
+		inputSum, outputSum := tx.Claim, uint64(0)
+		for range tx.Inputs {
+			inputSum += undo.SpentUtxos[spentIdx].TxOut.Amount
+			spentIdx++
+		}
+		if tx.Evidence != nil {
+			continue // Slashed stake is burnt, not a fee
+		}
+		for _, output := range tx.Outputs {
+			outputSum += output.Amount
+		}
+		if inputSum < outputSum {
+			continue
+		}
+
+		size := TxSize(tx)
+		stats.TxCount++
+		stats.Size += size
+		if size > 0 {
+			rates = append(rates, float64(inputSum-outputSum)/float64(size))
+		}
+	}
+
+	if len(rates) > 0 {
+		sort.Float64s(rates)
+		stats.MinRate = rates[0]
+		stats.MedianRate = median(rates)
+	}
+	stats.Full = float64(stats.TxCount) >= fullBlockRatio*float64(prt.MaxTxsPerBlock-1) ||
+		float64(stats.Size) >= fullBlockRatio*float64(prt.MaxBlockSize-CoinbaseSizeReserve)
+
+	p.feeEstimator.RecordBlock(stats)
+	return nil
+}
+
+// loadFeeHistoryNoLock fills fee estimator with the most recent main chain blocks
+func (p *BlockChain) loadFeeHistoryNoLock() {
+	if p.LatestBlockHash == "" {
+		return
+	}
+
+	from := uint64(0)
+	if p.LatestHeight >= FeeHistoryBlocks {
+		from = p.LatestHeight - FeeHistoryBlocks + 1
+	}
+	for height := from; height <= p.LatestHeight; height++ {
+		blk, err := p.getBlockByHeightNoLock(height)
+		if err != nil {
+			logger.Warn("[FeeEstimator] Failed to load block ", height, ": ", err)
+			return
+		}
+		if err := p.recordBlockFeesNoLock(blk); err != nil {
+			logger.Warn("[FeeEstimator] Failed to record block ", height, ": ", err)
+			return
+		}
+	}
+}
+
+// median of values (0 if empty)
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64{}, values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
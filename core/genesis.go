@@ -24,7 +24,13 @@ func (p *BlockChain) SetGenesisBlock() (*Block, error) {
 		genesisTimestamp = time.Now().Unix()
 	}
 
-	txs, err := p.setGenesisTxs(genesisTimestamp)
+	// Consensus timing and params are recorded so that every validator runs the same rules
+	consensusData, err := p.genesisConsensusBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	txs, err := p.setGenesisTxs(genesisTimestamp, consensusData)
 	if err != nil {
 		return nil, err
 	}
@@ -54,7 +60,7 @@ func (p *BlockChain) SetGenesisBlock() (*Block, error) {
 	return block, nil
 }
 
-func (p *BlockChain) setGenesisTxs(genesisTimestamp int64) ([]*Transaction, error) {
+func (p *BlockChain) setGenesisTxs(genesisTimestamp int64, data []byte) ([]*Transaction, error) {
 	txIns := []*TxInput{}
 	txOuts := []*TxOutput{}
 
@@ -86,6 +92,7 @@ func (p *BlockChain) setGenesisTxs(genesisTimestamp int64) ([]*Transaction, erro
 			Inputs:    txIns,
 			Outputs:   txOuts,
 			Memo:      "ABCFE Chain Genesis Block",
+			Data:      data,
 		},
 	}
 
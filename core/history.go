@@ -0,0 +1,303 @@
+package core
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/abcfe/abcfe-node/common/logger"
+	"github.com/abcfe/abcfe-node/common/utils"
+	prt "github.com/abcfe/abcfe-node/protocol"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+const (
+	AddressIndexVersion = "2" // Bump to rebuild the address history index on start
+
+	DefaultAddressTxLimit = 20
+	MaxAddressTxLimit     = 100
+)
+
+// Address tx entry types
+const (
+	AddressTxReceived = "received" // Address only received outputs
+	AddressTxSent     = "sent"     // Address spent inputs (change outputs are netted)
+)
+
+// AddressTxInfo represents transaction info for a specific address (one entry per tx)
+type AddressTxInfo struct {
+	TxID      prt.Hash `json:"txId"`
+	Type      string   `json:"type"`      // "received" or "sent"
+	Received  uint64   `json:"received"`  // Sum of outputs paid to the address
+	Sent      uint64   `json:"sent"`      // Sum of address UTXOs spent by the tx
+	NetAmount int64    `json:"netAmount"` // Received - Sent
+	Timestamp int64    `json:"timestamp"` // Transaction timestamp
+	Height    uint64   `json:"height"`    // Block height
+	TxIndex   uint32   `json:"txIndex"`   // Position of tx in block
+	Amount    uint64   `json:"amount"`    // Amount received (same as Received)
+	Spent     bool     `json:"spent"`     // Whether all outputs paid to the address are spent
+	Index     uint64   `json:"index"`     // First output index paid to the address
+	Outputs   []uint64 `json:"outputs"`   // Output indexes paid to the address
+}
+
+// AddressTxQuery filter and paging options of GetAddressTransactions
+type AddressTxQuery struct {
+	FromHeight uint64 // Inclusive
+	ToHeight   uint64 // Inclusive, 0 = latest
+	Cursor     string // NextCursor of previous page ("" = first page)
+	Limit      int    // 0 = DefaultAddressTxLimit
+	Desc       bool   // Newest first
+}
+
+// GetAddressTransactions returns indexed sent / received transactions of an address.
+// Returns next cursor ("" when there are no more entries).
+func (p *BlockChain) GetAddressTransactions(address prt.Address, query AddressTxQuery) ([]*AddressTxInfo, string, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = DefaultAddressTxLimit
+	}
+	if limit > MaxAddressTxLimit {
+		limit = MaxAddressTxLimit
+	}
+
+	toHeight := query.ToHeight
+	if toHeight == 0 || toHeight > p.LatestHeight {
+		toHeight = p.LatestHeight
+	}
+	if query.FromHeight > toHeight {
+		return []*AddressTxInfo{}, "", nil
+	}
+
+	// Key range [from, to+1)
+	start := utils.GetAddressHistoryKey(address, query.FromHeight, 0)
+	end := utils.GetAddressHistoryKey(address, toHeight+1, 0)
+
+	if query.Cursor != "" {
+		cursorHeight, cursorIdx, err := ParseAddressTxCursor(query.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		if query.Desc {
+			cursorKey := utils.GetAddressHistoryKey(address, cursorHeight, cursorIdx)
+			if string(cursorKey) < string(end) {
+				end = cursorKey
+			}
+		} else {
+			cursorKey := utils.GetAddressHistoryKey(address, cursorHeight, cursorIdx+1)
+			if string(cursorKey) > string(start) {
+				start = cursorKey
+			}
+		}
+	}
+
+	iter := p.db.NewIterator(&util.Range{Start: start, Limit: end}, nil)
+	defer iter.Release()
+
+	next := iter.Next
+	ok := iter.First()
+	if query.Desc {
+		next = iter.Prev
+		ok = iter.Last()
+	}
+
+	txInfos := []*AddressTxInfo{}
+	hasMore := false
+	for ; ok; ok = next() {
+		if len(txInfos) == limit {
+			hasMore = true
+			break
+		}
+
+		var info AddressTxInfo
+		if err := utils.DeserializeData(iter.Value(), &info, utils.SerializationFormatGob); err != nil {
+			return nil, "", fmt.Errorf("failed to deserialize address tx entry: %w", err)
+		}
+		if err := p.setAddressTxSpent(&info); err != nil {
+			return nil, "", err
+		}
+		txInfos = append(txInfos, &info)
+	}
+	if err := iter.Error(); err != nil {
+		return nil, "", fmt.Errorf("failed to iterate address history: %w", err)
+	}
+
+	nextCursor := ""
+	if hasMore {
+		last := txInfos[len(txInfos)-1]
+		nextCursor = fmt.Sprintf("%d:%d", last.Height, last.TxIndex)
+	}
+
+	return txInfos, nextCursor, nil
+}
+
+// setAddressTxSpent sets spent flag from current UTXO status (missing UTXO counts as spent)
+func (p *BlockChain) setAddressTxSpent(info *AddressTxInfo) error {
+	info.Spent = len(info.Outputs) > 0
+	for _, outputIndex := range info.Outputs {
+		utxoBytes, err := p.db.Get(utils.GetUtxoKey(info.TxID, int(outputIndex)), nil)
+		if err == leveldb.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get utxo data from db: %w", err)
+		}
+		var utxo UTXO
+		if err := utils.DeserializeData(utxoBytes, &utxo, utils.SerializationFormatGob); err != nil {
+			return fmt.Errorf("failed to deserialize utxo data: %w", err)
+		}
+		if !utxo.Spent {
+			info.Spent = false
+			return nil
+		}
+	}
+	return nil
+}
+
+// ParseAddressTxCursor parses "height:txIndex" cursor
+func ParseAddressTxCursor(cursor string) (uint64, uint32, error) {
+	parts := strings.Split(cursor, ":")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid cursor: %s", cursor)
+	}
+	height, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid cursor height: %w", err)
+	}
+	txIdx, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid cursor index: %w", err)
+	}
+	return height, uint32(txIdx), nil
+}
+
+// saveAddressHistory writes address history entries of block txs into batch.
+// Spent amounts come from UTXOs in db or outputs created earlier in the same block.
+func (p *BlockChain) saveAddressHistory(batch *leveldb.Batch, blk Block) error {
+	blockOutputs := make(map[string]*TxOutput)
+
+	for txIdx, tx := range blk.Transactions {
+		received := make(map[prt.Address]uint64)
+		sent := make(map[prt.Address]uint64)
+		outputs := make(map[prt.Address][]uint64)
+
+		if blk.Header.Height > 0 { // Genesis Block processes only output
+			for _, input := range tx.Inputs {
+				utxoKey := string(utils.GetUtxoKey(input.TxID, int(input.OutputIndex)))
+				if output, exists := blockOutputs[utxoKey]; exists {
+					sent[output.Address] += output.Amount
+					continue
+				}
+				utxo, err := p.GetUtxoByTxIdAndIdx(input.TxID, input.OutputIndex)
+				if err != nil {
+					return fmt.Errorf("failed to get spent utxo: %w", err)
+				}
+				sent[utxo.TxOut.Address] += utxo.TxOut.Amount
+			}
+		}
+
+		for outputIndex, output := range tx.Outputs {
+			received[output.Address] += output.Amount
+			outputs[output.Address] = append(outputs[output.Address], uint64(outputIndex))
+			blockOutputs[string(utils.GetUtxoKey(tx.ID, outputIndex))] = output
+		}
+
+		addresses := make(map[prt.Address]bool)
+		for address := range received {
+			addresses[address] = true
+		}
+		for address := range sent {
+			addresses[address] = true
+		}
+
+		for address := range addresses {
+			info := AddressTxInfo{
+				TxID:      tx.ID,
+				Type:      AddressTxReceived,
+				Received:  received[address],
+				Sent:      sent[address],
+				NetAmount: int64(received[address]) - int64(sent[address]),
+				Timestamp: tx.Timestamp,
+				Height:    blk.Header.Height,
+				TxIndex:   uint32(txIdx),
+				Amount:    received[address],
+				Outputs:   outputs[address],
+			}
+			if len(info.Outputs) > 0 {
+				info.Index = info.Outputs[0]
+			}
+			if info.Sent > 0 {
+				info.Type = AddressTxSent
+			}
+
+			infoBytes, err := utils.SerializeData(info, utils.SerializationFormatGob)
+			if err != nil {
+				return fmt.Errorf("failed to serialize address tx entry: %w", err)
+			}
+			batch.Put(utils.GetAddressHistoryKey(address, blk.Header.Height, uint32(txIdx)), infoBytes)
+		}
+	}
+
+	return nil
+}
+
+// deleteAddressHistory removes history entries of a rolled back block for the given addresses
+func (p *BlockChain) deleteAddressHistory(batch *leveldb.Batch, blk *Block, addresses map[prt.Address]bool) {
+	for address := range addresses {
+		for txIdx := range blk.Transactions {
+			batch.Delete(utils.GetAddressHistoryKey(address, blk.Header.Height, uint32(txIdx)))
+		}
+	}
+}
+
+// rebuildAddressHistory indexes all main chain blocks when index is missing or outdated
+func (p *BlockChain) rebuildAddressHistory() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	versionBytes, err := p.db.Get([]byte(prt.PrefixMetaAddrIndex), nil)
+	if err != nil && err != leveldb.ErrNotFound {
+		return fmt.Errorf("failed to get address index version: %w", err)
+	}
+	if string(versionBytes) == AddressIndexVersion {
+		return nil
+	}
+
+	if p.LatestBlockHash != "" {
+		logger.Info("[History] Rebuilding address history index up to height ", p.LatestHeight)
+
+		// Drop stale entries
+		batch := new(leveldb.Batch)
+		iter := p.db.NewIterator(util.BytesPrefix([]byte(prt.PrefixAddressHistory)), nil)
+		for iter.Next() {
+			batch.Delete(append([]byte{}, iter.Key()...))
+		}
+		iter.Release()
+		if err := iter.Error(); err != nil {
+			return fmt.Errorf("failed to iterate address history: %w", err)
+		}
+
+		// Spent UTXOs are only flagged, never deleted, so input amounts are still in db
+		for height := uint64(0); height <= p.LatestHeight; height++ {
+			blk, err := p.getBlockByHeightNoLock(height)
+			if err != nil {
+				return fmt.Errorf("failed to load block %d: %w", height, err)
+			}
+			if err := p.saveAddressHistory(batch, *blk); err != nil {
+				return fmt.Errorf("failed to index block %d: %w", height, err)
+			}
+		}
+
+		if err := p.db.Write(batch, nil); err != nil {
+			return fmt.Errorf("failed to write address history: %w", err)
+		}
+	}
+
+	if err := p.db.Put([]byte(prt.PrefixMetaAddrIndex), []byte(AddressIndexVersion), nil); err != nil {
+		return fmt.Errorf("failed to save address index version: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,257 @@
+package core
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"time"
+
+	"github.com/abcfe/abcfe-node/common/crypto"
+	"github.com/abcfe/abcfe-node/common/utils"
+	prt "github.com/abcfe/abcfe-node/protocol"
+)
+
+const (
+	MaxPreimageSize = 64 // Max size of HTLC preimage (bytes)
+)
+
+// HashLock conditions of a hash timelock contract (HTLC) output.
+// Before Expiry the recipient spends it by revealing the preimage of Hash,
+// from Expiry on the refund address takes it back.
+type HashLock struct {
+	Hash      prt.Hash    `json:"hash"`      // sha256 of the secret preimage
+	Recipient prt.Address `json:"recipient"` // Claims with the preimage before Expiry
+	Refund    prt.Address `json:"refund"`    // Takes the output back from Expiry on
+	Expiry    uint64      `json:"expiry"`    // Block height the claim path closes and the refund path opens
+}
+
+// Address contract address the HTLC output is paid to
+func (p *HashLock) Address() prt.Address {
+	return crypto.HTLCAddress(p.Hash, p.Recipient, p.Refund, p.Expiry)
+}
+
+// HashPreimage hash of HTLC preimage (sha256)
+func HashPreimage(preimage []byte) prt.Hash {
+	return prt.Hash(sha256.Sum256(preimage))
+}
+
+// validateHTLCOutput validates hash lock of new output
+func validateHTLCOutput(output *TxOutput) error {
+	if output.HTLC == nil {
+		if output.TxType == TxTypeHTLC {
+			return fmt.Errorf("HTLC output has no hash lock")
+		}
+		return nil
+	}
+
+	lock := output.HTLC
+	if output.TxType != TxTypeHTLC {
+		return fmt.Errorf("hash lock on output of type %d", output.TxType)
+	}
+	if lock.Hash == (prt.Hash{}) {
+		return fmt.Errorf("hash lock has empty hash")
+	}
+	if lock.Recipient == (prt.Address{}) || lock.Refund == (prt.Address{}) {
+		return fmt.Errorf("hash lock needs recipient and refund addresses")
+	}
+	if lock.Expiry == 0 {
+		return fmt.Errorf("hash lock has no expiry height")
+	}
+	if output.Address != lock.Address() {
+		return fmt.Errorf("HTLC output address does not match hash lock")
+	}
+	return nil
+}
+
+// validateHTLCInput validates input spending HTLC output:
+// claim (preimage given) must be signed by the recipient, refund by the refund address
+func validateHTLCInput(tx *Transaction, input *TxInput, utxo *UTXO) error {
+	if input.MultiSig != nil || len(input.Signatures) > 0 {
+		return fmt.Errorf("HTLC input must be signed with a single key")
+	}
+
+	signer, err := verifyInputSigner(tx, input)
+	if err != nil {
+		return err
+	}
+
+	lock := utxo.TxOut.HTLC
+	if len(input.Preimage) > 0 {
+		if len(input.Preimage) > MaxPreimageSize {
+			return fmt.Errorf("HTLC preimage too large: %d bytes (max %d)", len(input.Preimage), MaxPreimageSize)
+		}
+		if HashPreimage(input.Preimage) != lock.Hash {
+			return fmt.Errorf("HTLC preimage does not match hash")
+		}
+		if signer != lock.Recipient {
+			return fmt.Errorf("HTLC claim must be signed by recipient")
+		}
+		return nil
+	}
+
+	if signer != lock.Refund {
+		return fmt.Errorf("HTLC refund must be signed by refund address")
+	}
+	return nil
+}
+
+// checkHTLCExpiry returns error if HTLC path of input is not open in a block at ctx:
+// claim needs height < Expiry, refund needs height >= Expiry
+func checkHTLCExpiry(input *TxInput, utxo *UTXO, ctx spendContext) error {
+	lock := utxo.TxOut.HTLC
+	if len(input.Preimage) > 0 {
+		if ctx.Height >= lock.Expiry {
+			return fmt.Errorf("HTLC %s:%d expired at height %d (claiming at %d)", utils.HashToString(utxo.TxId), utxo.OutputIndex, lock.Expiry, ctx.Height)
+		}
+		return nil
+	}
+	if ctx.Height < lock.Expiry {
+		return fmt.Errorf("HTLC %s:%d refundable from height %d (refunding at %d)", utils.HashToString(utxo.TxId), utxo.OutputIndex, lock.Expiry, ctx.Height)
+	}
+	return nil
+}
+
+// BuildHTLCClaimTx creates signed tx claiming HTLC output with preimage (key must be the recipient)
+func (p *BlockChain) BuildHTLCClaimTx(txId prt.Hash, outputIndex uint64, preimage []byte, to prt.Address, fee uint64, privateKeyBytes, publicKeyBytes []byte) (*Transaction, error) {
+	if len(preimage) == 0 {
+		return nil, fmt.Errorf("preimage is required to claim HTLC")
+	}
+	return p.buildHTLCSpendTx(txId, outputIndex, preimage, to, fee, privateKeyBytes, publicKeyBytes)
+}
+
+// BuildHTLCRefundTx creates signed tx taking expired HTLC output back (key must be the refund address)
+func (p *BlockChain) BuildHTLCRefundTx(txId prt.Hash, outputIndex uint64, to prt.Address, fee uint64, privateKeyBytes, publicKeyBytes []byte) (*Transaction, error) {
+	return p.buildHTLCSpendTx(txId, outputIndex, nil, to, fee, privateKeyBytes, publicKeyBytes)
+}
+
+// buildHTLCSpendTx creates tx spending whole HTLC output to address (amount - fee)
+func (p *BlockChain) buildHTLCSpendTx(txId prt.Hash, outputIndex uint64, preimage []byte, to prt.Address, fee uint64, privateKeyBytes, publicKeyBytes []byte) (*Transaction, error) {
+	if fee == 0 {
+		fee = p.GetMinFee()
+	}
+
+	input := &TxInput{TxID: txId, OutputIndex: outputIndex, PublicKey: publicKeyBytes, Preimage: preimage}
+	if input.PublicKey == nil {
+		input.PublicKey = []byte{}
+	}
+	utxo, err := p.resolveInputUtxo(input, nil, true)
+	if err != nil {
+		return nil, err
+	}
+	if utxo.Spent {
+		return nil, fmt.Errorf("HTLC output already spent")
+	}
+	if utxo.TxOut.HTLC == nil {
+		return nil, fmt.Errorf("output %s:%d is not an HTLC", utils.HashToString(txId), outputIndex)
+	}
+	if utxo.TxOut.Amount <= fee {
+		return nil, fmt.Errorf("HTLC amount %d does not cover fee %d", utxo.TxOut.Amount, fee)
+	}
+
+	tx := &Transaction{
+		Version:   p.cfg.Version.Transaction,
+		NetworkID: p.cfg.Common.NetworkID,
+		Timestamp: time.Now().Unix(),
+		Inputs:    []*TxInput{input},
+		Outputs: []*TxOutput{{
+			Address: to,
+			Amount:  utxo.TxOut.Amount - fee,
+			TxType:  TxTypeGeneral,
+		}},
+		Data: []byte{},
+	}
+	if err := signTx(tx, privateKeyBytes); err != nil {
+		return nil, err
+	}
+
+	// Catch wrong key / path before broadcasting
+	if err := ValidateTxInputSignature(tx, input, utxo); err != nil {
+		return nil, err
+	}
+	if err := checkInputLock(input, utxo, p.nextSpendContext()); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// HTLCStatus state of HTLC output
+type HTLCStatus struct {
+	Utxo      *UTXO
+	Spender   *Transaction // Tx which spent the output (nil if unspent)
+	Preimage  []byte       // Preimage revealed by a claim
+	Refunded  bool         // Spent through the refund path
+	Expired   bool         // Claim path closed for the next block
+	Confirmed bool         // Spender is in a block (not only in mempool)
+}
+
+// GetHTLC returns HTLC output and how it was spent.
+// A claim reveals the preimage, which the counterparty of an atomic swap uses on the other chain.
+func (p *BlockChain) GetHTLC(txId prt.Hash, outputIndex uint64) (*HTLCStatus, error) {
+	utxo, err := p.resolveInputUtxo(&TxInput{TxID: txId, OutputIndex: outputIndex}, nil, true)
+	if err != nil {
+		return nil, err
+	}
+	if utxo.TxOut.HTLC == nil {
+		return nil, fmt.Errorf("output %s:%d is not an HTLC", utils.HashToString(txId), outputIndex)
+	}
+
+	status := &HTLCStatus{
+		Utxo:    utxo,
+		Expired: p.nextSpendContext().Height >= utxo.TxOut.HTLC.Expiry,
+	}
+
+	if utxo.Spent {
+		blk, err := p.GetBlockByHeight(utxo.SpentHeight)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get spending block: %w", err)
+		}
+		status.Spender = findSpender(blk.Transactions, txId, outputIndex)
+		status.Confirmed = true
+	} else if p.Mempool != nil {
+		status.Spender = p.Mempool.GetSpender(txId, outputIndex)
+	}
+
+	if status.Spender != nil {
+		for _, input := range status.Spender.Inputs {
+			if input.TxID == txId && input.OutputIndex == outputIndex {
+				status.Preimage = input.Preimage
+				status.Refunded = len(input.Preimage) == 0
+			}
+		}
+	}
+	return status, nil
+}
+
+// findSpender finds tx spending outpoint in txs
+func findSpender(txs []*Transaction, txId prt.Hash, outputIndex uint64) *Transaction {
+	for _, tx := range txs {
+		for _, input := range tx.Inputs {
+			if input.TxID == txId && input.OutputIndex == outputIndex {
+				return tx
+			}
+		}
+	}
+	return nil
+}
+
+// sameOutput compares outputs including hash lock and delegation
+func sameOutput(a, b *TxOutput) bool {
+	if a.Address != b.Address || a.Amount != b.Amount || a.TxType != b.TxType ||
+		a.LockHeight != b.LockHeight || a.LockTime != b.LockTime || a.RelativeLockHeight != b.RelativeLockHeight {
+		return false
+	}
+	if (a.Validator == nil) != (b.Validator == nil) || (a.Validator != nil && *a.Validator != *b.Validator) {
+		return false
+	}
+	if a.HTLC == nil || b.HTLC == nil {
+		return a.HTLC == nil && b.HTLC == nil
+	}
+	return *a.HTLC == *b.HTLC
+}
+
+// htlcSpender address which must sign input spending HTLC output (recipient with preimage, refund address without)
+func htlcSpender(input *TxInput, lock *HashLock) prt.Address {
+	if len(input.Preimage) > 0 {
+		return lock.Recipient
+	}
+	return lock.Refund
+}
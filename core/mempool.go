@@ -1,32 +1,104 @@
 package core
 
 import (
+	"container/heap"
 	"fmt"
-	"sort"
 	"sync"
+	"time"
 
 	prt "github.com/abcfe/abcfe-node/protocol"
 
+	"github.com/abcfe/abcfe-node/common/logger"
 	"github.com/abcfe/abcfe-node/common/utils"
 )
 
+const (
+	MaxMempoolChainLength = 25 // Max unconfirmed ancestors + 1 of a mempool tx
+
+	DefaultMempoolMaxTxs   = 5000             // Used when config value is 0
+	DefaultMempoolMaxBytes = 10 * 1024 * 1024 // 10MB, used when config value is 0
+	DefaultMempoolMaxAge   = 3 * time.Hour    // Used when config value is 0
+)
+
 // TxWithFee stores transaction and fee information together
 type TxWithFee struct {
-	Tx  *Transaction
-	Fee uint64 // Implicit fee (caching)
+	Tx      *Transaction
+	Fee     uint64 // Implicit fee (caching)
+	Size    uint64 // Serialized size in bytes
+	AddedAt int64  // Time added to mempool (Unix timestamp)
+}
+
+// MempoolStats mempool usage, limits and eviction counters
+type MempoolStats struct {
+	TxCount   int    `json:"txCount"`
+	Bytes     uint64 `json:"bytes"`
+	MaxTxs    int    `json:"maxTxs"`
+	MaxBytes  uint64 `json:"maxBytes"`
+	MaxAgeSec int64  `json:"maxAgeSec"`
+
+	EvictedFull     uint64 `json:"evictedFull"`     // Evicted for lower fee rate when mempool was full
+	EvictedExpired  uint64 `json:"evictedExpired"`  // Expired after max age
+	EvictedConflict uint64 `json:"evictedConflict"` // Inputs spent by a committed block
+	EvictedReplaced uint64 `json:"evictedReplaced"` // Replaced by fee (RBF)
+}
+
+// TxSize returns serialized (JSON) size of transaction in bytes
+func TxSize(tx *Transaction) uint64 {
+	txBytes, err := utils.SerializeData(tx, utils.SerializationFormatJSON)
+	if err != nil {
+		return 0
+	}
+	return uint64(len(txBytes))
 }
 
 type Mempool struct {
 	transactions map[string]*TxWithFee // Includes fee info
+	spends       map[string]string     // Outpoint (txId:index) -> spending tx id
+	totalBytes   uint64                // Sum of tx sizes
+	revision     uint64                // Bumped on every change of txs or fees
 	mu           sync.RWMutex
+
+	// Limits
+	maxTxs   int
+	maxBytes uint64
+	maxAge   time.Duration
+
+	// Eviction counters
+	evictedFull     uint64
+	evictedExpired  uint64
+	evictedConflict uint64
+	evictedReplaced uint64
 }
 
 func NewMempool() *Mempool {
+	return NewMempoolWithLimits(0, 0, 0)
+}
+
+// NewMempoolWithLimits creates mempool with count / byte cap and max tx age (0 = default)
+func NewMempoolWithLimits(maxTxs int, maxBytes uint64, maxAge time.Duration) *Mempool {
+	if maxTxs <= 0 {
+		maxTxs = DefaultMempoolMaxTxs
+	}
+	if maxBytes == 0 {
+		maxBytes = DefaultMempoolMaxBytes
+	}
+	if maxAge <= 0 {
+		maxAge = DefaultMempoolMaxAge
+	}
 	return &Mempool{
 		transactions: make(map[string]*TxWithFee),
+		spends:       make(map[string]string),
+		maxTxs:       maxTxs,
+		maxBytes:     maxBytes,
+		maxAge:       maxAge,
 	}
 }
 
+// outpointKey key of a spent output in mempool spend index
+func outpointKey(txId prt.Hash, outputIndex uint64) string {
+	return fmt.Sprintf("%s:%d", utils.HashToString(txId), outputIndex)
+}
+
 // NewTranaction adds transaction to mempool (with fee info)
 func (p *Mempool) NewTranaction(tx *Transaction) error {
 	p.mu.Lock()
@@ -39,12 +111,9 @@ func (p *Mempool) NewTranaction(tx *Transaction) error {
 	}
 
 	// Fee is set later in BlockChain.AddTxToMempool
-	// Saved as 0 for now
-	p.transactions[txId] = &TxWithFee{
-		Tx:  tx,
-		Fee: 0,
-	}
-	return nil
+	// Saved as 0 for now (a fee-less tx can never replace a conflicting one)
+	_, err := p.addTxNoLock(tx, 0)
+	return err
 }
 
 // NewTransactionWithFee adds transaction to mempool with fee info
@@ -52,17 +121,275 @@ func (p *Mempool) NewTransactionWithFee(tx *Transaction, fee uint64) error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
+	_, err := p.addTxNoLock(tx, fee)
+	return err
+}
+
+// restoreTx adds a tx loaded from mempool snapshot keeping its original add time
+func (p *Mempool) restoreTx(tx *Transaction, fee uint64, addedAt int64) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, err := p.addTxNoLock(tx, fee); err != nil {
+		return err
+	}
+	if entry, exists := p.transactions[utils.HashToString(tx.ID)]; exists && addedAt > 0 {
+		entry.AddedAt = addedAt
+	}
+	return nil
+}
+
+// AddTxWithReplacement adds transaction to mempool and returns the transactions it replaced (replace-by-fee).
+// A tx spending outpoints already spent in mempool is accepted only if every conflicting tx
+// signals RBF and the new fee is strictly higher than the sum of their fees.
+// Descendants of replaced txs spend outputs which no longer exist, so they are evicted (and paid for) too.
+func (p *Mempool) AddTxWithReplacement(tx *Transaction, fee uint64) ([]*TxWithFee, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.addTxNoLock(tx, fee)
+}
+
+// addTxNoLock adds transaction, evicting replaced conflicts (lock must be held).
+// When mempool is full, txs with the lowest fee rate are evicted to make room.
+func (p *Mempool) addTxNoLock(tx *Transaction, fee uint64) ([]*TxWithFee, error) {
 	txId := utils.HashToString(tx.ID)
 
 	if _, exists := p.transactions[txId]; exists {
-		return fmt.Errorf("tx already exists in mempool")
+		return nil, fmt.Errorf("tx already exists in mempool")
+	}
+
+	size := TxSize(tx)
+	if size > p.maxBytes {
+		return nil, fmt.Errorf("tx too large for mempool: %d bytes > max %d bytes", size, p.maxBytes)
+	}
+
+	// Full mempool only accepts txs paying more than the cheapest entry (evidence is always accepted)
+	if tx.Evidence == nil && p.isFullNoLock(size) {
+		if _, minFee, minSize := p.lowestFeeRateNoLock(); minSize > 0 && fee*minSize <= minFee*size {
+			return nil, fmt.Errorf("mempool full: fee rate too low")
+		}
+	}
+
+	// Unconfirmed parents must stay within chain length limit
+	ancestors := p.ancestorsNoLock(tx)
+	if len(ancestors)+1 > MaxMempoolChainLength {
+		return nil, fmt.Errorf("too many unconfirmed ancestors: %d (max %d)", len(ancestors), MaxMempoolChainLength-1)
+	}
+
+	// Collect conflicting txs (same outpoints)
+	conflicts := make(map[string]*TxWithFee)
+	for _, input := range tx.Inputs {
+		if spenderId, exists := p.spends[outpointKey(input.TxID, input.OutputIndex)]; exists {
+			conflicts[spenderId] = p.transactions[spenderId]
+		}
+	}
+
+	var replaced []*TxWithFee
+	if len(conflicts) > 0 {
+		evicted := make(map[string]*TxWithFee)
+		for spenderId, conflict := range conflicts {
+			if conflict.Tx.Evidence != nil {
+				return nil, fmt.Errorf("input already slashed by evidence tx %s", spenderId)
+			}
+			if tx.Evidence == nil && !SignalsRBF(conflict.Tx) {
+				return nil, fmt.Errorf("input already spent by non-replaceable tx %s", spenderId)
+			}
+			evicted[spenderId] = conflict
+			for descId, desc := range p.descendantsNoLock(spenderId) {
+				evicted[descId] = desc
+			}
+		}
+
+		var evictedFees uint64
+		for evictedId, entry := range evicted {
+			if _, isAncestor := ancestors[evictedId]; isAncestor {
+				return nil, fmt.Errorf("replacement spends output of replaced tx %s", evictedId)
+			}
+			evictedFees += entry.Fee
+		}
+		// Evidence replaces the offender's txs spending its stake regardless of fees
+		if tx.Evidence == nil && fee <= evictedFees {
+			return nil, fmt.Errorf("replacement fee too low: got %d, must be higher than %d", fee, evictedFees)
+		}
+
+		for evictedId, entry := range evicted {
+			p.delTxNoLock(evictedId)
+			replaced = append(replaced, entry)
+		}
+		p.evictedReplaced += uint64(len(replaced))
 	}
 
 	p.transactions[txId] = &TxWithFee{
-		Tx:  tx,
-		Fee: fee,
+		Tx:      tx,
+		Fee:     fee,
+		Size:    size,
+		AddedAt: time.Now().Unix(),
 	}
-	return nil
+	p.totalBytes += size
+	p.revision++
+	for _, input := range tx.Inputs {
+		p.spends[outpointKey(input.TxID, input.OutputIndex)] = txId
+	}
+
+	// Trim to limits
+	for p.isFullNoLock(0) {
+		lowestId, _, _ := p.lowestFeeRateNoLock()
+		if lowestId == "" {
+			break // Only evidence left
+		}
+		evicted := p.removeWithDescendantsNoLock(lowestId)
+		p.evictedFull += uint64(len(evicted))
+		if _, exists := p.transactions[txId]; !exists {
+			return replaced, fmt.Errorf("mempool full: fee rate too low")
+		}
+	}
+	return replaced, nil
+}
+
+// isFullNoLock checks if mempool exceeds its limits after adding extra bytes (lock must be held)
+func (p *Mempool) isFullNoLock(extra uint64) bool {
+	count := len(p.transactions)
+	if extra > 0 {
+		count++
+	}
+	return count > p.maxTxs || p.totalBytes+extra > p.maxBytes
+}
+
+// lowestFeeRateNoLock returns the tx to evict first and its fee / size (lock must be held).
+// A tx is scored by the fee rate of itself plus its descendants, so low-fee parents
+// with high-fee children (CPFP) are kept. Evidence txs are never evicted.
+func (p *Mempool) lowestFeeRateNoLock() (string, uint64, uint64) {
+	var lowestId string
+	var lowestFee, lowestSize uint64
+	for txId, entry := range p.transactions {
+		if entry.Tx.Evidence != nil {
+			continue
+		}
+		fee, size := entry.Fee, entry.Size
+		for _, desc := range p.descendantsNoLock(txId) {
+			fee += desc.Fee
+			size += desc.Size
+		}
+		if lowestId == "" || fee*lowestSize < lowestFee*size || (fee*lowestSize == lowestFee*size && txId > lowestId) {
+			lowestId, lowestFee, lowestSize = txId, fee, size
+		}
+	}
+	return lowestId, lowestFee, lowestSize
+}
+
+// ancestorsNoLock returns unconfirmed mempool ancestors of transaction (lock must be held)
+func (p *Mempool) ancestorsNoLock(tx *Transaction) map[string]*TxWithFee {
+	ancestors := make(map[string]*TxWithFee)
+	stack := []*Transaction{tx}
+	for len(stack) > 0 {
+		cur := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, input := range cur.Inputs {
+			parentId := utils.HashToString(input.TxID)
+			if _, seen := ancestors[parentId]; seen {
+				continue
+			}
+			if parent, exists := p.transactions[parentId]; exists {
+				ancestors[parentId] = parent
+				stack = append(stack, parent.Tx)
+			}
+		}
+	}
+	return ancestors
+}
+
+// descendantsNoLock returns mempool txs spending outputs of txId, directly or through other mempool txs (lock must be held)
+func (p *Mempool) descendantsNoLock(txId string) map[string]*TxWithFee {
+	descendants := make(map[string]*TxWithFee)
+	queue := []string{txId}
+	for len(queue) > 0 {
+		entry, exists := p.transactions[queue[0]]
+		queue = queue[1:]
+		if !exists {
+			continue
+		}
+		for outputIndex := range entry.Tx.Outputs {
+			childId, spent := p.spends[outpointKey(entry.Tx.ID, uint64(outputIndex))]
+			if !spent {
+				continue
+			}
+			if _, seen := descendants[childId]; seen {
+				continue
+			}
+			descendants[childId] = p.transactions[childId]
+			queue = append(queue, childId)
+		}
+	}
+	return descendants
+}
+
+// collectPackageNoLock appends not yet selected ancestors of entry (parents first) and entry itself to pkg (lock must be held)
+func (p *Mempool) collectPackageNoLock(entry *TxWithFee, selected, visited map[string]bool, pkg []*TxWithFee) []*TxWithFee {
+	txId := utils.HashToString(entry.Tx.ID)
+	if selected[txId] || visited[txId] {
+		return pkg
+	}
+	visited[txId] = true
+
+	for _, input := range entry.Tx.Inputs {
+		if parent, exists := p.transactions[utils.HashToString(input.TxID)]; exists {
+			pkg = p.collectPackageNoLock(parent, selected, visited, pkg)
+		}
+	}
+	return append(pkg, entry)
+}
+
+// selectCandidate ancestor package score of a mempool tx during SelectTxs
+type selectCandidate struct {
+	txId    string
+	entry   *TxWithFee
+	fee     uint64 // Fee of tx and its not yet selected ancestors
+	size    uint64 // Size of tx and its not yet selected ancestors
+	count   int    // Tx count of the package
+	version int    // Bumped on every score change
+}
+
+// item returns queue item with the current score of candidate
+func (c *selectCandidate) item() selectItem {
+	return selectItem{cand: c, fee: c.fee, size: c.size, version: c.version}
+}
+
+// selectItem queued score of a candidate (stale when version differs from candidate)
+type selectItem struct {
+	cand    *selectCandidate
+	fee     uint64
+	size    uint64
+	version int
+}
+
+// selectQueue max-heap of package scores: evidence first, then higher fee rate, tx id breaks ties
+type selectQueue []selectItem
+
+func (q selectQueue) Len() int { return len(q) }
+
+func (q selectQueue) Less(i, j int) bool {
+	a, b := q[i], q[j]
+	aEvidence, bEvidence := a.cand.entry.Tx.Evidence != nil, b.cand.entry.Tx.Evidence != nil
+	if aEvidence != bEvidence {
+		return aEvidence
+	}
+	lhs, rhs := a.fee*b.size, b.fee*a.size
+	if lhs != rhs {
+		return lhs > rhs
+	}
+	return a.cand.txId < b.cand.txId
+}
+
+func (q selectQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *selectQueue) Push(x interface{}) { *q = append(*q, x.(selectItem)) }
+
+func (q *selectQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
 }
 
 func (p *Mempool) GetTx(txId prt.Hash) *Transaction {
@@ -77,34 +404,107 @@ func (p *Mempool) GetTx(txId prt.Hash) *Transaction {
 	return nil
 }
 
-// GetTxs extracts transactions from mempool for block addition (sorted by fee descending)
+// GetTxs extracts transactions from mempool for block addition (see SelectTxs).
+// Room for the coinbase tx is left within block limits.
 func (p *Mempool) GetTxs() []*Transaction {
+	selected, _ := p.SelectTxs(prt.MaxTxsPerBlock-1, prt.MaxBlockSize-CoinbaseSizeReserve)
+
+	txs := make([]*Transaction, 0, len(selected))
+	for _, entry := range selected {
+		txs = append(txs, entry.Tx)
+	}
+	return txs
+}
+
+// SelectTxs selects txs within tx count and byte limits.
+// Txs are selected as packages (tx + unconfirmed ancestors) by fee per serialized byte,
+// so a high-fee child pulls in its low-fee parent (child-pays-for-parent).
+// Evidence txs (fee-less) go first.
+// Parents are always placed before their children.
+// Txs left out are returned with the reason they did not fit.
+func (p *Mempool) SelectTxs(maxTxs int, maxBytes uint64) ([]*TxWithFee, []TemplateTx) {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 
-	// Convert to TxWithFee slice
-	txsWithFee := make([]*TxWithFee, 0, len(p.transactions))
-	for _, txWithFee := range p.transactions {
-		txsWithFee = append(txsWithFee, txWithFee)
+	// Ancestor score of each tx (tx + unconfirmed ancestors), updated incrementally as ancestors are selected
+	candidates := make(map[string]*selectCandidate, len(p.transactions))
+	queue := make(selectQueue, 0, len(p.transactions))
+	for txId, entry := range p.transactions {
+		cand := &selectCandidate{txId: txId, entry: entry, fee: entry.Fee, size: entry.Size, count: 1}
+		for _, ancestor := range p.ancestorsNoLock(entry.Tx) {
+			cand.fee += ancestor.Fee
+			cand.size += ancestor.Size
+			cand.count++
+		}
+		candidates[txId] = cand
+		queue = append(queue, cand.item())
 	}
+	heap.Init(&queue)
+
+	selected := make(map[string]bool)
+	reasons := make(map[string]string)
+	var result []*TxWithFee
+	var totalBytes uint64
+
+	for queue.Len() > 0 {
+		item := heap.Pop(&queue).(selectItem)
+		cand := item.cand
+		if selected[cand.txId] || item.version != cand.version {
+			continue // Selected with a child or score changed (newer item queued)
+		}
 
-	// Sort by fee descending
-	sort.Slice(txsWithFee, func(i, j int) bool {
-		return txsWithFee[i].Fee > txsWithFee[j].Fee
-	})
+		// A package left out only fits later if it shrinks (ancestor selected), which queues it again
+		if len(result)+cand.count > maxTxs {
+			reasons[cand.txId] = TemplateSkipTxCount
+			continue
+		}
+		if totalBytes+cand.size > maxBytes {
+			reasons[cand.txId] = TemplateSkipBlockSize
+			continue
+		}
 
-	// Extract only Transaction
-	txs := make([]*Transaction, 0, len(txsWithFee))
-	for _, txWithFee := range txsWithFee {
-		txs = append(txs, txWithFee.Tx)
+		pkg := p.collectPackageNoLock(cand.entry, selected, make(map[string]bool), nil)
+		for _, member := range pkg {
+			selected[utils.HashToString(member.Tx.ID)] = true
+			result = append(result, member)
+		}
+		totalBytes += cand.size
+
+		// Remove selected members from ancestor scores of their descendants
+		updated := make(map[string]*selectCandidate)
+		for _, member := range pkg {
+			for childId := range p.descendantsNoLock(utils.HashToString(member.Tx.ID)) {
+				if selected[childId] {
+					continue
+				}
+				child := candidates[childId]
+				child.fee -= member.Fee
+				child.size -= member.Size
+				child.count--
+				child.version++
+				updated[childId] = child
+			}
+		}
+		for _, child := range updated {
+			heap.Push(&queue, child.item())
+		}
 	}
 
-	// Limit tx count if exceeds max (prioritize higher fees)
-	if len(txs) > prt.MaxTxsPerBlock {
-		return txs[:prt.MaxTxsPerBlock]
+	var skipped []TemplateTx
+	for txId, entry := range p.transactions {
+		if selected[txId] {
+			continue
+		}
+		skipped = append(skipped, TemplateTx{
+			TxID:   entry.Tx.ID,
+			Fee:    entry.Fee,
+			Size:   entry.Size,
+			Reason: reasons[txId],
+		})
 	}
+	sortTemplateTxs(skipped)
 
-	return txs
+	return result, skipped
 }
 
 // GetTxCount returns transaction count in mempool
@@ -118,9 +518,189 @@ func (p *Mempool) DelTx(txId prt.Hash) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	strTxId := utils.HashToString(txId)
+	p.delTxNoLock(utils.HashToString(txId))
+}
+
+// RemoveTxWithDescendants removes transaction and all mempool txs spending its outputs.
+// Used when a tx becomes invalid; confirmed txs are removed with DelTx since their children stay valid.
+func (p *Mempool) RemoveTxWithDescendants(txId prt.Hash) []*TxWithFee {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.removeWithDescendantsNoLock(utils.HashToString(txId))
+}
+
+// RemoveConflictingTx removes tx whose inputs were spent by a committed block (with its descendants)
+func (p *Mempool) RemoveConflictingTx(txId prt.Hash) []*TxWithFee {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	removed := p.removeWithDescendantsNoLock(utils.HashToString(txId))
+	p.evictedConflict += uint64(len(removed))
+	return removed
+}
+
+// removeWithDescendantsNoLock removes tx and its descendants (lock must be held)
+func (p *Mempool) removeWithDescendantsNoLock(txId string) []*TxWithFee {
+	entry, exists := p.transactions[txId]
+	if !exists {
+		return nil
+	}
+
+	removed := []*TxWithFee{entry}
+	for descId, desc := range p.descendantsNoLock(txId) {
+		p.delTxNoLock(descId)
+		removed = append(removed, desc)
+	}
+	p.delTxNoLock(txId)
+	return removed
+}
+
+// ExpireTxs removes txs older than max age (with their descendants)
+func (p *Mempool) ExpireTxs(now time.Time) []*TxWithFee {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	cutoff := now.Add(-p.maxAge).Unix()
+	var expired []*TxWithFee
+	for txId, entry := range p.transactions {
+		if entry.AddedAt >= cutoff {
+			continue
+		}
+		if _, exists := p.transactions[txId]; !exists {
+			continue // Already removed as descendant
+		}
+		expired = append(expired, p.removeWithDescendantsNoLock(txId)...)
+	}
+	p.evictedExpired += uint64(len(expired))
+	return expired
+}
+
+// GetAllTxs returns all mempool txs, parents before children
+func (p *Mempool) GetAllTxs() []*Transaction {
+	entries := p.GetAllEntries()
+
+	txs := make([]*Transaction, 0, len(entries))
+	for _, entry := range entries {
+		txs = append(txs, entry.Tx)
+	}
+	return txs
+}
+
+// GetAllEntries returns all mempool entries, parents before children
+func (p *Mempool) GetAllEntries() []*TxWithFee {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	visited := make(map[string]bool)
+	var entries []*TxWithFee
+	for _, entry := range p.transactions {
+		entries = p.collectPackageNoLock(entry, nil, visited, entries)
+	}
+	return entries
+}
+
+// GetStats returns mempool usage, limits and eviction counters
+func (p *Mempool) GetStats() MempoolStats {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return MempoolStats{
+		TxCount:         len(p.transactions),
+		Bytes:           p.totalBytes,
+		MaxTxs:          p.maxTxs,
+		MaxBytes:        p.maxBytes,
+		MaxAgeSec:       int64(p.maxAge / time.Second),
+		EvictedFull:     p.evictedFull,
+		EvictedExpired:  p.evictedExpired,
+		EvictedConflict: p.evictedConflict,
+		EvictedReplaced: p.evictedReplaced,
+	}
+}
+
+// Revision returns counter bumped on every change of mempool txs or fees
+func (p *Mempool) Revision() uint64 {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.revision
+}
+
+// delTxNoLock removes transaction and its spend index entries (lock must be held)
+func (p *Mempool) delTxNoLock(txId string) {
+	txWithFee, exists := p.transactions[txId]
+	if !exists {
+		return
+	}
+	for _, input := range txWithFee.Tx.Inputs {
+		key := outpointKey(input.TxID, input.OutputIndex)
+		if p.spends[key] == txId {
+			delete(p.spends, key)
+		}
+	}
+	p.totalBytes -= txWithFee.Size
+	p.revision++
+	delete(p.transactions, txId)
+}
+
+// GetTxWithFee returns mempool entry of transaction (nil if not in mempool)
+func (p *Mempool) GetTxWithFee(txId prt.Hash) *TxWithFee {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.transactions[utils.HashToString(txId)]
+}
+
+// GetOutput returns output of an unconfirmed mempool tx (nil if tx is not in mempool)
+func (p *Mempool) GetOutput(txId prt.Hash, outputIndex uint64) *TxOutput {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	entry, exists := p.transactions[utils.HashToString(txId)]
+	if !exists || outputIndex >= uint64(len(entry.Tx.Outputs)) {
+		return nil
+	}
+	return entry.Tx.Outputs[outputIndex]
+}
+
+// GetSpender returns mempool tx spending the output (nil if no mempool tx spends it)
+func (p *Mempool) GetSpender(txId prt.Hash, outputIndex uint64) *Transaction {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
 
-	delete(p.transactions, strTxId)
+	spenderId, exists := p.spends[outpointKey(txId, outputIndex)]
+	if !exists {
+		return nil
+	}
+	if entry, exists := p.transactions[spenderId]; exists {
+		return entry.Tx
+	}
+	return nil
+}
+
+// GetUnspentOutputs returns outputs of mempool txs paid to address which no other mempool tx spends.
+// Returned UTXOs are unconfirmed (Height 0).
+func (p *Mempool) GetUnspentOutputs(address prt.Address) []*UTXO {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var result []*UTXO
+	for _, entry := range p.transactions {
+		for outputIndex, output := range entry.Tx.Outputs {
+			if output.Address != address {
+				continue
+			}
+			if _, spent := p.spends[outpointKey(entry.Tx.ID, uint64(outputIndex))]; spent {
+				continue
+			}
+			result = append(result, &UTXO{
+				TxId:        entry.Tx.ID,
+				OutputIndex: uint64(outputIndex),
+				TxOut:       *output,
+				Unconfirmed: true,
+			})
+		}
+	}
+	return result
 }
 
 // Clear clears mempool
@@ -129,6 +709,9 @@ func (p *Mempool) Clear() {
 	defer p.mu.Unlock()
 
 	p.transactions = make(map[string]*TxWithFee)
+	p.spends = make(map[string]string)
+	p.totalBytes = 0
+	p.revision++
 }
 
 // UpdateTxFee updates fee of transaction in mempool
@@ -139,6 +722,7 @@ func (p *Mempool) UpdateTxFee(txId prt.Hash, fee uint64) {
 	strTxId := utils.HashToString(txId)
 	if txWithFee, exists := p.transactions[strTxId]; exists {
 		txWithFee.Fee = fee
+		p.revision++
 	}
 }
 
@@ -146,13 +730,69 @@ func (p *BlockChain) isOnMempool(txId prt.Hash, outputIndex uint64) bool {
 	p.Mempool.mu.RLock()
 	defer p.Mempool.mu.RUnlock()
 
-	for _, txWithFee := range p.Mempool.transactions {
-		for _, input := range txWithFee.Tx.Inputs {
-			if input.OutputIndex == outputIndex && input.TxID == txId {
-				return true
-			}
+	_, exists := p.Mempool.spends[outpointKey(txId, outputIndex)]
+	return exists
+}
+
+// RevalidateMempool drops expired txs and txs whose inputs were spent by committed blocks.
+// Called after every block added to the main chain.
+func (p *BlockChain) RevalidateMempool() {
+	for _, entry := range p.Mempool.ExpireTxs(time.Now()) {
+		logger.Info("[Mempool] Tx ", utils.HashToString(entry.Tx.ID)[:16], " expired")
+	}
+
+	// Parents first, so a conflicting parent removes its children before they are checked
+	for _, tx := range p.Mempool.GetAllTxs() {
+		if p.Mempool.GetTx(tx.ID) == nil {
+			continue
+		}
+		if err := p.checkTxInputs(tx); err != nil {
+			removed := p.Mempool.RemoveConflictingTx(tx.ID)
+			logger.Info("[Mempool] Tx ", utils.HashToString(tx.ID)[:16], " dropped (", len(removed), " txs): ", err)
 		}
 	}
+}
 
-	return false
+// checkTxInputs checks that all inputs of tx still exist unspent (committed or in mempool),
+// its reward claim is still covered by the claimant's balance and its validator can still be unjailed
+func (p *BlockChain) checkTxInputs(tx *Transaction) error {
+	ctx := p.nextSpendContext()
+	for _, input := range tx.Inputs {
+		utxo, err := p.resolveInputUtxo(input, nil, true)
+		if err != nil {
+			return err
+		}
+		if utxo.Spent {
+			return fmt.Errorf("UTXO already spent: %s:%d", utils.HashToString(input.TxID), input.OutputIndex)
+		}
+		// Locks may apply again after a rollback
+		if err := checkInputLock(input, utxo, ctx); err != nil {
+			return err
+		}
+	}
+
+	if tx.Claim > 0 {
+		claimant, err := claimantOf(tx)
+		if err != nil {
+			return err
+		}
+		balance, err := p.rewardBalance(claimant)
+		if err != nil {
+			return err
+		}
+		if tx.Claim > balance {
+			return fmt.Errorf("reward claim %d exceeds reward balance %d", tx.Claim, balance)
+		}
+	}
+
+	if tx.Unjail {
+		validator, ok := jailChangeOf(tx)
+		if !ok {
+			return fmt.Errorf("unjail tx has no signer")
+		}
+		if err := p.checkUnjail(validator, ctx.Height); err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -0,0 +1,96 @@
+package core
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/abcfe/abcfe-node/common/logger"
+	"github.com/abcfe/abcfe-node/common/utils"
+	prt "github.com/abcfe/abcfe-node/protocol"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+const (
+	DefaultMempoolSnapshotInterval = time.Minute // Used when config value is 0
+)
+
+// mempoolSnapshot pending txs saved on disk (parents before children)
+type mempoolSnapshot struct {
+	SavedAt int64
+	Entries []mempoolSnapshotEntry
+}
+
+type mempoolSnapshotEntry struct {
+	Tx      *Transaction
+	AddedAt int64
+}
+
+// SaveMempool writes snapshot of pending txs to db
+func (p *BlockChain) SaveMempool() error {
+	entries := p.Mempool.GetAllEntries()
+
+	snapshot := mempoolSnapshot{
+		SavedAt: time.Now().Unix(),
+		Entries: make([]mempoolSnapshotEntry, 0, len(entries)),
+	}
+	for _, entry := range entries {
+		snapshot.Entries = append(snapshot.Entries, mempoolSnapshotEntry{Tx: entry.Tx, AddedAt: entry.AddedAt})
+	}
+
+	snapshotBytes, err := utils.SerializeData(snapshot, utils.SerializationFormatGob)
+	if err != nil {
+		return fmt.Errorf("failed to serialize mempool snapshot: %w", err)
+	}
+	if err := p.db.Put([]byte(prt.PrefixMetaMempool), snapshotBytes, nil); err != nil {
+		return fmt.Errorf("failed to save mempool snapshot: %w", err)
+	}
+	return nil
+}
+
+// LoadMempool reloads saved pending txs into mempool.
+// Each tx is revalidated; txs which became invalid (spent, expired, ...) are dropped.
+// Returns loaded and dropped tx counts.
+func (p *BlockChain) LoadMempool() (int, int, error) {
+	snapshotBytes, err := p.db.Get([]byte(prt.PrefixMetaMempool), nil)
+	if err == leveldb.ErrNotFound {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get mempool snapshot: %w", err)
+	}
+
+	var snapshot mempoolSnapshot
+	if err := utils.DeserializeData(snapshotBytes, &snapshot, utils.SerializationFormatGob); err != nil {
+		return 0, 0, fmt.Errorf("failed to deserialize mempool snapshot: %w", err)
+	}
+
+	loaded, dropped := 0, 0
+	for _, entry := range snapshot.Entries {
+		tx := entry.Tx
+		if err := p.ValidateTransaction(tx); err != nil {
+			logger.Debug("[Mempool] Dropping saved tx ", utils.HashToString(tx.ID)[:16], ": ", err)
+			dropped++
+			continue
+		}
+
+		fee, err := p.CalculateTxFee(tx)
+		if err != nil {
+			dropped++
+			continue
+		}
+
+		if err := p.Mempool.restoreTx(tx, fee, entry.AddedAt); err != nil {
+			logger.Debug("[Mempool] Dropping saved tx ", utils.HashToString(tx.ID)[:16], ": ", err)
+			dropped++
+			continue
+		}
+		loaded++
+	}
+
+	// Txs which waited too long before the restart
+	expired := p.Mempool.ExpireTxs(time.Now())
+	loaded -= len(expired)
+	dropped += len(expired)
+
+	return loaded, dropped, nil
+}
@@ -0,0 +1,410 @@
+package core
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/abcfe/abcfe-node/common/crypto"
+	"github.com/abcfe/abcfe-node/common/utils"
+	prt "github.com/abcfe/abcfe-node/protocol"
+)
+
+const (
+	MaxMultisigKeys = 15 // Max keys (n) of an m-of-n multisig address
+)
+
+// MultiSigScript m-of-n key set which owns a multisig address
+type MultiSigScript struct {
+	Threshold  int      `json:"threshold"`  // Required signatures (m)
+	PublicKeys [][]byte `json:"publicKeys"` // Sorted public keys (n)
+}
+
+// NewMultiSigScript creates m-of-n script with keys in canonical (sorted) order
+func NewMultiSigScript(threshold int, publicKeys [][]byte) (*MultiSigScript, error) {
+	keys := make([][]byte, len(publicKeys))
+	copy(keys, publicKeys)
+	sort.Slice(keys, func(i, j int) bool {
+		return bytes.Compare(keys[i], keys[j]) < 0
+	})
+
+	script := &MultiSigScript{Threshold: threshold, PublicKeys: keys}
+	if _, err := script.parseKeys(); err != nil {
+		return nil, err
+	}
+	return script, nil
+}
+
+// parseKeys validates script (threshold, key count, order, duplicates) and parses its keys
+func (p *MultiSigScript) parseKeys() ([]*ecdsa.PublicKey, error) {
+	n := len(p.PublicKeys)
+	if n == 0 || n > MaxMultisigKeys {
+		return nil, fmt.Errorf("multisig needs 1 to %d keys, got %d", MaxMultisigKeys, n)
+	}
+	if p.Threshold <= 0 || p.Threshold > n {
+		return nil, fmt.Errorf("invalid multisig threshold %d of %d", p.Threshold, n)
+	}
+
+	keys := make([]*ecdsa.PublicKey, n)
+	for i, keyBytes := range p.PublicKeys {
+		if i > 0 && bytes.Compare(p.PublicKeys[i-1], keyBytes) >= 0 {
+			return nil, fmt.Errorf("multisig keys must be sorted and unique")
+		}
+		key, err := crypto.BytesToPublicKey(keyBytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid multisig key %d: %w", i, err)
+		}
+		keys[i] = key
+	}
+	return keys, nil
+}
+
+// Address derives multisig address of the script
+func (p *MultiSigScript) Address() (prt.Address, error) {
+	keys, err := p.parseKeys()
+	if err != nil {
+		return prt.Address{}, err
+	}
+	return crypto.MultisigAddress(p.Threshold, keys)
+}
+
+// keyIndex position of public key in script (-1 if not a member)
+func (p *MultiSigScript) keyIndex(publicKey []byte) int {
+	for i, key := range p.PublicKeys {
+		if bytes.Equal(key, publicKey) {
+			return i
+		}
+	}
+	return -1
+}
+
+// checkSignatureSlots rejects multisig inputs carrying more signature slots than the script has keys
+func checkSignatureSlots(tx *Transaction) error {
+	for i, input := range tx.Inputs {
+		if input != nil && input.MultiSig != nil && len(input.Signatures) > len(input.MultiSig.PublicKeys) {
+			return fmt.Errorf("input[%d]: %d signature slots for %d multisig keys", i, len(input.Signatures), len(input.MultiSig.PublicKeys))
+		}
+	}
+	return nil
+}
+
+// validateMultisigInput checks that input script owns utxo and at least threshold slots hold valid signatures
+func validateMultisigInput(tx *Transaction, input *TxInput, utxo *UTXO) error {
+	if len(input.PublicKey) != 0 || input.Signature != (prt.Signature{}) {
+		return fmt.Errorf("multisig input must not carry single key public key / signature")
+	}
+
+	keys, err := input.MultiSig.parseKeys()
+	if err != nil {
+		return err
+	}
+	address, err := crypto.MultisigAddress(input.MultiSig.Threshold, keys)
+	if err != nil {
+		return err
+	}
+	if address != utxo.TxOut.Address {
+		return fmt.Errorf("multisig script does not match UTXO owner")
+	}
+
+	if len(input.Signatures) != len(keys) {
+		return fmt.Errorf("multisig input needs %d signature slots, got %d", len(keys), len(input.Signatures))
+	}
+
+	txHashBytes := utils.HashToBytes(tx.ID)
+	signed := 0
+	for i, sig := range input.Signatures {
+		if sig == (prt.Signature{}) {
+			continue // Not signed by this key
+		}
+		if !crypto.VerifySignature(keys[i], txHashBytes, sig) {
+			return fmt.Errorf("invalid multisig signature of key %d", i)
+		}
+		signed++
+	}
+	if signed < input.MultiSig.Threshold {
+		return fmt.Errorf("not enough multisig signatures: %d of %d required", signed, input.MultiSig.Threshold)
+	}
+
+	return nil
+}
+
+// CreateMultisigTx builds unsigned tx spending UTXOs of the multisig address (change goes back to it).
+// Parties add their signatures with SignMultisigTx and merge them with CombineMultisigTxs.
+func (p *BlockChain) CreateMultisigTx(script *MultiSigScript, recipients []TxRecipient, fee uint64, memo string, data []byte, txType uint8) (*Transaction, *CoinSelection, error) {
+	return p.BuildUnsignedTx(TxSpender{MultiSig: script}, recipients, fee, memo, data, txType, TxBuildOptions{})
+}
+
+// TxSpender owner of the inputs of an unsigned tx (single key or m-of-n multisig)
+type TxSpender struct {
+	PublicKey []byte          // Single key owner
+	MultiSig  *MultiSigScript // Multisig owner (PublicKey empty)
+}
+
+// Address address whose UTXOs the spender can sign for
+func (p TxSpender) Address() (prt.Address, error) {
+	if p.MultiSig != nil {
+		if len(p.PublicKey) != 0 {
+			return prt.Address{}, fmt.Errorf("spender must be either a public key or a multisig script")
+		}
+		return p.MultiSig.Address()
+	}
+	publicKey, err := crypto.BytesToPublicKey(p.PublicKey)
+	if err != nil {
+		return prt.Address{}, fmt.Errorf("invalid spender public key: %w", err)
+	}
+	return crypto.PublicKeyToAddress(publicKey)
+}
+
+// BuildUnsignedTx builds transfer tx from the spender's UTXOs without signing it (change goes back to the spender).
+// Multisig inputs get empty signature slots.
+func (p *BlockChain) BuildUnsignedTx(spender TxSpender, recipients []TxRecipient, fee uint64, memo string, data []byte, txType uint8, opts TxBuildOptions) (*Transaction, *CoinSelection, error) {
+	from, err := spender.Address()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	amount, err := sumRecipients(recipients)
+	if err != nil {
+		return nil, nil, err
+	}
+	requiredAmount, err := requiredWithFee(amount, fee)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	utxos, err := p.GetUtxoList(from, true)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get UTXO list: %w", err)
+	}
+	selection, err := SelectCoins(opts.CoinSelection, utxos, requiredAmount, p.GetMinFee())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to select UTXOs: %w", err)
+	}
+
+	publicKey := spender.PublicKey
+	if publicKey == nil {
+		publicKey = []byte{}
+	}
+	var sequence uint64
+	if opts.Replaceable {
+		sequence = TxSequenceRBF
+	}
+
+	txIns := make([]*TxInput, 0, len(selection.Utxos))
+	for _, utxo := range selection.Utxos {
+		txIns = append(txIns, &TxInput{
+			TxID:        utxo.TxId,
+			OutputIndex: utxo.OutputIndex,
+			PublicKey:   publicKey,
+			Sequence:    sequence,
+			MultiSig:    spender.MultiSig,
+		})
+	}
+
+	var txOuts []*TxOutput
+	for _, recipient := range recipients {
+		txOuts = append(txOuts, recipient.output(txType))
+	}
+	if selection.Change > 0 {
+		txOuts = append(txOuts, &TxOutput{Address: from, Amount: selection.Change, TxType: changeTxType(txType)})
+	}
+
+	normalizedData := data
+	if normalizedData == nil {
+		normalizedData = []byte{}
+	}
+
+	tx := &Transaction{
+		Version:   p.cfg.Version.Transaction,
+		NetworkID: p.cfg.Common.NetworkID,
+		Timestamp: time.Now().Unix(),
+		Inputs:    txIns,
+		Outputs:   txOuts,
+		Memo:      memo,
+		Data:      normalizedData,
+	}
+	tx.ID = utils.Hash(tx)
+
+	// Empty signature slots (added after hashing, not part of tx hash)
+	if spender.MultiSig != nil {
+		for _, input := range tx.Inputs {
+			input.Signatures = make([]prt.Signature, len(spender.MultiSig.PublicKeys))
+		}
+	}
+
+	return tx, selection, nil
+}
+
+// SignMultisigTx fills the signature slot of publicKey in every multisig input it belongs to.
+// Returns number of inputs signed.
+func SignMultisigTx(tx *Transaction, privateKeyBytes, publicKeyBytes []byte) (int, error) {
+	signed, err := signTxInputs(tx, privateKeyBytes, publicKeyBytes)
+	if err != nil {
+		return signed, err
+	}
+	if signed == 0 {
+		return 0, fmt.Errorf("key is not part of any multisig input")
+	}
+	return signed, nil
+}
+
+// signTxInputs signs every input owned by publicKey (single key inputs with that key, multisig inputs containing it)
+// without changing the tx hash. Returns number of inputs signed.
+func signTxInputs(tx *Transaction, privateKeyBytes, publicKeyBytes []byte) (int, error) {
+	if err := ValidateTxHash(tx); err != nil {
+		return 0, err
+	}
+	if err := checkSignatureSlots(tx); err != nil {
+		return 0, err
+	}
+
+	privateKey, err := crypto.BytesToPrivateKey(privateKeyBytes)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse private key: %w", err)
+	}
+
+	txHashBytes := utils.HashToBytes(tx.ID)
+	signed := 0
+	for i, input := range tx.Inputs {
+		slot := -1
+		if input.MultiSig != nil {
+			slot = input.MultiSig.keyIndex(publicKeyBytes)
+			if slot < 0 {
+				continue
+			}
+			if len(input.Signatures) < len(input.MultiSig.PublicKeys) {
+				input.Signatures = append(input.Signatures, make([]prt.Signature, len(input.MultiSig.PublicKeys)-len(input.Signatures))...)
+			}
+		} else if !bytes.Equal(input.PublicKey, publicKeyBytes) {
+			continue
+		}
+
+		sig, err := crypto.SignData(privateKey, txHashBytes)
+		if err != nil {
+			return signed, fmt.Errorf("failed to sign input[%d]: %w", i, err)
+		}
+		if slot >= 0 {
+			input.Signatures[slot] = sig
+		} else {
+			input.Signature = sig
+		}
+		signed++
+	}
+
+	return signed, nil
+}
+
+// CombineMultisigTxs merges signature slots of copies of the same multisig tx signed by different parties
+func CombineMultisigTxs(txs []*Transaction) (*Transaction, error) {
+	if len(txs) == 0 {
+		return nil, fmt.Errorf("no transactions to combine")
+	}
+
+	combined := txs[0]
+	if err := ValidateTxHash(combined); err != nil {
+		return nil, err
+	}
+	if err := checkSignatureSlots(combined); err != nil {
+		return nil, err
+	}
+	for n, tx := range txs[1:] {
+		if tx.ID != combined.ID || len(tx.Inputs) != len(combined.Inputs) {
+			return nil, fmt.Errorf("tx %d is not the same transaction", n+1)
+		}
+		if err := ValidateTxHash(tx); err != nil {
+			return nil, err
+		}
+		if err := checkSignatureSlots(tx); err != nil {
+			return nil, fmt.Errorf("tx %d: %w", n+1, err)
+		}
+		for i, input := range tx.Inputs {
+			mergeInputSignatures(combined.Inputs[i], input)
+		}
+	}
+
+	return combined, nil
+}
+
+// mergeInputSignatures copies signatures of input missing in target (same input of the same tx)
+func mergeInputSignatures(target, input *TxInput) {
+	if target.MultiSig == nil {
+		if target.Signature == (prt.Signature{}) {
+			target.Signature = input.Signature
+		}
+		return
+	}
+
+	if len(target.Signatures) < len(target.MultiSig.PublicKeys) {
+		target.Signatures = append(target.Signatures, make([]prt.Signature, len(target.MultiSig.PublicKeys)-len(target.Signatures))...)
+	}
+	for slot, sig := range input.Signatures {
+		if slot < len(target.Signatures) && sig != (prt.Signature{}) && target.Signatures[slot] == (prt.Signature{}) {
+			target.Signatures[slot] = sig
+		}
+	}
+}
+
+// MultisigStatus signatures collected and required per multisig input
+type MultisigStatus struct {
+	Signed   []int // Valid signatures per input (-1 for single key inputs)
+	Required []int
+	Complete bool
+}
+
+// GetMultisigStatus counts valid signatures of every multisig input
+func GetMultisigStatus(tx *Transaction) *MultisigStatus {
+	status := &MultisigStatus{Complete: true}
+	txHashBytes := utils.HashToBytes(tx.ID)
+	for _, input := range tx.Inputs {
+		if input.MultiSig == nil {
+			status.Signed = append(status.Signed, -1)
+			status.Required = append(status.Required, 1)
+			continue
+		}
+
+		signed := 0
+		keys, err := input.MultiSig.parseKeys()
+		if err == nil {
+			for i, sig := range input.Signatures {
+				if i < len(keys) && sig != (prt.Signature{}) && crypto.VerifySignature(keys[i], txHashBytes, sig) {
+					signed++
+				}
+			}
+		}
+		status.Signed = append(status.Signed, signed)
+		status.Required = append(status.Required, input.MultiSig.Threshold)
+		if signed < input.MultiSig.Threshold {
+			status.Complete = false
+		}
+	}
+	return status
+}
+
+// EncodeTxHex encodes transaction (with signatures) as hex of its JSON form for passing between signers
+func EncodeTxHex(tx *Transaction) (string, error) {
+	txBytes, err := json.Marshal(tx)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode tx: %w", err)
+	}
+	return hex.EncodeToString(txBytes), nil
+}
+
+// DecodeTxHex decodes transaction encoded by EncodeTxHex
+func DecodeTxHex(txHex string) (*Transaction, error) {
+	txBytes, err := hex.DecodeString(txHex)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tx hex: %w", err)
+	}
+	var tx Transaction
+	if err := json.Unmarshal(txBytes, &tx); err != nil {
+		return nil, fmt.Errorf("failed to decode tx: %w", err)
+	}
+	if err := checkSignatureSlots(&tx); err != nil {
+		return nil, err
+	}
+	return &tx, nil
+}
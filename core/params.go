@@ -0,0 +1,129 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/abcfe/abcfe-node/common/logger"
+	"github.com/abcfe/abcfe-node/config"
+)
+
+// Consensus parameters:
+//   - Epoch length, commission / slash rates, downtime tracking and unbonding period come from
+//     [consensus] config (0 = default).
+//   - Like the consensus timing, parameters other than the default are recorded in the data of the
+//     genesis tx, and a node uses the parameters of its genesis block over its own config.
+//   - Until the genesis block is known the config values apply (no block depends on them yet).
+
+// ConsensusParams consensus-critical parameters every node of a network must share
+type ConsensusParams struct {
+	EpochBlocks        uint64 `json:"epochBlocks"`
+	CommissionRate     uint64 `json:"commissionRate"`
+	SlashRate          uint64 `json:"slashRate"`
+	SignedBlocksWindow uint64 `json:"signedBlocksWindow"`
+	MinSignedPerWindow uint64 `json:"minSignedPerWindow"`
+	DowntimeJailBlocks uint64 `json:"downtimeJailBlocks"`
+	UnbondingBlocks    uint64 `json:"unbondingBlocks"`
+}
+
+// genesisConsensusData consensus timing and parameters recorded in the genesis tx (one JSON object)
+type genesisConsensusData struct {
+	ConsensusTiming
+	ConsensusParams
+}
+
+// DefaultConsensusParams parameters of a network not configuring any
+func DefaultConsensusParams() ConsensusParams {
+	return ConsensusParams{
+		EpochBlocks:        DefaultEpochBlocks,
+		CommissionRate:     DefaultCommissionRate,
+		SlashRate:          DefaultSlashRate,
+		SignedBlocksWindow: DefaultSignedBlocksWindow,
+		MinSignedPerWindow: DefaultMinSignedPerWindow,
+		DowntimeJailBlocks: DefaultDowntimeJailBlocks,
+		UnbondingBlocks:    DefaultUnbondingBlocks,
+	}
+}
+
+// ConfigConsensusParams parameters of [consensus] config (defaults for unset or out of range values)
+func ConfigConsensusParams(cfg *config.Config) ConsensusParams {
+	params := DefaultConsensusParams()
+	orDefault := func(value uint64, max uint64, def *uint64) {
+		if value > 0 && (max == 0 || value <= max) {
+			*def = value
+		}
+	}
+	orDefault(cfg.Consensus.EpochBlocks, 0, &params.EpochBlocks)
+	orDefault(cfg.Consensus.CommissionRate, 100, &params.CommissionRate)
+	orDefault(cfg.Consensus.SlashRate, 100, &params.SlashRate)
+	orDefault(cfg.Consensus.SignedBlocksWindow, 0, &params.SignedBlocksWindow)
+	orDefault(cfg.Consensus.MinSignedPerWindow, 100, &params.MinSignedPerWindow)
+	orDefault(cfg.Consensus.DowntimeJailBlocks, 0, &params.DowntimeJailBlocks)
+	orDefault(cfg.Consensus.UnbondingBlocks, 0, &params.UnbondingBlocks)
+	return params
+}
+
+// Validate checks that lengths are positive and rates are percents
+func (c ConsensusParams) Validate() error {
+	if c.EpochBlocks == 0 || c.SignedBlocksWindow == 0 || c.DowntimeJailBlocks == 0 || c.UnbondingBlocks == 0 {
+		return fmt.Errorf("epochBlocks, signedBlocksWindow, downtimeJailBlocks and unbondingBlocks must be positive")
+	}
+	if c.CommissionRate == 0 || c.CommissionRate > 100 || c.SlashRate == 0 || c.SlashRate > 100 ||
+		c.MinSignedPerWindow == 0 || c.MinSignedPerWindow > 100 {
+		return fmt.Errorf("commissionRate, slashRate and minSignedPerWindow must be between 1 and 100")
+	}
+	return nil
+}
+
+// genesisConsensusBytes returns timing and parameters recorded in the genesis tx (nil if both are the default)
+func (p *BlockChain) genesisConsensusBytes() ([]byte, error) {
+	timing := ConfigConsensusTiming(p.cfg)
+	if err := timing.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid consensus timing: %w", err)
+	}
+	params := ConfigConsensusParams(p.cfg)
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid consensus params: %w", err)
+	}
+	if timing == DefaultConsensusTiming() && params == DefaultConsensusParams() {
+		return nil, nil
+	}
+	data, err := json.Marshal(genesisConsensusData{ConsensusTiming: timing, ConsensusParams: params})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal consensus data: %w", err)
+	}
+	return data, nil
+}
+
+// GetConsensusParams parameters of the network recorded in the genesis block (default if none recorded).
+// Does not take the chain lock, so it is safe to call while holding it.
+func (p *BlockChain) GetConsensusParams() ConsensusParams {
+	p.paramsMu.Lock()
+	defer p.paramsMu.Unlock()
+
+	if p.params != nil {
+		return *p.params
+	}
+
+	genesis, err := p.getBlockByHeightNoLock(0)
+	if err != nil || len(genesis.Transactions) == 0 {
+		return ConfigConsensusParams(p.cfg)
+	}
+
+	params := DefaultConsensusParams()
+	if data := genesis.Transactions[0].Data; len(data) > 0 {
+		if err := json.Unmarshal(data, &params); err != nil {
+			logger.Error("[Consensus] Failed to unmarshal genesis params, using defaults: ", err)
+			params = DefaultConsensusParams()
+		}
+	}
+	if err := params.Validate(); err != nil {
+		logger.Error("[Consensus] Invalid genesis params, using defaults: ", err)
+		params = DefaultConsensusParams()
+	}
+	if params != ConfigConsensusParams(p.cfg) {
+		logger.Warn("[Consensus] Config params differ from the genesis block, using genesis params: ", params)
+	}
+	p.params = &params
+	return params
+}
@@ -0,0 +1,335 @@
+package core
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"github.com/abcfe/abcfe-node/common/crypto"
+	"github.com/abcfe/abcfe-node/common/utils"
+	prt "github.com/abcfe/abcfe-node/protocol"
+)
+
+const (
+	PartialTxVersion = 1 // Format version of PartialTx
+)
+
+// PartialTx portable partially signed transaction (similar to PSBT).
+// Carries the unsigned tx together with the txs whose outputs it spends, so an offline signer can check
+// owners, amounts and fee without chain access (a previous tx must hash to the input's tx ID).
+// Signatures are not part of the tx hash, so copies signed by different parties can be combined.
+type PartialTx struct {
+	Version int               `json:"version"`
+	Tx      *Transaction      `json:"tx"`
+	Inputs  []*PartialTxInput `json:"inputs"` // Spent outputs, same order as Tx.Inputs
+}
+
+// PartialTxInput output spent by an input of a partial tx
+type PartialTxInput struct {
+	TxID        prt.Hash     `json:"txId"`
+	OutputIndex uint64       `json:"outputIndex"`
+	Output      TxOutput     `json:"output"`                // Owner, amount and locks of the spent output
+	PrevTx      *Transaction `json:"prevTx"`                // Tx creating the output (its hash commits to the output)
+	Height      uint64       `json:"height"`                // Confirmation height
+	Unconfirmed bool         `json:"unconfirmed,omitempty"` // Output of a mempool tx
+}
+
+// utxo converts spent output data back to UTXO (for ownership / lock checks)
+func (p *PartialTxInput) utxo() *UTXO {
+	return &UTXO{
+		TxId:        p.TxID,
+		OutputIndex: p.OutputIndex,
+		TxOut:       p.Output,
+		Height:      p.Height,
+		Unconfirmed: p.Unconfirmed,
+	}
+}
+
+// checkPrevTx checks that spent output data is the output of the previous tx hashing to the input's tx ID
+func (p *PartialTxInput) checkPrevTx() error {
+	if p.PrevTx == nil {
+		return fmt.Errorf("previous tx of spent output is missing")
+	}
+	if p.PrevTx.ID != p.TxID {
+		return fmt.Errorf("previous tx is not the tx of the spent output")
+	}
+	if err := ValidateTxHash(p.PrevTx); err != nil {
+		return fmt.Errorf("invalid previous tx: %w", err)
+	}
+	if p.OutputIndex >= uint64(len(p.PrevTx.Outputs)) || !sameOutput(p.PrevTx.Outputs[p.OutputIndex], &p.Output) {
+		return fmt.Errorf("spent output data does not match previous tx")
+	}
+	return nil
+}
+
+// spentTx copy of the tx creating utxo (mempool tx for an unconfirmed output)
+func (p *BlockChain) spentTx(utxo *UTXO) (*Transaction, error) {
+	if !utxo.Unconfirmed {
+		return p.GetTx(utxo.TxId)
+	}
+	tx := p.Mempool.GetTx(utxo.TxId)
+	if tx == nil {
+		return nil, fmt.Errorf("mempool tx %s not found", utils.HashToString(utxo.TxId))
+	}
+	txBytes, err := utils.SerializeData(tx, utils.SerializationFormatGob)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize tx: %w", err)
+	}
+	var txCopy Transaction
+	if err := utils.DeserializeData(txBytes, &txCopy, utils.SerializationFormatGob); err != nil {
+		return nil, fmt.Errorf("failed to deserialize tx: %w", err)
+	}
+	return &txCopy, nil
+}
+
+// CreatePartialTx builds unsigned transfer tx of spender and attaches the spent outputs
+func (p *BlockChain) CreatePartialTx(spender TxSpender, recipients []TxRecipient, fee uint64, memo string, data []byte, txType uint8, opts TxBuildOptions) (*PartialTx, *CoinSelection, error) {
+	tx, selection, err := p.BuildUnsignedTx(spender, recipients, fee, memo, data, txType, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ptx := &PartialTx{Version: PartialTxVersion, Tx: tx}
+	for _, utxo := range selection.Utxos {
+		prevTx, err := p.spentTx(utxo)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get tx of spent output: %w", err)
+		}
+		ptx.Inputs = append(ptx.Inputs, &PartialTxInput{
+			TxID:        utxo.TxId,
+			OutputIndex: utxo.OutputIndex,
+			Output:      utxo.TxOut,
+			PrevTx:      prevTx,
+			Height:      utxo.Height,
+			Unconfirmed: utxo.Unconfirmed,
+		})
+	}
+	return ptx, selection, nil
+}
+
+// Validate checks partial tx consistency without chain access:
+// tx hash, spent output data of every input (against its previous tx), input owners and that inputs cover outputs
+func (p *PartialTx) Validate() error {
+	if p.Version != PartialTxVersion {
+		return fmt.Errorf("unsupported partial tx version: %d", p.Version)
+	}
+	if p.Tx == nil {
+		return fmt.Errorf("partial tx has no transaction")
+	}
+	if len(p.Tx.Inputs) == 0 {
+		return fmt.Errorf("partial tx has no inputs")
+	}
+	if err := ValidateTxHash(p.Tx); err != nil {
+		return err
+	}
+	if err := checkSignatureSlots(p.Tx); err != nil {
+		return err
+	}
+	if len(p.Inputs) != len(p.Tx.Inputs) {
+		return fmt.Errorf("partial tx has %d spent outputs for %d inputs", len(p.Inputs), len(p.Tx.Inputs))
+	}
+
+	for i, input := range p.Tx.Inputs {
+		spent := p.Inputs[i]
+		if spent == nil || spent.TxID != input.TxID || spent.OutputIndex != input.OutputIndex {
+			return fmt.Errorf("input[%d]: spent output data does not match input", i)
+		}
+		if err := spent.checkPrevTx(); err != nil {
+			return fmt.Errorf("input[%d]: %w", i, err)
+		}
+
+		owner, err := TxSpender{PublicKey: input.PublicKey, MultiSig: input.MultiSig}.Address()
+		if err != nil {
+			return fmt.Errorf("input[%d]: %w", i, err)
+		}
+		if spent.Output.HTLC != nil {
+			if owner != htlcSpender(input, spent.Output.HTLC) {
+				return fmt.Errorf("input[%d]: key cannot spend HTLC output on this path", i)
+			}
+			continue
+		}
+		if owner != spent.Output.Address {
+			return fmt.Errorf("input[%d]: key does not own spent output %s", i, utils.AddressToString(spent.Output.Address))
+		}
+	}
+
+	if _, err := p.Fee(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// InputTotal sum of spent outputs (amounts checked against the previous txs)
+func (p *PartialTx) InputTotal() (uint64, error) {
+	var total uint64
+	for i, spent := range p.Inputs {
+		if spent == nil {
+			return 0, fmt.Errorf("input[%d]: spent output data is missing", i)
+		}
+		if err := spent.checkPrevTx(); err != nil {
+			return 0, fmt.Errorf("input[%d]: %w", i, err)
+		}
+		if total > math.MaxUint64-spent.Output.Amount {
+			return 0, fmt.Errorf("input amount overflow")
+		}
+		total += spent.Output.Amount
+	}
+	return total, nil
+}
+
+// Fee implicit fee (inputs - outputs)
+func (p *PartialTx) Fee() (uint64, error) {
+	inputTotal, err := p.InputTotal()
+	if err != nil {
+		return 0, err
+	}
+	var outputTotal uint64
+	for _, output := range p.Tx.Outputs {
+		if outputTotal > math.MaxUint64-output.Amount {
+			return 0, fmt.Errorf("output amount overflow")
+		}
+		outputTotal += output.Amount
+	}
+	if outputTotal > inputTotal {
+		return 0, fmt.Errorf("outputs (%d) exceed inputs (%d)", outputTotal, inputTotal)
+	}
+	return inputTotal - outputTotal, nil
+}
+
+// Sign adds signature of key to every input it owns (single key or multisig member).
+// Returns number of inputs signed.
+func (p *PartialTx) Sign(privateKeyBytes, publicKeyBytes []byte) (int, error) {
+	if err := p.Validate(); err != nil {
+		return 0, err
+	}
+	signed, err := signTxInputs(p.Tx, privateKeyBytes, publicKeyBytes)
+	if err != nil {
+		return signed, err
+	}
+	if signed == 0 {
+		return 0, fmt.Errorf("key does not own any input of the partial tx")
+	}
+	return signed, nil
+}
+
+// PartialTxStatus valid signatures collected and required per input
+type PartialTxStatus struct {
+	Signed   []int
+	Required []int
+	Complete bool
+}
+
+// Status counts valid signatures of every input
+func (p *PartialTx) Status() *PartialTxStatus {
+	status := &PartialTxStatus{Complete: true}
+	txHashBytes := utils.HashToBytes(p.Tx.ID)
+	for _, input := range p.Tx.Inputs {
+		signed, required := 0, 1
+		if input.MultiSig != nil {
+			required = input.MultiSig.Threshold
+			if keys, err := input.MultiSig.parseKeys(); err == nil {
+				for i, sig := range input.Signatures {
+					if i < len(keys) && sig != (prt.Signature{}) && crypto.VerifySignature(keys[i], txHashBytes, sig) {
+						signed++
+					}
+				}
+			}
+		} else if input.Signature != (prt.Signature{}) {
+			if valid, err := crypto.VerifySignatureWithBytes(input.PublicKey, txHashBytes, input.Signature); err == nil && valid {
+				signed = 1
+			}
+		}
+
+		status.Signed = append(status.Signed, signed)
+		status.Required = append(status.Required, required)
+		if signed < required {
+			status.Complete = false
+		}
+	}
+	return status
+}
+
+// CombinePartialTxs merges signatures of copies of the same partial tx signed by different parties
+func CombinePartialTxs(ptxs []*PartialTx) (*PartialTx, error) {
+	if len(ptxs) == 0 {
+		return nil, fmt.Errorf("no partial txs to combine")
+	}
+
+	combined := ptxs[0]
+	if err := combined.Validate(); err != nil {
+		return nil, fmt.Errorf("partial tx 0: %w", err)
+	}
+	for n, ptx := range ptxs[1:] {
+		if err := ptx.Validate(); err != nil {
+			return nil, fmt.Errorf("partial tx %d: %w", n+1, err)
+		}
+		if ptx.Tx.ID != combined.Tx.ID {
+			return nil, fmt.Errorf("partial tx %d is not the same transaction", n+1)
+		}
+		for i, input := range ptx.Tx.Inputs {
+			mergeInputSignatures(combined.Tx.Inputs[i], input)
+		}
+	}
+
+	return combined, nil
+}
+
+// Finalize verifies every input signature against the spent outputs and returns the tx ready to broadcast
+func (p *PartialTx) Finalize() (*Transaction, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	if status := p.Status(); !status.Complete {
+		return nil, fmt.Errorf("partial tx is not fully signed: signed %v, required %v", status.Signed, status.Required)
+	}
+
+	for i, input := range p.Tx.Inputs {
+		if input.MultiSig == nil {
+			continue // Verified by Status
+		}
+		if err := validateMultisigInput(p.Tx, input, p.Inputs[i].utxo()); err != nil {
+			return nil, fmt.Errorf("input[%d]: %w", i, err)
+		}
+	}
+	return p.Tx, nil
+}
+
+// CheckPartialTxInputs checks that spent output data of partial tx matches the chain (and mempool) unspent outputs
+func (p *BlockChain) CheckPartialTxInputs(ptx *PartialTx) error {
+	for i, input := range ptx.Tx.Inputs {
+		utxo, err := p.resolveInputUtxo(input, nil, true)
+		if err != nil {
+			return fmt.Errorf("input[%d]: %w", i, err)
+		}
+		if utxo.Spent {
+			return fmt.Errorf("input[%d]: UTXO already spent", i)
+		}
+		if !sameOutput(&utxo.TxOut, &ptx.Inputs[i].Output) {
+			return fmt.Errorf("input[%d]: spent output data does not match chain", i)
+		}
+	}
+	return nil
+}
+
+// EncodePartialTx encodes partial tx as hex of its JSON form
+func EncodePartialTx(ptx *PartialTx) (string, error) {
+	ptxBytes, err := json.Marshal(ptx)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode partial tx: %w", err)
+	}
+	return hex.EncodeToString(ptxBytes), nil
+}
+
+// DecodePartialTx decodes partial tx encoded by EncodePartialTx
+func DecodePartialTx(ptxHex string) (*PartialTx, error) {
+	ptxBytes, err := hex.DecodeString(ptxHex)
+	if err != nil {
+		return nil, fmt.Errorf("invalid partial tx hex: %w", err)
+	}
+	var ptx PartialTx
+	if err := json.Unmarshal(ptxBytes, &ptx); err != nil {
+		return nil, fmt.Errorf("failed to decode partial tx: %w", err)
+	}
+	return &ptx, nil
+}
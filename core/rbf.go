@@ -0,0 +1,180 @@
+package core
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/abcfe/abcfe-node/common/crypto"
+	"github.com/abcfe/abcfe-node/common/logger"
+	"github.com/abcfe/abcfe-node/common/utils"
+	prt "github.com/abcfe/abcfe-node/protocol"
+)
+
+const (
+	TxSequenceRBF uint64 = 1 // Minimum input sequence which opts in to replace-by-fee
+)
+
+// SignalsRBF checks if transaction opts in to replace-by-fee (any input sequence >= TxSequenceRBF)
+func SignalsRBF(tx *Transaction) bool {
+	for _, input := range tx.Inputs {
+		if input.Sequence >= TxSequenceRBF {
+			return true
+		}
+	}
+	return false
+}
+
+// AddTxToMempool validates transaction, calculates its fee and adds it to mempool.
+// Conflicting replaceable txs paying a lower fee are evicted and returned.
+func (p *BlockChain) AddTxToMempool(tx *Transaction) ([]*Transaction, error) {
+	if err := p.ValidateTransaction(tx); err != nil {
+		return nil, err
+	}
+
+	fee, err := p.CalculateTxFee(tx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate fee: %w", err)
+	}
+
+	replacedEntries, err := p.Mempool.AddTxWithReplacement(tx, fee)
+	if err != nil {
+		return nil, err
+	}
+
+	replaced := make([]*Transaction, 0, len(replacedEntries))
+	for _, entry := range replacedEntries {
+		logger.Info("[Mempool] Tx ", utils.HashToString(entry.Tx.ID)[:16], " (fee ", entry.Fee, ") replaced by ", utils.HashToString(tx.ID)[:16], " (fee ", fee, ")")
+		replaced = append(replaced, entry.Tx)
+	}
+	return replaced, nil
+}
+
+// BumpTxFee creates a replacement of a pending replaceable tx paying newFee.
+// The replacement spends the same inputs and pays the same recipients; the fee increase
+// is taken from the change output, adding more sender UTXOs when change is not enough.
+func (p *BlockChain) BumpTxFee(txId prt.Hash, newFee uint64, privateKeyBytes, publicKeyBytes []byte) (*Transaction, error) {
+	orig := p.Mempool.GetTxWithFee(txId)
+	if orig == nil {
+		return nil, fmt.Errorf("tx not found in mempool: %s", utils.HashToString(txId))
+	}
+	if !SignalsRBF(orig.Tx) {
+		return nil, fmt.Errorf("tx does not signal replace-by-fee")
+	}
+
+	// Sender is the owner of the signing key
+	pubKey, err := crypto.BytesToPublicKey(publicKeyBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key: %w", err)
+	}
+	from, err := crypto.PublicKeyToAddress(pubKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive address: %w", err)
+	}
+
+	// Same inputs (all must belong to sender), same reward claim
+	inputSum := orig.Tx.Claim
+	var txIns []*TxInput
+	for i, input := range orig.Tx.Inputs {
+		utxo, err := p.resolveInputUtxo(input, nil, true)
+		if err != nil {
+			return nil, fmt.Errorf("input[%d]: %w", i, err)
+		}
+		if utxo.TxOut.Address != from {
+			return nil, fmt.Errorf("input[%d] is not owned by %s", i, utils.AddressToString(from))
+		}
+		inputSum += utxo.TxOut.Amount
+		txIns = append(txIns, &TxInput{
+			TxID:        input.TxID,
+			OutputIndex: input.OutputIndex,
+			PublicKey:   publicKeyBytes,
+			Sequence:    TxSequenceRBF,
+		})
+	}
+
+	var outputSum uint64
+	for _, output := range orig.Tx.Outputs {
+		outputSum += output.Amount
+	}
+	if inputSum < outputSum {
+		return nil, fmt.Errorf("invalid tx: inputSum < outputSum")
+	}
+	oldFee := inputSum - outputSum
+	if newFee <= oldFee {
+		return nil, fmt.Errorf("new fee %d must be higher than current fee %d", newFee, oldFee)
+	}
+
+	// Recipients are kept, change (last output paid back to sender) absorbs the increase
+	var txOuts []*TxOutput
+	var change uint64
+	changeIdx := -1
+	for i := len(orig.Tx.Outputs) - 1; i >= 0; i-- {
+		if orig.Tx.Outputs[i].Address == from {
+			changeIdx = i
+			break
+		}
+	}
+	for i, output := range orig.Tx.Outputs {
+		if i == changeIdx {
+			change = output.Amount
+			continue
+		}
+		recipientOut := *output // Keeps timelocks
+		txOuts = append(txOuts, &recipientOut)
+	}
+
+	// Available funds for change + fee
+	available := change + oldFee
+	if available < newFee {
+		utxos, err := p.GetUtxoList(from, true)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get UTXO list: %w", err)
+		}
+		for _, utxo := range utxos {
+			if available >= newFee {
+				break
+			}
+			if utxo.TxId == txId {
+				continue // Own change output disappears with the replaced tx
+			}
+			txIns = append(txIns, &TxInput{
+				TxID:        utxo.TxId,
+				OutputIndex: utxo.OutputIndex,
+				PublicKey:   publicKeyBytes,
+				Sequence:    TxSequenceRBF,
+			})
+			available += utxo.TxOut.Amount
+		}
+		if available < newFee {
+			return nil, fmt.Errorf("not enough balance to bump fee: have %d, need %d", available, newFee)
+		}
+	}
+
+	if available > newFee {
+		txType := TxTypeGeneral
+		if changeIdx >= 0 {
+			txType = orig.Tx.Outputs[changeIdx].TxType
+		}
+		txOuts = append(txOuts, &TxOutput{Address: from, Amount: available - newFee, TxType: txType})
+	}
+
+	tx := &Transaction{
+		Version:   orig.Tx.Version,
+		NetworkID: orig.Tx.NetworkID,
+		Timestamp: time.Now().Unix(),
+		Inputs:    txIns,
+		Outputs:   txOuts,
+		Memo:      orig.Tx.Memo,
+		Data:      orig.Tx.Data,
+		Claim:     orig.Tx.Claim,
+		Unjail:    orig.Tx.Unjail,
+	}
+	if tx.Data == nil {
+		tx.Data = []byte{}
+	}
+
+	if err := signTx(tx, privateKeyBytes); err != nil {
+		return nil, err
+	}
+
+	return tx, nil
+}
@@ -0,0 +1,621 @@
+package core
+
+import (
+	"fmt"
+
+	"github.com/abcfe/abcfe-node/common/logger"
+	"github.com/abcfe/abcfe-node/common/utils"
+	prt "github.com/abcfe/abcfe-node/protocol"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+const (
+	MaxReorgDepth   = 100 // Maximum number of main chain blocks rolled back by a single reorg
+	MaxOrphanBlocks = 256 // Maximum number of blocks waiting for an unknown parent
+)
+
+// BlockImportStatus result of ImportBlock
+type BlockImportStatus int
+
+const (
+	BlockImportExtended  BlockImportStatus = iota // Appended on top of the main chain
+	BlockImportSideChain                          // Stored as side-chain block, main chain kept
+	BlockImportReorged                            // Side chain won the fork choice and became the main chain
+	BlockImportKnown                              // Block already stored (main, side or orphan)
+	BlockImportOrphan                             // Parent unknown, kept until the parent arrives
+)
+
+func (s BlockImportStatus) String() string {
+	switch s {
+	case BlockImportExtended:
+		return "extended"
+	case BlockImportSideChain:
+		return "side-chain"
+	case BlockImportReorged:
+		return "reorged"
+	case BlockImportKnown:
+		return "known"
+	case BlockImportOrphan:
+		return "orphan"
+	default:
+		return "unknown"
+	}
+}
+
+// ImportBlock adds a block received from the network.
+// Unlike AddBlock, the block does not need to extend the current tip:
+// blocks on other branches are kept as side-chain blocks and the fork choice
+// decides whether the main chain is reorganized onto them.
+func (p *BlockChain) ImportBlock(blk Block) (BlockImportStatus, error) {
+	p.mu.Lock()
+
+	var reverted []*Block
+	status, err := p.importBlockNoLock(&blk, &reverted)
+
+	// Connect blocks which were waiting for this one
+	if err == nil && status != BlockImportKnown && status != BlockImportOrphan {
+		queue := []prt.Hash{blk.Header.Hash}
+		for len(queue) > 0 {
+			parentHash := queue[0]
+			queue = queue[1:]
+
+			for _, child := range p.takeOrphansNoLock(parentHash) {
+				childStatus, childErr := p.importBlockNoLock(child, &reverted)
+				if childErr != nil {
+					logger.Warn("[Reorg] Failed to import orphan block height=", child.Header.Height, ": ", childErr)
+					continue
+				}
+				if childStatus == BlockImportReorged {
+					status = BlockImportReorged
+				}
+				queue = append(queue, child.Header.Hash)
+			}
+		}
+	}
+
+	p.mu.Unlock()
+
+	// Transactions of rolled back blocks go back to mempool (outside chain lock)
+	p.restoreRevertedTxs(reverted)
+
+	return status, err
+}
+
+// importBlockNoLock imports single block (lock must be held)
+func (p *BlockChain) importBlockNoLock(blk *Block, reverted *[]*Block) (BlockImportStatus, error) {
+	blkHash := blk.Header.Hash
+
+	if p.hasBlockNoLock(blkHash) {
+		return BlockImportKnown, nil
+	}
+	if _, exists := p.orphans[blkHash]; exists {
+		return BlockImportKnown, nil
+	}
+
+	// Empty chain: only genesis can be imported
+	if p.LatestBlockHash == "" {
+		if blk.Header.Height != 0 {
+			return BlockImportOrphan, p.addOrphanNoLock(blk)
+		}
+		if err := p.validateBlockNoLock(*blk, true); err != nil {
+			return BlockImportKnown, fmt.Errorf("invalid genesis block: %w", err)
+		}
+		if _, err := p.addBlockNoLock(*blk); err != nil {
+			return BlockImportKnown, err
+		}
+		return BlockImportExtended, nil
+	}
+
+	if blk.Header.Height == 0 {
+		return BlockImportKnown, fmt.Errorf("genesis block mismatch: %s", utils.HashToString(blkHash))
+	}
+
+	// Stateless checks before anything is stored
+	if err := p.validateBlockStateless(blk); err != nil {
+		return BlockImportKnown, err
+	}
+
+	// 1. Extends main chain tip
+	if utils.HashToString(blk.Header.PrevHash) == p.LatestBlockHash {
+		if err := p.validateBlockNoLock(*blk, true); err != nil {
+			return BlockImportKnown, err
+		}
+		if _, err := p.addBlockNoLock(*blk); err != nil {
+			return BlockImportKnown, err
+		}
+		return BlockImportExtended, nil
+	}
+
+	// 2. Parent unknown: keep as orphan
+	if !p.hasBlockNoLock(blk.Header.PrevHash) {
+		return BlockImportOrphan, p.addOrphanNoLock(blk)
+	}
+
+	// 3. Fork: store as side-chain block
+	if blk.Header.Height+MaxReorgDepth < p.LatestHeight {
+		return BlockImportKnown, fmt.Errorf("fork too deep: block height %d, chain height %d", blk.Header.Height, p.LatestHeight)
+	}
+	if err := p.saveSideBlockNoLock(blk); err != nil {
+		return BlockImportKnown, err
+	}
+
+	branch, ancestorHeight, err := p.getBranchNoLock(blk)
+	if err != nil {
+		return BlockImportSideChain, err
+	}
+
+	// Main chain blocks above the common ancestor (tip first)
+	var disconnect []*Block
+	for h := p.LatestHeight; h > ancestorHeight; h-- {
+		mainBlk, err := p.getBlockByHeightNoLock(h)
+		if err != nil {
+			return BlockImportSideChain, fmt.Errorf("failed to load main chain block %d: %w", h, err)
+		}
+		disconnect = append(disconnect, mainBlk)
+	}
+
+	// 4. Fork choice
+	if !p.isBetterBranchNoLock(branch, disconnect) {
+		logger.Info("[Reorg] Side-chain block stored: height=", blk.Header.Height, " hash=", utils.HashToString(blkHash)[:16], " ancestor=", ancestorHeight)
+		return BlockImportSideChain, nil
+	}
+
+	// 5. Reorganize
+	if err := p.reorganizeNoLock(branch, disconnect); err != nil {
+		return BlockImportSideChain, err
+	}
+	*reverted = append(*reverted, disconnect...)
+
+	logger.Info("[Reorg] Chain reorganized: ancestor=", ancestorHeight, " rolled back=", len(disconnect), " connected=", len(branch), " new tip=", p.LatestHeight)
+	return BlockImportReorged, nil
+}
+
+// validateBlockStateless validates block fields which do not depend on chain state
+func (p *BlockChain) validateBlockStateless(blk *Block) error {
+	if err := ValidateMerkleRoot(blk); err != nil {
+		return err
+	}
+	if err := ValidateBlockHash(blk); err != nil {
+		return err
+	}
+	if err := ValidateProposer(blk); err != nil {
+		return err
+	}
+	if err := ValidateProposerSignature(blk, p.epochOrNilNoLock(blk.Header.Height), p.proposerValidator); err != nil {
+		return err
+	}
+	return nil
+}
+
+// isBlockFinalized checks if block carries valid 2/3+ commit signatures
+func (p *BlockChain) isBlockFinalized(blk *Block) bool {
+	if len(blk.CommitSignatures) == 0 || p.proposerValidator == nil {
+		return false
+	}
+	return p.proposerValidator.ValidateCommitSignatures(p.epochOrNilNoLock(blk.Header.Height), blk.Header.Height, blk.Header.Hash, blk.CommitSignatures) == nil
+}
+
+// isBetterBranchNoLock fork choice rule
+// 1. Finalized main chain blocks are never rolled back
+// 2. A branch containing a finalized block wins over a non-finalized main chain
+// 3. Otherwise the longer chain wins (ties keep the current main chain)
+func (p *BlockChain) isBetterBranchNoLock(branch []*Block, disconnect []*Block) bool {
+	branchFinalized := false
+	for _, blk := range branch {
+		if p.isBlockFinalized(blk) {
+			branchFinalized = true
+			break
+		}
+	}
+
+	for _, blk := range disconnect {
+		if p.isBlockFinalized(blk) {
+			if branchFinalized {
+				logger.Error("[Reorg] Conflicting finalized blocks at height ", blk.Header.Height, ": main=", utils.HashToString(blk.Header.Hash)[:16])
+			}
+			return false
+		}
+	}
+
+	if len(disconnect) > MaxReorgDepth {
+		logger.Warn("[Reorg] Reorg depth ", len(disconnect), " exceeds max ", MaxReorgDepth)
+		return false
+	}
+
+	if branchFinalized {
+		return true
+	}
+
+	tip := branch[len(branch)-1]
+	return tip.Header.Height > p.LatestHeight
+}
+
+// getBranchNoLock collects side-chain blocks from the common ancestor up to blk (ascending)
+func (p *BlockChain) getBranchNoLock(blk *Block) ([]*Block, uint64, error) {
+	branch := []*Block{blk}
+	cur := blk
+
+	for {
+		if cur.Header.Height == 0 {
+			return nil, 0, fmt.Errorf("side chain does not share genesis block")
+		}
+
+		parentHeight := cur.Header.Height - 1
+		if p.isMainChainNoLock(cur.Header.PrevHash, parentHeight) {
+			return branch, parentHeight, nil
+		}
+
+		parent, err := p.getSideBlockNoLock(cur.Header.PrevHash)
+		if err != nil {
+			return nil, 0, fmt.Errorf("broken side chain at height %d: %w", parentHeight, err)
+		}
+
+		branch = append([]*Block{parent}, branch...)
+		if len(branch) > MaxReorgDepth+1 {
+			return nil, 0, fmt.Errorf("side chain longer than max reorg depth %d", MaxReorgDepth)
+		}
+		cur = parent
+	}
+}
+
+// reorgJournal original main chain of a running reorg.
+// Each disconnect / connect is written in its own batch, so a crash in between leaves
+// a mixed chain; NewChainState uses the journal to restore the original main chain.
+type reorgJournal struct {
+	AncestorHeight uint64
+	AncestorHash   prt.Hash
+	MainChain      []prt.Hash // Original main chain blocks above the ancestor (ascending)
+}
+
+// reorganizeNoLock rolls main chain back to the common ancestor and connects branch
+func (p *BlockChain) reorganizeNoLock(branch []*Block, disconnect []*Block) error {
+	// 0. Record original main chain until the reorg is done
+	if err := p.saveReorgJournalNoLock(branch[0], disconnect); err != nil {
+		return err
+	}
+
+	// 1. Roll back main chain (tip first)
+	for _, blk := range disconnect {
+		if err := p.disconnectBlockNoLock(blk, true); err != nil {
+			return fmt.Errorf("failed to roll back block %d: %w", blk.Header.Height, err)
+		}
+	}
+
+	// 2. Connect side chain blocks (full validation against the rolled back state)
+	for i, blk := range branch {
+		err := p.validateBlockNoLock(*blk, true)
+		if err == nil {
+			_, err = p.addBlockNoLock(*blk)
+		}
+		if err != nil {
+			logger.Warn("[Reorg] Side-chain block ", blk.Header.Height, " invalid, restoring main chain: ", err)
+
+			// Undo partially connected branch and restore original main chain
+			for j := i - 1; j >= 0; j-- {
+				if undoErr := p.disconnectBlockNoLock(branch[j], true); undoErr != nil {
+					return fmt.Errorf("failed to restore main chain: %w", undoErr)
+				}
+			}
+			for k := len(disconnect) - 1; k >= 0; k-- {
+				if _, redoErr := p.addBlockNoLock(*disconnect[k]); redoErr != nil {
+					return fmt.Errorf("failed to restore main chain: %w", redoErr)
+				}
+				if delErr := p.db.Delete(utils.GetSideBlockKey(disconnect[k].Header.Hash), nil); delErr != nil {
+					return fmt.Errorf("failed to delete side block: %w", delErr)
+				}
+			}
+
+			// Invalid block is dropped from side storage
+			if delErr := p.db.Delete(utils.GetSideBlockKey(blk.Header.Hash), nil); delErr != nil {
+				logger.Warn("[Reorg] Failed to delete invalid side block: ", delErr)
+			}
+			if delErr := p.db.Delete([]byte(prt.PrefixMetaReorg), nil); delErr != nil {
+				return fmt.Errorf("failed to delete reorg journal: %w", delErr)
+			}
+			return fmt.Errorf("side chain block %d invalid: %w", blk.Header.Height, err)
+		}
+
+		// Block is on main chain now
+		if err := p.db.Delete(utils.GetSideBlockKey(blk.Header.Hash), nil); err != nil {
+			return fmt.Errorf("failed to delete side block: %w", err)
+		}
+	}
+
+	if err := p.db.Delete([]byte(prt.PrefixMetaReorg), nil); err != nil {
+		return fmt.Errorf("failed to delete reorg journal: %w", err)
+	}
+	return nil
+}
+
+// saveReorgJournalNoLock records common ancestor (parent of first branch block) and main chain blocks above it
+func (p *BlockChain) saveReorgJournalNoLock(first *Block, disconnect []*Block) error {
+	journal := reorgJournal{
+		AncestorHeight: first.Header.Height - 1,
+		AncestorHash:   first.Header.PrevHash,
+	}
+	for i := len(disconnect) - 1; i >= 0; i-- {
+		journal.MainChain = append(journal.MainChain, disconnect[i].Header.Hash)
+	}
+
+	journalBytes, err := utils.SerializeData(journal, utils.SerializationFormatGob)
+	if err != nil {
+		return fmt.Errorf("failed to serialize reorg journal: %w", err)
+	}
+	if err := p.db.Put([]byte(prt.PrefixMetaReorg), journalBytes, nil); err != nil {
+		return fmt.Errorf("failed to save reorg journal: %w", err)
+	}
+	return nil
+}
+
+// recoverReorg restores the original main chain of a reorg interrupted by a crash:
+// blocks which are not part of it are rolled back to the common ancestor and
+// the rest of the original main chain is connected again from side storage
+func (p *BlockChain) recoverReorg() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	journalBytes, err := p.db.Get([]byte(prt.PrefixMetaReorg), nil)
+	if err == leveldb.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get reorg journal: %w", err)
+	}
+	var journal reorgJournal
+	if err := utils.DeserializeData(journalBytes, &journal, utils.SerializationFormatGob); err != nil {
+		return fmt.Errorf("failed to deserialize reorg journal: %w", err)
+	}
+	if p.LatestHeight < journal.AncestorHeight {
+		return fmt.Errorf("chain height %d below reorg ancestor %d", p.LatestHeight, journal.AncestorHeight)
+	}
+
+	logger.Warn("[Reorg] Recovering interrupted reorg: ancestor=", journal.AncestorHeight, " tip=", p.LatestHeight)
+
+	// 1. Roll back blocks of the abandoned branch (main chain blocks below the tip are kept)
+	for p.LatestHeight > journal.AncestorHeight {
+		idx := p.LatestHeight - journal.AncestorHeight - 1
+		if idx < uint64(len(journal.MainChain)) && utils.HashToString(journal.MainChain[idx]) == p.LatestBlockHash {
+			break
+		}
+		blk, err := p.getBlockByHeightNoLock(p.LatestHeight)
+		if err != nil {
+			return fmt.Errorf("failed to load block %d: %w", p.LatestHeight, err)
+		}
+		if err := p.disconnectBlockNoLock(blk, true); err != nil {
+			return fmt.Errorf("failed to roll back block %d: %w", blk.Header.Height, err)
+		}
+	}
+	if p.LatestHeight == journal.AncestorHeight && p.LatestBlockHash != utils.HashToString(journal.AncestorHash) {
+		return fmt.Errorf("chain tip does not match reorg ancestor %d", journal.AncestorHeight)
+	}
+
+	// 2. Connect the rest of the original main chain
+	for _, hash := range journal.MainChain[p.LatestHeight-journal.AncestorHeight:] {
+		blk, err := p.getSideBlockNoLock(hash)
+		if err != nil {
+			return fmt.Errorf("failed to load main chain block: %w", err)
+		}
+		if _, err := p.addBlockNoLock(*blk); err != nil {
+			return fmt.Errorf("failed to restore main chain block %d: %w", blk.Header.Height, err)
+		}
+		if err := p.db.Delete(utils.GetSideBlockKey(hash), nil); err != nil {
+			return fmt.Errorf("failed to delete side block: %w", err)
+		}
+	}
+
+	if err := p.db.Delete([]byte(prt.PrefixMetaReorg), nil); err != nil {
+		return fmt.Errorf("failed to delete reorg journal: %w", err)
+	}
+	logger.Info("[Reorg] Main chain restored: tip=", p.LatestHeight)
+	return nil
+}
+
+// disconnectBlockNoLock removes the tip block from main chain and restores the state before it
+// by applying the block undo record. keepSide moves the block to side storage (reorg),
+// otherwise the block is dropped (operator rollback) so that it can be imported again.
+func (p *BlockChain) disconnectBlockNoLock(blk *Block, keepSide bool) error {
+	if utils.HashToString(blk.Header.Hash) != p.LatestBlockHash {
+		return fmt.Errorf("block %d is not the chain tip", blk.Header.Height)
+	}
+	if blk.Header.Height == 0 {
+		return fmt.Errorf("genesis block cannot be rolled back")
+	}
+
+	batch := new(leveldb.Batch)
+
+	// 1. Restore spent UTXOs and delete created outputs
+	undo, err := p.getBlockUndoNoLock(blk)
+	if err != nil {
+		return err
+	}
+	touched, err := p.applyBlockUndo(batch, undo)
+	if err != nil {
+		return err
+	}
+
+	// 2. Delete tx data
+	for _, tx := range blk.Transactions {
+		p.deleteTxDataNoLock(batch, tx)
+	}
+
+	// 3. Address history
+	p.deleteAddressHistory(batch, blk, touched)
+	p.deleteEpoch(batch, blk)
+
+	// 4. Remove block from main chain
+	batch.Delete(utils.GetBlockHashKey(blk.Header.Hash))
+	batch.Delete(utils.GetBlockHeightKey(blk.Header.Height))
+	if keepSide {
+		blkBytes, err := utils.SerializeData(blk, utils.SerializationFormatGob)
+		if err != nil {
+			return fmt.Errorf("failed to block serialization: %w", err)
+		}
+		batch.Put(utils.GetSideBlockKey(blk.Header.Hash), blkBytes)
+	}
+
+	// 5. Rewind chain state to parent
+	parentHeight := blk.Header.Height - 1
+	parentHash := utils.HashToString(blk.Header.PrevHash)
+	batch.Delete([]byte(fmt.Sprintf("%s%d", prt.PrefixMetaHeight, blk.Header.Height)))
+	batch.Put([]byte(prt.PrefixMetaHeight), []byte(fmt.Sprintf("%d", parentHeight)))
+	batch.Put([]byte(prt.PrefixMetaBlockHash), []byte(parentHash))
+
+	if err := p.db.Write(batch, nil); err != nil {
+		return fmt.Errorf("failed to write batch: %w", err)
+	}
+
+	p.LatestHeight = parentHeight
+	p.LatestBlockHash = parentHash
+	p.feeEstimator.RemoveFrom(blk.Header.Height)
+
+	// Cached account balances follow the restored UTXO set
+	for address := range touched {
+		if err := p.refreshAccountBalanceNoLock(address); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deleteTxDataNoLock deletes everything saveTxData wrote for the transaction
+func (p *BlockChain) deleteTxDataNoLock(batch *leveldb.Batch, tx *Transaction) {
+	batch.Delete(utils.GetTxHashKey(tx.ID))
+	batch.Delete(utils.GetTxBlockHashKey(tx.ID))
+	batch.Delete(utils.GetTxInputKey(tx.ID, prt.WholeTxIdx))
+	batch.Delete(utils.GetTxOutputKey(tx.ID, prt.WholeTxIdx))
+	for idx := range tx.Inputs {
+		batch.Delete(utils.GetTxInputKey(tx.ID, idx))
+	}
+	for idx := range tx.Outputs {
+		batch.Delete(utils.GetTxOutputKey(tx.ID, idx))
+	}
+}
+
+// refreshAccountBalanceNoLock recalculates cached balance of an existing account
+func (p *BlockChain) refreshAccountBalanceNoLock(address prt.Address) error {
+	key := []byte(prt.PrefixAddress + utils.AddressToString(address))
+	data, err := p.db.Get(key, nil)
+	if err == leveldb.ErrNotFound {
+		return nil // No account record
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get account: %w", err)
+	}
+
+	var account Account
+	if err := utils.DeserializeData(data, &account, utils.SerializationFormatGob); err != nil {
+		return fmt.Errorf("failed to deserialize account: %w", err)
+	}
+
+	utxoList, err := p.GetUtxoList(address, false)
+	if err != nil {
+		return fmt.Errorf("failed to get utxo list: %w", err)
+	}
+	account.Balance = p.CalBalanceUtxo(utxoList)
+
+	return p.saveAccount(&account)
+}
+
+// restoreRevertedTxs puts transactions of rolled back blocks back into mempool
+func (p *BlockChain) restoreRevertedTxs(reverted []*Block) {
+	// reverted is tip first, re-add in chain order so parents come before children
+	for i := len(reverted) - 1; i >= 0; i-- {
+		for _, tx := range reverted[i].Transactions {
+			if len(tx.Inputs) == 0 && tx.Evidence == nil {
+				continue // Coinbase belongs to the abandoned block
+			}
+			if _, err := p.GetTx(tx.ID); err == nil {
+				continue // Also included in the new main chain
+			}
+			if err := p.ValidateTransaction(tx); err != nil {
+				logger.Debug("[Reorg] Dropping reverted tx ", utils.HashToString(tx.ID)[:16], ": ", err)
+				continue
+			}
+			fee, err := p.CalculateTxFee(tx)
+			if err != nil {
+				continue
+			}
+			if err := p.Mempool.NewTransactionWithFee(tx, fee); err == nil {
+				logger.Debug("[Reorg] Reverted tx returned to mempool: ", utils.HashToString(tx.ID)[:16])
+			}
+		}
+	}
+}
+
+// hasBlockNoLock checks if block is stored on main or side chain
+func (p *BlockChain) hasBlockNoLock(hash prt.Hash) bool {
+	if ok, _ := p.db.Has(utils.GetBlockHashKey(hash), nil); ok {
+		return true
+	}
+	ok, _ := p.db.Has(utils.GetSideBlockKey(hash), nil)
+	return ok
+}
+
+// isMainChainNoLock checks if hash is the main chain block at height
+func (p *BlockChain) isMainChainNoLock(hash prt.Hash, height uint64) bool {
+	blkHashBytes, err := p.db.Get(utils.GetBlockHeightKey(height), nil)
+	if err != nil {
+		return false
+	}
+	var mainHash prt.Hash
+	copy(mainHash[:], blkHashBytes)
+	return mainHash == hash
+}
+
+// saveSideBlockNoLock stores block in side-chain storage
+func (p *BlockChain) saveSideBlockNoLock(blk *Block) error {
+	blkBytes, err := utils.SerializeData(blk, utils.SerializationFormatGob)
+	if err != nil {
+		return fmt.Errorf("failed to block serialization: %w", err)
+	}
+	if err := p.db.Put(utils.GetSideBlockKey(blk.Header.Hash), blkBytes, nil); err != nil {
+		return fmt.Errorf("failed to save side block: %w", err)
+	}
+	return nil
+}
+
+// getSideBlockNoLock loads side-chain block by hash
+func (p *BlockChain) getSideBlockNoLock(hash prt.Hash) (*Block, error) {
+	blkBytes, err := p.db.Get(utils.GetSideBlockKey(hash), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get side block: %w", err)
+	}
+	var blk Block
+	if err := utils.DeserializeData(blkBytes, &blk, utils.SerializationFormatGob); err != nil {
+		return nil, fmt.Errorf("failed to deserialize side block: %w", err)
+	}
+	return &blk, nil
+}
+
+// GetSideBlock returns side-chain (non-canonical) block by hash
+func (p *BlockChain) GetSideBlock(hash prt.Hash) (*Block, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.getSideBlockNoLock(hash)
+}
+
+// addOrphanNoLock keeps block until its parent arrives
+func (p *BlockChain) addOrphanNoLock(blk *Block) error {
+	if p.orphans == nil {
+		p.orphans = make(map[prt.Hash]*Block)
+	}
+	if len(p.orphans) >= MaxOrphanBlocks {
+		return fmt.Errorf("orphan pool full (%d blocks)", MaxOrphanBlocks)
+	}
+	p.orphans[blk.Header.Hash] = blk
+	return nil
+}
+
+// takeOrphansNoLock removes and returns orphans whose parent is parentHash
+func (p *BlockChain) takeOrphansNoLock(parentHash prt.Hash) []*Block {
+	var children []*Block
+	for hash, orphan := range p.orphans {
+		if orphan.Header.PrevHash == parentHash {
+			children = append(children, orphan)
+			delete(p.orphans, hash)
+		}
+	}
+	return children
+}
@@ -0,0 +1,459 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/abcfe/abcfe-node/common/logger"
+	"github.com/abcfe/abcfe-node/common/utils"
+	prt "github.com/abcfe/abcfe-node/protocol"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// Staking on chain:
+//   - Stake tx: outputs of TxTypeStaking paid back to the staker (the single key signing every input).
+//     Staked outputs are excluded from spendable balance.
+//   - Unstake tx: spends staked outputs; outputs must be restaked (TxTypeStaking) or unbonding
+//     (TxTypeUnStaking, RelativeLockHeight >= unbonding blocks), so funds return only after unbonding.
+//
+// Unspent staked outputs are indexed at commit (and reverted with the block undo record), so the stake of
+// every address as of the tip is derived from chain data only. Validator sets are taken from it at epoch boundaries (epoch.go).
+// Delegated outputs (TxTypeDelegate) follow the same rules and are indexed per validator (delegation.go).
+// Unbonding outputs are indexed per staker as well, so evidence slashes stake still unbonding (evidence.go).
+
+const (
+	DefaultUnbondingBlocks = 100 // Unbonding period when not configured
+	StakeIndexVersion      = "3" // Bump to rebuild the stake index on startup
+)
+
+// Stake staked outputs of an address
+type Stake struct {
+	Address   prt.Address
+	PublicKey []byte  // Key which signed the stake tx (validator identity)
+	Amount    uint64  // Sum of staked outputs
+	Height    uint64  // Earliest confirmation height of staked outputs
+	Utxos     []*UTXO // Staked outputs
+}
+
+// StakeSnapshot stakes of every address as of a main chain block
+type StakeSnapshot struct {
+	Height    uint64
+	BlockHash string
+	Stakes    []*Stake // Sorted by address
+}
+
+// GetUnbondingBlocks blocks unstaked funds stay locked after the unstake tx is committed
+func (p *BlockChain) GetUnbondingBlocks() uint64 {
+	return p.GetConsensusParams().UnbondingBlocks
+}
+
+// changeTxType script type of change output (staking / HTLC types only apply to the recipient outputs)
+func changeTxType(txType uint8) uint8 {
+	switch txType {
+	case TxTypeStaking, TxTypeUnStaking, TxTypeHTLC, TxTypeDelegate:
+		return TxTypeGeneral
+	}
+	return txType
+}
+
+// isBondedType checks if outputs of type are bonded (staked or delegated, spendable only by unstaking)
+func isBondedType(txType uint8) bool {
+	return txType == TxTypeStaking || txType == TxTypeDelegate
+}
+
+// validateStakingTx validates staking / delegation rules of tx spending inputUtxos
+func (p *BlockChain) validateStakingTx(tx *Transaction, inputUtxos []*UTXO) error {
+	var bondedIn, liquidIn, liquidOut uint64
+	staking := false
+	for _, utxo := range inputUtxos {
+		if isBondedType(utxo.TxOut.TxType) {
+			bondedIn += utxo.TxOut.Amount
+			staking = true
+		} else {
+			liquidIn += utxo.TxOut.Amount
+		}
+	}
+	for i, output := range tx.Outputs {
+		if output.Validator != nil && output.TxType != TxTypeDelegate {
+			return fmt.Errorf("output[%d]: validator on output of type %d", i, output.TxType)
+		}
+		switch output.TxType {
+		case TxTypeStaking, TxTypeUnStaking, TxTypeDelegate:
+			staking = true
+		default:
+			liquidOut += output.Amount
+		}
+	}
+	// Spendable outputs are paid only by spendable inputs and claimed rewards, never by bonded inputs
+	if bondedIn > 0 && liquidOut > liquidIn+tx.Claim {
+		return fmt.Errorf("staked outputs can only be restaked or unstaked")
+	}
+	if !staking {
+		return nil
+	}
+
+	// Every input must be signed by the staker key
+	staker, err := singleKeySigner(tx, inputUtxos)
+	if err != nil {
+		return fmt.Errorf("staking tx %w", err)
+	}
+
+	var unstakedOut uint64
+	for i, output := range tx.Outputs {
+		if output.TxType != TxTypeStaking && output.TxType != TxTypeUnStaking && output.TxType != TxTypeDelegate {
+			continue
+		}
+		if output.Address != staker {
+			return fmt.Errorf("output[%d]: staking output must be paid back to the staker", i)
+		}
+		if output.HTLC != nil || output.LockHeight > 0 || output.LockTime > 0 {
+			return fmt.Errorf("output[%d]: staking output cannot carry other locks", i)
+		}
+
+		switch output.TxType {
+		case TxTypeDelegate:
+			if output.Validator == nil || *output.Validator == (prt.Address{}) {
+				return fmt.Errorf("output[%d]: delegated output has no validator", i)
+			}
+			if *output.Validator == staker {
+				return fmt.Errorf("output[%d]: cannot delegate to own address (stake instead)", i)
+			}
+			fallthrough
+		case TxTypeStaking:
+			if output.RelativeLockHeight > 0 {
+				return fmt.Errorf("output[%d]: staked output cannot carry relative lock", i)
+			}
+			continue
+		}
+		if unbonding := p.GetUnbondingBlocks(); output.RelativeLockHeight < unbonding {
+			return fmt.Errorf("output[%d]: unstaked output must stay locked for %d blocks (unbonding)", i, unbonding)
+		}
+		unstakedOut += output.Amount
+	}
+	if unstakedOut > bondedIn {
+		return fmt.Errorf("unstaking %d exceeds staked inputs %d", unstakedOut, bondedIn)
+	}
+
+	return nil
+}
+
+// singleKeySigner address of the single key which must sign every input (staking / reward claim txs)
+func singleKeySigner(tx *Transaction, inputUtxos []*UTXO) (prt.Address, error) {
+	for i, input := range tx.Inputs {
+		if input.MultiSig != nil || inputUtxos[i].TxOut.HTLC != nil {
+			return prt.Address{}, fmt.Errorf("input[%d]: inputs must be owned by a single key", i)
+		}
+		if !bytes.Equal(input.PublicKey, tx.Inputs[0].PublicKey) {
+			return prt.Address{}, fmt.Errorf("input[%d]: inputs must be signed by the same key", i)
+		}
+	}
+	return TxSpender{PublicKey: tx.Inputs[0].PublicKey}.Address()
+}
+
+// isStakeIndexed checks if outputs of type are kept in the stake index (bonded or unbonding)
+func isStakeIndexed(txType uint8) bool {
+	return isBondedType(txType) || txType == TxTypeUnStaking
+}
+
+// stakeIndexKey index key of bonded / unbonding output (nil if the output is neither)
+func stakeIndexKey(utxo *UTXO) []byte {
+	output := &utxo.TxOut
+	switch {
+	case output.TxType == TxTypeStaking:
+		return utils.GetStakeKey(output.Address, utxo.TxId, int(utxo.OutputIndex))
+	case output.TxType == TxTypeUnStaking:
+		return utils.GetUnbondingKey(output.Address, utxo.TxId, int(utxo.OutputIndex))
+	case output.TxType == TxTypeDelegate && output.Validator != nil:
+		return utils.GetDelegationKey(*output.Validator, output.Address, utxo.TxId, int(utxo.OutputIndex))
+	}
+	return nil
+}
+
+// indexStakeOutput adds new staked / delegated / unbonding output of tx to stake index
+func indexStakeOutput(batch *leveldb.Batch, tx *Transaction, outputIndex int) {
+	output := tx.Outputs[outputIndex]
+	if !isStakeIndexed(output.TxType) || len(tx.Inputs) == 0 {
+		return // Genesis / coinbase outputs cannot stake (no staker key)
+	}
+	key := stakeIndexKey(&UTXO{TxId: tx.ID, OutputIndex: uint64(outputIndex), TxOut: *output})
+	if key == nil {
+		return
+	}
+	batch.Put(key, utils.GetUtxoKey(tx.ID, outputIndex))
+	if output.TxType == TxTypeStaking {
+		// Address is derived from the key, so the same key is recorded by every stake of the address
+		batch.Put(utils.GetStakerKeyKey(output.Address), tx.Inputs[0].PublicKey)
+	}
+}
+
+// unindexStakeOutput removes spent (or rolled back) staked / delegated / unbonding output from stake index
+func unindexStakeOutput(batch *leveldb.Batch, utxo *UTXO) {
+	if key := stakeIndexKey(utxo); key != nil {
+		batch.Delete(key)
+	}
+}
+
+// GetStakeSnapshot returns stakes of every address as of the current tip
+func (p *BlockChain) GetStakeSnapshot() (*StakeSnapshot, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	stakes, err := p.collectStakes([]byte(prt.PrefixStake))
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &StakeSnapshot{Height: p.LatestHeight, BlockHash: p.LatestBlockHash}
+	for _, stake := range stakes {
+		snapshot.Stakes = append(snapshot.Stakes, stake)
+	}
+	sort.Slice(snapshot.Stakes, func(i, j int) bool {
+		return bytes.Compare(snapshot.Stakes[i].Address[:], snapshot.Stakes[j].Address[:]) < 0
+	})
+	return snapshot, nil
+}
+
+// GetStake returns confirmed stake of address (nil if it has no staked outputs)
+func (p *BlockChain) GetStake(address prt.Address) (*Stake, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	stakes, err := p.collectStakes(utils.GetStakePrefix(address))
+	if err != nil {
+		return nil, err
+	}
+	return stakes[address], nil
+}
+
+// GetStakedBalance sum of confirmed staked outputs of address
+func (p *BlockChain) GetStakedBalance(address prt.Address) (uint64, error) {
+	stake, err := p.GetStake(address)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get staked balance: %w", err)
+	}
+	if stake == nil {
+		return 0, nil
+	}
+	return stake.Amount, nil
+}
+
+// collectStakes groups indexed staked outputs under prefix by owner
+func (p *BlockChain) collectStakes(prefix []byte) (map[prt.Address]*Stake, error) {
+	stakes := make(map[prt.Address]*Stake)
+	iter := p.db.NewIterator(util.BytesPrefix(prefix), nil)
+	defer iter.Release()
+
+	for iter.Next() {
+		utxo, err := p.loadUtxo(iter.Value())
+		if err != nil {
+			return nil, err
+		}
+
+		address := utxo.TxOut.Address
+		stake, exists := stakes[address]
+		if !exists {
+			publicKey, err := p.db.Get(utils.GetStakerKeyKey(address), nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get staker key of %s: %w", utils.AddressToString(address), err)
+			}
+			stake = &Stake{Address: address, PublicKey: publicKey, Height: utxo.Height}
+			stakes[address] = stake
+		}
+		stake.Amount += utxo.TxOut.Amount
+		if utxo.Height < stake.Height {
+			stake.Height = utxo.Height
+		}
+		stake.Utxos = append(stake.Utxos, utxo)
+	}
+	if err := iter.Error(); err != nil {
+		return nil, fmt.Errorf("failed to iterate stake index: %w", err)
+	}
+	return stakes, nil
+}
+
+// unbondingEnd height from which unbonding output can be spent
+func unbondingEnd(utxo *UTXO) uint64 {
+	end := utxo.Height + utxo.TxOut.RelativeLockHeight
+	if utxo.TxOut.LockHeight > end {
+		end = utxo.TxOut.LockHeight
+	}
+	return end
+}
+
+// collectUnbonding indexed unbonding outputs of address still locked in a block at height
+func (p *BlockChain) collectUnbonding(address prt.Address, height uint64) ([]*UTXO, error) {
+	iter := p.db.NewIterator(util.BytesPrefix(utils.GetUnbondingPrefix(address)), nil)
+	defer iter.Release()
+
+	var unbonding []*UTXO
+	for iter.Next() {
+		utxo, err := p.loadUtxo(iter.Value())
+		if err != nil {
+			return nil, err
+		}
+		if unbondingEnd(utxo) > height {
+			unbonding = append(unbonding, utxo)
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return nil, fmt.Errorf("failed to iterate unbonding index: %w", err)
+	}
+	return unbonding, nil
+}
+
+// loadUtxo reads UTXO by its db key
+func (p *BlockChain) loadUtxo(utxoKey []byte) (*UTXO, error) {
+	utxoBytes, err := p.db.Get(utxoKey, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get utxo data from db: %w", err)
+	}
+	var utxo UTXO
+	if err := utils.DeserializeData(utxoBytes, &utxo, utils.SerializationFormatGob); err != nil {
+		return nil, fmt.Errorf("failed to deserialize utxo data: %w", err)
+	}
+	return &utxo, nil
+}
+
+// BuildStakeTx creates signed tx staking amount of address (staked output goes back to the same address)
+func (p *BlockChain) BuildStakeTx(address prt.Address, amount uint64, fee uint64, privateKeyBytes, publicKeyBytes []byte) (*Transaction, *CoinSelection, error) {
+	if fee == 0 {
+		fee = p.GetMinFee()
+	}
+	recipients := []TxRecipient{{Address: address, Amount: amount}}
+	return p.BuildSignedTx(address, recipients, fee, "", nil, TxTypeStaking, TxBuildOptions{}, privateKeyBytes, publicKeyBytes)
+}
+
+// BuildUnstakeTx creates signed tx unstaking amount of address.
+// Fee is paid from the stake; unstaked funds are spendable after the unbonding period, the rest stays staked.
+func (p *BlockChain) BuildUnstakeTx(address prt.Address, amount uint64, fee uint64, privateKeyBytes, publicKeyBytes []byte) (*Transaction, error) {
+	stake, err := p.GetStake(address)
+	if err != nil {
+		return nil, err
+	}
+	if stake == nil {
+		return nil, fmt.Errorf("address %s has no stake", utils.AddressToString(address))
+	}
+	rest := TxOutput{Address: address, TxType: TxTypeStaking}
+	return p.buildUnbondTx(address, stake.Utxos, amount, fee, rest, privateKeyBytes, publicKeyBytes)
+}
+
+// buildUnbondTx creates signed tx unbonding amount (+ fee) from bonded outputs of address.
+// The rest is bonded again as an output like rest.
+func (p *BlockChain) buildUnbondTx(address prt.Address, utxos []*UTXO, amount uint64, fee uint64, rest TxOutput, privateKeyBytes, publicKeyBytes []byte) (*Transaction, error) {
+	if amount == 0 {
+		return nil, fmt.Errorf("unstake amount must be positive")
+	}
+	if fee == 0 {
+		fee = p.GetMinFee()
+	}
+	required, err := requiredWithFee(amount, fee)
+	if err != nil {
+		return nil, err
+	}
+
+	// Largest bonded outputs first, skipping ones a pending tx already spends
+	sort.Slice(utxos, func(i, j int) bool { return utxos[i].TxOut.Amount > utxos[j].TxOut.Amount })
+	var txIns []*TxInput
+	var total uint64
+	for _, utxo := range utxos {
+		if total >= required {
+			break
+		}
+		if p.isOnMempool(utxo.TxId, utxo.OutputIndex) {
+			continue
+		}
+		txIns = append(txIns, &TxInput{TxID: utxo.TxId, OutputIndex: utxo.OutputIndex, PublicKey: publicKeyBytes})
+		total += utxo.TxOut.Amount
+	}
+	if total < required {
+		return nil, fmt.Errorf("not enough stake: have %d, need %d (amount %d + fee %d)", total, required, amount, fee)
+	}
+
+	txOuts := []*TxOutput{{
+		Address:            address,
+		Amount:             amount,
+		TxType:             TxTypeUnStaking,
+		RelativeLockHeight: p.GetUnbondingBlocks(),
+	}}
+	if rest.Amount = total - required; rest.Amount > 0 {
+		txOuts = append(txOuts, &rest)
+	}
+
+	tx := &Transaction{
+		Version:   p.cfg.Version.Transaction,
+		NetworkID: p.cfg.Common.NetworkID,
+		Timestamp: time.Now().Unix(),
+		Inputs:    txIns,
+		Outputs:   txOuts,
+		Data:      []byte{},
+	}
+	if err := signTx(tx, privateKeyBytes); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// rebuildStakeIndex indexes unspent staked / delegated / unbonding outputs of all main chain blocks when index is missing or outdated
+func (p *BlockChain) rebuildStakeIndex() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	versionBytes, err := p.db.Get([]byte(prt.PrefixMetaStakeIx), nil)
+	if err != nil && err != leveldb.ErrNotFound {
+		return fmt.Errorf("failed to get stake index version: %w", err)
+	}
+	if string(versionBytes) == StakeIndexVersion {
+		return nil
+	}
+
+	if p.LatestBlockHash != "" {
+		logger.Info("[Staking] Rebuilding stake index up to height ", p.LatestHeight)
+
+		// Drop stale entries
+		batch := new(leveldb.Batch)
+		for _, prefix := range []string{prt.PrefixStake, prt.PrefixDelegation, prt.PrefixUnbonding} {
+			iter := p.db.NewIterator(util.BytesPrefix([]byte(prefix)), nil)
+			for iter.Next() {
+				batch.Delete(append([]byte{}, iter.Key()...))
+			}
+			iter.Release()
+			if err := iter.Error(); err != nil {
+				return fmt.Errorf("failed to iterate stake index: %w", err)
+			}
+		}
+
+		// Spent UTXOs stay in db flagged as spent, so only unspent outputs are indexed
+		for height := uint64(1); height <= p.LatestHeight; height++ {
+			blk, err := p.getBlockByHeightNoLock(height)
+			if err != nil {
+				return fmt.Errorf("failed to load block %d: %w", height, err)
+			}
+			for _, tx := range blk.Transactions {
+				for outputIndex, output := range tx.Outputs {
+					if !isStakeIndexed(output.TxType) {
+						continue
+					}
+					utxo, err := p.GetUtxoByTxIdAndIdx(tx.ID, uint64(outputIndex))
+					if err != nil {
+						return fmt.Errorf("failed to get staked output: %w", err)
+					}
+					if !utxo.Spent {
+						indexStakeOutput(batch, tx, outputIndex)
+					}
+				}
+			}
+		}
+
+		if err := p.db.Write(batch, nil); err != nil {
+			return fmt.Errorf("failed to write stake index: %w", err)
+		}
+	}
+
+	if err := p.db.Put([]byte(prt.PrefixMetaStakeIx), []byte(StakeIndexVersion), nil); err != nil {
+		return fmt.Errorf("failed to save stake index version: %w", err)
+	}
+	return nil
+}
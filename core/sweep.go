@@ -0,0 +1,180 @@
+package core
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/abcfe/abcfe-node/common/logger"
+	"github.com/abcfe/abcfe-node/common/utils"
+	prt "github.com/abcfe/abcfe-node/protocol"
+)
+
+const (
+	DefaultSweepInputsPerTx = 50  // Inputs consolidated by one sweep tx
+	DefaultSweepMaxTxs      = 10  // Sweep txs created by one call
+	MaxSweepInputsPerTx     = 200 // Keeps a sweep tx well below block size limit
+)
+
+// SweepOptions options of UTXO consolidation
+type SweepOptions struct {
+	To             prt.Address // Destination (zero address = back to the swept address)
+	MaxInputsPerTx int         // 0 = DefaultSweepInputsPerTx
+	MaxTxs         int         // 0 = DefaultSweepMaxTxs
+	MaxUtxoAmount  uint64      // Only sweep UTXOs worth at most this amount (0 = all)
+	Fee            uint64      // Fee per tx (0 = economy estimate for the tx size)
+}
+
+// SweepResult consolidation txs added to mempool
+type SweepResult struct {
+	Txs       []*Transaction
+	Swept     int    // UTXOs spent by the sweep txs
+	Amount    uint64 // Sum of sweep outputs
+	Fees      uint64 // Sum of sweep tx fees
+	Remaining int    // Sweepable UTXOs left for another call
+}
+
+// SweepUtxos consolidates confirmed UTXOs of from into one output per tx (smallest first) and adds the txs to mempool.
+// Batches which are not worth the fee, and single UTXO batches sent back to the same address, are skipped.
+func (p *BlockChain) SweepUtxos(from prt.Address, opts SweepOptions, privateKeyBytes, publicKeyBytes []byte) (*SweepResult, error) {
+	maxInputs := opts.MaxInputsPerTx
+	if maxInputs <= 0 {
+		maxInputs = DefaultSweepInputsPerTx
+	}
+	if maxInputs > MaxSweepInputsPerTx {
+		return nil, fmt.Errorf("too many inputs per tx: %d > max %d", maxInputs, MaxSweepInputsPerTx)
+	}
+	maxTxs := opts.MaxTxs
+	if maxTxs <= 0 {
+		maxTxs = DefaultSweepMaxTxs
+	}
+	to := opts.To
+	if to == (prt.Address{}) {
+		to = from
+	}
+
+	utxos, err := p.GetUtxoList(from, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get UTXO list: %w", err)
+	}
+
+	// Confirmed outputs only (unconfirmed ones would build long mempool chains)
+	var candidates []*UTXO
+	for _, utxo := range utxos {
+		if p.Mempool.GetTx(utxo.TxId) != nil {
+			continue
+		}
+		if opts.MaxUtxoAmount > 0 && utxo.TxOut.Amount > opts.MaxUtxoAmount {
+			continue
+		}
+		candidates = append(candidates, utxo)
+	}
+	candidates = sortUtxos(candidates, false)
+
+	result := &SweepResult{}
+	for start := 0; start < len(candidates); start += maxInputs {
+		if len(result.Txs) >= maxTxs {
+			result.Remaining = len(candidates) - start
+			break
+		}
+
+		end := start + maxInputs
+		if end > len(candidates) {
+			end = len(candidates)
+		}
+		batch := candidates[start:end]
+		if len(batch) == 1 && to == from {
+			break // Nothing to consolidate
+		}
+
+		tx, fee, err := p.buildSweepTx(batch, to, opts.Fee, privateKeyBytes, publicKeyBytes)
+		if err != nil {
+			return result, err
+		}
+		if tx == nil {
+			// Batches are sorted by amount, later ones are worth more
+			logger.Debug("[Sweep] batch of ", len(batch), " UTXOs not worth fee ", fee)
+			continue
+		}
+
+		if _, err := p.AddTxToMempool(tx); err != nil {
+			return result, fmt.Errorf("failed to add sweep tx to mempool: %w", err)
+		}
+
+		result.Txs = append(result.Txs, tx)
+		result.Swept += len(batch)
+		result.Amount += tx.Outputs[0].Amount
+		result.Fees += fee
+		logger.Info("[Sweep] ", utils.AddressToString(from), ": ", len(batch), " UTXOs -> ", tx.Outputs[0].Amount, " (fee ", fee, ") tx ", utils.HashToString(tx.ID)[:16])
+	}
+
+	return result, nil
+}
+
+// buildSweepTx creates signed tx spending every UTXO of batch into one output.
+// Returns nil tx (and the fee it would need) if the batch is not worth the fee.
+func (p *BlockChain) buildSweepTx(batch []*UTXO, to prt.Address, fee uint64, privateKeyBytes, publicKeyBytes []byte) (*Transaction, uint64, error) {
+	var total uint64
+	for _, utxo := range batch {
+		total += utxo.TxOut.Amount
+	}
+
+	// Resolve nil becoming empty slice after GOB deserialization
+	// Normalize nil to empty slice to maintain hash consistency
+	normalizedPublicKey := publicKeyBytes
+	if normalizedPublicKey == nil {
+		normalizedPublicKey = []byte{}
+	}
+
+	build := func(fee uint64) (*Transaction, error) {
+		txIns := make([]*TxInput, 0, len(batch))
+		for _, utxo := range batch {
+			txIns = append(txIns, &TxInput{
+				TxID:        utxo.TxId,
+				OutputIndex: utxo.OutputIndex,
+				PublicKey:   normalizedPublicKey,
+			})
+		}
+
+		tx := &Transaction{
+			Version:   p.cfg.Version.Transaction,
+			NetworkID: p.cfg.Common.NetworkID,
+			Timestamp: time.Now().Unix(),
+			Inputs:    txIns,
+			Outputs:   []*TxOutput{{Address: to, Amount: total - fee, TxType: TxTypeGeneral}},
+			Memo:      "",
+			Data:      []byte{},
+		}
+		if err := signTx(tx, privateKeyBytes); err != nil {
+			return nil, err
+		}
+		return tx, nil
+	}
+
+	minFee := p.GetMinFee()
+	if fee == 0 {
+		// Size of a signed sweep tx barely depends on the output amount
+		fee = minFee
+		if total <= fee {
+			return nil, fee, nil
+		}
+		sizing, err := build(fee)
+		if err != nil {
+			return nil, 0, err
+		}
+		rate := p.EstimateFees(TxSize(sizing)).Economy.FeeRate
+		fee = uint64(math.Ceil(rate * float64(TxSize(sizing))))
+	}
+	if fee < minFee {
+		fee = minFee
+	}
+	if total <= fee {
+		return nil, fee, nil
+	}
+
+	tx, err := build(fee)
+	if err != nil {
+		return nil, 0, err
+	}
+	return tx, fee, nil
+}
@@ -0,0 +1,144 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/abcfe/abcfe-node/common/utils"
+	prt "github.com/abcfe/abcfe-node/protocol"
+)
+
+const (
+	CoinbaseSizeReserve = 1024 // Block bytes kept free for the coinbase tx
+)
+
+// Reasons a mempool tx is not included in block template
+const (
+	TemplateSkipTxCount   = "block tx count limit reached"
+	TemplateSkipBlockSize = "block size limit reached"
+	TemplateSkipInvalid   = "invalid"
+)
+
+// TemplateTx tx entry of block template
+type TemplateTx struct {
+	TxID   prt.Hash
+	Fee    uint64
+	Size   uint64 // Serialized size in bytes
+	Reason string // Why tx was not included (skipped txs only)
+}
+
+// FeeRate fee per serialized byte
+func (t TemplateTx) FeeRate() float64 {
+	if t.Size == 0 {
+		return 0
+	}
+	return float64(t.Fee) / float64(t.Size)
+}
+
+// BlockTemplate txs selected from mempool for next block (coinbase not included)
+type BlockTemplate struct {
+	Height       uint64
+	Txs          []*Transaction // Selected txs in block order
+	Included     []TemplateTx
+	Skipped      []TemplateTx // Mempool txs left out and why
+	InvalidTxIds []prt.Hash   // Txs which failed validation (to be removed from mempool)
+	TotalFees    uint64
+	Size         uint64 // Sum of selected tx sizes
+	MaxSize      uint64
+	MaxTxs       int
+}
+
+// BuildBlockTemplate selects mempool txs for block at height (and timestamp, for timelocks) by fee rate and validates them.
+// Does not modify mempool.
+func (p *BlockChain) BuildBlockTemplate(height uint64, timestamp int64) *BlockTemplate {
+	template := &BlockTemplate{
+		Height:  height,
+		MaxSize: prt.MaxBlockSize - CoinbaseSizeReserve,
+		MaxTxs:  prt.MaxTxsPerBlock - 1, // Coinbase
+	}
+
+	candidates, skipped := p.Mempool.SelectTxs(template.MaxTxs, template.MaxSize)
+	template.Skipped = skipped
+
+	// Track UTXOs used in this block to prevent double-spending
+	usedUTXOs := make(map[string]bool)
+
+	// Outputs of txs already selected for this block (spendable by their mempool children)
+	pending := make(map[string]*UTXO)
+
+	// Rewards claimed by txs already selected (per claimant)
+	claims := make(map[prt.Address]uint64)
+
+	// Validators already slashed by selected evidence txs
+	jailChanges := make(map[prt.Address]bool)
+
+	for _, entry := range candidates {
+		tx := entry.Tx
+		skip := func(err error) {
+			template.InvalidTxIds = append(template.InvalidTxIds, tx.ID)
+			template.Skipped = append(template.Skipped, TemplateTx{
+				TxID:   tx.ID,
+				Fee:    entry.Fee,
+				Size:   entry.Size,
+				Reason: fmt.Sprintf("%s: %v", TemplateSkipInvalid, err),
+			})
+		}
+
+		if err := p.validateTransaction(tx, pending, false, spendContext{Height: height, Time: timestamp}); err != nil {
+			skip(err)
+			continue
+		}
+
+		// Check for duplicate UTXO usage within this block
+		var doubleSpend error
+		for _, input := range tx.Inputs {
+			utxoKey := fmt.Sprintf("%s:%d", utils.HashToString(input.TxID), input.OutputIndex)
+			if usedUTXOs[utxoKey] {
+				doubleSpend = fmt.Errorf("double-spend of UTXO %s", utxoKey)
+				break
+			}
+		}
+		if doubleSpend != nil {
+			skip(doubleSpend)
+			continue
+		}
+
+		// Calculate fee (mempool entry may not have it cached)
+		fee, err := p.CalculateTxFee(tx)
+		if err != nil {
+			skip(fmt.Errorf("failed to calculate fee: %w", err))
+			continue
+		}
+
+		if err := p.addBlockClaim(claims, tx); err != nil {
+			skip(err)
+			continue
+		}
+		if err := addBlockJailChange(jailChanges, tx); err != nil {
+			skip(err)
+			continue
+		}
+
+		// Mark UTXOs as used
+		for _, input := range tx.Inputs {
+			utxoKey := fmt.Sprintf("%s:%d", utils.HashToString(input.TxID), input.OutputIndex)
+			usedUTXOs[utxoKey] = true
+		}
+		addPendingOutputs(pending, tx, height)
+
+		template.Txs = append(template.Txs, tx)
+		template.Included = append(template.Included, TemplateTx{TxID: tx.ID, Fee: fee, Size: entry.Size})
+		template.TotalFees += fee
+		template.Size += entry.Size
+	}
+
+	sortTemplateTxs(template.Skipped)
+	return template
+}
+
+// sortTemplateTxs sorts by fee rate descending
+func sortTemplateTxs(txs []TemplateTx) {
+	sort.SliceStable(txs, func(i, j int) bool {
+		return txs[i].Fee*txs[j].Size > txs[j].Fee*txs[i].Size
+	})
+}
@@ -0,0 +1,103 @@
+package core
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/abcfe/abcfe-node/common/utils"
+	prt "github.com/abcfe/abcfe-node/protocol"
+)
+
+// spendContext block height and time at which inputs are spent
+type spendContext struct {
+	Height uint64
+	Time   int64
+}
+
+// nextSpendContext context of the next block (used for mempool txs and wallet balances)
+func (p *BlockChain) nextSpendContext() spendContext {
+	ctx := spendContext{Time: time.Now().Unix()}
+	if p.LatestBlockHash != "" {
+		ctx.Height = p.LatestHeight + 1
+	}
+	return ctx
+}
+
+// HasTimelock checks if output carries any lock condition
+func (p *TxOutput) HasTimelock() bool {
+	return p.LockHeight > 0 || p.LockTime > 0 || p.RelativeLockHeight > 0
+}
+
+// validateOutputLocks validates lock fields of new outputs
+func validateOutputLocks(tx *Transaction) error {
+	for i, output := range tx.Outputs {
+		if output.LockTime < 0 {
+			return fmt.Errorf("output[%d]: negative lock time %d", i, output.LockTime)
+		}
+		if err := validateHTLCOutput(output); err != nil {
+			return fmt.Errorf("output[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// checkInputLock returns error if input cannot spend utxo in a block at ctx (timelocks and HTLC expiry)
+func checkInputLock(input *TxInput, utxo *UTXO, ctx spendContext) error {
+	if err := checkUtxoLock(utxo, ctx); err != nil {
+		return err
+	}
+	if utxo.TxOut.HTLC != nil {
+		return checkHTLCExpiry(input, utxo, ctx)
+	}
+	return nil
+}
+
+// checkUtxoLock returns error if utxo cannot be spent in a block at ctx.
+//   - LockHeight: block height >= LockHeight
+//   - LockTime: block timestamp >= LockTime
+//   - RelativeLockHeight: block height >= confirmation height + RelativeLockHeight (unconfirmed outputs stay locked)
+func checkUtxoLock(utxo *UTXO, ctx spendContext) error {
+	output := &utxo.TxOut
+	if output.LockHeight > 0 && ctx.Height < output.LockHeight {
+		return fmt.Errorf("UTXO %s:%d locked until height %d (spending at %d)", utils.HashToString(utxo.TxId), utxo.OutputIndex, output.LockHeight, ctx.Height)
+	}
+	if output.LockTime > 0 && ctx.Time < output.LockTime {
+		return fmt.Errorf("UTXO %s:%d locked until time %d (spending at %d)", utils.HashToString(utxo.TxId), utxo.OutputIndex, output.LockTime, ctx.Time)
+	}
+	if output.RelativeLockHeight > 0 {
+		if utxo.Unconfirmed {
+			return fmt.Errorf("UTXO %s:%d has relative lock and is not confirmed yet", utils.HashToString(utxo.TxId), utxo.OutputIndex)
+		}
+		if unlockHeight := utxo.Height + output.RelativeLockHeight; ctx.Height < unlockHeight {
+			return fmt.Errorf("UTXO %s:%d locked until height %d (confirmed at %d + %d, spending at %d)",
+				utils.HashToString(utxo.TxId), utxo.OutputIndex, unlockHeight, utxo.Height, output.RelativeLockHeight, ctx.Height)
+		}
+	}
+	return nil
+}
+
+// GetLockedUtxoList gets confirmed unspent outputs of address which cannot be spent in the next block yet
+func (p *BlockChain) GetLockedUtxoList(address prt.Address) ([]*UTXO, error) {
+	utxos, err := p.getUnspentUtxos(address, false)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := p.nextSpendContext()
+	var locked []*UTXO
+	for _, utxo := range utxos {
+		if !isBondedType(utxo.TxOut.TxType) && checkUtxoLock(utxo, ctx) != nil {
+			locked = append(locked, utxo)
+		}
+	}
+	return locked, nil
+}
+
+// GetLockedBalance gets sum of timelocked outputs of address
+func (p *BlockChain) GetLockedBalance(address prt.Address) (uint64, error) {
+	locked, err := p.GetLockedUtxoList(address)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get locked balance: %w", err)
+	}
+	return p.CalBalanceUtxo(locked), nil
+}
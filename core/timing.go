@@ -0,0 +1,116 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/abcfe/abcfe-node/common/logger"
+	"github.com/abcfe/abcfe-node/config"
+)
+
+// Consensus timing:
+//   - Phase durations, block interval and round timeout come from [consensus] config (0 = default).
+//   - The round timeout grows every round of a height: roundTimeoutMs + roundTimeoutDeltaMs*round.
+//   - Timing other than the default is recorded in the data of the genesis tx. A node uses the timing of
+//     its genesis block over its own config, so every validator of a network runs the same phases.
+//     The consensus engine starts rounds only once the genesis block (and so the timing) is known.
+
+const (
+	DefaultBlockProduceMs      = 1000  // Block production check interval
+	DefaultBlockIntervalMs     = 1000  // Minimum interval between blocks
+	DefaultProposingMs         = 2000  // Proposing phase duration
+	DefaultVotingMs            = 3000  // Voting phase duration (votes are spread within it)
+	DefaultCommittingMs        = 2000  // Committing phase duration
+	DefaultRoundTimeoutMs      = 20000 // Timeout of round 0
+	DefaultRoundTimeoutDeltaMs = 0     // Timeout increase per round
+)
+
+// ConsensusTiming consensus phase durations (milliseconds)
+type ConsensusTiming struct {
+	BlockProduceMs      uint64 `json:"blockProduceMs"`
+	BlockIntervalMs     uint64 `json:"blockIntervalMs"`
+	ProposingMs         uint64 `json:"proposingMs"`
+	VotingMs            uint64 `json:"votingMs"`
+	CommittingMs        uint64 `json:"committingMs"`
+	RoundTimeoutMs      uint64 `json:"roundTimeoutMs"`
+	RoundTimeoutDeltaMs uint64 `json:"roundTimeoutDeltaMs"`
+}
+
+// DefaultConsensusTiming timing of a network not configuring any
+func DefaultConsensusTiming() ConsensusTiming {
+	return ConsensusTiming{
+		BlockProduceMs:      DefaultBlockProduceMs,
+		BlockIntervalMs:     DefaultBlockIntervalMs,
+		ProposingMs:         DefaultProposingMs,
+		VotingMs:            DefaultVotingMs,
+		CommittingMs:        DefaultCommittingMs,
+		RoundTimeoutMs:      DefaultRoundTimeoutMs,
+		RoundTimeoutDeltaMs: DefaultRoundTimeoutDeltaMs,
+	}
+}
+
+// ConfigConsensusTiming timing of [consensus] config (defaults for unset values)
+func ConfigConsensusTiming(cfg *config.Config) ConsensusTiming {
+	timing := DefaultConsensusTiming()
+	orDefault := func(value uint64, def *uint64) {
+		if value > 0 {
+			*def = value
+		}
+	}
+	orDefault(cfg.Consensus.BlockProduceMs, &timing.BlockProduceMs)
+	orDefault(cfg.Consensus.BlockIntervalMs, &timing.BlockIntervalMs)
+	orDefault(cfg.Consensus.ProposingMs, &timing.ProposingMs)
+	orDefault(cfg.Consensus.VotingMs, &timing.VotingMs)
+	orDefault(cfg.Consensus.CommittingMs, &timing.CommittingMs)
+	orDefault(cfg.Consensus.RoundTimeoutMs, &timing.RoundTimeoutMs)
+	orDefault(cfg.Consensus.RoundTimeoutDeltaMs, &timing.RoundTimeoutDeltaMs)
+	return timing
+}
+
+// Validate checks that a round fits proposing, two voting phases and committing
+func (t ConsensusTiming) Validate() error {
+	if t.BlockProduceMs == 0 || t.VotingMs == 0 || t.RoundTimeoutMs == 0 {
+		return fmt.Errorf("blockProduceMs, votingMs and roundTimeoutMs must be positive")
+	}
+	if phases := t.ProposingMs + 2*t.VotingMs + t.CommittingMs; t.RoundTimeoutMs <= phases {
+		return fmt.Errorf("roundTimeoutMs %d must exceed proposing + 2*voting + committing (%dms)", t.RoundTimeoutMs, phases)
+	}
+	return nil
+}
+
+// RoundTimeout timeout of round (base + delta*round)
+func (t ConsensusTiming) RoundTimeout(round uint32) time.Duration {
+	return time.Duration(t.RoundTimeoutMs+t.RoundTimeoutDeltaMs*uint64(round)) * time.Millisecond
+}
+
+// GetConsensusTiming timing of the network recorded in the genesis block (default if none recorded).
+// Returns an error until the genesis block is known: the timing never falls back to the config.
+func (p *BlockChain) GetConsensusTiming() (ConsensusTiming, error) {
+	p.timingMu.Lock()
+	defer p.timingMu.Unlock()
+
+	if p.timing != nil {
+		return *p.timing, nil
+	}
+
+	genesis, err := p.GetBlockByHeight(0)
+	if err != nil || len(genesis.Transactions) == 0 {
+		return ConsensusTiming{}, fmt.Errorf("genesis block is not known yet")
+	}
+
+	timing := DefaultConsensusTiming()
+	if data := genesis.Transactions[0].Data; len(data) > 0 {
+		if err := json.Unmarshal(data, &timing); err != nil {
+			return ConsensusTiming{}, fmt.Errorf("failed to unmarshal genesis timing: %w", err)
+		}
+	}
+	if err := timing.Validate(); err != nil {
+		return ConsensusTiming{}, fmt.Errorf("invalid genesis timing: %w", err)
+	}
+	if timing != ConfigConsensusTiming(p.cfg) {
+		logger.Warn("[Consensus] Config timing differs from the genesis block, using genesis timing: ", timing)
+	}
+	p.timing = &timing
+	return timing, nil
+}
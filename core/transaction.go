@@ -23,20 +23,47 @@ type Transaction struct {
 
 	Memo string `json:"memo"` // Transaction memo (replaces inputData)
 	Data []byte `json:"data"` // Arbitrary data (smart contract calls, etc.)
+
+	// Delegation rewards withdrawn by the signer, counted as input (see delegation.go)
+	Claim uint64 `json:"claim,omitempty"`
+
+	// Double-sign evidence; the tx slashes and jails the offender (see evidence.go)
+	Evidence *Evidence `json:"evidence,omitempty"`
+
+	// Releases the signer's validator jailed for downtime (see downtime.go)
+	Unjail bool `json:"unjail,omitempty"`
 }
 
 type TxInput struct {
-	TxID        prt.Hash      `json:"txId"`        // Referenced transaction ID
-	OutputIndex uint64        `json:"outputIndex"` // Referenced output index
-	Signature   prt.Signature `json:"signature"`   // Signature
-	PublicKey   []byte        `json:"publicKey"`   // Public key
-	// Sequence    uint64        `json:"sequence"`    // Sequence number (RBF support)
+	TxID        prt.Hash      `json:"txId"`               // Referenced transaction ID
+	OutputIndex uint64        `json:"outputIndex"`        // Referenced output index
+	Signature   prt.Signature `json:"signature"`          // Signature
+	PublicKey   []byte        `json:"publicKey"`          // Public key
+	Sequence    uint64        `json:"sequence,omitempty"` // Sequence number (RBF support: >= TxSequenceRBF opts in to replace-by-fee)
+
+	// Multisig inputs (PublicKey / Signature stay empty)
+	MultiSig   *MultiSigScript `json:"multiSig,omitempty"`   // m-of-n key set of the spent multisig address
+	Signatures []prt.Signature `json:"signatures,omitempty"` // One slot per MultiSig key (empty = not signed), not part of tx hash
+
+	// HTLC claim: secret whose sha256 matches the spent output's hash lock (empty for refund / other inputs)
+	Preimage []byte `json:"preimage,omitempty"`
 }
 
 type TxOutput struct {
 	Address prt.Address `json:"address"` // Receiver address
 	Amount  uint64      `json:"amount"`  // Amount (changed to uint64)
 	TxType  uint8       `json:"txType"`  // Script type (General/Staking/Etc)
+
+	// Timelocks (0 = none, every set condition must hold to spend)
+	LockHeight         uint64 `json:"lockHeight,omitempty"`         // Spendable from this block height
+	LockTime           int64  `json:"lockTime,omitempty"`           // Spendable from this block timestamp (unix seconds)
+	RelativeLockHeight uint64 `json:"relativeLockHeight,omitempty"` // Spendable this many blocks after confirmation
+
+	// Hash timelock contract (TxType must be TxTypeHTLC and Address the contract address)
+	HTLC *HashLock `json:"htlc,omitempty"`
+
+	// Validator the output is delegated to (TxType must be TxTypeDelegate, Address is the delegator)
+	Validator *prt.Address `json:"validator,omitempty"`
 }
 
 // Tx Input and Output pair
@@ -45,19 +72,108 @@ type TxIOPair struct {
 	TxOuts []*TxOutput `json:"txOuts"`
 }
 
+// MaxTxRecipients max number of recipient outputs in a transfer tx built by this node
+const MaxTxRecipients = 100
+
+// TxRecipient receiver address and amount of a transfer output
+type TxRecipient struct {
+	Address prt.Address `json:"address"`
+	Amount  uint64      `json:"amount"`
+
+	// Optional timelocks of the output (vesting / escrow)
+	LockHeight         uint64 `json:"lockHeight,omitempty"`
+	LockTime           int64  `json:"lockTime,omitempty"`
+	RelativeLockHeight uint64 `json:"relativeLockHeight,omitempty"`
+
+	// Optional hash timelock contract (Address is replaced by the contract address)
+	HTLC *HashLock `json:"htlc,omitempty"`
+
+	// Optional validator to delegate the output to (Address stays the delegator)
+	Validator *prt.Address `json:"validator,omitempty"`
+}
+
+// output converts recipient to tx output
+func (p TxRecipient) output(txType uint8) *TxOutput {
+	output := &TxOutput{
+		Address:            p.Address,
+		Amount:             p.Amount,
+		TxType:             txType,
+		LockHeight:         p.LockHeight,
+		LockTime:           p.LockTime,
+		RelativeLockHeight: p.RelativeLockHeight,
+	}
+	if p.HTLC != nil {
+		output.Address = p.HTLC.Address()
+		output.TxType = TxTypeHTLC
+		output.HTLC = p.HTLC
+	}
+	if p.Validator != nil {
+		output.TxType = TxTypeDelegate
+		output.Validator = p.Validator
+	}
+	return output
+}
+
+// sumRecipients validates recipient list and returns total amount sent
+func sumRecipients(recipients []TxRecipient) (uint64, error) {
+	if len(recipients) == 0 {
+		return 0, fmt.Errorf("no recipients")
+	}
+	if len(recipients) > MaxTxRecipients {
+		return 0, fmt.Errorf("too many recipients: %d > max %d", len(recipients), MaxTxRecipients)
+	}
+
+	var total uint64
+	for i, recipient := range recipients {
+		if recipient.Amount == 0 {
+			return 0, fmt.Errorf("recipient[%d]: amount must be positive", i)
+		}
+		if recipient.LockTime < 0 {
+			return 0, fmt.Errorf("recipient[%d]: negative lock time", i)
+		}
+		if total+recipient.Amount < total {
+			return 0, fmt.Errorf("recipient[%d]: total amount overflows", i)
+		}
+		total += recipient.Amount
+	}
+
+	return total, nil
+}
+
+// requiredWithFee total amount plus fee (with overflow check)
+func requiredWithFee(amount uint64, fee uint64) (uint64, error) {
+	if amount+fee < amount {
+		return 0, fmt.Errorf("amount %d + fee %d overflows", amount, fee)
+	}
+	return amount + fee, nil
+}
+
 func (p *BlockChain) SetTransferTx(from prt.Address, to prt.Address, amount uint64, fee uint64, memo string, data []byte, txType uint8) (*Transaction, error) {
+	return p.SetMultiTransferTx(from, []TxRecipient{{Address: to, Amount: amount}}, fee, memo, data, txType)
+}
+
+// SetMultiTransferTx builds unsigned tx paying every recipient (one output each) plus change to sender
+func (p *BlockChain) SetMultiTransferTx(from prt.Address, recipients []TxRecipient, fee uint64, memo string, data []byte, txType uint8) (*Transaction, error) {
+	amount, err := sumRecipients(recipients)
+	if err != nil {
+		return nil, err
+	}
+	requiredAmount, err := requiredWithFee(amount, fee)
+	if err != nil {
+		return nil, err
+	}
+
 	utxos, err := p.GetUtxoList(from, true)
 	if err != nil {
 		return nil, err
 	}
 
 	// Verify total required amount including fee
-	requiredAmount := amount + fee
 	if p.CalBalanceUtxo(utxos) < requiredAmount {
 		return &Transaction{}, fmt.Errorf("not enough balance: need %d (amount) + %d (fee) = %d", amount, fee, requiredAmount)
 	}
 
-	txInAndOut, err := p.setTxIOPair(utxos, from, to, amount, fee, txType)
+	txInAndOut, err := p.setTxIOPair(utxos, from, recipients, fee, txType, DefaultCoinSelection)
 	if err != nil {
 		return &Transaction{}, err
 	}
@@ -90,39 +206,40 @@ func (p *BlockChain) SetTransferTx(from prt.Address, to prt.Address, amount uint
 }
 
 // Configure tx input and output (including fee)
-func (p *BlockChain) setTxIOPair(utxos []*UTXO, from prt.Address, to prt.Address, amount uint64, fee uint64, txType uint8) (*TxIOPair, error) {
+// Outputs follow recipient order, change (if any) is always the last output
+func (p *BlockChain) setTxIOPair(utxos []*UTXO, from prt.Address, recipients []TxRecipient, fee uint64, txType uint8, coinSelection string) (*TxIOPair, error) {
 	var txInAndOut TxIOPair
-	var total uint64
+
+	amount, err := sumRecipients(recipients)
+	if err != nil {
+		return nil, err
+	}
 
 	// Total required amount including fee
-	requiredAmount := amount + fee
+	requiredAmount, err := requiredWithFee(amount, fee)
+	if err != nil {
+		return nil, err
+	}
 
 	// set tx in
-	for _, utxo := range utxos {
-		// Stop if amount including fee is satisfied
-		if total >= requiredAmount {
-			break
-		}
+	selection, err := SelectCoins(coinSelection, utxos, requiredAmount, p.GetMinFee())
+	if err != nil {
+		return nil, err
+	}
+	for _, utxo := range selection.Utxos {
 		// ! Pubkey needs pre-processing, signature needs post-processing
 		txIn := p.setTxInput(utxo.TxId, utxo.OutputIndex, prt.Signature{}, nil)
 		txInAndOut.TxIns = append(txInAndOut.TxIns, txIn)
-
-		total += utxo.TxOut.Amount
 	}
 
-	// Error if not enough funds even after using all UTXOs
-	if total < requiredAmount {
-		return nil, fmt.Errorf("not enough balance: required %d (amount %d + fee %d), have %d", requiredAmount, amount, fee, total)
+	// set tx out - Amount to each receiver
+	for _, recipient := range recipients {
+		txInAndOut.TxOuts = append(txInAndOut.TxOuts, recipient.output(txType))
 	}
 
-	// set tx out - Amount to receiver
-	txOut := p.setTxOutput(to, amount, txType)
-	txInAndOut.TxOuts = append(txInAndOut.TxOuts, txOut)
-
 	// Return change if needed (Fee is not included in Output = Implicit fee)
-	if total > requiredAmount {
-		changeOut := total - requiredAmount // total - amount - fee
-		txOut := p.setTxOutput(from, changeOut, txType)
+	if selection.Change > 0 {
+		txOut := p.setTxOutput(from, selection.Change, txType)
 		txInAndOut.TxOuts = append(txInAndOut.TxOuts, txOut)
 	}
 
@@ -276,20 +393,33 @@ func (p *BlockChain) GetOutputTxByIdx(txId prt.Hash, idx int) (*TxOutput, error)
 }
 
 func (p *BlockChain) SubmitTx(from, to prt.Address, amount uint64, fee uint64, memo string, data []byte, txType uint8) error {
+	return p.SubmitMultiTx(from, []TxRecipient{{Address: to, Amount: amount}}, fee, memo, data, txType)
+}
+
+// SubmitMultiTx builds transfer tx to every recipient and adds it to mempool
+func (p *BlockChain) SubmitMultiTx(from prt.Address, recipients []TxRecipient, fee uint64, memo string, data []byte, txType uint8) error {
+	amount, err := sumRecipients(recipients)
+	if err != nil {
+		return err
+	}
+	requiredAmount, err := requiredWithFee(amount, fee)
+	if err != nil {
+		return err
+	}
+
 	utxoList, err := p.GetUtxoList(from, true)
 	if err != nil {
 		return fmt.Errorf("failed to get balance: %w", err)
 	}
 
 	// utxo 기반으로 밸런스 체크 (수수료 포함)
-	requiredAmount := amount + fee
 	balance := p.CalBalanceUtxo(utxoList)
 	if balance < requiredAmount {
 		return fmt.Errorf("not enough balance: need %d (amount %d + fee %d), have %d", requiredAmount, amount, fee, balance)
 	}
 
 	// set transaction (수수료 포함)
-	tx, err := p.SetTransferTx(from, to, amount, fee, memo, data, txType)
+	tx, err := p.SetMultiTransferTx(from, recipients, fee, memo, data, txType)
 	if err != nil {
 		return fmt.Errorf("failed to set transaction: %w", err)
 	}
@@ -338,31 +468,65 @@ func buildMerkleTree(hashes []prt.Hash) prt.Hash {
 	return buildMerkleTree(nextLevel)
 }
 
+// TxBuildOptions options of a wallet signed transfer
+type TxBuildOptions struct {
+	Replaceable   bool   // Opt in to replace-by-fee
+	CoinSelection string // Coin selection strategy ("" = DefaultCoinSelection)
+}
+
 // CreateSignedTx creates signed transaction (includes fee)
 func (p *BlockChain) CreateSignedTx(from, to prt.Address, amount uint64, fee uint64, memo string, data []byte, txType uint8, privateKeyBytes, publicKeyBytes []byte) (*Transaction, error) {
-	utxos, err := p.GetUtxoList(from, true)
+	tx, _, err := p.BuildSignedTx(from, []TxRecipient{{Address: to, Amount: amount}}, fee, memo, data, txType, TxBuildOptions{}, privateKeyBytes, publicKeyBytes)
+	return tx, err
+}
+
+// CreateReplaceableSignedTx creates signed transaction which opts in to replace-by-fee
+func (p *BlockChain) CreateReplaceableSignedTx(from, to prt.Address, amount uint64, fee uint64, memo string, data []byte, txType uint8, privateKeyBytes, publicKeyBytes []byte) (*Transaction, error) {
+	tx, _, err := p.BuildSignedTx(from, []TxRecipient{{Address: to, Amount: amount}}, fee, memo, data, txType, TxBuildOptions{Replaceable: true}, privateKeyBytes, publicKeyBytes)
+	return tx, err
+}
+
+// CreateSignedMultiTx creates signed transaction paying every recipient in one tx (single fee, single change output)
+func (p *BlockChain) CreateSignedMultiTx(from prt.Address, recipients []TxRecipient, fee uint64, memo string, data []byte, txType uint8, privateKeyBytes, publicKeyBytes []byte) (*Transaction, error) {
+	tx, _, err := p.BuildSignedTx(from, recipients, fee, memo, data, txType, TxBuildOptions{}, privateKeyBytes, publicKeyBytes)
+	return tx, err
+}
+
+// CreateReplaceableSignedMultiTx creates signed multi recipient transaction which opts in to replace-by-fee
+func (p *BlockChain) CreateReplaceableSignedMultiTx(from prt.Address, recipients []TxRecipient, fee uint64, memo string, data []byte, txType uint8, privateKeyBytes, publicKeyBytes []byte) (*Transaction, error) {
+	tx, _, err := p.BuildSignedTx(from, recipients, fee, memo, data, txType, TxBuildOptions{Replaceable: true}, privateKeyBytes, publicKeyBytes)
+	return tx, err
+}
+
+// BuildSignedTx creates signed transfer tx and returns the coin selection used for its inputs.
+// Surplus up to MinFee is added to fee instead of creating a dust change output.
+func (p *BlockChain) BuildSignedTx(from prt.Address, recipients []TxRecipient, fee uint64, memo string, data []byte, txType uint8, opts TxBuildOptions, privateKeyBytes, publicKeyBytes []byte) (*Transaction, *CoinSelection, error) {
+	amount, err := sumRecipients(recipients)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Total required amount including fee
+	requiredAmount, err := requiredWithFee(amount, fee)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get UTXO list: %w", err)
+		return nil, nil, err
 	}
 
-	// Debug: Check UTXO list
-	logger.Debug("[CreateSignedTx] from: ", utils.AddressToString(from))
-	logger.Debug("[CreateSignedTx] UTXO count: ", len(utxos))
-	for i, utxo := range utxos {
-		logger.Debug("[CreateSignedTx] UTXO[", i, "]: txId=", utils.HashToString(utxo.TxId)[:16], " amount=", utxo.TxOut.Amount, " spent=", utxo.Spent)
+	utxos, err := p.GetUtxoList(from, true)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get UTXO list: %w", err)
 	}
 
-	// Total required amount including fee
-	requiredAmount := amount + fee
 	balance := p.CalBalanceUtxo(utxos)
 	logger.Debug("[CreateSignedTx] balance: ", balance, " requiredAmount: ", requiredAmount)
 	if balance < requiredAmount {
-		return nil, fmt.Errorf("not enough balance: have %d, need %d (amount %d + fee %d)", balance, requiredAmount, amount, fee)
+		return nil, nil, fmt.Errorf("not enough balance: have %d, need %d (amount %d + fee %d)", balance, requiredAmount, amount, fee)
 	}
 
-	// Configure TX Input/Output
-	var txIns []*TxInput
-	var total uint64
+	// Select inputs
+	selection, err := SelectCoins(opts.CoinSelection, utxos, requiredAmount, p.GetMinFee())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to select UTXOs: %w", err)
+	}
 
 	// Resolve nil becoming empty slice after GOB deserialization
 	// Normalize nil to empty slice to maintain hash consistency
@@ -371,39 +535,36 @@ func (p *BlockChain) CreateSignedTx(from, to prt.Address, amount uint64, fee uin
 		normalizedPublicKey = []byte{}
 	}
 
-	for _, utxo := range utxos {
-		// 수수료 포함한 금액을 충족하면 중단
-		if total >= requiredAmount {
-			break
-		}
+	var sequence uint64
+	if opts.Replaceable {
+		sequence = TxSequenceRBF
+	}
+
+	// Configure TX Input/Output
+	var txIns []*TxInput
+	for _, utxo := range selection.Utxos {
 		txIn := &TxInput{
 			TxID:        utxo.TxId,
 			OutputIndex: utxo.OutputIndex,
 			PublicKey:   normalizedPublicKey,
+			Sequence:    sequence,
 			// Signature set later
 		}
 		txIns = append(txIns, txIn)
-		total += utxo.TxOut.Amount
 	}
 
-	if total < requiredAmount {
-		return nil, fmt.Errorf("not enough balance after collecting UTXOs: have %d, need %d", total, requiredAmount)
-	}
-
-	// Configure TX Output - Amount to receiver
+	// Configure TX Output - Amount to each receiver (change goes last)
 	var txOuts []*TxOutput
-	txOuts = append(txOuts, &TxOutput{
-		Address: to,
-		Amount:  amount,
-		TxType:  txType,
-	})
+	for _, recipient := range recipients {
+		txOuts = append(txOuts, recipient.output(txType))
+	}
 
 	// Change (Fee is not included in Output = Implicit fee)
-	if total > requiredAmount {
+	if selection.Change > 0 {
 		txOuts = append(txOuts, &TxOutput{
 			Address: from,
-			Amount:  total - requiredAmount, // total - amount - fee
-			TxType:  txType,
+			Amount:  selection.Change, // total - amount - fee (- excess)
+			TxType:  changeTxType(txType),
 		})
 	}
 
@@ -429,25 +590,34 @@ func (p *BlockChain) CreateSignedTx(from, to prt.Address, amount uint64, fee uin
 		Data:      normalizedData,
 	}
 
+	if err := signTx(tx, privateKeyBytes); err != nil {
+		return nil, nil, err
+	}
+
+	return tx, selection, nil
+}
+
+// signTx calculates TX ID and signs every input with single key
+func signTx(tx *Transaction, privateKeyBytes []byte) error {
 	// Calculate TX ID (before signing)
 	tx.ID = utils.Hash(tx)
 
 	// Sign each input
 	privateKey, err := crypto.BytesToPrivateKey(privateKeyBytes)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse private key: %w", err)
+		return fmt.Errorf("failed to parse private key: %w", err)
 	}
 
 	txHashBytes := utils.HashToBytes(tx.ID)
 	for i := range tx.Inputs {
 		sig, err := crypto.SignData(privateKey, txHashBytes)
 		if err != nil {
-			return nil, fmt.Errorf("failed to sign input[%d]: %w", i, err)
+			return fmt.Errorf("failed to sign input[%d]: %w", i, err)
 		}
 		tx.Inputs[i].Signature = sig
 	}
 
-	return tx, nil
+	return nil
 }
 
 // ValidateTxSignatures validates all input signatures of transaction
@@ -456,6 +626,15 @@ func (p *BlockChain) ValidateTxSignatures(tx *Transaction) error {
 		return fmt.Errorf("transaction is nil")
 	}
 
+	// Evidence TX inputs carry no signatures: they are authorized by the evidence, which pins them
+	// (and the outputs) to the offender's staked outputs
+	if tx.Evidence != nil {
+		if err := p.validateEvidenceTx(tx, false, p.nextSpendContext()); err != nil {
+			return fmt.Errorf("unauthorized evidence tx: %w", err)
+		}
+		return nil
+	}
+
 	// Coinbase TX does not need signature verification
 	if len(tx.Inputs) == 0 {
 		return nil
@@ -466,6 +645,18 @@ func (p *BlockChain) ValidateTxSignatures(tx *Transaction) error {
 	txHashBytes := utils.HashToBytes(tx.ID)
 
 	for i, input := range tx.Inputs {
+		// Multisig input: threshold of key set signatures
+		if input.MultiSig != nil {
+			utxo, err := p.resolveInputUtxo(input, nil, true)
+			if err != nil {
+				return fmt.Errorf("input[%d]: failed to get referenced UTXO: %w", i, err)
+			}
+			if err := ValidateTxInputSignature(tx, input, utxo); err != nil {
+				return fmt.Errorf("input[%d]: %w", i, err)
+			}
+			continue
+		}
+
 		// Error if public key is empty
 		if len(input.PublicKey) == 0 {
 			return fmt.Errorf("input[%d]: public key is empty", i)
@@ -497,11 +688,19 @@ func (p *BlockChain) ValidateTxSignatures(tx *Transaction) error {
 		}
 
 		// Get referenced UTXO
-		utxo, err := p.GetUtxoByTxIdAndIdx(input.TxID, input.OutputIndex)
+		utxo, err := p.resolveInputUtxo(input, nil, true)
 		if err != nil {
 			return fmt.Errorf("input[%d]: failed to get referenced UTXO: %w", i, err)
 		}
 
+		// Hash timelock output: owned by its recipient / refund address instead of the contract address
+		if utxo.TxOut.HTLC != nil || len(input.Preimage) > 0 {
+			if err := ValidateTxInputSignature(tx, input, utxo); err != nil {
+				return fmt.Errorf("input[%d]: %w", i, err)
+			}
+			continue
+		}
+
 		// Compare UTXO owner and signer address
 		if signerAddr != utxo.TxOut.Address {
 			return fmt.Errorf("input[%d]: signer address does not match UTXO owner", i)
@@ -510,62 +709,3 @@ func (p *BlockChain) ValidateTxSignatures(tx *Transaction) error {
 
 	return nil
 }
-
-// AddressTxInfo represents transaction info for a specific address
-type AddressTxInfo struct {
-	TxID      prt.Hash `json:"txId"`
-	Type      string   `json:"type"`      // Always "received" (outputs only)
-	Amount    uint64   `json:"amount"`    // Amount received
-	Timestamp int64    `json:"timestamp"` // Transaction timestamp
-	Height    uint64   `json:"height"`    // Block height
-	Spent     bool     `json:"spent"`     // Whether the output is spent
-	Index     uint64   `json:"index"`     // Output index
-}
-
-// GetAddressTransactions returns all transactions related to an address
-func (p *BlockChain) GetAddressTransactions(address prt.Address) ([]*AddressTxInfo, error) {
-	p.mu.RLock()
-	latestHeight := p.LatestHeight
-	p.mu.RUnlock()
-
-	var txInfos []*AddressTxInfo
-
-	// Iterate through all blocks
-	for height := uint64(0); height <= latestHeight; height++ {
-		block, err := p.GetBlockByHeight(height)
-		if err != nil {
-			continue
-		}
-
-		// Check each transaction in the block
-		for _, tx := range block.Transactions {
-			// Check outputs (received transactions)
-			for idx, output := range tx.Outputs {
-				if output.Address == address {
-					// Check if this output is spent by checking UTXO status
-					isSpent := true // Default: assume spent
-					utxo, err := p.GetUtxoByTxIdAndIdx(tx.ID, uint64(idx))
-					if err == nil {
-						// UTXO exists in DB, check if it's actually spendable
-						isSpent = utxo.Spent
-					}
-
-					txInfos = append(txInfos, &AddressTxInfo{
-						TxID:      tx.ID,
-						Type:      "received",
-						Amount:    output.Amount,
-						Timestamp: tx.Timestamp,
-						Height:    height,
-						Spent:     isSpent,
-						Index:     uint64(idx),
-					})
-				}
-			}
-
-			// Note: Inputs (sent transactions) are not included to avoid duplication
-			// The spending of a UTXO is reflected in the "spent" field of the received transaction
-		}
-	}
-
-	return txInfos, nil
-}
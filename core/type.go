@@ -6,4 +6,6 @@ const (
 	TxTypeUnStaking
 	TxTypeCoinbase // Coinbase transaction (block reward + fee)
 	TxTypeEtc
+	TxTypeHTLC     // Hash timelock contract output (see HashLock)
+	TxTypeDelegate // Stake delegated to a validator (see TxOutput.Validator)
 )
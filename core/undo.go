@@ -0,0 +1,206 @@
+package core
+
+import (
+	"fmt"
+
+	"github.com/abcfe/abcfe-node/common/logger"
+	"github.com/abcfe/abcfe-node/common/utils"
+	prt "github.com/abcfe/abcfe-node/protocol"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// BlockUndo records every UTXO and reward balance change made by a block (written by UpdateUtxo)
+type BlockUndo struct {
+	BlockHash    prt.Hash
+	Height       uint64
+	SpentUtxos   []UTXO // UTXOs consumed by the block (state before spending)
+	CreatedUtxos []UTXO // Outputs created by the block
+
+	Rewards  []RewardBalance   // Reward balances before the block (addresses the block claimed from or credited)
+	Jailed   []prt.Address     // Validators jailed by the block (evidence txs, downtime)
+	Released []Jail            // Jail records released (unjail txs) or replaced by the block
+	Uptimes  []ValidatorUptime // Uptime records before the block (in change order)
+}
+
+// GetBlockUndo returns undo record of a main chain block
+func (p *BlockChain) GetBlockUndo(blockHash prt.Hash) (*BlockUndo, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	undoBytes, err := p.db.Get(utils.GetUtxoUndoKey(blockHash), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get undo record: %w", err)
+	}
+
+	var undo BlockUndo
+	if err := utils.DeserializeData(undoBytes, &undo, utils.SerializationFormatGob); err != nil {
+		return nil, fmt.Errorf("failed to deserialize undo record: %w", err)
+	}
+	return &undo, nil
+}
+
+// getBlockUndoNoLock loads undo record of block.
+// Blocks committed before undo records existed are reconstructed from the block itself
+// (spent UTXOs are only flagged, never deleted, so their data is still in db).
+func (p *BlockChain) getBlockUndoNoLock(blk *Block) (*BlockUndo, error) {
+	undoBytes, err := p.db.Get(utils.GetUtxoUndoKey(blk.Header.Hash), nil)
+	if err == nil {
+		var undo BlockUndo
+		if err := utils.DeserializeData(undoBytes, &undo, utils.SerializationFormatGob); err != nil {
+			return nil, fmt.Errorf("failed to deserialize undo record: %w", err)
+		}
+		return &undo, nil
+	}
+	if err != leveldb.ErrNotFound {
+		return nil, fmt.Errorf("failed to get undo record: %w", err)
+	}
+
+	// Legacy block without undo record
+	undo := &BlockUndo{
+		BlockHash: blk.Header.Hash,
+		Height:    blk.Header.Height,
+	}
+	for _, tx := range blk.Transactions {
+		for _, input := range tx.Inputs {
+			utxo, err := p.GetUtxoByTxIdAndIdx(input.TxID, input.OutputIndex)
+			if err != nil {
+				return nil, fmt.Errorf("failed to rebuild undo record: %w", err)
+			}
+			utxo.Spent = false
+			utxo.SpentHeight = 0
+			undo.SpentUtxos = append(undo.SpentUtxos, *utxo)
+		}
+		for outputIndex, output := range tx.Outputs {
+			undo.CreatedUtxos = append(undo.CreatedUtxos, UTXO{
+				TxId:        tx.ID,
+				OutputIndex: uint64(outputIndex),
+				TxOut:       *output,
+				Height:      blk.Header.Height,
+			})
+		}
+	}
+	return undo, nil
+}
+
+// applyBlockUndo reverts UTXO and reward balance changes of a block into batch and returns touched addresses
+func (p *BlockChain) applyBlockUndo(batch *leveldb.Batch, undo *BlockUndo) (map[prt.Address]bool, error) {
+	addrLists := make(map[prt.Address]AddrUTXOSet)
+	touched := make(map[prt.Address]bool)
+
+	loadList := func(address prt.Address) (AddrUTXOSet, error) {
+		if list, exists := addrLists[address]; exists {
+			return list, nil
+		}
+		list := make(AddrUTXOSet)
+		listBytes, err := p.db.Get(utils.GetUtxoListKey(address), nil)
+		if err == nil {
+			if err := utils.DeserializeData(listBytes, &list, utils.SerializationFormatGob); err != nil {
+				return nil, fmt.Errorf("failed to deserialize utxo list: %w", err)
+			}
+		} else if err != leveldb.ErrNotFound {
+			return nil, fmt.Errorf("failed to get utxo list: %w", err)
+		}
+		addrLists[address] = list
+		touched[address] = true
+		return list, nil
+	}
+
+	// 1. Restore spent UTXOs
+	for i := len(undo.SpentUtxos) - 1; i >= 0; i-- {
+		utxo := undo.SpentUtxos[i]
+		utxoKey := utils.GetUtxoKey(utxo.TxId, int(utxo.OutputIndex))
+
+		utxoBytes, err := utils.SerializeData(utxo, utils.SerializationFormatGob)
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialize utxo: %w", err)
+		}
+		batch.Put(utxoKey, utxoBytes)
+		if key := stakeIndexKey(&utxo); key != nil {
+			batch.Put(key, utxoKey)
+		}
+
+		list, err := loadList(utxo.TxOut.Address)
+		if err != nil {
+			return nil, err
+		}
+		list[string(utxoKey)] = true
+	}
+
+	// 2. Delete created outputs
+	// Applied after restore so that an output created and spent in the same block ends up deleted
+	for i := len(undo.CreatedUtxos) - 1; i >= 0; i-- {
+		utxo := undo.CreatedUtxos[i]
+		utxoKey := utils.GetUtxoKey(utxo.TxId, int(utxo.OutputIndex))
+		batch.Delete(utxoKey)
+		unindexStakeOutput(batch, &utxo)
+
+		list, err := loadList(utxo.TxOut.Address)
+		if err != nil {
+			return nil, err
+		}
+		delete(list, string(utxoKey))
+	}
+
+	// 3. Restore reward balances
+	if err := revertRewards(batch, undo); err != nil {
+		return nil, err
+	}
+
+	// 4. Jail and uptime records
+	if err := revertJails(batch, undo); err != nil {
+		return nil, err
+	}
+	if err := revertUptimes(batch, undo); err != nil {
+		return nil, err
+	}
+
+	// 5. Save address UTXO lists
+	for address, list := range addrLists {
+		listBytes, err := utils.SerializeData(list, utils.SerializationFormatGob)
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialize utxo list: %w", err)
+		}
+		batch.Put(utils.GetUtxoListKey(address), listBytes)
+	}
+
+	batch.Delete(utils.GetUtxoUndoKey(undo.BlockHash))
+
+	return touched, nil
+}
+
+// RollbackToHeight rolls main chain back to height by applying undo records of the blocks above it (tip first).
+// Rolled back blocks are dropped (not kept as side-chain blocks) so that sync can import them again,
+// and their transactions are returned to mempool.
+func (p *BlockChain) RollbackToHeight(height uint64) error {
+	p.mu.Lock()
+
+	if p.LatestBlockHash == "" {
+		p.mu.Unlock()
+		return fmt.Errorf("no blocks in the chain yet")
+	}
+	if height >= p.LatestHeight {
+		p.mu.Unlock()
+		return fmt.Errorf("target height %d is not below current height %d", height, p.LatestHeight)
+	}
+
+	var reverted []*Block
+	for p.LatestHeight > height {
+		blk, err := p.getBlockByHeightNoLock(p.LatestHeight)
+		if err != nil {
+			p.mu.Unlock()
+			return fmt.Errorf("failed to load block %d: %w", p.LatestHeight, err)
+		}
+		if err := p.disconnectBlockNoLock(blk, false); err != nil {
+			p.mu.Unlock()
+			return fmt.Errorf("failed to roll back block %d: %w", blk.Header.Height, err)
+		}
+		reverted = append(reverted, blk)
+		logger.Info("[Rollback] Block rolled back: height=", blk.Header.Height, " hash=", utils.HashToString(blk.Header.Hash)[:16])
+	}
+
+	p.mu.Unlock()
+
+	p.restoreRevertedTxs(reverted)
+
+	return nil
+}
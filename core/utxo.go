@@ -17,23 +17,62 @@ type UTXO struct {
 	Height      uint64
 	Spent       bool // true : spent
 	SpentHeight uint64
+	Unconfirmed bool // Output of a mempool tx (not stored)
 }
 
 // Update UTXO
+// Every change (and reward balance change) is also recorded in the block undo record so that the block can be rolled back.
+// Txs are applied in block order, so a tx may spend outputs created earlier in the same block.
 func (p *BlockChain) UpdateUtxo(batch *leveldb.Batch, blk Block) error {
+	undo := &BlockUndo{
+		BlockHash: blk.Header.Hash,
+		Height:    blk.Header.Height,
+	}
+
+	// Load UTXO list per address once => prevent overwriting
+	addressUTXOMap := make(map[prt.Address]AddrUTXOSet)
+	loadUtxoList := func(address prt.Address) (AddrUTXOSet, error) {
+		if utxoList, exists := addressUTXOMap[address]; exists {
+			return utxoList, nil
+		}
+
+		utxoList := make(AddrUTXOSet)
+		utxoListBytes, err := p.db.Get(utils.GetUtxoListKey(address), nil)
+		if err == nil {
+			if err := utils.DeserializeData(utxoListBytes, &utxoList, utils.SerializationFormatGob); err != nil {
+				return nil, fmt.Errorf("failed to deserialize utxo list: %w", err)
+			}
+		} else if err != leveldb.ErrNotFound {
+			return nil, fmt.Errorf("failed to get utxo list: %w", err)
+		}
+		addressUTXOMap[address] = utxoList
+		return utxoList, nil
+	}
+
+	// UTXOs created by this block (not in db yet)
+	created := make(map[string]*UTXO)
+
+	// Fees of the block txs (input + claim - output), split with the block reward
+	var fees uint64
+
 	for _, tx := range blk.Transactions {
+		var txIn, txOut uint64
 		if blk.Header.Height > 0 { // Genesis Block processes only output
 			for _, input := range tx.Inputs {
 				// 1. Mark input UTXO as spent
 				utxoKey := utils.GetUtxoKey(input.TxID, int(input.OutputIndex))
-				utxoBytes, err := p.db.Get(utxoKey, nil)
-				if err != nil {
-					return fmt.Errorf("failed to get utxo data from db: %w", err)
-				}
 
 				var utxo UTXO
-				if err := utils.DeserializeData(utxoBytes, &utxo, utils.SerializationFormatGob); err != nil {
-					return fmt.Errorf("failed to deserialize utxo data: %w", err)
+				if blockUtxo, exists := created[string(utxoKey)]; exists {
+					utxo = *blockUtxo
+				} else {
+					utxoBytes, err := p.db.Get(utxoKey, nil)
+					if err != nil {
+						return fmt.Errorf("failed to get utxo data from db: %w", err)
+					}
+					if err := utils.DeserializeData(utxoBytes, &utxo, utils.SerializationFormatGob); err != nil {
+						return fmt.Errorf("failed to deserialize utxo data: %w", err)
+					}
 				}
 
 				// Verify if UTXO is already spent
@@ -41,8 +80,16 @@ func (p *BlockChain) UpdateUtxo(batch *leveldb.Batch, blk Block) error {
 					return fmt.Errorf("UTXO already spent: %s:%d", utils.HashToString(input.TxID), input.OutputIndex)
 				}
 
+				// Record state before spending
+				undo.SpentUtxos = append(undo.SpentUtxos, utxo)
+				txIn += utxo.TxOut.Amount
+				unindexStakeOutput(batch, &utxo)
+
 				utxo.Spent = true                    // Mark UTXO as spent
 				utxo.SpentHeight = blk.Header.Height // Height of block where spent
+				if _, exists := created[string(utxoKey)]; exists {
+					created[string(utxoKey)] = &utxo
+				}
 
 				utxoUpdBytes, err := utils.SerializeData(utxo, utils.SerializationFormatGob)
 				if err != nil {
@@ -52,51 +99,21 @@ func (p *BlockChain) UpdateUtxo(batch *leveldb.Batch, blk Block) error {
 				batch.Put(utxoKey, utxoUpdBytes)
 
 				// 2. Remove UTXO from address UTXO list
-				utxoListKey := utils.GetUtxoListKey(utxo.TxOut.Address)
-				utxoListBytes, err := p.db.Get(utxoListKey, nil)
+				utxoList, err := loadUtxoList(utxo.TxOut.Address)
 				if err != nil {
-					return fmt.Errorf("failed to get utxo data from db: %w", err)
-				}
-
-				var utxoList AddrUTXOSet
-				if err := utils.DeserializeData(utxoListBytes, &utxoList, utils.SerializationFormatGob); err != nil {
-					return fmt.Errorf("failed to deserialize utxo list: %w", err)
+					return err
 				}
 
 				// Remove from Map. O(1)
 				delete(utxoList, string(utxoKey))
-
-				updatedListBytes, err := utils.SerializeData(utxoList, utils.SerializationFormatGob)
-				if err != nil {
-					return fmt.Errorf("failed to serialize utxo list: %w", err)
-				}
-				batch.Put(utxoListKey, updatedListBytes)
 			}
 		}
-	}
 
-	// 2. Process OUTPUT
-	// Load UTXO list per address once => prevent overwriting
-	addressUTXOMap := make(map[prt.Address]AddrUTXOSet)
-
-	for _, tx := range blk.Transactions {
+		// 3. Process OUTPUT
 		for outputIndex, output := range tx.Outputs {
-			// Check if address UTXO list is already loaded
-			utxoList, exists := addressUTXOMap[output.Address]
-			if !exists {
-				// Load from DB if first time address
-				utxoListKey := utils.GetUtxoListKey(output.Address)
-				utxoListBytes, err := p.db.Get(utxoListKey, nil)
-				if err == nil {
-					if err := utils.DeserializeData(utxoListBytes, &utxoList, utils.SerializationFormatGob); err != nil {
-						return fmt.Errorf("failed to deserialize utxo list: %w", err)
-					}
-				} else if err != leveldb.ErrNotFound {
-					return fmt.Errorf("failed to get utxo list: %w", err)
-				} else {
-					utxoList = make(AddrUTXOSet)
-				}
-				addressUTXOMap[output.Address] = utxoList
+			utxoList, err := loadUtxoList(output.Address)
+			if err != nil {
+				return err
 			}
 
 			// Create and save UTXO
@@ -115,13 +132,36 @@ func (p *BlockChain) UpdateUtxo(batch *leveldb.Batch, blk Block) error {
 				return fmt.Errorf("failed to serialize utxo: %w", err)
 			}
 			batch.Put(utxoKey, utxoBytes)
+			undo.CreatedUtxos = append(undo.CreatedUtxos, newUtxo)
+			created[string(utxoKey)] = &newUtxo
+			indexStakeOutput(batch, tx, outputIndex)
 
 			// Add to memory list
 			utxoList[string(utxoKey)] = true
+			txOut += output.Amount
 		}
+		// Slashed stake of evidence txs is burnt, not paid as fee
+		if len(tx.Inputs) > 0 && tx.Evidence == nil && txIn+tx.Claim >= txOut {
+			fees += txIn + tx.Claim - txOut
+		}
+	}
+
+	// Reward claims and delegators' shares of the block reward
+	if err := p.applyRewards(batch, &blk, fees, undo); err != nil {
+		return err
+	}
+
+	// Jail offenders of evidence txs, release validators of unjail txs
+	if err := p.applyJails(batch, &blk, undo); err != nil {
+		return err
+	}
+
+	// Track signers of the parent block, jail validators offline for too long
+	if err := p.applyUptime(batch, &blk, undo); err != nil {
+		return err
 	}
 
-	// 3. Save all changes at once
+	// 4. Save all changes at once
 	for address, utxoList := range addressUTXOMap {
 		utxoListKey := utils.GetUtxoListKey(address)
 		updatedListBytes, err := utils.SerializeData(utxoList, utils.SerializationFormatGob)
@@ -131,20 +171,52 @@ func (p *BlockChain) UpdateUtxo(batch *leveldb.Batch, blk Block) error {
 		batch.Put(utxoListKey, updatedListBytes)
 	}
 
+	// 5. Save undo record
+	undoBytes, err := utils.SerializeData(undo, utils.SerializationFormatGob)
+	if err != nil {
+		return fmt.Errorf("failed to serialize undo record: %w", err)
+	}
+	batch.Put(utils.GetUtxoUndoKey(blk.Header.Hash), undoBytes)
+
 	return nil
 }
 
-// Final balance should include funds used in mempool.
+// GetUtxoList gets spendable UTXOs of address (timelocked, staked and delegated outputs are excluded).
+// With mempoolCheck, outputs spent by mempool txs are excluded and unspent outputs of
+// unconfirmed mempool txs (e.g. change) are included, so they can be spent right away.
 func (p *BlockChain) GetUtxoList(address prt.Address, mempoolCheck bool) ([]*UTXO, error) {
+	utxos, err := p.getUnspentUtxos(address, mempoolCheck)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := p.nextSpendContext()
+	result := utxos[:0]
+	for _, utxo := range utxos {
+		if isBondedType(utxo.TxOut.TxType) {
+			continue // Staked / delegated (spendable only by unstaking)
+		}
+		if checkUtxoLock(utxo, ctx) == nil {
+			result = append(result, utxo)
+		}
+	}
+	return result, nil
+}
+
+// getUnspentUtxos gets unspent UTXOs of address including timelocked ones
+func (p *BlockChain) getUnspentUtxos(address prt.Address, mempoolCheck bool) ([]*UTXO, error) {
 	utxoListKey := utils.GetUtxoListKey(address)
 	utxoListBytes, err := p.db.Get(utxoListKey, nil)
-	if err != nil {
+	if err != nil && !(mempoolCheck && err == leveldb.ErrNotFound) {
 		return nil, fmt.Errorf("failed to get utxo data from db: %w", err)
 	}
 
-	var utxoList AddrUTXOSet
-	if err := utils.DeserializeData(utxoListBytes, &utxoList, utils.SerializationFormatGob); err != nil {
-		return nil, fmt.Errorf("failed to deserialize utxo list: %w", err)
+	// Address without confirmed outputs may still spend unconfirmed ones
+	utxoList := make(AddrUTXOSet)
+	if err == nil {
+		if err := utils.DeserializeData(utxoListBytes, &utxoList, utils.SerializationFormatGob); err != nil {
+			return nil, fmt.Errorf("failed to deserialize utxo list: %w", err)
+		}
 	}
 
 	var result []*UTXO
@@ -173,6 +245,10 @@ func (p *BlockChain) GetUtxoList(address prt.Address, mempoolCheck bool) ([]*UTX
 		result = append(result, &utxo)
 	}
 
+	if mempoolCheck {
+		result = append(result, p.Mempool.GetUnspentOutputs(address)...)
+	}
+
 	return result, nil
 }
 
@@ -201,3 +277,50 @@ func (p *BlockChain) GetUtxoByTxIdAndIdx(txId prt.Hash, outputIndex uint64) (*UT
 
 	return &utxo, nil
 }
+
+// resolveInputUtxo resolves UTXO referenced by input.
+// Lookup order: outputs created earlier in the same block (pending), committed UTXOs,
+// then outputs of unconfirmed mempool txs (only when useMempool is set).
+func (p *BlockChain) resolveInputUtxo(input *TxInput, pending map[string]*UTXO, useMempool bool) (*UTXO, error) {
+	utxoKey := utils.GetUtxoKey(input.TxID, int(input.OutputIndex))
+	if utxo, exists := pending[string(utxoKey)]; exists {
+		return utxo, nil
+	}
+
+	utxoBytes, err := p.db.Get(utxoKey, nil)
+	if err == nil {
+		var utxo UTXO
+		if err := utils.DeserializeData(utxoBytes, &utxo, utils.SerializationFormatGob); err != nil {
+			return nil, fmt.Errorf("failed to deserialize UTXO: %w", err)
+		}
+		return &utxo, nil
+	}
+	if err != leveldb.ErrNotFound {
+		return nil, fmt.Errorf("failed to get utxo data from db: %w", err)
+	}
+
+	if useMempool {
+		if output := p.Mempool.GetOutput(input.TxID, input.OutputIndex); output != nil {
+			return &UTXO{
+				TxId:        input.TxID,
+				OutputIndex: input.OutputIndex,
+				TxOut:       *output,
+				Unconfirmed: true,
+			}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("UTXO not found: %s:%d", utils.HashToString(input.TxID), input.OutputIndex)
+}
+
+// addPendingOutputs registers outputs of tx so that later txs of the same block can spend them
+func addPendingOutputs(pending map[string]*UTXO, tx *Transaction, height uint64) {
+	for outputIndex, output := range tx.Outputs {
+		pending[string(utils.GetUtxoKey(tx.ID, outputIndex))] = &UTXO{
+			TxId:        tx.ID,
+			OutputIndex: uint64(outputIndex),
+			TxOut:       *output,
+			Height:      height,
+		}
+	}
+}
@@ -88,6 +88,18 @@ func ValidateTxCount(txs []*Transaction) error {
 	return nil
 }
 
+// ValidateBlockSize validates sum of serialized transaction sizes
+func ValidateBlockSize(txs []*Transaction) error {
+	var size uint64
+	for _, tx := range txs {
+		size += TxSize(tx)
+	}
+	if size > prt.MaxBlockSize {
+		return fmt.Errorf("block too large: max=%d bytes, got=%d bytes", prt.MaxBlockSize, size)
+	}
+	return nil
+}
+
 // ValidateDuplicateTx validates duplicate transactions
 func ValidateDuplicateTx(txs []*Transaction) error {
 	seen := make(map[prt.Hash]bool)
@@ -124,21 +136,21 @@ func ValidateProposer(block *Block) error {
 	return nil
 }
 
-// ValidateProposerSignature validates proposer signature (signature on block hash)
-func ValidateProposerSignature(block *Block, validator ProposerValidator) error {
+// ValidateProposerSignature validates proposer signature (signature on block hash) against validator set of epoch
+func ValidateProposerSignature(block *Block, epoch *Epoch, validator ProposerValidator) error {
 	if validator == nil {
 		// Skip if validator is not set (e.g., solo node)
 		return nil
 	}
 
 	// Check if proposer is a valid validator
-	if !validator.IsValidProposer(block.Proposer, block.Header.Height) {
+	if !validator.IsValidProposer(epoch, block.Proposer, block.Header.Height) {
 		return fmt.Errorf("proposer %s is not a valid validator for height %d",
 			utils.AddressToString(block.Proposer), block.Header.Height)
 	}
 
 	// Verify signature
-	if !validator.ValidateProposerSignature(block.Proposer, block.Header.Hash, block.Signature) {
+	if !validator.ValidateProposerSignature(epoch, block.Proposer, block.Header.Hash, block.Signature) {
 		return fmt.Errorf("invalid proposer signature for block %s",
 			utils.HashToString(block.Header.Hash))
 	}
@@ -151,6 +163,11 @@ func (p *BlockChain) ValidateBlock(block Block, checkCommit bool) error {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 
+	return p.validateBlockNoLock(block, checkCommit)
+}
+
+// validateBlockNoLock validates block without lock (internal use)
+func (p *BlockChain) validateBlockNoLock(block Block, checkCommit bool) error {
 	// Genesis block is validated separately
 	if block.Header.Height == 0 {
 		return p.validateGenesisBlock(&block)
@@ -192,23 +209,37 @@ func (p *BlockChain) ValidateBlock(block Block, checkCommit bool) error {
 		return err
 	}
 
-	// 7. Validate proposer signature (PoA)
-	if err := ValidateProposerSignature(&block, p.proposerValidator); err != nil {
+	// 7. Validate proposer signature (PoA) against the validator set of the block's epoch
+	epoch, err := p.getEpochNoLock(block.Header.Height)
+	if err != nil {
+		return fmt.Errorf("failed to get validator set: %w", err)
+	}
+	if err := ValidateProposerSignature(&block, epoch, p.proposerValidator); err != nil {
 		return err
 	}
 
 	// 8. Validate BFT commit signatures (2/3+ majority) - conditionally
 	if checkCommit && p.proposerValidator != nil {
-		if err := p.proposerValidator.ValidateCommitSignatures(block.Header.Hash, block.CommitSignatures); err != nil {
+		if err := p.proposerValidator.ValidateCommitSignatures(epoch, block.Header.Height, block.Header.Hash, block.CommitSignatures); err != nil {
 			return fmt.Errorf("BFT consensus validation failed: %w", err)
 		}
 	}
 
+	// 8-1. Validate commit signatures of the parent (downtime tracking)
+	if err := p.validateLastCommit(&block); err != nil {
+		return err
+	}
+
 	// 9. Validate transaction count
 	if err := ValidateTxCount(block.Transactions); err != nil {
 		return err
 	}
 
+	// 9-1. Validate block size
+	if err := ValidateBlockSize(block.Transactions); err != nil {
+		return err
+	}
+
 	// 10. Validate duplicate transactions
 	if err := ValidateDuplicateTx(block.Transactions); err != nil {
 		return err
@@ -219,11 +250,32 @@ func (p *BlockChain) ValidateBlock(block Block, checkCommit bool) error {
 		return err
 	}
 
-	// 12. Validate each transaction
+	// 12. Validate each transaction (in order, a tx may spend outputs of earlier txs in the block)
+	pending := make(map[string]*UTXO)
+	claims := make(map[prt.Address]uint64)
+	jailChanges := make(map[prt.Address]bool)
+	var fees uint64
 	for _, tx := range block.Transactions {
-		if err := p.ValidateTransaction(tx); err != nil {
+		if err := p.validateTransaction(tx, pending, false, spendContext{Height: block.Header.Height, Time: block.Header.Timestamp}); err != nil {
+			return fmt.Errorf("invalid transaction %s: %w", utils.HashToString(tx.ID), err)
+		}
+		if err := p.addBlockClaim(claims, tx); err != nil {
+			return fmt.Errorf("invalid transaction %s: %w", utils.HashToString(tx.ID), err)
+		}
+		if err := addBlockJailChange(jailChanges, tx); err != nil {
+			return fmt.Errorf("invalid transaction %s: %w", utils.HashToString(tx.ID), err)
+		}
+		fee, err := p.blockTxFee(tx, pending)
+		if err != nil {
 			return fmt.Errorf("invalid transaction %s: %w", utils.HashToString(tx.ID), err)
 		}
+		fees += fee
+		addPendingOutputs(pending, tx, block.Header.Height)
+	}
+
+	// 13. Validate coinbase amount (delegators' shares are credited at commit, not paid by the coinbase)
+	if err := p.validateCoinbaseReward(&block, epoch, fees); err != nil {
+		return err
 	}
 
 	return nil
@@ -255,8 +307,14 @@ func (p *BlockChain) validateGenesisBlock(block *Block) error {
 	return nil
 }
 
-// ValidateTransaction validates transaction
+// ValidateTransaction validates transaction.
+// Inputs may spend committed UTXOs or outputs of unconfirmed mempool txs, timelocks are checked against the next block.
 func (p *BlockChain) ValidateTransaction(tx *Transaction) error {
+	return p.validateTransaction(tx, nil, true, p.nextSpendContext())
+}
+
+// validateTransaction validates transaction resolving inputs via resolveInputUtxo
+func (p *BlockChain) validateTransaction(tx *Transaction, pending map[string]*UTXO, useMempool bool, ctx spendContext) error {
 	// Validate transaction hash
 	if err := ValidateTxHash(tx); err != nil {
 		return err
@@ -279,25 +337,30 @@ func (p *BlockChain) ValidateTransaction(tx *Transaction) error {
 		return fmt.Errorf("data too long: %d bytes > max %d bytes", len(tx.Data), maxDataSize)
 	}
 
+	// Validate output timelock fields
+	if err := validateOutputLocks(tx); err != nil {
+		return err
+	}
+
+	// Evidence transaction (spends the offender's stake without signatures) - validated separately
+	if tx.Evidence != nil {
+		return p.validateEvidenceTx(tx, useMempool, ctx)
+	}
+
 	// Coinbase transaction (no inputs) - validated separately
 	if len(tx.Inputs) == 0 {
 		return p.ValidateCoinbaseTx(tx)
 	}
 
-	// Validate Input/Output balance
-	var inputSum, outputSum uint64
+	// Validate Input/Output balance (claimed reward counts as input)
+	inputSum, outputSum := tx.Claim, uint64(0)
 
-	for _, input := range tx.Inputs {
+	inputUtxos := make([]*UTXO, len(tx.Inputs))
+	for i, input := range tx.Inputs {
 		// UTXO 존재 여부 확인
-		utxoKey := utils.GetUtxoKey(input.TxID, int(input.OutputIndex))
-		utxoBytes, err := p.db.Get(utxoKey, nil)
+		utxo, err := p.resolveInputUtxo(input, pending, useMempool)
 		if err != nil {
-			return fmt.Errorf("UTXO not found: %s:%d", utils.HashToString(input.TxID), input.OutputIndex)
-		}
-
-		var utxo UTXO
-		if err := utils.DeserializeData(utxoBytes, &utxo, utils.SerializationFormatGob); err != nil {
-			return fmt.Errorf("failed to deserialize UTXO: %w", err)
+			return err
 		}
 
 		// 이미 사용된 UTXO인지 확인
@@ -305,10 +368,22 @@ func (p *BlockChain) ValidateTransaction(tx *Transaction) error {
 			return fmt.Errorf("UTXO already spent: %s:%d", utils.HashToString(input.TxID), input.OutputIndex)
 		}
 
+		// 타임락 / HTLC 만료 높이 확인
+		if err := checkInputLock(input, utxo, ctx); err != nil {
+			return err
+		}
+
+		if inputSum+utxo.TxOut.Amount < inputSum {
+			return fmt.Errorf("input[%d]: total input amount overflows", i)
+		}
 		inputSum += utxo.TxOut.Amount
+		inputUtxos[i] = utxo
 	}
 
-	for _, output := range tx.Outputs {
+	for i, output := range tx.Outputs {
+		if outputSum+output.Amount < outputSum {
+			return fmt.Errorf("output[%d]: total output amount overflows", i)
+		}
 		outputSum += output.Amount
 	}
 
@@ -324,9 +399,26 @@ func (p *BlockChain) ValidateTransaction(tx *Transaction) error {
 		return fmt.Errorf("fee too low: got %d, minimum required %d", implicitFee, minFee)
 	}
 
+	// Staking / unstaking rules
+	if err := p.validateStakingTx(tx, inputUtxos); err != nil {
+		return err
+	}
+
+	// Delegation reward claim
+	if err := p.validateRewardClaim(tx, inputUtxos, useMempool); err != nil {
+		return err
+	}
+
+	// Release of a validator jailed for downtime
+	if err := p.validateUnjail(tx, inputUtxos, useMempool, ctx); err != nil {
+		return err
+	}
+
 	// Verify signature
-	if err := p.ValidateAllTxSignatures(tx); err != nil {
-		return fmt.Errorf("signature validation failed: %w", err)
+	for i, input := range tx.Inputs {
+		if err := ValidateTxInputSignature(tx, input, inputUtxos[i]); err != nil {
+			return fmt.Errorf("signature validation failed for input %d: %w", i, err)
+		}
 	}
 
 	return nil
@@ -344,6 +436,11 @@ func (p *BlockChain) ValidateCoinbaseTx(tx *Transaction) error {
 		return fmt.Errorf("coinbase tx must have at least one output")
 	}
 
+	// Rewards are claimed and validators unjailed only by signed txs
+	if tx.Claim > 0 || tx.Unjail {
+		return fmt.Errorf("coinbase tx cannot claim rewards or unjail")
+	}
+
 	// Total output amount must be positive
 	var totalOutput uint64
 	for _, output := range tx.Outputs {
@@ -359,15 +456,15 @@ func (p *BlockChain) ValidateCoinbaseTx(tx *Transaction) error {
 
 // CalculateTxFee calculates implicit fee of transaction
 func (p *BlockChain) CalculateTxFee(tx *Transaction) (uint64, error) {
-	// Coinbase TX has no fee
-	if len(tx.Inputs) == 0 {
+	// Coinbase TX has no fee, stake slashed by evidence TX is burnt
+	if len(tx.Inputs) == 0 || tx.Evidence != nil {
 		return 0, nil
 	}
 
-	var inputSum, outputSum uint64
+	inputSum, outputSum := tx.Claim, uint64(0)
 
 	for _, input := range tx.Inputs {
-		utxo, err := p.GetUtxoByTxIdAndIdx(input.TxID, input.OutputIndex)
+		utxo, err := p.resolveInputUtxo(input, nil, true)
 		if err != nil {
 			return 0, fmt.Errorf("failed to get UTXO: %w", err)
 		}
@@ -385,6 +482,49 @@ func (p *BlockChain) CalculateTxFee(tx *Transaction) (uint64, error) {
 	return inputSum - outputSum, nil
 }
 
+// blockTxFee calculates fee of tx whose inputs may spend outputs of earlier txs in the block (pending)
+func (p *BlockChain) blockTxFee(tx *Transaction, pending map[string]*UTXO) (uint64, error) {
+	if len(tx.Inputs) == 0 || tx.Evidence != nil {
+		return 0, nil
+	}
+
+	inputSum, outputSum := tx.Claim, uint64(0)
+	for _, input := range tx.Inputs {
+		utxo, err := p.resolveInputUtxo(input, pending, false)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get UTXO: %w", err)
+		}
+		inputSum += utxo.TxOut.Amount
+	}
+	for _, output := range tx.Outputs {
+		outputSum += output.Amount
+	}
+
+	if inputSum < outputSum {
+		return 0, fmt.Errorf("invalid tx: inputSum < outputSum")
+	}
+	return inputSum - outputSum, nil
+}
+
+// validateCoinbaseReward validates that coinbase txs pay at most the proposer's share of block reward + fees
+func (p *BlockChain) validateCoinbaseReward(block *Block, epoch *Epoch, fees uint64) error {
+	var paid uint64
+	for _, tx := range block.Transactions {
+		if len(tx.Inputs) > 0 || tx.Evidence != nil {
+			continue
+		}
+		for _, output := range tx.Outputs {
+			paid += output.Amount
+		}
+	}
+
+	proposerReward, _ := splitReward(epoch, block.Proposer, p.GetBlockReward()+fees)
+	if paid > proposerReward {
+		return fmt.Errorf("coinbase pays %d, more than proposer reward %d", paid, proposerReward)
+	}
+	return nil
+}
+
 // ValidateTxHash validates transaction hash
 // TX ID is calculated before signing, so hash calculation for verification must also exclude signature
 func ValidateTxHash(tx *Transaction) error {
@@ -404,9 +544,12 @@ func ValidateTxHash(tx *Transaction) error {
 
 	// Backup and remove signatures temporarily (TX ID is calculated before signing)
 	savedSignatures := make([]prt.Signature, len(tx.Inputs))
+	savedMultiSigs := make([][]prt.Signature, len(tx.Inputs))
 	for i, input := range tx.Inputs {
 		savedSignatures[i] = input.Signature
+		savedMultiSigs[i] = input.Signatures
 		input.Signature = prt.Signature{}
+		input.Signatures = nil
 	}
 
 	calculatedHash := utils.Hash(tx)
@@ -414,6 +557,7 @@ func ValidateTxHash(tx *Transaction) error {
 	// Restore signatures and data
 	for i, input := range tx.Inputs {
 		input.Signature = savedSignatures[i]
+		input.Signatures = savedMultiSigs[i]
 	}
 	tx.ID = storedHash
 	tx.Data = savedData
@@ -429,25 +573,50 @@ func ValidateTxHash(tx *Transaction) error {
 
 // ValidateTxInputSignature validates transaction input signature
 func ValidateTxInputSignature(tx *Transaction, input *TxInput, utxo *UTXO) error {
-	// Check if public key matches UTXO owner's address
+	// Hash timelock output: recipient with preimage or refund address
+	if utxo.TxOut.HTLC != nil {
+		return validateHTLCInput(tx, input, utxo)
+	}
+	if len(input.Preimage) > 0 {
+		return fmt.Errorf("preimage given for non HTLC output")
+	}
+
+	// m-of-n multisig input
+	if input.MultiSig != nil {
+		return validateMultisigInput(tx, input, utxo)
+	}
+	if len(input.Signatures) > 0 {
+		return fmt.Errorf("multisig signatures without multisig script")
+	}
+
+	signer, err := verifyInputSigner(tx, input)
+	if err != nil {
+		return err
+	}
+
+	// Check if address matches UTXO owner
+	if signer != utxo.TxOut.Address {
+		return fmt.Errorf("public key does not match UTXO owner")
+	}
+
+	return nil
+}
+
+// verifyInputSigner verifies single key signature of input and returns the signer address
+func verifyInputSigner(tx *Transaction, input *TxInput) (prt.Address, error) {
 	if len(input.PublicKey) == 0 {
-		return fmt.Errorf("public key is empty")
+		return prt.Address{}, fmt.Errorf("public key is empty")
 	}
 
 	// Derive address from public key
 	publicKey, err := crypto.BytesToPublicKey(input.PublicKey)
 	if err != nil {
-		return fmt.Errorf("failed to parse public key: %w", err)
+		return prt.Address{}, fmt.Errorf("failed to parse public key: %w", err)
 	}
 
 	derivedAddress, err := crypto.PublicKeyToAddress(publicKey)
 	if err != nil {
-		return fmt.Errorf("failed to derive address: %w", err)
-	}
-
-	// Check if address matches UTXO owner
-	if derivedAddress != utxo.TxOut.Address {
-		return fmt.Errorf("public key does not match UTXO owner")
+		return prt.Address{}, fmt.Errorf("failed to derive address: %w", err)
 	}
 
 	// Create data for signature verification (Use stored TX ID)
@@ -458,34 +627,28 @@ func ValidateTxInputSignature(tx *Transaction, input *TxInput, utxo *UTXO) error
 	valid := crypto.VerifySignature(publicKey, txHashBytes, input.Signature)
 	if !valid {
 		fmt.Printf("[DEBUG-VAL] Invalid signature for input %s:%d\n", utils.HashToString(input.TxID), input.OutputIndex)
-		return fmt.Errorf("invalid signature")
+		return prt.Address{}, fmt.Errorf("invalid signature")
 	}
 
-	return nil
+	return derivedAddress, nil
 }
 
 // ValidateAllTxSignatures validates all input signatures of transaction
 func (p *BlockChain) ValidateAllTxSignatures(tx *Transaction) error {
-	// Skip signature verification for genesis transaction
-	if len(tx.Inputs) == 0 {
+	// Skip signature verification for genesis transaction (and evidence transaction, authorized by the evidence)
+	if len(tx.Inputs) == 0 || tx.Evidence != nil {
 		return nil
 	}
 
 	for i, input := range tx.Inputs {
-		// Get UTXO
-		utxoKey := utils.GetUtxoKey(input.TxID, int(input.OutputIndex))
-		utxoBytes, err := p.db.Get(utxoKey, nil)
+		// Get UTXO (committed or unconfirmed mempool output)
+		utxo, err := p.resolveInputUtxo(input, nil, true)
 		if err != nil {
 			return fmt.Errorf("UTXO not found for input %d: %w", i, err)
 		}
 
-		var utxo UTXO
-		if err := utils.DeserializeData(utxoBytes, &utxo, utils.SerializationFormatGob); err != nil {
-			return fmt.Errorf("failed to deserialize UTXO: %w", err)
-		}
-
 		// Verify signature
-		if err := ValidateTxInputSignature(tx, input, &utxo); err != nil {
+		if err := ValidateTxInputSignature(tx, input, utxo); err != nil {
 			return fmt.Errorf("signature validation failed for input %d: %w", i, err)
 		}
 	}
@@ -124,10 +124,11 @@ type PeerInfo struct {
 	Port    int    `json:"port"`
 }
 
-// ProposalPayload block proposal payload
+// ProposalPayload block proposal payload (signature: core.ProposalSignHash by the proposer)
 type ProposalPayload struct {
 	Height     uint64        `json:"height"`
 	Round      uint32        `json:"round"`
+	POLRound   int32         `json:"polRound"` // Round of the POL of a re-proposed block (-1 for a new block)
 	BlockHash  prt.Hash      `json:"blockHash"`
 	BlockData  []byte        `json:"blockData"`
 	ProposerID string        `json:"proposerId"`
@@ -23,17 +23,17 @@ type P2PService struct {
 	// Message handler
 	blockHandler     func(*core.Block)
 	txHandler       func(*core.Transaction)
-	proposalHandler func(height uint64, round uint32, blockHash prt.Hash, block *core.Block)
+	proposalHandler func(height uint64, round uint32, polRound int32, blockHash prt.Hash, block *core.Block, proposer prt.Address, signature prt.Signature)
 	voteHandler     func(height uint64, round uint32, voteType uint8, blockHash prt.Hash, voterID prt.Address, signature prt.Signature)
 
 	// Message deduplication cache (prevent infinite relay)
 	seenMessages   map[string]time.Time
 	seenMessagesMu sync.RWMutex
 
-	// Proposal/Vote deduplication (based on height:round:proposer)
-	seenProposals   map[string]time.Time // key: "height:round:proposer"
+	// Proposal/Vote deduplication (block hash included so that conflicting messages reach double-sign detection)
+	seenProposals   map[string]time.Time // key: "height:round:proposer:blockHash"
 	seenProposalsMu sync.RWMutex
-	seenVotes       map[string]time.Time // key: "height:round:voteType:voter"
+	seenVotes       map[string]time.Time // key: "height:round:voteType:voter:blockHash"
 	seenVotesMu     sync.RWMutex
 
 	// Track connecting peer IDs (prevent duplicate connections)
@@ -159,7 +159,7 @@ func (s *P2PService) SetTxHandler(handler func(*core.Transaction)) {
 }
 
 // SetProposalHandler sets block proposal reception handler
-func (s *P2PService) SetProposalHandler(handler func(height uint64, round uint32, blockHash prt.Hash, block *core.Block)) {
+func (s *P2PService) SetProposalHandler(handler func(height uint64, round uint32, polRound int32, blockHash prt.Hash, block *core.Block, proposer prt.Address, signature prt.Signature)) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.proposalHandler = handler
@@ -357,8 +357,8 @@ func (s *P2PService) handleProposal(msg *Message, peer *Peer) {
 		return
 	}
 
-	// Check duplicate Proposal (process same height:round:proposer only once)
-	proposalKey := fmt.Sprintf("%d:%d:%s", payload.Height, payload.Round, payload.ProposerID)
+	// Check duplicate Proposal (process same height:round:proposer:blockHash only once)
+	proposalKey := fmt.Sprintf("%d:%d:%s:%s", payload.Height, payload.Round, payload.ProposerID, utils.HashToString(payload.BlockHash))
 	if s.hasSeenProposal(proposalKey) {
 		logger.Debug("[P2P] Duplicate proposal ignored: ", proposalKey)
 		return
@@ -372,18 +372,25 @@ func (s *P2PService) handleProposal(msg *Message, peer *Peer) {
 		return
 	}
 
-	logger.Info("[P2P] Received proposal - height: ", payload.Height, ", round: ", payload.Round, ", proposer: ", payload.ProposerID[:16])
+	logger.Info("[P2P] Received proposal - height: ", payload.Height, ", round: ", payload.Round, ", polRound: ", payload.POLRound, ", proposer: ", payload.ProposerID[:16])
 
 	// Relay to other peers (exclude sender)
 	s.relayMessage(msg, peer)
 
+	// Convert ProposerID to Address
+	proposerAddr, err := utils.StringToAddress(payload.ProposerID)
+	if err != nil {
+		logger.Error("[P2P] Failed to parse proposer address: ", err)
+		return
+	}
+
 	// Call proposal handler
 	s.mu.RLock()
 	handler := s.proposalHandler
 	s.mu.RUnlock()
 
 	if handler != nil {
-		handler(payload.Height, payload.Round, payload.BlockHash, &block)
+		handler(payload.Height, payload.Round, payload.POLRound, payload.BlockHash, &block, proposerAddr, payload.Signature)
 	} else {
 		logger.Warn("[P2P] Proposal handler not set!")
 	}
@@ -399,8 +406,8 @@ func (s *P2PService) handleVote(msg *Message, peer *Peer) {
 		return
 	}
 
-	// Check duplicate Vote (process same height:round:voteType:voter only once)
-	voteKey := fmt.Sprintf("%d:%d:%d:%s", payload.Height, payload.Round, payload.VoteType, payload.VoterID)
+	// Check duplicate Vote (process same height:round:voteType:voter:blockHash only once)
+	voteKey := fmt.Sprintf("%d:%d:%d:%s:%s", payload.Height, payload.Round, payload.VoteType, payload.VoterID, utils.HashToString(payload.BlockHash))
 	if s.hasSeenVote(voteKey) {
 		logger.Debug("[P2P] Duplicate vote ignored: ", voteKey)
 		return
@@ -862,7 +869,7 @@ type BlocksPayload struct {
 }
 
 // BroadcastProposal broadcasts block proposal (for consensus)
-func (s *P2PService) BroadcastProposal(height uint64, round uint32, blockHash prt.Hash, block *core.Block, proposerID string, signature prt.Signature) error {
+func (s *P2PService) BroadcastProposal(height uint64, round uint32, polRound int32, blockHash prt.Hash, block *core.Block, proposerID string, signature prt.Signature) error {
 	// Serialize block
 	blockData, err := utils.SerializeData(block, utils.SerializationFormatGob)
 	if err != nil {
@@ -872,6 +879,7 @@ func (s *P2PService) BroadcastProposal(height uint64, round uint32, blockHash pr
 	payload := ProposalPayload{
 		Height:     height,
 		Round:      round,
+		POLRound:   polRound,
 		BlockHash:  blockHash,
 		BlockData:  blockData,
 		ProposerID: proposerID,
@@ -1,7 +1,8 @@
 package protocol
 
 const (
-	MaxTxsPerBlock = 100
+	MaxTxsPerBlock = 100             // Including coinbase tx
+	MaxBlockSize   = 1 * 1024 * 1024 // Max sum of serialized tx sizes in a block (bytes)
 )
 
 const (
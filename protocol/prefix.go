@@ -5,14 +5,20 @@ const (
 	PrefixNetworkConfig = "net:config"
 
 	// Metadata related prefixes
-	PrefixMeta          = "meta:"       // Metadata key
-	PrefixMetaHeight    = "meta:height" // Latest block height
-	PrefixMetaBlockHash = "meta:hash"   // Latest block hash
+	PrefixMeta          = "meta:"        // Metadata key
+	PrefixMetaHeight    = "meta:height"  // Latest block height
+	PrefixMetaBlockHash = "meta:hash"    // Latest block hash
+	PrefixMetaAddrIndex = "meta:addrix"  // Address history index version
+	PrefixMetaMempool   = "meta:mempool" // Mempool snapshot (pending txs kept across restarts)
+	PrefixMetaStakeIx   = "meta:stakeix" // Stake index version
+	PrefixMetaEpochIx   = "meta:epochix" // Epoch history version
+	PrefixMetaReorg     = "meta:reorg"   // Journal of a running reorg (original main chain, removed when done)
 
 	// Block related prefixes
-	PrefixBlock         = "blk:"     // blk:Hash = Block data
-	PrefixBlockByHeight = "blk:h:"   // blk:h:Height = Block hash
-	PrefixBlockTxs      = "blk:txs:" // blk:txs:BlockHash:Index = Transaction hash
+	PrefixBlock         = "blk:"      // blk:Hash = Block data
+	PrefixBlockByHeight = "blk:h:"    // blk:h:Height = Block hash
+	PrefixBlockTxs      = "blk:txs:"  // blk:txs:BlockHash:Index = Transaction hash
+	PrefixSideBlock     = "side:blk:" // side:blk:Hash = Side-chain (non-canonical) block data
 
 	// Transaction related prefixes
 	PrefixTxs      = "tx:"        // tx:TxHash = Transaction data
@@ -31,13 +37,26 @@ const (
 	PrefixUtxo        = "utxo:"      // utxo:TxHash:Index = UTXO data
 	PrefixUtxoList    = "utxo:addr:" // utxo:addr:Address = UTXO key array
 	PrefixUtxoBalance = "utxo:bal:"  // utxo:bal:Address = Balance
+	PrefixUtxoUndo    = "utxo:undo:" // utxo:undo:BlockHash = Block undo record (spent / created UTXOs)
 
 	// Account related prefixes
 	PrefixAddress         = "addr:"      // addr:AccountAddress = Account data
 	PrefixAddressTxs      = "addr:txs:"  // addr:txs:AccountAddress = Transaction hash json-array
 	PrefixAddressReceived = "addr:recv:" // addr:recv:AccountAddress:Index = []{TxHash: index} (Received)
 	PrefixAddressSent     = "addr:sent:" // addr:sent:AccountAddress:Index = []TxHash (Sent)
+	PrefixAddressHistory  = "addr:hist:" // addr:hist:AccountAddress:Height:TxIndex = Address tx entry (sent / received, net amount)
 
-	// Consensus related prefixes
-	PrefixStakerInfo = "staker:" // Wallet address - Staking info
+	// Staking related prefixes (derived from committed staking txs)
+	PrefixStake     = "stake:"      // stake:Address:TxHash:Index = UTXO key of unspent staked output
+	PrefixStakerKey = "staker:key:" // staker:key:Address = Public key of staker (validator identity)
+	PrefixUnbonding = "unbond:"     // unbond:Address:TxHash:Index = UTXO key of unspent unbonding output
+	PrefixEpoch     = "epoch:"      // epoch:StartHeight = Validator set of the epoch starting at height
+
+	// Delegation related prefixes (derived from committed delegation txs and block rewards)
+	PrefixDelegation = "delegation:" // delegation:Validator:Delegator:TxHash:Index = UTXO key of unspent delegated output
+	PrefixReward     = "reward:"     // reward:Address = Claimable delegation reward balance
+
+	// Slashing related prefixes (derived from committed evidence / unjail txs and block commit signatures)
+	PrefixJail   = "jail:"   // jail:Address = Jail record of validator
+	PrefixUptime = "uptime:" // uptime:Address = Signed / missed heights of validator within the window
 )